@@ -0,0 +1,213 @@
+// ABOUTME: Approval gate that parks workflow tasks until a human approves or rejects them
+// ABOUTME: Decisions arrive from an interactive prompter or from the webhook server API
+
+package approval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Decision sources recorded alongside each decision
+const (
+	SourcePrompt  = "prompt"
+	SourceAPI     = "api"
+	SourceTimeout = "timeout"
+)
+
+var (
+	// ErrNotPending is returned when resolving an approval nobody is waiting for
+	ErrNotPending = errors.New("no pending approval")
+	// ErrNotAuthorized is returned when the approver is not in the task's approver list
+	ErrNotAuthorized = errors.New("approver is not allowed to resolve this approval")
+)
+
+// Request describes an approval a task is waiting for
+type Request struct {
+	ExecutionID string     `json:"execution_id"`
+	TaskID      string     `json:"task_id"`
+	TaskName    string     `json:"task_name"`
+	Message     string     `json:"message,omitempty"`
+	Approvers   []string   `json:"approvers,omitempty"`
+	RequestedAt time.Time  `json:"requested_at"`
+	Deadline    *time.Time `json:"deadline,omitempty"`
+}
+
+// Decision is the outcome of an approval request
+type Decision struct {
+	Approved  bool      `json:"approved"`
+	Approver  string    `json:"approver,omitempty"`
+	Comment   string    `json:"comment,omitempty"`
+	Source    string    `json:"source"`
+	DecidedAt time.Time `json:"decided_at"`
+}
+
+// Prompter asks a human for a decision, typically on a terminal. Prompt must
+// return promptly once ctx is cancelled, which happens when the request is
+// resolved some other way.
+type Prompter interface {
+	Prompt(ctx context.Context, req Request) (Decision, error)
+}
+
+// Gate tracks pending approvals and delivers decisions to the waiting tasks
+type Gate struct {
+	mu       sync.Mutex
+	pending  map[string]*pendingApproval
+	prompter Prompter
+	remote   bool
+}
+
+type pendingApproval struct {
+	request  Request
+	decision chan Decision
+}
+
+// NewGate creates an approval gate with no prompter and remote resolution disabled
+func NewGate() *Gate {
+	return &Gate{
+		pending: make(map[string]*pendingApproval),
+	}
+}
+
+// SetPrompter installs an interactive prompter used for every new request
+func (g *Gate) SetPrompter(prompter Prompter) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.prompter = prompter
+}
+
+// EnableRemote records that decisions can be delivered through Resolve by an API
+func (g *Gate) EnableRemote() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.remote = true
+}
+
+// CanResolve reports whether anything is able to answer a request
+func (g *Gate) CanResolve() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.prompter != nil || g.remote
+}
+
+// Wait registers the request and blocks until it is resolved or ctx is done
+func (g *Gate) Wait(ctx context.Context, req Request) (Decision, error) {
+	key := pendingKey(req.ExecutionID, req.TaskID)
+	if req.RequestedAt.IsZero() {
+		req.RequestedAt = time.Now()
+	}
+
+	g.mu.Lock()
+	if _, exists := g.pending[key]; exists {
+		g.mu.Unlock()
+		return Decision{}, fmt.Errorf("approval for task '%s' is already pending", req.TaskID)
+	}
+	p := &pendingApproval{request: req, decision: make(chan Decision, 1)}
+	g.pending[key] = p
+	prompter := g.prompter
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.pending, key)
+		g.mu.Unlock()
+	}()
+
+	promptCtx, cancelPrompt := context.WithCancel(ctx)
+	defer cancelPrompt()
+
+	if prompter != nil {
+		go func() {
+			decision, err := prompter.Prompt(promptCtx, req)
+			if err != nil {
+				return
+			}
+			decision.Source = SourcePrompt
+			_ = g.Resolve(req.ExecutionID, req.TaskID, decision)
+		}()
+	}
+
+	select {
+	case decision := <-p.decision:
+		return decision, nil
+	case <-ctx.Done():
+		return Decision{}, ctx.Err()
+	}
+}
+
+// Resolve delivers a decision to the task waiting on the given execution and task.
+// Only the first decision for a request is accepted.
+func (g *Gate) Resolve(executionID, taskID string, decision Decision) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	p, exists := g.pending[pendingKey(executionID, taskID)]
+	if !exists {
+		return fmt.Errorf("%w for task '%s' in execution '%s'", ErrNotPending, taskID, executionID)
+	}
+
+	if len(p.request.Approvers) > 0 && !contains(p.request.Approvers, decision.Approver) {
+		return fmt.Errorf("%w: '%s'", ErrNotAuthorized, decision.Approver)
+	}
+
+	if decision.DecidedAt.IsZero() {
+		decision.DecidedAt = time.Now()
+	}
+
+	select {
+	case p.decision <- decision:
+		return nil
+	default:
+		return fmt.Errorf("%w for task '%s' in execution '%s': already resolved", ErrNotPending, taskID, executionID)
+	}
+}
+
+// Pending lists the outstanding requests for an execution, or all of them when
+// executionID is empty, ordered by request time
+func (g *Gate) Pending(executionID string) []Request {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var requests []Request
+	for _, p := range g.pending {
+		if executionID == "" || p.request.ExecutionID == executionID {
+			requests = append(requests, p.request)
+		}
+	}
+
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].RequestedAt.Before(requests[j].RequestedAt)
+	})
+	return requests
+}
+
+// gateKey is the context key for the approval gate
+type gateKey struct{}
+
+// WithGate returns a context carrying the approval gate
+func WithGate(ctx context.Context, gate *Gate) context.Context {
+	return context.WithValue(ctx, gateKey{}, gate)
+}
+
+// GateFromContext returns the approval gate carried by ctx, or nil
+func GateFromContext(ctx context.Context) *Gate {
+	gate, _ := ctx.Value(gateKey{}).(*Gate)
+	return gate
+}
+
+func pendingKey(executionID, taskID string) string {
+	return executionID + "/" + taskID
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
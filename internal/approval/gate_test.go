@@ -0,0 +1,197 @@
+// ABOUTME: Tests for the approval gate that parks tasks until a decision arrives
+// ABOUTME: Covers API resolution, approver restrictions, pending listings and prompters
+
+package approval
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitForPending polls until the gate reports a pending request for the execution
+func waitForPending(t *testing.T, gate *Gate, executionID string) []Request {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if pending := gate.Pending(executionID); len(pending) > 0 {
+			return pending
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("no pending approval for execution %s", executionID)
+	return nil
+}
+
+func TestGate_ResolveDeliversDecision(t *testing.T) {
+	gate := NewGate()
+	gate.EnableRemote()
+
+	done := make(chan Decision, 1)
+	go func() {
+		decision, err := gate.Wait(context.Background(), Request{ExecutionID: "exec_1", TaskID: "promote"})
+		if err != nil {
+			t.Errorf("Wait returned error: %v", err)
+		}
+		done <- decision
+	}()
+
+	pending := waitForPending(t, gate, "exec_1")
+	if pending[0].TaskID != "promote" {
+		t.Errorf("Expected pending task 'promote', got %q", pending[0].TaskID)
+	}
+
+	err := gate.Resolve("exec_1", "promote", Decision{Approved: true, Approver: "alice", Comment: "ship it", Source: SourceAPI})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+
+	decision := <-done
+	if !decision.Approved || decision.Approver != "alice" || decision.Comment != "ship it" {
+		t.Errorf("Unexpected decision: %+v", decision)
+	}
+	if decision.DecidedAt.IsZero() {
+		t.Error("Expected DecidedAt to be set")
+	}
+	if len(gate.Pending("exec_1")) != 0 {
+		t.Error("Expected no pending approvals after resolution")
+	}
+}
+
+func TestGate_ResolveUnknownApproval(t *testing.T) {
+	gate := NewGate()
+
+	err := gate.Resolve("exec_1", "missing", Decision{Approved: true})
+	if !errors.Is(err, ErrNotPending) {
+		t.Errorf("Expected ErrNotPending, got %v", err)
+	}
+}
+
+func TestGate_ApproverRestriction(t *testing.T) {
+	gate := NewGate()
+	gate.EnableRemote()
+
+	done := make(chan Decision, 1)
+	go func() {
+		decision, _ := gate.Wait(context.Background(), Request{
+			ExecutionID: "exec_1",
+			TaskID:      "promote",
+			Approvers:   []string{"alice"},
+		})
+		done <- decision
+	}()
+
+	waitForPending(t, gate, "exec_1")
+
+	err := gate.Resolve("exec_1", "promote", Decision{Approved: true, Approver: "mallory"})
+	if !errors.Is(err, ErrNotAuthorized) {
+		t.Fatalf("Expected ErrNotAuthorized, got %v", err)
+	}
+
+	if err := gate.Resolve("exec_1", "promote", Decision{Approved: false, Approver: "alice"}); err != nil {
+		t.Fatalf("Resolve by allowed approver failed: %v", err)
+	}
+
+	if decision := <-done; decision.Approved || decision.Approver != "alice" {
+		t.Errorf("Unexpected decision: %+v", decision)
+	}
+}
+
+func TestGate_WaitHonoursContext(t *testing.T) {
+	gate := NewGate()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := gate.Wait(ctx, Request{ExecutionID: "exec_1", TaskID: "promote"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected DeadlineExceeded, got %v", err)
+	}
+	if len(gate.Pending("")) != 0 {
+		t.Error("Expected pending approval to be removed after timeout")
+	}
+}
+
+func TestGate_PendingFiltersByExecution(t *testing.T) {
+	gate := NewGate()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() { _, _ = gate.Wait(ctx, Request{ExecutionID: "exec_1", TaskID: "a"}) }()
+	go func() { _, _ = gate.Wait(ctx, Request{ExecutionID: "exec_2", TaskID: "b"}) }()
+
+	waitForPending(t, gate, "exec_1")
+	waitForPending(t, gate, "exec_2")
+
+	if pending := gate.Pending("exec_2"); len(pending) != 1 || pending[0].TaskID != "b" {
+		t.Errorf("Expected only task 'b' for exec_2, got %+v", pending)
+	}
+	if pending := gate.Pending(""); len(pending) != 2 {
+		t.Errorf("Expected 2 pending approvals overall, got %d", len(pending))
+	}
+}
+
+func TestGate_CanResolve(t *testing.T) {
+	gate := NewGate()
+	if gate.CanResolve() {
+		t.Error("Expected a bare gate to be unable to resolve approvals")
+	}
+
+	gate.EnableRemote()
+	if !gate.CanResolve() {
+		t.Error("Expected remote-enabled gate to resolve approvals")
+	}
+}
+
+func TestGate_TerminalPrompter(t *testing.T) {
+	gate := NewGate()
+	var out strings.Builder
+	gate.SetPrompter(NewTerminalPrompter(strings.NewReader("yes\nlooks good\n"), &out))
+
+	decision, err := gate.Wait(context.Background(), Request{
+		ExecutionID: "exec_1",
+		TaskID:      "promote",
+		TaskName:    "Promote to production",
+		Message:     "Promote build 42?",
+	})
+	if err != nil {
+		t.Fatalf("Wait returned error: %v", err)
+	}
+
+	if !decision.Approved {
+		t.Error("Expected approval from 'yes' answer")
+	}
+	if decision.Comment != "looks good" {
+		t.Errorf("Expected comment 'looks good', got %q", decision.Comment)
+	}
+	if decision.Source != SourcePrompt {
+		t.Errorf("Expected source %q, got %q", SourcePrompt, decision.Source)
+	}
+	if !strings.Contains(out.String(), "Promote build 42?") {
+		t.Errorf("Expected prompt to include the message, got %q", out.String())
+	}
+}
+
+func TestTerminalPrompter_RefusesUnlistedUser(t *testing.T) {
+	t.Setenv("USER", "mallory")
+	var out strings.Builder
+	prompter := NewTerminalPrompter(strings.NewReader("yes\n"), &out)
+
+	_, err := prompter.Prompt(context.Background(), Request{
+		ExecutionID: "exec_1",
+		TaskID:      "promote",
+		TaskName:    "Promote to production",
+		Approvers:   []string{"alice", "bob"},
+	})
+	if !errors.Is(err, ErrNotAuthorized) {
+		t.Fatalf("Expected ErrNotAuthorized, got %v", err)
+	}
+	if !strings.Contains(out.String(), "mallory may not resolve this approval; it is restricted to alice, bob") {
+		t.Errorf("Expected a rejection message, got %q", out.String())
+	}
+	if strings.Contains(out.String(), "Approve?") {
+		t.Errorf("Expected no question for an unlisted user, got %q", out.String())
+	}
+}
@@ -0,0 +1,121 @@
+// ABOUTME: Terminal prompter that asks the operator to approve or reject a gated task
+// ABOUTME: Serialises prompts so concurrent approvals do not interleave on the terminal
+
+package approval
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// TerminalPrompter asks for approval decisions on an interactive terminal
+type TerminalPrompter struct {
+	mu       sync.Mutex
+	out      io.Writer
+	approver string
+	lines    chan string
+}
+
+// NewTerminalPrompter creates a prompter reading answers from in and writing
+// prompts to out. Decisions are attributed to the current OS user.
+func NewTerminalPrompter(in io.Reader, out io.Writer) *TerminalPrompter {
+	approver := os.Getenv("USER")
+	if approver == "" {
+		approver = os.Getenv("USERNAME")
+	}
+
+	// A single reader goroutine feeds every prompt, so a prompt abandoned
+	// because the approval was resolved elsewhere never loses the next answer
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	return &TerminalPrompter{out: out, approver: approver, lines: lines}
+}
+
+// IsTerminal reports whether f is attached to an interactive terminal
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Prompt asks the operator to approve the request
+func (t *TerminalPrompter) Prompt(ctx context.Context, req Request) (Decision, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// The request may have been resolved while waiting for the terminal
+	if err := ctx.Err(); err != nil {
+		return Decision{}, err
+	}
+
+	_, _ = fmt.Fprintf(t.out, "\n⏸️  Approval required: %s (%s)\n", req.TaskName, req.TaskID)
+	if req.Message != "" {
+		_, _ = fmt.Fprintf(t.out, "   %s\n", req.Message)
+	}
+	if req.Deadline != nil {
+		_, _ = fmt.Fprintf(t.out, "   Respond before %s\n", req.Deadline.Format("15:04:05"))
+	}
+	// Asking someone whose answer the gate would refuse leaves them waiting
+	// on a task that never moves
+	if len(req.Approvers) > 0 && !contains(req.Approvers, t.approver) {
+		_, _ = fmt.Fprintf(t.out, "   ❌ %s may not resolve this approval; it is restricted to %s\n",
+			approverOrUnknown(t.approver), strings.Join(req.Approvers, ", "))
+		return Decision{}, fmt.Errorf("%w: '%s'", ErrNotAuthorized, t.approver)
+	}
+
+	_, _ = fmt.Fprint(t.out, "Approve? [y/N]: ")
+
+	answer, err := t.readLine(ctx)
+	if err != nil {
+		return Decision{}, err
+	}
+
+	_, _ = fmt.Fprint(t.out, "Comment (optional): ")
+	comment, err := t.readLine(ctx)
+	if err != nil {
+		return Decision{}, err
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return Decision{
+		Approved: answer == "y" || answer == "yes",
+		Approver: t.approver,
+		Comment:  strings.TrimSpace(comment),
+	}, nil
+}
+
+// approverOrUnknown names the local user in messages
+func approverOrUnknown(approver string) string {
+	if approver == "" {
+		return "The current user"
+	}
+	return approver
+}
+
+// readLine waits for the next line of input or for ctx to be cancelled
+func (t *TerminalPrompter) readLine(ctx context.Context) (string, error) {
+	select {
+	case line, ok := <-t.lines:
+		if !ok {
+			return "", io.EOF
+		}
+		return line, nil
+	case <-ctx.Done():
+		_, _ = fmt.Fprintln(t.out, "\n   (resolved elsewhere)")
+		return "", ctx.Err()
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/sarlalian/ritual/internal/orchestrator"
+	"github.com/sarlalian/ritual/internal/redact"
 	"github.com/sarlalian/ritual/pkg/types"
 )
 
@@ -47,11 +48,22 @@ Examples:
 }
 
 func dryRunWorkflow(cmd *cobra.Command, args []string) error {
-	workflowPath := args[0]
+	workflowPath := workflowRef(args[0])
+
+	reports, err := parseReportSpecs()
+	if err != nil {
+		return err
+	}
 
 	// Get logger from global state
 	logger := GetLogger()
 
+	redactor := redact.New(redact.DefaultPatterns)
+	inputValues, err := collectInputs(redactor)
+	if err != nil {
+		return err
+	}
+
 	// Create orchestrator configuration with dry-run enabled
 	orchConfig := &orchestrator.Config{
 		DryRun:         true,
@@ -59,6 +71,10 @@ func dryRunWorkflow(cmd *cobra.Command, args []string) error {
 		Logger:         logger,
 		Verbose:        verboseMode,
 		HistoryDir:     historyDir,
+		Redactor:       redactor,
+		KeyFile:        runKeyFile,
+		PassphraseEnv:  runPassphraseEnv,
+		Environment:    runEnvironment,
 	}
 
 	// Create orchestrator
@@ -77,16 +93,19 @@ func dryRunWorkflow(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Add command-line variables
+	// Command-line variables are also set in the environment, as they were
+	// before workflows declared inputs
 	envVars = append(envVars, runVariables...)
 
 	// Execute workflow in dry-run mode
-	ctx := context.Background()
+	ctx := types.WithInputs(context.Background(), inputValues)
 	result, err := orch.ExecuteWorkflowFile(ctx, workflowPath, envVars)
 	if err != nil {
 		return fmt.Errorf("failed to execute dry-run: %w", err)
 	}
 
+	writeReportsOrWarn(reports, result, workflowPath)
+
 	// Display results based on format
 	switch dryRunFormat {
 	case "json":
@@ -138,5 +157,11 @@ func init() {
 
 	dryRunCmd.Flags().StringVar(&dryRunFormat, "format", "text", "output format (text, json)")
 	dryRunCmd.Flags().StringSliceVar(&runVariables, "var", []string{}, "set workflow variables (key=value)")
+	dryRunCmd.Flags().StringSliceVar(&runVarFiles, "var-file", []string{}, "load workflow inputs from a YAML, JSON or .env file")
 	dryRunCmd.Flags().StringVar(&runEnvFile, "env-file", "", "load environment variables from file")
+	dryRunCmd.Flags().StringVar(&runWorkflowName, "workflow", "", "name of the workflow to use from a file holding several")
+	dryRunCmd.Flags().StringVar(&runEnvironment, "environment", "", "layer variables/environments/<name> over the workflow's variable files")
+	dryRunCmd.Flags().StringVar(&runKeyFile, "key-file", "", "read the passphrase for encrypted variable files from this file")
+	dryRunCmd.Flags().StringVar(&runPassphraseEnv, "passphrase-env", "", "read the passphrase for encrypted variable files from this environment variable")
+	addReportFlag(dryRunCmd)
 }
@@ -0,0 +1,127 @@
+// ABOUTME: Lint command running semantic checks over workflow files
+// ABOUTME: Prints findings as text, JSON or SARIF and fails when any reach the --fail-on severity
+
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sarlalian/ritual/internal/lint"
+	"github.com/sarlalian/ritual/internal/tasks"
+)
+
+var (
+	lintFormat      string
+	lintDisabled    []string
+	lintFailOn      string
+	lintEnvironment string
+	lintListRules   bool
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint [workflow.yaml...]",
+	Short: "Check workflows for likely mistakes",
+	Long: `Check workflow files for problems validate does not catch: unknown task
+config keys, variables that are used but never defined or defined but never
+used, conditions that are always false, dependencies on optional tasks
+without a trigger_rule, and unterminated quotes in shell commands.
+
+Each rule has a severity of error, warning or info. Disable rules with
+--disable, or in the workflow with a comment on the line of the key or task
+it applies to, or on the line before it:
+
+  vars:
+    legacy_region: eu-west-1  # ritual-lint disable=unused-variable
+  # ritual-lint disable=shell-quoting,unknown-config-key
+  - name: Build
+
+"# ritual-lint disable-file=<rule>" disables a rule for the whole file.
+
+Examples:
+  ritual lint workflow.yaml
+  ritual lint *.yaml --fail-on warning
+  ritual lint deploy.yaml --environment production
+  ritual lint workflow.yaml --format sarif > ritual.sarif
+  ritual lint --list-rules`,
+	RunE: lintWorkflows,
+}
+
+func lintWorkflows(cmd *cobra.Command, args []string) error {
+	if lintListRules {
+		for _, rule := range lint.Rules() {
+			fmt.Printf("%-20s %-8s %s\n", rule.ID, rule.Severity, rule.Description)
+		}
+		return nil
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("requires at least 1 workflow file")
+	}
+
+	failOn, err := lint.ParseSeverity(lintFailOn)
+	if err != nil && lintFailOn != "none" {
+		return fmt.Errorf("invalid --fail-on: %w", err)
+	}
+	known := make(map[string]bool)
+	for _, rule := range lint.Rules() {
+		known[rule.ID] = true
+	}
+	for _, id := range lintDisabled {
+		if !known[id] {
+			return fmt.Errorf("unknown rule '%s' in --disable (see ritual lint --list-rules)", id)
+		}
+	}
+
+	registry := tasks.New()
+	opts := lint.Options{Disabled: lintDisabled, Environment: lintEnvironment}
+	var findings []lint.Finding
+	for _, path := range args {
+		fileFindings, err := lint.CheckFile(path, registry, opts)
+		if err != nil {
+			return err
+		}
+		findings = append(findings, fileFindings...)
+	}
+
+	switch lintFormat {
+	case "text":
+		err = lint.WriteText(os.Stdout, findings)
+		if err == nil && len(findings) == 0 {
+			fmt.Println("✅ No problems found")
+		}
+	case "json":
+		err = lint.WriteJSON(os.Stdout, findings)
+	case "sarif":
+		err = lint.WriteSARIF(os.Stdout, findings)
+	default:
+		return fmt.Errorf("unknown format: %s", lintFormat)
+	}
+	if err != nil {
+		return err
+	}
+
+	if failOn != "" {
+		failed := 0
+		for _, f := range findings {
+			if f.Severity.AtLeast(failOn) {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("lint found %d problem(s) of severity %s or higher", failed, failOn)
+		}
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+
+	lintCmd.Flags().StringVar(&lintFormat, "format", "text", "output format (text, json, sarif)")
+	lintCmd.Flags().StringSliceVar(&lintDisabled, "disable", nil, "rules to skip")
+	lintCmd.Flags().StringVar(&lintFailOn, "fail-on", "error", "exit with an error when a finding is this severe: error, warning, info or none")
+	lintCmd.Flags().StringVar(&lintEnvironment, "environment", "", "include the variables of variables/environments/<name>")
+	lintCmd.Flags().BoolVar(&lintListRules, "list-rules", false, "list the available rules")
+}
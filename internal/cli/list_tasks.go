@@ -66,6 +66,7 @@ func listTasks(cmd *cobra.Command, args []string) error {
 		"notify":    "Alias for slack task",
 		"debug":     "Log messages for debugging workflows",
 		"log":       "Alias for debug task",
+		"approval":  "Pause a branch until a human approves (terminal prompt or API)",
 	}
 
 	// Group tasks by category
@@ -77,13 +78,14 @@ func listTasks(cmd *cobra.Command, args []string) error {
 		"Remote":        {"ssh", "remote"},
 		"Communication": {"email", "mail", "ses", "aws_email", "slack", "notify"},
 		"Debug":         {"debug", "log"},
+		"Control":       {"approval"},
 	}
 
 	fmt.Println("✨ Available Task Types")
 	fmt.Println()
 
 	// Display by category
-	categoryOrder := []string{"Core", "File Ops", "Compression", "Security", "Remote", "Communication", "Debug"}
+	categoryOrder := []string{"Core", "File Ops", "Compression", "Security", "Remote", "Communication", "Control", "Debug"}
 	for _, category := range categoryOrder {
 		taskList := categories[category]
 		hasTask := false
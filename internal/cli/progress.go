@@ -0,0 +1,47 @@
+// ABOUTME: Live progress renderer for the run command built on execution events
+// ABOUTME: Prints one line per task state change as the workflow runs
+
+package cli
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/sarlalian/ritual/internal/events"
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+// progressRenderer prints task progress as events arrive
+type progressRenderer struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// newProgressRenderer creates a renderer writing to out
+func newProgressRenderer(out io.Writer) *progressRenderer {
+	return &progressRenderer{out: out}
+}
+
+// OnEvent prints a progress line for task state changes
+func (p *progressRenderer) OnEvent(event events.Event) {
+	var line string
+	switch event.Type {
+	case events.TaskStarted:
+		line = fmt.Sprintf("▶️  %s (%s) started", event.TaskName, event.TaskID)
+	case events.TaskSkipped:
+		line = fmt.Sprintf("⏭️  %s (%s) skipped: %s", event.TaskName, event.TaskID, event.Message)
+	case events.TaskFinished:
+		icon := "✅"
+		if event.Status == types.TaskFailed {
+			icon = "❌"
+		}
+		line = fmt.Sprintf("%s %s (%s) %s in %s", icon, event.TaskName, event.TaskID, event.Status, event.Duration)
+	default:
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, _ = fmt.Fprintln(p.out, line)
+}
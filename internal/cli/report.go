@@ -0,0 +1,58 @@
+// ABOUTME: Shared --report flag handling for run, dry-run and validate
+// ABOUTME: Parses format=path specs up front and writes every report from the final result
+
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sarlalian/ritual/internal/report"
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+var reportSpecs []string
+
+// addReportFlag registers --report on a command
+func addReportFlag(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&reportSpecs, "report", []string{},
+		"write a report as format=path (junit, markdown, json; path \"-\" or omitted for stdout), repeatable")
+}
+
+// parseReportSpecs validates the --report values before any work is done
+func parseReportSpecs() ([]report.Spec, error) {
+	specs := make([]report.Spec, 0, len(reportSpecs))
+	for _, value := range reportSpecs {
+		spec, err := report.ParseSpec(value)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// writeReports renders result into every requested report
+func writeReports(specs []report.Spec, result *types.Result, workflowPath string) error {
+	if len(specs) == 0 {
+		return nil
+	}
+
+	r := report.New(result, workflowPath)
+	for _, spec := range specs {
+		if err := spec.WriteTo(r, os.Stdout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeReportsOrWarn writes reports, warning instead of failing so a broken
+// report path does not mask the run's own outcome
+func writeReportsOrWarn(specs []report.Spec, result *types.Result, workflowPath string) {
+	if err := writeReports(specs, result, workflowPath); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+	}
+}
@@ -5,20 +5,42 @@ package cli
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
 
+	"github.com/sarlalian/ritual/internal/approval"
+	contextManager "github.com/sarlalian/ritual/internal/context"
+	"github.com/sarlalian/ritual/internal/metrics"
 	"github.com/sarlalian/ritual/internal/orchestrator"
+	"github.com/sarlalian/ritual/internal/output"
+	"github.com/sarlalian/ritual/internal/redact"
+	"github.com/sarlalian/ritual/internal/tracing"
+	"github.com/sarlalian/ritual/internal/variables"
+	"github.com/sarlalian/ritual/internal/workflow/parser"
 	"github.com/sarlalian/ritual/pkg/types"
 )
 
 var (
-	runMode      string
-	runVariables []string
-	runEnvFile   string
+	runMode          string
+	runVariables     []string
+	runVarFiles      []string
+	runEnvFile       string
+	runEnvironment   string
+	runWorkflowName  string
+	runKeyFile       string
+	runPassphraseEnv string
+	runProgress      bool
+	runOutputJSON    bool
+	runOutputMax     int
+	runOutputDir     string
+	runMetrics       string
+	runTraceFile     string
+	runTraceURL      string
 )
 
 // runCmd represents the run command
@@ -39,17 +61,35 @@ Examples:
   ritual run workflow.yaml
   ritual run workflow.yaml --mode sequential
   ritual run workflow.yaml --var key=value --var env=prod
-  ritual run workflow.yaml --env-file .env.prod`,
+  ritual run workflow.yaml --var-file inputs/prod.yaml
+  ritual run pipelines.yaml#deploy
+  ritual run pipelines.yaml --workflow deploy
+  ritual run workflow.yaml --env-file .env.prod
+  ritual run workflow.yaml --output-json > outputs.json
+  ritual run workflow.yaml --report junit=out.xml --report markdown=summary.md`,
 	Args: cobra.ExactArgs(1),
 	RunE: runWorkflow,
 }
 
 func runWorkflow(cmd *cobra.Command, args []string) error {
-	workflowPath := args[0]
+	workflowPath := workflowRef(args[0])
+	ctx := context.Background()
+
+	reports, err := parseReportSpecs()
+	if err != nil {
+		return err
+	}
 
 	// Get logger from global state
 	logger := GetLogger()
 
+	redactor := redact.New(redact.DefaultPatterns)
+	inputValues, err := collectInputs(redactor)
+	if err != nil {
+		return err
+	}
+	ctx = types.WithInputs(ctx, inputValues)
+
 	// Create orchestrator configuration
 	orchConfig := &orchestrator.Config{
 		DryRun:         false,
@@ -57,6 +97,12 @@ func runWorkflow(cmd *cobra.Command, args []string) error {
 		Logger:         logger,
 		Verbose:        verboseMode,
 		HistoryDir:     historyDir,
+		OutputLimit:    runOutputMax,
+		OutputDir:      runOutputDir,
+		Redactor:       redactor,
+		KeyFile:        runKeyFile,
+		PassphraseEnv:  runPassphraseEnv,
+		Environment:    runEnvironment,
 	}
 
 	// Create orchestrator
@@ -65,6 +111,31 @@ func runWorkflow(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create orchestrator: %w", err)
 	}
 
+	if runProgress {
+		orch.GetEventBus().Subscribe(newProgressRenderer(os.Stderr))
+	}
+
+	var collector *metrics.Collector
+	if runMetrics != "" {
+		collector = metrics.NewCollector()
+		orch.GetEventBus().Subscribe(collector)
+	}
+
+	if runTraceFile != "" {
+		orch.GetEventBus().Subscribe(tracing.NewTracer(tracing.NewFileExporter(runTraceFile), logger))
+	}
+	if runTraceURL != "" {
+		orch.GetEventBus().Subscribe(tracing.NewTracer(tracing.NewHTTPExporter(runTraceURL, nil), logger))
+	}
+	if tp := os.Getenv("TRACEPARENT"); tp != "" {
+		ctx = types.WithTraceParent(ctx, tp)
+	}
+
+	// Approval tasks prompt on the terminal when one is attached
+	if approval.IsTerminal(os.Stdin) {
+		orch.GetApprovalGate().SetPrompter(approval.NewTerminalPrompter(os.Stdin, os.Stderr))
+	}
+
 	// Build environment variables list
 	envVars := []string{}
 
@@ -75,18 +146,32 @@ func runWorkflow(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Add command-line variables
+	// Command-line variables are also set in the environment, as they were
+	// before workflows declared inputs
 	envVars = append(envVars, runVariables...)
 
 	// Execute workflow
-	ctx := context.Background()
 	result, err := orch.ExecuteWorkflowFile(ctx, workflowPath, envVars)
+
+	// Written even when the run errors, so the textfile never goes stale
+	if collector != nil {
+		if err := collector.WriteTextFile(runMetrics); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+		}
+	}
+
 	if err != nil {
 		return fmt.Errorf("failed to execute workflow: %w", err)
 	}
 
+	writeReportsOrWarn(reports, result, workflowPath)
+
 	// Display results
-	if err := displayResult(result); err != nil {
+	display := displayResult
+	if runOutputJSON {
+		display = displayOutputsJSON
+	}
+	if err := display(result); err != nil {
 		return fmt.Errorf("failed to display results: %w", err)
 	}
 
@@ -98,56 +183,158 @@ func runWorkflow(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// workflowRef returns the workflow file argument, pointed at the workflow
+// named by --workflow when the flag is set
+func workflowRef(arg string) string {
+	if runWorkflowName == "" {
+		return arg
+	}
+	path, _ := parser.ResolveWorkflowRef(nil, arg)
+	return path + "#" + runWorkflowName
+}
+
 // loadEnvFile loads environment variables from a file
 func loadEnvFile(path string, envVars *[]string) error {
-	data, err := os.ReadFile(path)
+	entries, _, err := readEnvFile(path)
 	if err != nil {
 		return err
 	}
+	*envVars = append(*envVars, entries...)
+	return nil
+}
+
+// readEnvFile returns the entries of an environment file and the line each
+// is on
+func readEnvFile(path string) ([]string, []int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
 
+	var entries []string
+	var lineNumbers []int
 	lines := strings.Split(string(data), "\n")
-	for _, line := range lines {
+	for i, line := range lines {
 		line = strings.TrimSpace(line)
 		// Skip empty lines and comments
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
 		// Add to env vars
-		*envVars = append(*envVars, line)
+		entries = append(entries, line)
+		lineNumbers = append(lineNumbers, i+1)
 	}
 
-	return nil
+	return entries, lineNumbers, nil
+}
+
+// collectInputs gathers values for the workflow's inputs from each --var-file
+// in order and then --var, so values given on the command line win. Values
+// read from encrypted files are masked like secrets.
+func collectInputs(redactor *redact.Redactor) (map[string]interface{}, error) {
+	values, _, err := collectInputOrigins(runKeyFile, runPassphraseEnv, redactor)
+	return values, err
+}
+
+// collectInputOrigins gathers input values as collectInputs does, along with
+// the flag, file and line each value came from
+func collectInputOrigins(keyFile, passphraseEnv string, redactor *redact.Redactor) (map[string]interface{}, map[string]contextManager.Origin, error) {
+	values := make(map[string]interface{})
+	origins := make(map[string]contextManager.Origin)
+
+	var loadedPath string
+	var keyLines map[string]int
+	loader := variables.New("")
+	loader.SetKeySource(keyFile, passphraseEnv)
+	loader.OnDecrypt(func(value string) { redactor.Add(value) })
+	loader.OnLoad(func(path string, lines map[string]int) { loadedPath, keyLines = path, lines })
+	for _, file := range runVarFiles {
+		fileValues, err := loader.LoadVariableFile(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		for key, value := range fileValues {
+			values[key] = value
+			origins[key] = contextManager.Origin{File: loadedPath, Line: keyLines[key], Detail: "--var-file"}
+		}
+	}
+
+	for _, assignment := range runVariables {
+		key, value, ok := strings.Cut(assignment, "=")
+		if !ok {
+			return nil, nil, fmt.Errorf("invalid --var '%s' (expected key=value)", assignment)
+		}
+		values[key] = value
+		origins[key] = contextManager.Origin{Detail: "--var"}
+	}
+
+	return values, origins, nil
 }
 
 // displayResult displays workflow execution results
 func displayResult(result *types.Result) error {
-	if result.ParseError != nil {
-		fmt.Fprintf(os.Stderr, "❌ Parse Error: %s\n", result.ParseError)
+	if !displayErrors(result) {
 		return nil
 	}
 
-	if result.DependencyError != nil {
-		fmt.Fprintf(os.Stderr, "❌ Dependency Error: %s\n", result.DependencyError)
+	if result.WorkflowResult != nil {
+		printWorkflowResult(result.WorkflowResult)
+	}
+
+	return nil
+}
+
+// displayOutputsJSON prints only the workflow's outputs, as JSON on stdout,
+// so scripts can consume them. Errors still go to stderr.
+func displayOutputsJSON(result *types.Result) error {
+	if !displayErrors(result) {
 		return nil
 	}
 
+	outputs := map[string]interface{}{}
+	if result.WorkflowResult != nil {
+		if result.WorkflowResult.Status != types.WorkflowSuccess {
+			fmt.Fprintf(os.Stderr, "❌ Workflow %s: %s\n", result.WorkflowResult.Name, result.WorkflowResult.Status)
+		}
+		if result.WorkflowResult.Outputs != nil {
+			outputs = result.WorkflowResult.Outputs
+		}
+	}
+
+	data, err := json.MarshalIndent(outputs, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// displayErrors prints the result's errors to stderr and reports whether the
+// workflow got as far as running
+func displayErrors(result *types.Result) bool {
+	if result.ParseError != nil {
+		printError(os.Stderr, "❌ Parse Error: ", result.ParseError)
+		return false
+	}
+
+	if result.DependencyError != nil {
+		printError(os.Stderr, "❌ Dependency Error: ", result.DependencyError)
+		return false
+	}
+
 	if len(result.ValidationErrors) > 0 {
 		fmt.Fprintf(os.Stderr, "❌ Validation Errors:\n")
 		for _, err := range result.ValidationErrors {
-			fmt.Fprintf(os.Stderr, "  - %s\n", err)
+			printError(os.Stderr, "  - ", err)
 		}
-		return nil
+		return false
 	}
 
 	if result.ExecutionError != nil {
 		fmt.Fprintf(os.Stderr, "❌ Execution Error: %s\n", result.ExecutionError)
 	}
 
-	if result.WorkflowResult != nil {
-		printWorkflowResult(result.WorkflowResult)
-	}
-
-	return nil
+	return true
 }
 
 // printWorkflowResult prints workflow execution summary
@@ -163,6 +350,23 @@ func printWorkflowResult(wr *types.WorkflowResult) {
 	fmt.Printf("   Duration: %s\n", wr.Duration)
 	fmt.Printf("   Tasks: %d\n", len(wr.Tasks))
 
+	// Failed and skipped tasks are neither changed nor unchanged, and tasks
+	// whose executors do not report changes are counted apart
+	changedCount, unchangedCount, untrackedCount := 0, 0, 0
+	for _, taskResult := range wr.Tasks {
+		switch {
+		case taskResult.Changed:
+			changedCount++
+		case taskResult.Status != types.TaskSuccess && taskResult.Status != types.TaskWarning:
+			// Failed or skipped
+		case taskResult.ChangeTracked:
+			unchangedCount++
+		default:
+			untrackedCount++
+		}
+	}
+	fmt.Printf("   Changed: %d, Unchanged: %d, Not tracked: %d\n", changedCount, unchangedCount, untrackedCount)
+
 	// Print task results
 	if len(wr.Tasks) > 0 {
 		fmt.Printf("\nTasks:\n")
@@ -177,7 +381,12 @@ func printWorkflowResult(wr *types.WorkflowResult) {
 				icon = "⚠️"
 			}
 
-			fmt.Printf("  %s %s (%s) - %s\n", icon, taskResult.Name, taskID, taskResult.Status)
+			changed := ""
+			if taskResult.Changed {
+				changed = " (changed)"
+			}
+
+			fmt.Printf("  %s %s (%s) - %s%s\n", icon, taskResult.Name, taskID, taskResult.Status, changed)
 			if taskResult.Message != "" && verboseMode {
 				fmt.Printf("    %s\n", taskResult.Message)
 			}
@@ -186,6 +395,19 @@ func printWorkflowResult(wr *types.WorkflowResult) {
 			}
 		}
 	}
+
+	if len(wr.Outputs) > 0 {
+		names := make([]string, 0, len(wr.Outputs))
+		for name := range wr.Outputs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		fmt.Printf("\nOutputs:\n")
+		for _, name := range names {
+			fmt.Printf("  %s: %v\n", name, wr.Outputs[name])
+		}
+	}
 }
 
 // hasErrors checks if the result contains errors
@@ -210,5 +432,18 @@ func init() {
 
 	runCmd.Flags().StringVar(&runMode, "mode", "parallel", "execution mode (parallel, sequential)")
 	runCmd.Flags().StringSliceVar(&runVariables, "var", []string{}, "set workflow variables (key=value)")
+	runCmd.Flags().StringSliceVar(&runVarFiles, "var-file", []string{}, "load workflow inputs from a YAML, JSON or .env file")
 	runCmd.Flags().StringVar(&runEnvFile, "env-file", "", "load environment variables from file")
+	runCmd.Flags().StringVar(&runWorkflowName, "workflow", "", "name of the workflow to use from a file holding several")
+	runCmd.Flags().StringVar(&runEnvironment, "environment", "", "layer variables/environments/<name> over the workflow's variable files")
+	runCmd.Flags().StringVar(&runKeyFile, "key-file", "", "read the passphrase for encrypted variable files from this file")
+	runCmd.Flags().StringVar(&runPassphraseEnv, "passphrase-env", "", "read the passphrase for encrypted variable files from this environment variable")
+	runCmd.Flags().BoolVar(&runProgress, "progress", false, "print task progress as the workflow runs")
+	runCmd.Flags().BoolVar(&runOutputJSON, "output-json", false, "print only the workflow's outputs, as JSON on stdout")
+	runCmd.Flags().IntVar(&runOutputMax, "output-limit", output.DefaultMaxBytes, "bytes of each task's stdout/stderr kept in results")
+	runCmd.Flags().StringVar(&runOutputDir, "output-dir", "", "directory to spool full command and ssh output to (default: only output over --output-limit, kept in <history-dir>/output for 7 days)")
+	runCmd.Flags().StringVar(&runMetrics, "metrics-file", "", "write Prometheus metrics in node-exporter textfile format after the run")
+	addReportFlag(runCmd)
+	runCmd.Flags().StringVar(&runTraceFile, "trace-file", "", "append an OTLP/JSON trace of the run to this file")
+	runCmd.Flags().StringVar(&runTraceURL, "trace-endpoint", "", "POST an OTLP/JSON trace of the run to this collector URL (e.g. http://localhost:4318/v1/traces)")
 }
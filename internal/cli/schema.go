@@ -0,0 +1,54 @@
+// ABOUTME: Schema command printing a JSON Schema for workflow files
+// ABOUTME: Lets editors using yaml-language-server validate and complete workflows
+
+package cli
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sarlalian/ritual/internal/schema"
+	"github.com/sarlalian/ritual/internal/tasks"
+)
+
+var schemaOutput string
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print a JSON Schema for workflow files",
+	Long: `Print a JSON Schema describing workflow files: the workflow sections, the
+keys every task accepts, and the config fields of each task type and alias,
+with their types, allowed values, defaults and descriptions.
+
+Point yaml-language-server at the schema with a comment at the top of a
+workflow file:
+
+  # yaml-language-server: $schema=./ritual.schema.json
+
+Examples:
+  ritual schema > ritual.schema.json
+  ritual schema --output .vscode/ritual.schema.json`,
+	Args: cobra.NoArgs,
+	RunE: printSchema,
+}
+
+func printSchema(cmd *cobra.Command, args []string) error {
+	data, err := json.MarshalIndent(schema.Generate(tasks.New()), "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if schemaOutput != "" {
+		return os.WriteFile(schemaOutput, data, 0644)
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+	schemaCmd.Flags().StringVarP(&schemaOutput, "output", "o", "", "write the schema here instead of stdout")
+}
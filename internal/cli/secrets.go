@@ -0,0 +1,107 @@
+// ABOUTME: Secrets command for creating and inspecting encrypted secrets files
+// ABOUTME: Files are read by the encrypted_file secrets provider
+
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/sarlalian/ritual/internal/secrets"
+)
+
+var (
+	secretsKeyFile       string
+	secretsPassphraseEnv string
+	secretsOutput        string
+)
+
+// secretsCmd groups the secrets file helpers
+var secretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "Encrypt and decrypt secrets files",
+	Long: `Manage encrypted secrets files for the encrypted_file secrets provider.
+
+A secrets file is a YAML map of names to values, encrypted with AES-256-GCM
+using a key derived from a passphrase. The passphrase is read from --key-file,
+the variable named by --passphrase-env, RITUAL_PASSPHRASE or RITUAL_KEY_FILE.
+
+Examples:
+  RITUAL_PASSPHRASE=... ritual secrets encrypt secrets.yaml --output secrets.enc
+  ritual secrets decrypt secrets.enc --key-file ~/.ritual.key`,
+}
+
+var secretsEncryptCmd = &cobra.Command{
+	Use:   "encrypt [secrets.yaml]",
+	Short: "Encrypt a YAML secrets file",
+	Args:  cobra.ExactArgs(1),
+	RunE:  encryptSecretsFile,
+}
+
+var secretsDecryptCmd = &cobra.Command{
+	Use:   "decrypt [secrets.enc]",
+	Short: "Print the decrypted contents of a secrets file",
+	Args:  cobra.ExactArgs(1),
+	RunE:  decryptSecretsFile,
+}
+
+func encryptSecretsFile(cmd *cobra.Command, args []string) error {
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	if secrets.IsEncrypted(data) {
+		return fmt.Errorf("%s is already encrypted", args[0])
+	}
+
+	values := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("%s must contain a YAML map of secret names to values: %w", args[0], err)
+	}
+
+	passphrase, err := secrets.LoadPassphrase(secretsKeyFile, secretsPassphraseEnv)
+	if err != nil {
+		return err
+	}
+	encrypted, err := secrets.Encrypt(data, passphrase)
+	if err != nil {
+		return err
+	}
+
+	if secretsOutput == "" {
+		_, err = os.Stdout.Write(encrypted)
+		return err
+	}
+	return os.WriteFile(secretsOutput, encrypted, 0600)
+}
+
+func decryptSecretsFile(cmd *cobra.Command, args []string) error {
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	passphrase, err := secrets.LoadPassphrase(secretsKeyFile, secretsPassphraseEnv)
+	if err != nil {
+		return err
+	}
+	plaintext, err := secrets.Decrypt(data, passphrase)
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stdout.Write(plaintext)
+	return err
+}
+
+func init() {
+	rootCmd.AddCommand(secretsCmd)
+	secretsCmd.AddCommand(secretsEncryptCmd, secretsDecryptCmd)
+
+	secretsCmd.PersistentFlags().StringVar(&secretsKeyFile, "key-file", "", "read the passphrase from this file")
+	secretsCmd.PersistentFlags().StringVar(&secretsPassphraseEnv, "passphrase-env", "", "read the passphrase from this environment variable")
+	secretsEncryptCmd.Flags().StringVarP(&secretsOutput, "output", "o", "", "write the encrypted file here instead of stdout")
+}
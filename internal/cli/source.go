@@ -0,0 +1,65 @@
+// ABOUTME: Source snippets for errors that know where in a workflow file they were found
+// ABOUTME: Prints the line at fault with a caret under the column, as compilers do
+
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+// printError writes an error line, followed by the source line it points
+// at when the error carries a position in a readable file
+func printError(w io.Writer, prefix string, err error) {
+	fmt.Fprintf(w, "%s%s\n", prefix, err)
+	if snippet := sourceSnippet(err); snippet != "" {
+		fmt.Fprint(w, indent(snippet, "    "))
+	}
+}
+
+// sourceSnippet renders the line an error's position points at, or ""
+func sourceSnippet(err error) string {
+	pos, ok := types.ErrorPosition(err)
+	if !ok || pos.File == "" {
+		return ""
+	}
+	data, readErr := os.ReadFile(pos.File)
+	if readErr != nil {
+		return ""
+	}
+	lines := strings.Split(string(data), "\n")
+	if pos.Line > len(lines) {
+		return ""
+	}
+
+	line := strings.TrimRight(lines[pos.Line-1], "\r")
+	number := fmt.Sprintf("%d", pos.Line)
+	gutter := strings.Repeat(" ", len(number))
+	snippet := fmt.Sprintf("%s |\n%s | %s\n", gutter, number, line)
+	if pos.Column > 0 && pos.Column <= len(line)+1 {
+		// Keep tabs so the caret lines up with the text above it
+		padding := strings.Map(func(r rune) rune {
+			if r == '\t' {
+				return r
+			}
+			return ' '
+		}, line[:pos.Column-1])
+		snippet += fmt.Sprintf("%s | %s^\n", gutter, padding)
+	}
+	return snippet
+}
+
+// indent prefixes every line of text with prefix
+func indent(text, prefix string) string {
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "")
+}
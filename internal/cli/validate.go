@@ -5,6 +5,7 @@ package cli
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/spf13/cobra"
 
@@ -28,15 +29,21 @@ The validate command checks:
 
 Examples:
   ritual validate workflow.yaml
-  ritual validate examples/complex.yaml`,
+  ritual validate examples/complex.yaml
+  ritual validate workflow.yaml --report junit=validate.xml`,
 	Args: cobra.ExactArgs(1),
 	RunE: validateWorkflow,
 }
 
 func validateWorkflow(cmd *cobra.Command, args []string) error {
-	workflowPath := args[0]
+	workflowPath := workflowRef(args[0])
 	logger := GetLogger()
 
+	reports, err := parseReportSpecs()
+	if err != nil {
+		return err
+	}
+
 	logger.Info().Str("workflow", workflowPath).Msg("Validating workflow")
 
 	// Create orchestrator
@@ -60,18 +67,20 @@ func validateWorkflow(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	writeReportsOrWarn(reports, result, workflowPath)
+
 	// Check for errors
 	if hasErrors(result) {
 		if result.ParseError != nil {
-			fmt.Printf("❌ Parse Error: %s\n", result.ParseError)
+			printError(os.Stdout, "❌ Parse Error: ", result.ParseError)
 		}
 		if result.DependencyError != nil {
-			fmt.Printf("❌ Dependency Error: %s\n", result.DependencyError)
+			printError(os.Stdout, "❌ Dependency Error: ", result.DependencyError)
 		}
 		if len(result.ValidationErrors) > 0 {
 			fmt.Printf("❌ Validation Errors:\n")
 			for _, err := range result.ValidationErrors {
-				fmt.Printf("  - %s\n", err)
+				printError(os.Stdout, "  - ", err)
 			}
 		}
 		return fmt.Errorf("validation failed")
@@ -83,4 +92,7 @@ func validateWorkflow(cmd *cobra.Command, args []string) error {
 
 func init() {
 	rootCmd.AddCommand(validateCmd)
+
+	validateCmd.Flags().StringVar(&runWorkflowName, "workflow", "", "name of the workflow to use from a file holding several")
+	addReportFlag(validateCmd)
 }
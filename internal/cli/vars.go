@@ -0,0 +1,309 @@
+// ABOUTME: Vars command group and its encrypt, decrypt and edit variable file commands
+// ABOUTME: Plaintext is only printed to stdout or held in a private temporary file while editing
+
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sarlalian/ritual/internal/secrets"
+	"github.com/sarlalian/ritual/internal/variables"
+)
+
+var (
+	varsKeyFile       string
+	varsPassphraseEnv string
+	varsOutput        string
+	varsInPlace       bool
+	varsPerValue      bool
+	varsKeys          []string
+)
+
+// varsCmd groups the variable file helpers
+var varsCmd = &cobra.Command{
+	Use:   "vars",
+	Short: "Explain a workflow's variables, and encrypt, decrypt and edit variable files",
+	Long: `Explain where a workflow's variables come from, and manage encrypted variable
+files listed in a workflow's variable_files.
+
+"ritual vars <workflow>" is short for "ritual vars explain <workflow>".
+
+A variable file is encrypted either whole, or value by value with its keys
+left readable (--values) so changes can be reviewed in diffs. Both use
+AES-256-GCM with a key derived from a passphrase read from --key-file, the
+variable named by --passphrase-env, RITUAL_PASSPHRASE or RITUAL_KEY_FILE.
+Encrypted files are decrypted in memory when a workflow loads them.
+
+Examples:
+  ritual vars explain deploy.yaml --var-file prod.yaml
+  ritual vars encrypt prod.yaml --in-place
+  ritual vars encrypt prod.yaml --values --keys '*password*' --keys '*token*' -i
+  ritual vars decrypt prod.yaml --key-file ~/.ritual.key
+  ritual vars edit prod.yaml`,
+}
+
+var varsEncryptCmd = &cobra.Command{
+	Use:   "encrypt [vars-file]",
+	Short: "Encrypt a variable file, whole or value by value",
+	Args:  cobra.ExactArgs(1),
+	RunE:  encryptVarsFile,
+}
+
+var varsDecryptCmd = &cobra.Command{
+	Use:   "decrypt [vars-file]",
+	Short: "Print the decrypted contents of a variable file",
+	Args:  cobra.ExactArgs(1),
+	RunE:  decryptVarsFile,
+}
+
+var varsEditCmd = &cobra.Command{
+	Use:   "edit [vars-file]",
+	Short: "Edit an encrypted variable file in $EDITOR and re-encrypt it",
+	Args:  cobra.ExactArgs(1),
+	RunE:  editVarsFile,
+}
+
+func encryptVarsFile(cmd *cobra.Command, args []string) error {
+	file := args[0]
+	if varsInPlace && varsOutput != "" {
+		return fmt.Errorf("--in-place and --output cannot be used together")
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if secrets.IsEncrypted(data) {
+		return fmt.Errorf("%s is already encrypted", file)
+	}
+
+	format := variables.FormatOf(file, data)
+	if _, err := variables.ValuePaths(data, format); err != nil {
+		return fmt.Errorf("%s is not a valid variable file: %w", file, err)
+	}
+	if !varsPerValue && variables.HasEncryptedValues(data) {
+		return fmt.Errorf("%s already has encrypted values; use --values to encrypt more of them", file)
+	}
+
+	passphrase, err := secrets.LoadPassphrase(varsKeyFile, varsPassphraseEnv)
+	if err != nil {
+		return err
+	}
+
+	var encrypted []byte
+	if varsPerValue {
+		if strings.EqualFold(filepath.Ext(file), ".json") {
+			return fmt.Errorf("per-value encryption supports YAML and .env files; encrypt %s whole instead", file)
+		}
+		encrypted, err = variables.EncryptValues(data, format, secrets.NewValueCipher(passphrase), matchKeys(varsKeys))
+	} else {
+		encrypted, err = secrets.Encrypt(data, passphrase)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case varsInPlace:
+		return replaceFile(file, encrypted)
+	case varsOutput != "":
+		return os.WriteFile(varsOutput, encrypted, 0600)
+	default:
+		_, err = os.Stdout.Write(encrypted)
+		return err
+	}
+}
+
+func decryptVarsFile(cmd *cobra.Command, args []string) error {
+	plaintext, _, _, err := readVarsPlaintext(args[0])
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(plaintext)
+	return err
+}
+
+func editVarsFile(cmd *cobra.Command, args []string) error {
+	file := args[0]
+	plaintext, cipher, encryptedPaths, err := readVarsPlaintext(file)
+	if err != nil {
+		return err
+	}
+	format := variables.FormatOf(file, plaintext)
+
+	// Values that were plaintext stay plaintext; values that were encrypted
+	// and values added while editing are encrypted
+	plainPaths := make(map[string]bool)
+	if cipher != nil {
+		paths, err := variables.ValuePaths(plaintext, format)
+		if err != nil {
+			return err
+		}
+		for _, p := range paths {
+			plainPaths[p] = !encryptedPaths[p]
+		}
+	}
+
+	edited, err := editInTempFile(file, plaintext)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(edited, plaintext) {
+		fmt.Fprintln(os.Stderr, "No changes")
+		return nil
+	}
+	if _, err := variables.ValuePaths(edited, format); err != nil {
+		return fmt.Errorf("edited file is not valid, %s was not changed: %w", file, err)
+	}
+
+	var encrypted []byte
+	if cipher != nil {
+		encrypted, err = variables.EncryptValues(edited, format, cipher, func(p string) bool {
+			return !plainPaths[p]
+		})
+	} else {
+		var passphrase []byte
+		if passphrase, err = secrets.LoadPassphrase(varsKeyFile, varsPassphraseEnv); err == nil {
+			encrypted, err = secrets.Encrypt(edited, passphrase)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return replaceFile(file, encrypted)
+}
+
+// readVarsPlaintext decrypts a variable file in memory. For files encrypted
+// value by value it also returns the cipher and the paths of the encrypted
+// values; both are nil for whole files.
+func readVarsPlaintext(file string) ([]byte, *secrets.ValueCipher, map[string]bool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	wholeFile := secrets.IsEncrypted(data)
+	if !wholeFile && !variables.HasEncryptedValues(data) {
+		return nil, nil, nil, fmt.Errorf("%s is not encrypted", file)
+	}
+
+	passphrase, err := secrets.LoadPassphrase(varsKeyFile, varsPassphraseEnv)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if wholeFile {
+		plaintext, err := secrets.Decrypt(data, passphrase)
+		return plaintext, nil, nil, err
+	}
+
+	cipher := secrets.NewValueCipher(passphrase)
+	encryptedPaths := make(map[string]bool)
+	plaintext, err := variables.DecryptValues(data, variables.FormatOf(file, data), cipher, func(p, _ string) {
+		encryptedPaths[p] = true
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return plaintext, cipher, encryptedPaths, nil
+}
+
+// editInTempFile opens plaintext in the user's editor and returns the result.
+// The file lives in a private directory, in memory where /dev/shm exists,
+// and is removed as soon as the editor exits.
+func editInTempFile(file string, plaintext []byte) ([]byte, error) {
+	base := ""
+	if info, err := os.Stat("/dev/shm"); err == nil && info.IsDir() {
+		base = "/dev/shm"
+	}
+	dir, err := os.MkdirTemp(base, "ritual-vars-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	// Keep the name so editors pick the right syntax highlighting
+	tmp := filepath.Join(dir, strings.TrimSuffix(filepath.Base(file), variables.EncryptedSuffix))
+	if err := os.WriteFile(tmp, plaintext, 0600); err != nil {
+		return nil, err
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	// Run through the shell so editors given with arguments, such as
+	// "code --wait", work
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", tmp)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("editor failed, %s was not changed: %w", file, err)
+	}
+	return os.ReadFile(tmp)
+}
+
+// replaceFile atomically replaces file with data, keeping its permissions
+func replaceFile(file string, data []byte) error {
+	mode := os.FileMode(0600)
+	if info, err := os.Stat(file); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// matchKeys returns a matcher for value paths whose last key matches one of
+// patterns, or nil to match every value
+func matchKeys(patterns []string) func(string) bool {
+	if len(patterns) == 0 {
+		return nil
+	}
+	return func(valuePath string) bool {
+		key := strings.ToLower(valuePath[strings.LastIndex(valuePath, ".")+1:])
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(strings.ToLower(pattern), key); ok {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(varsCmd)
+	varsCmd.AddCommand(varsEncryptCmd, varsDecryptCmd, varsEditCmd)
+
+	varsCmd.PersistentFlags().StringVar(&varsKeyFile, "key-file", "", "read the passphrase from this file")
+	varsCmd.PersistentFlags().StringVar(&varsPassphraseEnv, "passphrase-env", "", "read the passphrase from this environment variable")
+	varsEncryptCmd.Flags().StringVarP(&varsOutput, "output", "o", "", "write the encrypted file here instead of stdout")
+	varsEncryptCmd.Flags().BoolVarP(&varsInPlace, "in-place", "i", false, "replace the file with its encrypted form")
+	varsEncryptCmd.Flags().BoolVar(&varsPerValue, "values", false, "encrypt each value in place, leaving keys readable")
+	varsEncryptCmd.Flags().StringSliceVar(&varsKeys, "keys", nil, "with --values, only encrypt values whose key matches these globs")
+}
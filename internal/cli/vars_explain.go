@@ -0,0 +1,254 @@
+// ABOUTME: Vars explain command printing a workflow's resolved variables and environment
+// ABOUTME: Shows where each value came from and what it overrode, with secrets masked
+
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	contextManager "github.com/sarlalian/ritual/internal/context"
+	"github.com/sarlalian/ritual/internal/orchestrator"
+	"github.com/sarlalian/ritual/internal/redact"
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+var (
+	explainAll  bool
+	explainJSON bool
+)
+
+var varsExplainCmd = &cobra.Command{
+	Use:   "explain [workflow.yaml] [name...]",
+	Short: "Show a workflow's resolved variables and where each came from",
+	Long: `Resolve a workflow's inputs, variable files, vars and environment as a run
+would, without running any task, and print every value with its source: the
+file and line it was set on, or the flag that set it, followed by the values
+it overrode. Secrets and values with sensitive names are masked.
+
+Environment entries inherited unchanged from the process are left out unless
+--all is given. Names after the workflow limit the output to those entries.
+
+Examples:
+  ritual vars explain deploy.yaml
+  ritual vars explain deploy.yaml region db_host --var-file prod.yaml
+  ritual vars deploy.yaml --environment prod --json`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: explainVars,
+}
+
+// explanation is the JSON form of the explain output
+type explanation struct {
+	Variables   map[string]contextManager.Provenance `json:"variables"`
+	Environment map[string]contextManager.Provenance `json:"environment"`
+}
+
+func explainVars(cmd *cobra.Command, args []string) error {
+	workflowPath := workflowRef(args[0])
+
+	redactor := redact.New(redact.DefaultPatterns)
+	inputValues, inputOrigins, err := collectInputOrigins(varsKeyFile, varsPassphraseEnv, redactor)
+	if err != nil {
+		return err
+	}
+
+	envVars := []string{}
+	var overrideOrigins []contextManager.Origin
+	if runEnvFile != "" {
+		entries, lines, err := readEnvFile(runEnvFile)
+		if err != nil {
+			return fmt.Errorf("failed to load environment file: %w", err)
+		}
+		envVars = append(envVars, entries...)
+		for _, line := range lines {
+			overrideOrigins = append(overrideOrigins, contextManager.Origin{File: runEnvFile, Line: line, Detail: "--env-file"})
+		}
+	}
+	for range runVariables {
+		overrideOrigins = append(overrideOrigins, contextManager.Origin{Detail: "--var"})
+	}
+	envVars = append(envVars, runVariables...)
+
+	orch, err := orchestrator.New(&orchestrator.Config{
+		MaxConcurrency: 10,
+		Logger:         GetLogger(),
+		Verbose:        verboseMode,
+		HistoryDir:     historyDir,
+		Redactor:       redactor,
+		KeyFile:        varsKeyFile,
+		PassphraseEnv:  varsPassphraseEnv,
+		Environment:    runEnvironment,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create orchestrator: %w", err)
+	}
+
+	mgr, ok := orch.GetContextManager().(*contextManager.Manager)
+	if !ok {
+		return fmt.Errorf("the context manager does not record provenance")
+	}
+	mgr.SetInputOrigins(inputOrigins)
+	mgr.SetOverrideOrigins(overrideOrigins)
+
+	ctx := types.WithInputs(context.Background(), inputValues)
+	result, err := orch.ResolveContext(ctx, workflowPath, envVars)
+	if err != nil {
+		return err
+	}
+	if hasErrors(result) {
+		displayErrors(result)
+		os.Exit(1)
+	}
+
+	out := explanation{
+		Variables:   selectEntries(mgr.VariableProvenance(), args[1:], true),
+		Environment: selectEntries(mgr.EnvironmentProvenance(), args[1:], explainAll),
+	}
+	for name, entry := range out.Variables {
+		// Inputs not given on the command line took their default
+		if entry.Source == contextManager.SourceInput && entry.Detail == "" {
+			entry.Detail = "default"
+		}
+		out.Variables[name] = maskProvenance(redactor, name, entry)
+	}
+	for name, entry := range out.Environment {
+		out.Environment[name] = maskProvenance(redactor, name, entry)
+	}
+
+	if explainJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(out)
+	}
+
+	printProvenance("Variables", out.Variables)
+	printProvenance("Environment", out.Environment)
+	return nil
+}
+
+// selectEntries keeps the named entries, or all of them when no names are
+// given. Values inherited untouched from the process environment are only
+// kept when includeSystem is set or they are asked for by name.
+func selectEntries(entries map[string]contextManager.Provenance, names []string, includeSystem bool) map[string]contextManager.Provenance {
+	selected := make(map[string]contextManager.Provenance)
+	if len(names) > 0 {
+		for _, name := range names {
+			if entry, ok := entries[name]; ok {
+				selected[name] = entry
+			}
+		}
+		return selected
+	}
+	for name, entry := range entries {
+		if includeSystem || entry.Source != contextManager.SourceSystem {
+			selected[name] = entry
+		}
+	}
+	return selected
+}
+
+// maskProvenance masks the values in entry as they would be in logs, and
+// entirely when name is sensitive
+func maskProvenance(redactor *redact.Redactor, name string, entry contextManager.Provenance) contextManager.Provenance {
+	mask := func(origin contextManager.Origin) contextManager.Origin {
+		if redactor.SensitiveName(name) && origin.Value != nil && origin.Value != "" {
+			origin.Value = redact.Mask
+		} else {
+			origin.Value = redactor.Value(origin.Value)
+		}
+		origin.Detail = redactor.String(origin.Detail)
+		return origin
+	}
+
+	masked := contextManager.Provenance{Origin: mask(entry.Origin)}
+	for _, shadowed := range entry.Shadowed {
+		masked.Shadowed = append(masked.Shadowed, mask(shadowed))
+	}
+	return masked
+}
+
+// printProvenance prints each entry's value and origin, then the values it
+// overrode, most recent first
+func printProvenance(title string, entries map[string]contextManager.Provenance) {
+	if len(entries) == 0 {
+		return
+	}
+
+	fmt.Printf("%s:\n", title)
+	for _, name := range contextManager.SortedNames(entries) {
+		entry := entries[name]
+		fmt.Printf("  %s = %s\n", name, formatValue(entry.Value))
+		fmt.Printf("      from %s\n", describeOrigin(entry.Origin))
+		for _, shadowed := range entry.Shadowed {
+			fmt.Printf("      overrides %s from %s\n", formatValue(shadowed.Value), describeOrigin(shadowed))
+		}
+	}
+	fmt.Println()
+}
+
+// describeOrigin formats an origin as "source location (detail)"
+func describeOrigin(origin contextManager.Origin) string {
+	description := origin.Source
+	origin.File = displayPath(origin.File)
+	if location := origin.Location(); location != "" {
+		description += " " + location
+	}
+	if origin.Detail != "" {
+		description += " (" + origin.Detail + ")"
+	}
+	return description
+}
+
+// displayPath shortens absolute paths under the working directory
+func displayPath(path string) string {
+	if !filepath.IsAbs(path) {
+		return path
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(cwd, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return path
+}
+
+// formatValue renders a value compactly, quoting strings
+func formatValue(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// addExplainFlags registers the flags of the explain command on cmd
+func addExplainFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&runVariables, "var", []string{}, "set workflow variables (key=value)")
+	cmd.Flags().StringSliceVar(&runVarFiles, "var-file", []string{}, "load workflow inputs from a YAML, JSON or .env file")
+	cmd.Flags().StringVar(&runEnvFile, "env-file", "", "load environment variables from file")
+	cmd.Flags().StringVar(&runWorkflowName, "workflow", "", "name of the workflow to use from a file holding several")
+	cmd.Flags().StringVar(&runEnvironment, "environment", "", "layer variables/environments/<name> over the workflow's variable files")
+	cmd.Flags().BoolVar(&explainAll, "all", false, "include environment entries inherited from the process")
+	cmd.Flags().BoolVar(&explainJSON, "json", false, "print the provenance as JSON")
+}
+
+func init() {
+	varsCmd.AddCommand(varsExplainCmd)
+	addExplainFlags(varsExplainCmd)
+
+	// "ritual vars <workflow>" is short for "ritual vars explain <workflow>"
+	varsCmd.Args = cobra.ArbitraryArgs
+	varsCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return cmd.Help()
+		}
+		return explainVars(cmd, args)
+	}
+	addExplainFlags(varsCmd)
+}
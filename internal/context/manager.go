@@ -10,6 +10,8 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/sarlalian/ritual/internal/redact"
+	"github.com/sarlalian/ritual/internal/secrets"
 	"github.com/sarlalian/ritual/internal/variables"
 	"github.com/sarlalian/ritual/pkg/types"
 )
@@ -21,7 +23,19 @@ type Manager struct {
 	envOverrides   map[string]string
 	variableLoader *variables.FileLoader
 	workflowDir    string
+	redactor       *redact.Redactor
+	inputs         map[string]interface{}
+	keyFile        string
+	passphraseEnv  string
+	environment    string
 	mu             sync.RWMutex // Protects concurrent access to context
+
+	// Where each value came from, recorded by Initialize
+	workflowFile       string
+	inputOrigins       map[string]Origin
+	overrideOrigins    []Origin
+	variableOrigins    provenance
+	environmentOrigins provenance
 }
 
 // New creates a new context manager
@@ -33,6 +47,9 @@ func New(templateEngine types.TemplateEngine) *Manager {
 		envOverrides:   make(map[string]string),
 		variableLoader: variables.New(cwd),
 		workflowDir:    cwd,
+
+		variableOrigins:    make(provenance),
+		environmentOrigins: make(provenance),
 	}
 }
 
@@ -44,11 +61,19 @@ func NewWithWorkflowDir(templateEngine types.TemplateEngine, workflowDir string)
 		envOverrides:   make(map[string]string),
 		variableLoader: variables.New(workflowDir),
 		workflowDir:    workflowDir,
+
+		variableOrigins:    make(provenance),
+		environmentOrigins: make(provenance),
 	}
 }
 
 // Initialize sets up the initial context from workflow and environment
 func (m *Manager) Initialize(workflow *types.Workflow, envVars []string) error {
+	m.mu.Lock()
+	m.variableOrigins = make(provenance)
+	m.environmentOrigins = make(provenance)
+	m.mu.Unlock()
+
 	// Load environment variables from system
 	m.loadSystemEnvironment()
 
@@ -62,8 +87,11 @@ func (m *Manager) Initialize(workflow *types.Workflow, envVars []string) error {
 		return fmt.Errorf("failed to load environment overrides: %w", err)
 	}
 
-	// Load variables from external files first
-	if err := m.loadVariableFiles(workflow.VariableFiles); err != nil {
+	// Resolved inputs come first so variable files and vars can use them
+	m.loadInputs()
+
+	// Load variables from external files
+	if err := m.loadVariableFiles(workflow.VariableFiles, workflow.VariableMerge); err != nil {
 		return fmt.Errorf("failed to load variable files: %w", err)
 	}
 
@@ -75,6 +103,16 @@ func (m *Manager) Initialize(workflow *types.Workflow, envVars []string) error {
 	// Set up workflow metadata
 	m.setupWorkflowMetadata(workflow)
 
+	// Learn sensitive values by name before anything can print them
+	m.redactor.AddEnvironment(m.context.Environment)
+	m.redactor.AddVariables(m.context.Variables)
+
+	// Attach secrets last so templates in vars cannot copy secret values
+	// into variables
+	if err := m.loadSecrets(workflow.Secrets); err != nil {
+		return fmt.Errorf("failed to load secrets: %w", err)
+	}
+
 	return nil
 }
 
@@ -83,6 +121,106 @@ func (m *Manager) GetContext() *types.WorkflowContext {
 	return m.context
 }
 
+// Snapshot returns a shallow copy of the workflow context that can be read
+// safely while other tasks register their results
+func (m *Manager) Snapshot() *types.WorkflowContext {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snapshot := &types.WorkflowContext{
+		Environment: make(map[string]string, len(m.context.Environment)),
+		Variables:   make(map[string]interface{}, len(m.context.Variables)),
+		Tasks:       make(map[string]*types.TaskResult, len(m.context.Tasks)),
+		Imports:     m.context.Imports,
+		Metadata:    make(map[string]interface{}, len(m.context.Metadata)),
+		Secrets:     m.context.Secrets,
+	}
+	for k, v := range m.context.Environment {
+		snapshot.Environment[k] = v
+	}
+	for k, v := range m.context.Variables {
+		snapshot.Variables[k] = v
+	}
+	for k, v := range m.context.Tasks {
+		snapshot.Tasks[k] = v
+	}
+	for k, v := range m.context.Metadata {
+		snapshot.Metadata[k] = v
+	}
+
+	return snapshot
+}
+
+// SetRedactor sets the redactor that learns sensitive values as the context
+// is initialised and as secrets are fetched
+func (m *Manager) SetRedactor(redactor *redact.Redactor) {
+	m.redactor = redactor
+}
+
+// SetInputs sets the resolved values of the workflow's declared inputs for
+// the next Initialize
+func (m *Manager) SetInputs(inputs map[string]interface{}) {
+	m.inputs = inputs
+}
+
+// SetVariableEnvironment selects the environment whose overlay file in
+// variables/environments is loaded after the workflow's variable files
+func (m *Manager) SetVariableEnvironment(environment string) {
+	m.environment = environment
+}
+
+// SetInputOrigins sets where the caller got each input's value, such as a
+// --var flag or a line of a --var-file. Inputs without one took their default
+// or came from a caller that does not say.
+func (m *Manager) SetInputOrigins(origins map[string]Origin) {
+	m.inputOrigins = origins
+}
+
+// SetOverrideOrigins sets where each entry of the next Initialize's envVars
+// came from, by position
+func (m *Manager) SetOverrideOrigins(origins []Origin) {
+	m.overrideOrigins = origins
+}
+
+// SetWorkflowFile sets the file the workflow was read from, so values from
+// its environment and vars sections are recorded with their line
+func (m *Manager) SetWorkflowFile(path string) {
+	m.workflowFile = path
+}
+
+// VariableProvenance returns where each variable came from and the values it
+// overrode, as recorded by the last Initialize
+func (m *Manager) VariableProvenance() map[string]Provenance {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.variableOrigins.copy()
+}
+
+// EnvironmentProvenance returns where each environment entry came from and
+// the values it overrode, as recorded by the last Initialize
+func (m *Manager) EnvironmentProvenance() map[string]Provenance {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.environmentOrigins.copy()
+}
+
+// workflowOrigin returns the origin of a value from one of the workflow's
+// sections, with its line when the key is found in the workflow file
+func (m *Manager) workflowOrigin(source string, lines map[string]int, name string, value interface{}) Origin {
+	origin := Origin{Source: source, Value: value}
+	if line, ok := lines[name]; ok {
+		origin.File, origin.Line = m.workflowFile, line
+	}
+	return origin
+}
+
+// SetVariableKeySource sets where the passphrase for encrypted variable files
+// is read from; empty values fall back to RITUAL_PASSPHRASE and RITUAL_KEY_FILE
+func (m *Manager) SetVariableKeySource(keyFile, passphraseEnv string) {
+	m.keyFile = keyFile
+	m.passphraseEnv = passphraseEnv
+}
+
 // SetWorkflowDir updates the workflow directory for variable file loading
 func (m *Manager) SetWorkflowDir(dir string) {
 	m.workflowDir = dir
@@ -207,9 +345,13 @@ func (m *Manager) Clone() types.ContextManager {
 	defer m.mu.RUnlock()
 
 	clone := &Manager{
-		context:        &types.WorkflowContext{},
+		context:        &types.WorkflowContext{Secrets: m.context.Secrets},
 		templateEngine: m.templateEngine,
 		envOverrides:   make(map[string]string),
+		redactor:       m.redactor,
+
+		variableOrigins:    make(provenance),
+		environmentOrigins: make(provenance),
 	}
 
 	// Deep copy environment
@@ -254,6 +396,7 @@ func (m *Manager) loadSystemEnvironment() {
 		parts := strings.SplitN(env, "=", 2)
 		if len(parts) == 2 {
 			m.context.Environment[parts[0]] = parts[1]
+			m.environmentOrigins.set(parts[0], Origin{Source: SourceSystem, Value: parts[1]})
 		}
 	}
 }
@@ -264,6 +407,8 @@ func (m *Manager) loadWorkflowEnvironment(workflowEnv map[string]string) error {
 		return nil
 	}
 
+	lines := m.workflowKeyLines("environment")
+
 	// Process environment variables in multiple passes to handle dependencies
 	processed := make(map[string]bool)
 	maxPasses := 5 // Prevent infinite loops
@@ -284,6 +429,7 @@ func (m *Manager) loadWorkflowEnvironment(workflowEnv map[string]string) error {
 			}
 
 			m.context.Environment[key] = evaluated
+			m.environmentOrigins.set(key, m.workflowOrigin(SourceEnvironment, lines, key, evaluated))
 			processed[key] = true
 			progressMade = true
 		}
@@ -293,6 +439,9 @@ func (m *Manager) loadWorkflowEnvironment(workflowEnv map[string]string) error {
 			for key, value := range workflowEnv {
 				if !processed[key] {
 					m.context.Environment[key] = value
+					origin := m.workflowOrigin(SourceEnvironment, lines, key, value)
+					origin.Detail = "template could not be evaluated"
+					m.environmentOrigins.set(key, origin)
 					processed[key] = true
 				}
 			}
@@ -317,7 +466,7 @@ func (m *Manager) loadWorkflowEnvironment(workflowEnv map[string]string) error {
 
 // loadEnvironmentOverrides loads environment variables from command line (key=value format)
 func (m *Manager) loadEnvironmentOverrides(envVars []string) error {
-	for _, envVar := range envVars {
+	for i, envVar := range envVars {
 		parts := strings.SplitN(envVar, "=", 2)
 		if len(parts) != 2 {
 			return fmt.Errorf("invalid environment variable format '%s' (expected key=value)", envVar)
@@ -335,6 +484,14 @@ func (m *Manager) loadEnvironmentOverrides(envVars []string) error {
 		}
 
 		m.context.Environment[key] = evaluated
+
+		origin := Origin{Source: SourceOverride}
+		if i < len(m.overrideOrigins) {
+			origin = m.overrideOrigins[i]
+			origin.Source = SourceOverride
+		}
+		origin.Value = evaluated
+		m.environmentOrigins.set(key, origin)
 	}
 
 	return nil
@@ -350,8 +507,11 @@ func (m *Manager) loadWorkflowVariables(workflowVars map[string]interface{}) err
 		m.context.Variables = make(map[string]interface{})
 	}
 
+	lines := m.workflowKeyLines("vars")
+
 	// Process variables in multiple passes to handle dependencies
 	processed := make(map[string]bool)
+	pending := make(map[string]error)
 	maxPasses := 5 // Prevent infinite loops
 
 	for pass := 0; pass < maxPasses; pass++ {
@@ -363,7 +523,32 @@ func (m *Manager) loadWorkflowVariables(workflowVars map[string]interface{}) err
 			}
 
 			// Try to process this variable
-			if strValue, ok := value.(string); ok {
+			if src, err := parseSource(value); err != nil {
+				return fmt.Errorf("variable '%s': %w", key, err)
+			} else if src != nil {
+				evaluated, err := m.evaluateSource(src)
+				if err != nil {
+					// Its templates may need variables not loaded yet
+					pending[key] = err
+					continue
+				}
+				resolved, cached, err := m.readSource(evaluated)
+				if err != nil {
+					return fmt.Errorf("variable '%s': %w", key, err)
+				}
+				m.context.Variables[key] = resolved
+				origin := m.workflowOrigin(SourceVars, lines, key, resolved)
+				// The unevaluated target, so provenance never shows secrets
+				// templated into it
+				origin.Detail = src.kind + " " + src.target
+				if cached {
+					origin.Detail = joinDetails(origin.Detail, "cached")
+				}
+				m.variableOrigins.set(key, origin)
+				processed[key] = true
+				progressMade = true
+				continue
+			} else if strValue, ok := value.(string); ok {
 				evaluated, err := m.templateEngine.Evaluate(strValue, m.context)
 				if err != nil {
 					// If evaluation fails, skip this pass and try later
@@ -373,6 +558,7 @@ func (m *Manager) loadWorkflowVariables(workflowVars map[string]interface{}) err
 			} else {
 				m.context.Variables[key] = value
 			}
+			m.variableOrigins.set(key, m.workflowOrigin(SourceVars, lines, key, m.context.Variables[key]))
 
 			processed[key] = true
 			progressMade = true
@@ -381,8 +567,15 @@ func (m *Manager) loadWorkflowVariables(workflowVars map[string]interface{}) err
 		// If no progress was made, try to process remaining variables without templates
 		if !progressMade {
 			for key, value := range workflowVars {
+				if err, ok := pending[key]; ok && !processed[key] {
+					// A source cannot be read without its target
+					return fmt.Errorf("variable '%s': %w", key, err)
+				}
 				if !processed[key] {
 					m.context.Variables[key] = value
+					origin := m.workflowOrigin(SourceVars, lines, key, value)
+					origin.Detail = "template could not be evaluated"
+					m.variableOrigins.set(key, origin)
 					processed[key] = true
 				}
 			}
@@ -405,6 +598,42 @@ func (m *Manager) loadWorkflowVariables(workflowVars map[string]interface{}) err
 	return nil
 }
 
+// loadInputs copies the resolved inputs into the variables
+func (m *Manager) loadInputs() {
+	if len(m.inputs) == 0 {
+		return
+	}
+	if m.context.Variables == nil {
+		m.context.Variables = make(map[string]interface{})
+	}
+	for name, value := range m.inputs {
+		m.context.Variables[name] = value
+
+		origin := m.inputOrigins[name]
+		origin.Source, origin.Value = SourceInput, value
+		m.variableOrigins.set(name, origin)
+	}
+}
+
+// loadSecrets prepares the workflow's declared secrets. Values are fetched
+// from their providers only when a template calls secret.
+func (m *Manager) loadSecrets(declared map[string]types.SecretConfig) error {
+	store, err := secrets.NewStore(declared, m.workflowDir, func(name string) (string, bool) {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		value, ok := m.context.Environment[name]
+		return value, ok
+	})
+	if err != nil {
+		return err
+	}
+	if m.redactor != nil {
+		store.OnResolve(func(value string) { m.redactor.Add(value) })
+	}
+	m.context.Secrets = store
+	return nil
+}
+
 // setupWorkflowMetadata sets up workflow metadata in the context
 func (m *Manager) setupWorkflowMetadata(workflow *types.Workflow) {
 	if m.context.Metadata == nil {
@@ -486,9 +715,11 @@ func LoadEnvironmentFile(filename string) ([]string, error) {
 	return vars, nil
 }
 
-// loadVariableFiles loads variables from external files
-func (m *Manager) loadVariableFiles(variableFiles []string) error {
-	if len(variableFiles) == 0 {
+// loadVariableFiles loads variables from external files, then the overlay
+// file of the selected environment. Later files are merged into earlier
+// ones as merge says.
+func (m *Manager) loadVariableFiles(variableFiles []string, merge *types.MergeConfig) error {
+	if len(variableFiles) == 0 && m.environment == "" {
 		return nil
 	}
 
@@ -496,6 +727,10 @@ func (m *Manager) loadVariableFiles(variableFiles []string) error {
 		m.context.Variables = make(map[string]interface{})
 	}
 
+	// Values from encrypted files are masked like secrets
+	m.variableLoader.SetKeySource(m.keyFile, m.passphraseEnv)
+	m.variableLoader.OnDecrypt(func(value string) { m.redactor.Add(value) })
+
 	// Process each variable file in order
 	for _, filePath := range variableFiles {
 		// Evaluate template in file path (allows dynamic file selection)
@@ -504,38 +739,90 @@ func (m *Manager) loadVariableFiles(variableFiles []string) error {
 			return fmt.Errorf("failed to evaluate variable file path '%s': %w", filePath, err)
 		}
 
-		// Load variables from file
-		fileVars, err := m.variableLoader.LoadVariableFile(evaluatedPath)
-		if err != nil {
-			return fmt.Errorf("failed to load variable file '%s': %w", evaluatedPath, err)
+		if err := m.loadVariableFile(evaluatedPath, "", merge); err != nil {
+			return err
 		}
+	}
 
-		// Resolve any file references within the loaded variables
-		resolvedVars, err := m.variableLoader.ResolveVariableReferences(fileVars)
+	if m.environment != "" {
+		overlay, err := m.variableLoader.EnvironmentOverlay(m.environment)
 		if err != nil {
-			return fmt.Errorf("failed to resolve variable references in file '%s': %w", evaluatedPath, err)
+			return err
 		}
+		return m.loadVariableFile(overlay, "environment "+m.environment, merge)
+	}
 
-		// Merge variables into context (later files override earlier ones)
-		for key, value := range resolvedVars {
-			// Evaluate string templates in loaded variables
-			if strValue, ok := value.(string); ok {
-				evaluated, err := m.templateEngine.Evaluate(strValue, m.context)
-				if err != nil {
-					// If template evaluation fails, use the raw value
-					m.context.Variables[key] = strValue
-				} else {
-					m.context.Variables[key] = evaluated
-				}
+	return nil
+}
+
+// loadVariableFile merges one variable file into the variables. detail, if
+// set, is recorded with the origin of each of its values.
+func (m *Manager) loadVariableFile(path, detail string, merge *types.MergeConfig) error {
+	// The first file loaded is the variable file itself; files it references
+	// with "@path" are loaded after it
+	var loadedPath string
+	var keyLines map[string]int
+	m.variableLoader.OnLoad(func(path string, lines map[string]int) {
+		if loadedPath == "" {
+			loadedPath, keyLines = path, lines
+		}
+	})
+
+	// Load variables from file
+	fileVars, err := m.variableLoader.LoadVariableFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to load variable file '%s': %w", path, err)
+	}
+
+	// Resolve any file references within the loaded variables
+	resolvedVars, err := m.variableLoader.ResolveVariableReferences(fileVars)
+	if err != nil {
+		return fmt.Errorf("failed to resolve variable references in file '%s': %w", path, err)
+	}
+
+	// Merge variables into context (later files override earlier ones)
+	for key, value := range resolvedVars {
+		origin := Origin{Source: SourceVariableFile, File: loadedPath, Line: keyLines[key], Detail: detail, Value: value}
+		if ref, ok := fileVars[key].(string); ok && strings.HasPrefix(ref, "@") {
+			origin.Source = SourceFileRef
+			origin.Detail = joinDetails(origin.Detail, "loaded from "+ref)
+		}
+
+		// Inputs given by the caller win over file defaults
+		if _, isInput := m.inputs[key]; isInput {
+			m.variableOrigins.shadow(key, origin)
+			continue
+		}
+
+		// Evaluate string templates in loaded variables
+		if strValue, ok := value.(string); ok {
+			evaluated, err := m.templateEngine.Evaluate(strValue, m.context)
+			if err != nil {
+				// If template evaluation fails, use the raw value
+				m.context.Variables[key] = strValue
 			} else {
-				m.context.Variables[key] = value
+				m.context.Variables[key] = evaluated
 			}
+		} else if existing, ok := m.context.Variables[key]; ok {
+			m.context.Variables[key] = variables.MergeValue(key, existing, value, merge)
+			if isMap(existing) && isMap(value) && (merge == nil || merge.Mode != variables.MergeShallow) {
+				origin.Detail = joinDetails(origin.Detail, "merged")
+			}
+		} else {
+			m.context.Variables[key] = value
 		}
+		origin.Value = m.context.Variables[key]
+		m.variableOrigins.set(key, origin)
 	}
 
 	return nil
 }
 
+func isMap(value interface{}) bool {
+	_, ok := value.(map[string]interface{})
+	return ok
+}
+
 // GetTemplateEngine returns the template engine used by this context manager
 func (m *Manager) GetTemplateEngine() types.TemplateEngine {
 	return m.templateEngine
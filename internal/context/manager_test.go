@@ -5,9 +5,12 @@ package context
 
 import (
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/sarlalian/ritual/internal/redact"
+	"github.com/sarlalian/ritual/internal/secrets"
 	"github.com/sarlalian/ritual/internal/template"
 	"github.com/sarlalian/ritual/pkg/types"
 )
@@ -337,6 +340,199 @@ func TestManager_Clone(t *testing.T) {
 	}
 }
 
+func TestManager_Secrets(t *testing.T) {
+	manager := New(template.New())
+
+	workflow := &types.Workflow{
+		Name:        "secrets-workflow",
+		Environment: map[string]string{"DB_PASSWORD": "hunter2"},
+		Secrets: map[string]types.SecretConfig{
+			"db": {Provider: "env", Options: map[string]interface{}{"key": "DB_PASSWORD"}},
+		},
+		Variables: map[string]interface{}{
+			"copied": "{{ secret \"db\" }}",
+		},
+	}
+
+	if err := manager.Initialize(workflow, nil); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	result, err := manager.EvaluateString("password={{ secret \"db\" }}")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result != "password=hunter2" {
+		t.Errorf("Expected 'password=hunter2', got '%s'", result)
+	}
+
+	for name, value := range manager.GetContext().Variables {
+		if value == "hunter2" {
+			t.Errorf("Expected secret value not to be stored in variable '%s'", name)
+		}
+	}
+
+	if _, err := manager.EvaluateString("{{ secret \"missing\" }}"); err == nil || !strings.Contains(err.Error(), "not declared") {
+		t.Errorf("Expected undeclared secret error, got: %v", err)
+	}
+
+	clone := manager.Clone()
+	if result, err := clone.EvaluateString("{{ secret \"db\" }}"); err != nil || result != "hunter2" {
+		t.Errorf("Expected clone to resolve secrets, got %q (%v)", result, err)
+	}
+}
+
+func TestManager_EncryptedVariableFiles(t *testing.T) {
+	dir := t.TempDir()
+	encrypted, err := secrets.Encrypt([]byte("region: eu-west-1\ndb_host: db.internal\n"), []byte("pw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "prod.yaml"), encrypted, 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_MANAGER_PASSPHRASE", "pw")
+
+	manager := NewWithWorkflowDir(template.New(), dir)
+	redactor := redact.New(redact.DefaultPatterns)
+	manager.SetRedactor(redactor)
+	manager.SetVariableKeySource("", "TEST_MANAGER_PASSPHRASE")
+
+	workflow := &types.Workflow{Name: "encrypted-vars", VariableFiles: []string{"prod.yaml"}}
+	if err := manager.Initialize(workflow, nil); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if value, _ := manager.GetVariable("db_host"); value != "db.internal" {
+		t.Errorf("Expected decrypted variable, got %v", value)
+	}
+	if masked := redactor.String("connect db.internal"); masked != "connect "+redact.Mask {
+		t.Errorf("Expected decrypted values to be masked, got %q", masked)
+	}
+}
+
+func TestManager_Provenance(t *testing.T) {
+	dir := t.TempDir()
+	workflowFile := filepath.Join(dir, "workflow.yaml")
+	files := map[string]string{
+		"workflow.yaml": "name: provenance\nenvironment:\n  REGION: eu-west-1\nvars:\n  size: \"{{ .vars.base }}-xl\"\n",
+		"common.yaml":   "base: small\nsize: small\nversion: 0.0.1\n",
+		"prod.yaml":     "# production\nbase: large\ncert: \"@cert.yaml\"\n",
+		"cert.yaml":     "cert: CERTDATA\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manager := NewWithWorkflowDir(template.New(), dir)
+	manager.SetWorkflowFile(workflowFile)
+	manager.SetInputs(map[string]interface{}{"version": "1.2.0"})
+	manager.SetInputOrigins(map[string]Origin{"version": {Detail: "--var"}})
+	manager.SetOverrideOrigins([]Origin{{File: ".env", Line: 3, Detail: "--env-file"}})
+
+	workflow := &types.Workflow{
+		Name:          "provenance",
+		Environment:   map[string]string{"REGION": "eu-west-1"},
+		VariableFiles: []string{"common.yaml", "prod.yaml"},
+		Variables:     map[string]interface{}{"size": "{{ .vars.base }}-xl"},
+	}
+	if err := manager.Initialize(workflow, []string{"REGION=us-west-2"}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	vars := manager.VariableProvenance()
+	base := vars["base"]
+	if base.Source != SourceVariableFile || base.Location() != filepath.Join(dir, "prod.yaml")+":2" || base.Value != "large" {
+		t.Errorf("Expected base from prod.yaml:2, got %+v", base)
+	}
+	if len(base.Shadowed) != 1 || base.Shadowed[0].Location() != filepath.Join(dir, "common.yaml")+":1" || base.Shadowed[0].Value != "small" {
+		t.Errorf("Expected base to shadow common.yaml:1, got %+v", base.Shadowed)
+	}
+
+	size := vars["size"]
+	if size.Source != SourceVars || size.Location() != workflowFile+":5" || size.Value != "large-xl" {
+		t.Errorf("Expected size from the workflow's vars, got %+v", size)
+	}
+	if len(size.Shadowed) != 1 || size.Shadowed[0].Source != SourceVariableFile {
+		t.Errorf("Expected size to shadow the file value, got %+v", size.Shadowed)
+	}
+
+	if cert := vars["cert"]; cert.Source != SourceFileRef || cert.Detail != "loaded from @cert.yaml" || cert.Line != 3 {
+		t.Errorf("Expected cert from a file reference, got %+v", cert)
+	}
+
+	// Inputs win over variable files, which are recorded as shadowed
+	version := vars["version"]
+	if version.Source != SourceInput || version.Detail != "--var" || len(version.Shadowed) != 1 || version.Shadowed[0].Value != "0.0.1" {
+		t.Errorf("Expected version from an input shadowing common.yaml, got %+v", version)
+	}
+
+	region := manager.EnvironmentProvenance()["REGION"]
+	if region.Source != SourceOverride || region.Location() != ".env:3" || region.Value != "us-west-2" {
+		t.Errorf("Expected REGION from the override, got %+v", region)
+	}
+	if len(region.Shadowed) == 0 || region.Shadowed[0].Source != SourceEnvironment || region.Shadowed[0].Line != 3 {
+		t.Errorf("Expected REGION to shadow the workflow environment, got %+v", region.Shadowed)
+	}
+}
+
+func TestManager_VariableFileMergeAndEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"base.yaml":                        "db:\n  host: localhost\n  port: 5432\nservices:\n  - name: api\n    replicas: 1\n  - name: web\n    replicas: 1\n",
+		"variables/environments/prod.yaml": "db:\n  host: db.prod\nservices:\n  - name: api\n    replicas: 3\n  - name: worker\n    replicas: 2\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manager := NewWithWorkflowDir(template.New(), dir)
+	manager.SetVariableEnvironment("prod")
+	workflow := &types.Workflow{
+		Name:          "merge",
+		VariableFiles: []string{"base.yaml"},
+		VariableMerge: &types.MergeConfig{Paths: map[string]string{"services": "merge_by_key"}},
+	}
+	if err := manager.Initialize(workflow, nil); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	db := manager.GetContext().Variables["db"].(map[string]interface{})
+	if db["host"] != "db.prod" || db["port"] != 5432 {
+		t.Errorf("Expected db.host from the overlay and db.port from the base, got %v", db)
+	}
+
+	services := manager.GetContext().Variables["services"].([]interface{})
+	if len(services) != 3 {
+		t.Fatalf("Expected 3 services, got %v", services)
+	}
+	api := services[0].(map[string]interface{})
+	if api["name"] != "api" || api["replicas"] != 3 {
+		t.Errorf("Expected api with 3 replicas first, got %v", api)
+	}
+	if worker := services[2].(map[string]interface{}); worker["name"] != "worker" {
+		t.Errorf("Expected worker appended last, got %v", worker)
+	}
+
+	origin := manager.VariableProvenance()["db"]
+	if origin.File != filepath.Join(dir, "variables/environments/prod.yaml") || origin.Detail != "environment prod, merged" {
+		t.Errorf("Expected db from the prod overlay, got %+v", origin)
+	}
+
+	manager.SetVariableEnvironment("staging")
+	if err := manager.Initialize(workflow, nil); err == nil || !strings.Contains(err.Error(), "no variable file for environment 'staging'") {
+		t.Errorf("Expected a missing overlay error, got %v", err)
+	}
+}
+
 func TestParseVariableString(t *testing.T) {
 	tests := []struct {
 		input       string
@@ -0,0 +1,119 @@
+// ABOUTME: Provenance of the variables and environment entries in a workflow context
+// ABOUTME: Records the source, file and line of each value and the earlier values it overrode
+
+package context
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/sarlalian/ritual/internal/variables"
+)
+
+// Sources of context values, in the order Initialize applies them
+const (
+	SourceSystem       = "system"         // the process environment
+	SourceEnvironment  = "environment"    // the workflow's environment section
+	SourceOverride     = "override"       // entries passed to Initialize, e.g. --env-file and --var
+	SourceInput        = "input"          // declared inputs
+	SourceVariableFile = "variable_file"  // files listed in variable_files
+	SourceFileRef      = "file_reference" // "@path" values in a variable file
+	SourceVars         = "vars"           // the workflow's vars section
+)
+
+// Origin describes where a value was set
+type Origin struct {
+	Source string      `json:"source"`
+	File   string      `json:"file,omitempty"`
+	Line   int         `json:"line,omitempty"`
+	Detail string      `json:"detail,omitempty"`
+	Value  interface{} `json:"value,omitempty"`
+}
+
+// Location returns "file:line", "file" or "" when the origin has no file
+func (o Origin) Location() string {
+	switch {
+	case o.File == "":
+		return ""
+	case o.Line > 0:
+		return fmt.Sprintf("%s:%d", o.File, o.Line)
+	default:
+		return o.File
+	}
+}
+
+// Provenance is the origin of a value and the values it replaced, most
+// recent first
+type Provenance struct {
+	Origin
+	Shadowed []Origin `json:"shadowed,omitempty"`
+}
+
+// provenance maps names to their provenance
+type provenance map[string]*Provenance
+
+// set records origin as the current origin of name, shadowing the previous one
+func (p provenance) set(name string, origin Origin) {
+	current, exists := p[name]
+	if !exists {
+		p[name] = &Provenance{Origin: origin}
+		return
+	}
+	current.Shadowed = append([]Origin{current.Origin}, current.Shadowed...)
+	current.Origin = origin
+}
+
+// shadow records origin as a value that lost to the current one
+func (p provenance) shadow(name string, origin Origin) {
+	if current, exists := p[name]; exists {
+		current.Shadowed = append(current.Shadowed, origin)
+	}
+}
+
+// copy returns a copy safe to hand to callers
+func (p provenance) copy() map[string]Provenance {
+	copied := make(map[string]Provenance, len(p))
+	for name, entry := range p {
+		copied[name] = Provenance{
+			Origin:   entry.Origin,
+			Shadowed: append([]Origin(nil), entry.Shadowed...),
+		}
+	}
+	return copied
+}
+
+// joinDetails joins the non-empty details of an origin
+func joinDetails(details ...string) string {
+	var kept []string
+	for _, detail := range details {
+		if detail != "" {
+			kept = append(kept, detail)
+		}
+	}
+	return strings.Join(kept, ", ")
+}
+
+// SortedNames returns the names in entries in order
+func SortedNames(entries map[string]Provenance) []string {
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// workflowKeyLines returns the lines of the keys under section in the
+// workflow file, when it is known and readable
+func (m *Manager) workflowKeyLines(section string) map[string]int {
+	if m.workflowFile == "" {
+		return nil
+	}
+	content, err := os.ReadFile(m.workflowFile)
+	if err != nil {
+		return nil
+	}
+	return variables.KeyLines(content, variables.FormatYAML, section)
+}
@@ -0,0 +1,371 @@
+// ABOUTME: Dynamic variable sources resolved when a workflow's vars are loaded
+// ABOUTME: Reads values from commands, files, HTTP endpoints or the environment, with timeouts and caching
+
+package context
+
+import (
+	"bytes"
+	stdcontext "context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/sarlalian/ritual/pkg/coerce"
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+// Keys that make a vars entry a dynamic source
+const (
+	FromCommand = "from_command"
+	FromFile    = "from_file"
+	FromHTTP    = "from_http"
+	FromEnv     = "from_env"
+)
+
+// DefaultSourceTimeout bounds commands and HTTP requests that set no timeout
+const DefaultSourceTimeout = 30 * time.Second
+
+// maxSourceBytes caps how much of a command's output or a response is read
+const maxSourceBytes = 10 << 20
+
+var sourceKinds = []string{FromCommand, FromFile, FromHTTP, FromEnv}
+
+// variableSource is a vars entry whose value is read from outside the
+// workflow
+type variableSource struct {
+	kind       string
+	target     string // command, path, URL or environment variable name
+	required   bool
+	defaultVal interface{}
+	hasDefault bool
+	timeout    time.Duration
+	cache      time.Duration
+	trim       bool
+	parse      string
+	headers    map[string]string
+}
+
+// parseSource returns the source declared by value, or nil when value is
+// an ordinary variable. A map is a source when it has exactly one from_*
+// key; any other key must be a known option.
+func parseSource(value interface{}) (*variableSource, error) {
+	spec, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	var kinds []string
+	for _, kind := range sourceKinds {
+		if _, ok := spec[kind]; ok {
+			kinds = append(kinds, kind)
+		}
+	}
+	switch len(kinds) {
+	case 0:
+		return nil, nil
+	case 1:
+	default:
+		return nil, fmt.Errorf("only one of %s may be set, got %s", strings.Join(sourceKinds, ", "), strings.Join(kinds, " and "))
+	}
+
+	src := &variableSource{kind: kinds[0], timeout: DefaultSourceTimeout, trim: true}
+	target, ok := spec[src.kind].(string)
+	if !ok || target == "" {
+		return nil, fmt.Errorf("%s must be a non-empty string", src.kind)
+	}
+	src.target = target
+
+	keys := make([]string, 0, len(spec))
+	for key := range spec {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		option := spec[key]
+		var err error
+		switch key {
+		case src.kind:
+		case "required":
+			src.required, err = coerce.Bool(option)
+		case "trim":
+			src.trim, err = coerce.Bool(option)
+		case "default":
+			src.defaultVal, src.hasDefault = option, true
+		case "timeout":
+			src.timeout, err = positiveDuration(option)
+		case "cache":
+			src.cache, err = positiveDuration(option)
+		case "parse":
+			src.parse, _ = option.(string)
+			if src.parse != "json" && src.parse != "yaml" {
+				err = fmt.Errorf("must be json or yaml, got %v", option)
+			}
+		case "headers":
+			if src.kind != FromHTTP {
+				err = fmt.Errorf("only applies to %s", FromHTTP)
+				break
+			}
+			src.headers, err = coerce.StringMap(option)
+		default:
+			return nil, fmt.Errorf("unknown option '%s' for %s", key, src.kind)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return src, nil
+}
+
+// positiveDuration converts value with coerce.Duration, rejecting zero and
+// negative durations
+func positiveDuration(value interface{}) (time.Duration, error) {
+	d, err := coerce.Duration(value)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("expected a positive duration such as 30s, got %v", value)
+	}
+	return d, nil
+}
+
+// CheckVariableSources validates the dynamic sources in a workflow's vars
+func CheckVariableSources(vars map[string]interface{}) []error {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		if _, err := parseSource(vars[name]); err != nil {
+			errs = append(errs, types.NewValidationError("vars."+name, vars[name], err.Error()))
+		}
+	}
+	return errs
+}
+
+// evaluateSource returns a copy of src with templates in its target and
+// headers evaluated against the context
+func (m *Manager) evaluateSource(src *variableSource) (*variableSource, error) {
+	evaluated := *src
+	var err error
+	if evaluated.target, err = m.templateEngine.Evaluate(src.target, m.context); err != nil {
+		return nil, err
+	}
+	if len(src.headers) > 0 {
+		evaluated.headers = make(map[string]string, len(src.headers))
+		for name, value := range src.headers {
+			if evaluated.headers[name], err = m.templateEngine.Evaluate(value, m.context); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &evaluated, nil
+}
+
+// sourceCache holds the values of sources with a cache duration for the
+// life of the process
+var sourceCache = struct {
+	sync.Mutex
+	entries map[string]cachedSource
+}{entries: make(map[string]cachedSource)}
+
+type cachedSource struct {
+	value   interface{}
+	expires time.Time
+}
+
+// cacheKey identifies what a source reads, so differently configured
+// entries never share a value
+func (m *Manager) cacheKey(src *variableSource) string {
+	headers := make([]string, 0, len(src.headers))
+	for name, value := range src.headers {
+		headers = append(headers, name+":"+value)
+	}
+	sort.Strings(headers)
+	return strings.Join([]string{src.kind, src.target, m.workflowDir, src.parse, fmt.Sprint(src.trim), strings.Join(headers, "\n")}, "\x00")
+}
+
+// readSource reads the value of an evaluated source. cached reports whether
+// it was served from the cache.
+func (m *Manager) readSource(src *variableSource) (value interface{}, cached bool, err error) {
+	if src.cache > 0 && src.kind != FromEnv {
+		key := m.cacheKey(src)
+		sourceCache.Lock()
+		entry, ok := sourceCache.entries[key]
+		sourceCache.Unlock()
+		if ok && time.Now().Before(entry.expires) {
+			return entry.value, true, nil
+		}
+		defer func() {
+			if err == nil {
+				sourceCache.Lock()
+				sourceCache.entries[key] = cachedSource{value: value, expires: time.Now().Add(src.cache)}
+				sourceCache.Unlock()
+			}
+		}()
+	}
+
+	var raw string
+	switch src.kind {
+	case FromCommand:
+		raw, err = m.runSourceCommand(src)
+	case FromFile:
+		raw, err = m.readSourceFile(src)
+	case FromHTTP:
+		raw, err = fetchSource(src)
+	case FromEnv:
+		raw = m.context.Environment[src.target]
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	if src.trim {
+		raw = strings.TrimSpace(raw)
+	}
+	if raw == "" {
+		switch {
+		case src.hasDefault:
+			return src.defaultVal, false, nil
+		case src.required && src.kind == FromEnv:
+			return nil, false, fmt.Errorf("environment variable %s is required but not set", src.target)
+		case src.required:
+			return nil, false, fmt.Errorf("%s %s is required but produced no value", src.kind, src.target)
+		}
+	}
+
+	if src.parse != "" {
+		var parsed interface{}
+		if err := yaml.Unmarshal([]byte(raw), &parsed); err != nil {
+			return nil, false, fmt.Errorf("failed to parse %s output as %s: %w", src.kind, src.parse, err)
+		}
+		return parsed, false, nil
+	}
+	return raw, false, nil
+}
+
+// runSourceCommand runs a from_command source with the workflow's
+// environment, in the workflow's directory
+func (m *Manager) runSourceCommand(src *variableSource) (string, error) {
+	ctx, cancel := stdcontext.WithTimeout(stdcontext.Background(), src.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", src.target)
+	cmd.Dir = m.workflowDir
+	// Don't wait for children of the shell still holding its output open
+	cmd.WaitDelay = 100 * time.Millisecond
+	for name, value := range m.context.Environment {
+		cmd.Env = append(cmd.Env, name+"="+value)
+	}
+	stdout := &limitedBuffer{limit: maxSourceBytes, onLimit: cancel}
+	var stderr bytes.Buffer
+	cmd.Stdout = stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if stdout.exceeded {
+		return "", fmt.Errorf("command '%s' printed more than %d bytes", src.target, maxSourceBytes)
+	}
+	if err != nil {
+		if errors.Is(ctx.Err(), stdcontext.DeadlineExceeded) {
+			return "", fmt.Errorf("command '%s' timed out after %s", src.target, src.timeout)
+		}
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			return "", fmt.Errorf("command '%s' failed: %w: %s", src.target, err, detail)
+		}
+		return "", fmt.Errorf("command '%s' failed: %w", src.target, err)
+	}
+	return stdout.buf.String(), nil
+}
+
+// errSourceTooLarge stops the copy of a command's output once it passes the
+// limit
+var errSourceTooLarge = errors.New("output limit exceeded")
+
+// limitedBuffer collects up to limit bytes and calls onLimit, which kills the
+// command, as soon as more arrive
+type limitedBuffer struct {
+	buf      bytes.Buffer
+	limit    int
+	onLimit  func()
+	exceeded bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.exceeded {
+		return 0, errSourceTooLarge
+	}
+	if b.buf.Len()+len(p) > b.limit {
+		b.exceeded = true
+		b.onLimit()
+		return 0, errSourceTooLarge
+	}
+	return b.buf.Write(p)
+}
+
+// readSourceFile reads a from_file source, relative to the workflow's
+// directory
+func (m *Manager) readSourceFile(src *variableSource) (string, error) {
+	path := src.target
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(m.workflowDir, path)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		// A missing file falls back to the default when there is one
+		if os.IsNotExist(err) && src.hasDefault {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return string(content), nil
+}
+
+// fetchSource GETs a from_http source and returns the body of a 2xx response
+func fetchSource(src *variableSource) (string, error) {
+	ctx, cancel := stdcontext.WithTimeout(stdcontext.Background(), src.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src.target, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL '%s': %w", src.target, err)
+	}
+	for name, value := range src.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if errors.Is(ctx.Err(), stdcontext.DeadlineExceeded) {
+			return "", fmt.Errorf("GET %s timed out after %s", src.target, src.timeout)
+		}
+		return "", fmt.Errorf("GET %s failed: %w", src.target, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSourceBytes+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response from %s: %w", src.target, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("GET %s returned %s", src.target, resp.Status)
+	}
+	if len(body) > maxSourceBytes {
+		return "", fmt.Errorf("response from %s larger than %d bytes", src.target, maxSourceBytes)
+	}
+	return string(body), nil
+}
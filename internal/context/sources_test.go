@@ -0,0 +1,159 @@
+// ABOUTME: Tests for dynamic variable sources in a workflow's vars
+// ABOUTME: Covers commands, files, HTTP, the environment, defaults, caching, timeouts and validation
+
+package context
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sarlalian/ritual/internal/template"
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+func TestManager_VariableSources(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("Authorization") != "Bearer eu" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"ami": "ami-123"}`)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "motd.txt"), []byte("  hello\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := NewWithWorkflowDir(template.New(), dir)
+	workflow := &types.Workflow{
+		Name:        "sources",
+		Environment: map[string]string{"STAGE": "prod"},
+		Variables: map[string]interface{}{
+			"region": "eu",
+			"sha":    map[string]interface{}{FromCommand: "echo {{ .vars.region }}-$STAGE-$(basename $PWD)"},
+			"motd":   map[string]interface{}{FromFile: "motd.txt"},
+			"raw":    map[string]interface{}{FromFile: "motd.txt", "trim": false},
+			"image": map[string]interface{}{
+				FromHTTP:  server.URL,
+				"headers": map[string]interface{}{"Authorization": "Bearer {{ .vars.region }}"},
+				"parse":   "json",
+				"cache":   "1m",
+			},
+			"stage":   map[string]interface{}{FromEnv: "STAGE", "required": true},
+			"missing": map[string]interface{}{FromEnv: "RITUAL_TEST_UNSET", "default": 3},
+		},
+	}
+
+	for run := 0; run < 2; run++ {
+		if err := manager.Initialize(workflow, nil); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+
+	vars := manager.GetContext().Variables
+	if want := "eu-prod-" + filepath.Base(dir); vars["sha"] != want {
+		t.Errorf("Expected sha %q, got %v", want, vars["sha"])
+	}
+	if vars["motd"] != "hello" || vars["raw"] != "  hello\n" {
+		t.Errorf("Expected trimmed and raw motd, got %q and %q", vars["motd"], vars["raw"])
+	}
+	if image, ok := vars["image"].(map[string]interface{}); !ok || image["ami"] != "ami-123" {
+		t.Errorf("Expected the parsed response, got %v", vars["image"])
+	}
+	if vars["stage"] != "prod" || vars["missing"] != 3 {
+		t.Errorf("Expected stage from the environment and missing from its default, got %v and %v", vars["stage"], vars["missing"])
+	}
+
+	// The second run is served from the cache
+	if n := requests.Load(); n != 1 {
+		t.Errorf("Expected 1 request, got %d", n)
+	}
+	if origin := manager.VariableProvenance()["image"]; origin.Detail != "from_http "+server.URL+", cached" {
+		t.Errorf("Expected the cached source in the provenance, got %q", origin.Detail)
+	}
+	if origin := manager.VariableProvenance()["sha"]; !strings.Contains(origin.Detail, "{{ .vars.region }}") {
+		t.Errorf("Expected the unevaluated command in the provenance, got %q", origin.Detail)
+	}
+}
+
+func TestManager_VariableSourceErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/large" {
+			_, _ = w.Write(bytes.Repeat([]byte("x"), maxSourceBytes+1))
+			return
+		}
+		http.Error(w, "gone", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		source map[string]interface{}
+		want   string
+	}{
+		{"failing command", map[string]interface{}{FromCommand: "echo oops >&2; exit 3"}, "exit status 3: oops"},
+		{"timeout", map[string]interface{}{FromCommand: "sleep 5", "timeout": "50ms"}, "timed out after 50ms"},
+		{"oversized output", map[string]interface{}{FromCommand: "yes"}, "printed more than"},
+		{"missing file", map[string]interface{}{FromFile: "nope.txt"}, "failed to read"},
+		{"http status", map[string]interface{}{FromHTTP: server.URL}, "404 Not Found"},
+		{"oversized response", map[string]interface{}{FromHTTP: server.URL + "/large"}, "larger than"},
+		{"required env", map[string]interface{}{FromEnv: "RITUAL_TEST_UNSET", "required": true}, "RITUAL_TEST_UNSET is required but not set"},
+		{"required output", map[string]interface{}{FromCommand: "true", "required": true}, "required but produced no value"},
+		{"unknown reference", map[string]interface{}{FromCommand: "echo {{ .vars.nope }}"}, "nope"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := NewWithWorkflowDir(template.New(), t.TempDir())
+			workflow := &types.Workflow{Name: "errors", Variables: map[string]interface{}{"value": tt.source}}
+			err := manager.Initialize(workflow, nil)
+			if err == nil || !strings.Contains(err.Error(), "variable 'value'") || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestCheckVariableSources(t *testing.T) {
+	vars := map[string]interface{}{
+		"plain":    map[string]interface{}{"from_address": "ops@example.com"},
+		"good":     map[string]interface{}{FromCommand: "date", "timeout": "5s", "cache": "1h"},
+		"loose":    map[string]interface{}{FromEnv: "HOME", "timeout": 30, "required": "yes", "trim": "off"},
+		"negative": map[string]interface{}{FromCommand: "date", "cache": -5},
+		"both":     map[string]interface{}{FromCommand: "date", FromEnv: "HOME"},
+		"option":   map[string]interface{}{FromEnv: "HOME", "retries": 3},
+		"duration": map[string]interface{}{FromHTTP: "http://example.com", "timeout": "soon"},
+		"headers":  map[string]interface{}{FromFile: "a.txt", "headers": map[string]interface{}{"A": "b"}},
+		"empty":    map[string]interface{}{FromFile: ""},
+	}
+
+	errs := CheckVariableSources(vars)
+	var fields []string
+	for _, err := range errs {
+		fields = append(fields, err.(*types.ValidationError).Field)
+	}
+	if want := "vars.both,vars.duration,vars.empty,vars.headers,vars.negative,vars.option"; strings.Join(fields, ",") != want {
+		t.Errorf("Expected errors for %s, got %v", want, errs)
+	}
+
+	// Options accept the same loose forms as task config
+	src, err := parseSource(vars["loose"])
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if src.timeout != 30*time.Second || !src.required || src.trim {
+		t.Errorf("Expected a 30s timeout, required and untrimmed, got %v, %v and %v", src.timeout, src.required, src.trim)
+	}
+}
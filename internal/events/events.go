@@ -0,0 +1,179 @@
+// ABOUTME: Typed execution events and a fan-out bus for workflow lifecycle observers
+// ABOUTME: The executor and orchestrator publish; loggers, renderers, servers and history subscribe
+
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sarlalian/ritual/internal/redact"
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+// Type identifies the kind of execution event
+type Type string
+
+// Event types published during a workflow execution
+const (
+	WorkflowStarted  Type = "workflow.started"
+	WorkflowFinished Type = "workflow.finished"
+	TaskQueued       Type = "task.queued"
+	TaskStarted      Type = "task.started"
+	TaskFinished     Type = "task.finished"
+	TaskSkipped      Type = "task.skipped"
+	LogLine          Type = "log.line"
+)
+
+// Output streams carried by LogLine events
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+	StreamLog    = "log"
+)
+
+// Event describes something that happened during a workflow execution. Only
+// the fields relevant to the event type are set.
+type Event struct {
+	Type        Type      `json:"type"`
+	Time        time.Time `json:"time"`
+	ExecutionID string    `json:"execution_id,omitempty"`
+	Workflow    string    `json:"workflow,omitempty"`
+
+	TaskID   string           `json:"task_id,omitempty"`
+	TaskName string           `json:"task_name,omitempty"`
+	TaskType string           `json:"task_type,omitempty"`
+	Status   types.TaskStatus `json:"status,omitempty"`
+	Message  string           `json:"message,omitempty"`
+	Attempt  int              `json:"attempt,omitempty"`
+
+	// Dependencies lists the IDs of the task's dependencies on TaskQueued events
+	Dependencies []string `json:"dependencies,omitempty"`
+
+	// Stream and Line are set on LogLine events
+	Stream string `json:"stream,omitempty"`
+	Line   string `json:"line,omitempty"`
+
+	Duration time.Duration `json:"duration,omitempty"`
+
+	// TaskResult is set on TaskFinished and TaskSkipped events
+	TaskResult *types.TaskResult `json:"-"`
+
+	// Result is set on WorkflowFinished events and includes parse,
+	// validation and dependency errors as well as the workflow result
+	Result *types.Result `json:"-"`
+
+	// WorkflowPath and EnvVars are set on workflow events
+	WorkflowPath string   `json:"workflow_path,omitempty"`
+	EnvVars      []string `json:"-"`
+
+	// TraceParent is the W3C traceparent the run continues, on WorkflowStarted
+	TraceParent string `json:"traceparent,omitempty"`
+}
+
+// Observer receives execution events. OnEvent is called synchronously from the
+// publishing goroutine, possibly concurrently for parallel tasks, so observers
+// must be safe for concurrent use and should return quickly.
+type Observer interface {
+	OnEvent(event Event)
+}
+
+// ObserverFunc adapts a function to the Observer interface
+type ObserverFunc func(event Event)
+
+// OnEvent calls f(event)
+func (f ObserverFunc) OnEvent(event Event) {
+	f(event)
+}
+
+// Bus fans published events out to every subscribed observer
+type Bus struct {
+	mu            sync.RWMutex
+	nextID        int
+	subscriptions []subscription
+}
+
+type subscription struct {
+	id       int
+	observer Observer
+}
+
+// NewBus creates an event bus with no subscribers
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe attaches an observer and returns a function that detaches it
+func (b *Bus) Subscribe(observer Observer) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	b.subscriptions = append(b.subscriptions, subscription{id: id, observer: observer})
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for i, sub := range b.subscriptions {
+			if sub.id == id {
+				b.subscriptions = append(b.subscriptions[:i:i], b.subscriptions[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish delivers the event to every observer in subscription order. A nil
+// bus discards events, so publishers need not check for one.
+func (b *Bus) Publish(event Event) {
+	if b == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	subscriptions := b.subscriptions
+	b.mu.RUnlock()
+
+	for _, sub := range subscriptions {
+		sub.observer.OnEvent(event)
+	}
+}
+
+// busKey is the context key for the event bus
+type busKey struct{}
+
+// WithBus returns a context carrying the event bus, letting tasks publish
+// log lines for the execution they belong to
+func WithBus(ctx context.Context, bus *Bus) context.Context {
+	return context.WithValue(ctx, busKey{}, bus)
+}
+
+// BusFromContext returns the event bus carried by ctx, or nil
+func BusFromContext(ctx context.Context) *Bus {
+	bus, _ := ctx.Value(busKey{}).(*Bus)
+	return bus
+}
+
+// Log publishes a line of task output on the bus carried by ctx, if any, with
+// sensitive values masked by the context's redactor
+func Log(ctx context.Context, task *types.TaskConfig, stream, line string) {
+	bus := BusFromContext(ctx)
+	if bus == nil {
+		return
+	}
+
+	bus.Publish(Event{
+		Type:        LogLine,
+		ExecutionID: types.ExecutionIDFromContext(ctx),
+		TaskID:      task.ID,
+		TaskName:    task.Name,
+		TaskType:    task.Type,
+		Stream:      stream,
+		Line:        redact.FromContext(ctx).String(line),
+	})
+}
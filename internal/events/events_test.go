@@ -0,0 +1,151 @@
+// ABOUTME: Tests for the execution event bus and context helpers
+// ABOUTME: Verifies fan-out order, unsubscription, nil-bus safety and task log lines
+
+package events
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+// recorder collects events for assertions
+type recorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *recorder) OnEvent(event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func TestBus_PublishFansOutInOrder(t *testing.T) {
+	bus := NewBus()
+
+	var order []string
+	bus.Subscribe(ObserverFunc(func(Event) { order = append(order, "first") }))
+	bus.Subscribe(ObserverFunc(func(Event) { order = append(order, "second") }))
+
+	bus.Publish(Event{Type: TaskStarted, TaskID: "build"})
+
+	if len(order) != 2 || order[0] != "first" || order[1] != "second" {
+		t.Errorf("Expected observers to run in subscription order, got %v", order)
+	}
+}
+
+func TestBus_PublishSetsTime(t *testing.T) {
+	bus := NewBus()
+	rec := &recorder{}
+	bus.Subscribe(rec)
+
+	bus.Publish(Event{Type: WorkflowStarted})
+
+	if rec.events[0].Time.IsZero() {
+		t.Error("Expected Publish to stamp the event time")
+	}
+}
+
+func TestBus_Unsubscribe(t *testing.T) {
+	bus := NewBus()
+	first, second := &recorder{}, &recorder{}
+
+	unsubscribe := bus.Subscribe(first)
+	bus.Subscribe(second)
+
+	bus.Publish(Event{Type: TaskStarted})
+	unsubscribe()
+	bus.Publish(Event{Type: TaskFinished})
+
+	if len(first.events) != 1 {
+		t.Errorf("Expected unsubscribed observer to see 1 event, got %d", len(first.events))
+	}
+	if len(second.events) != 2 {
+		t.Errorf("Expected remaining observer to see 2 events, got %d", len(second.events))
+	}
+}
+
+func TestBus_NilBusDiscards(t *testing.T) {
+	var bus *Bus
+	bus.Publish(Event{Type: TaskStarted}) // must not panic
+}
+
+func TestBus_ConcurrentPublish(t *testing.T) {
+	bus := NewBus()
+	rec := &recorder{}
+	bus.Subscribe(rec)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			bus.Publish(Event{Type: LogLine})
+		}()
+	}
+	wg.Wait()
+
+	if len(rec.events) != 50 {
+		t.Errorf("Expected 50 events, got %d", len(rec.events))
+	}
+}
+
+func TestLog(t *testing.T) {
+	bus := NewBus()
+	rec := &recorder{}
+	bus.Subscribe(rec)
+
+	task := &types.TaskConfig{ID: "dump", Name: "Dump Database", Type: "command"}
+	ctx := types.WithExecutionID(WithBus(context.Background(), bus), "exec_1")
+
+	Log(ctx, task, StreamStdout, "dumping table users")
+	Log(context.Background(), task, StreamStdout, "no bus, dropped")
+
+	if len(rec.events) != 1 {
+		t.Fatalf("Expected 1 log event, got %d", len(rec.events))
+	}
+
+	event := rec.events[0]
+	if event.Type != LogLine || event.TaskID != "dump" || event.Stream != StreamStdout {
+		t.Errorf("Unexpected log event: %+v", event)
+	}
+	if event.Line != "dumping table users" {
+		t.Errorf("Expected line to be carried, got %q", event.Line)
+	}
+	if event.ExecutionID != "exec_1" {
+		t.Errorf("Expected execution ID from context, got %q", event.ExecutionID)
+	}
+}
+
+func TestLineWriter(t *testing.T) {
+	bus := NewBus()
+	rec := &recorder{}
+	bus.Subscribe(rec)
+
+	task := &types.TaskConfig{ID: "dump", Name: "Dump", Type: "command"}
+	w := NewLineWriter(WithBus(context.Background(), bus), task, StreamStderr)
+
+	_, _ = w.Write([]byte("par"))
+	_, _ = w.Write([]byte("tial line\r\nsecond\nthi"))
+	_, _ = w.Write([]byte("rd"))
+
+	if len(rec.events) != 2 {
+		t.Fatalf("Expected 2 complete lines before flush, got %d", len(rec.events))
+	}
+
+	w.Flush()
+	w.Flush() // nothing left to publish
+
+	want := []string{"partial line", "second", "third"}
+	if len(rec.events) != len(want) {
+		t.Fatalf("Expected %d lines, got %d", len(want), len(rec.events))
+	}
+	for i, line := range want {
+		if rec.events[i].Line != line || rec.events[i].Stream != StreamStderr {
+			t.Errorf("Line %d: expected %q on stderr, got %q on %s", i, line, rec.events[i].Line, rec.events[i].Stream)
+		}
+	}
+}
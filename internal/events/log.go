@@ -0,0 +1,49 @@
+// ABOUTME: Observer that writes execution events to the structured logger
+// ABOUTME: Produces the task lifecycle messages previously logged directly by the executor
+
+package events
+
+import (
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+// LogObserver logs execution events
+type LogObserver struct {
+	logger types.Logger
+}
+
+// NewLogObserver creates an observer that logs to the given logger
+func NewLogObserver(logger types.Logger) *LogObserver {
+	return &LogObserver{logger: logger}
+}
+
+// OnEvent logs the event
+func (l *LogObserver) OnEvent(event Event) {
+	if l.logger == nil {
+		return
+	}
+
+	switch event.Type {
+	case WorkflowStarted:
+		l.logger.Info().Msgf("Starting workflow execution: %s", event.Workflow)
+	case WorkflowFinished:
+		if event.Result != nil && event.Result.WorkflowResult != nil {
+			l.logger.Info().Msgf("Workflow '%s' completed with status %s in %v",
+				event.Workflow, event.Result.WorkflowResult.Status, event.Duration)
+		}
+	case TaskQueued:
+		l.logger.Debug().Msgf("Task '%s' queued", event.TaskName)
+	case TaskStarted:
+		l.logger.Info().Msgf("Executing task '%s' (%s)", event.TaskName, event.TaskType)
+	case TaskSkipped:
+		l.logger.Info().Msgf("Task '%s' skipped: %s", event.TaskName, event.Message)
+	case TaskFinished:
+		if event.Status == types.TaskSuccess {
+			l.logger.Info().Msgf("Task '%s' completed successfully", event.TaskName)
+		} else {
+			l.logger.Info().Msgf("Task '%s' failed: %s", event.TaskName, event.Message)
+		}
+	case LogLine:
+		l.logger.Info().Msgf("[%s] %s", event.TaskID, event.Line)
+	}
+}
@@ -0,0 +1,65 @@
+// ABOUTME: io.Writer that turns task output into LogLine events as it is produced
+// ABOUTME: Splits on newlines and flushes any trailing partial line when the task ends
+
+package events
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+// maxLineBytes bounds a buffered partial line; longer lines are published in pieces
+const maxLineBytes = 64 * 1024
+
+// LineWriter publishes each complete line written to it as a LogLine event
+type LineWriter struct {
+	mu      sync.Mutex
+	ctx     context.Context
+	task    *types.TaskConfig
+	stream  string
+	partial []byte
+}
+
+// NewLineWriter creates a writer publishing lines of the given stream for task
+// on the bus carried by ctx. Without a bus, writes are discarded.
+func NewLineWriter(ctx context.Context, task *types.TaskConfig, stream string) *LineWriter {
+	return &LineWriter{ctx: ctx, task: task, stream: stream}
+}
+
+// Write publishes every complete line in p and buffers the remainder
+func (w *LineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	data := append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		Log(w.ctx, w.task, w.stream, strings.TrimSuffix(string(data[:i]), "\r"))
+		data = data[i+1:]
+	}
+	for len(data) >= maxLineBytes {
+		Log(w.ctx, w.task, w.stream, string(data[:maxLineBytes]))
+		data = data[maxLineBytes:]
+	}
+	w.partial = append(w.partial[:0:0], data...)
+
+	return len(p), nil
+}
+
+// Flush publishes any buffered partial line
+func (w *LineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.partial) > 0 {
+		Log(w.ctx, w.task, w.stream, strings.TrimSuffix(string(w.partial), "\r"))
+		w.partial = nil
+	}
+}
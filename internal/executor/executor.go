@@ -5,10 +5,14 @@ package executor
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"sync"
+	"strings"
 	"time"
 
+	"github.com/sarlalian/ritual/internal/events"
+	"github.com/sarlalian/ritual/internal/expression"
+	"github.com/sarlalian/ritual/internal/redact"
 	"github.com/sarlalian/ritual/internal/workflow/resolver"
 	"github.com/sarlalian/ritual/pkg/types"
 )
@@ -18,6 +22,7 @@ type Executor struct {
 	contextManager types.ContextManager
 	taskRegistry   map[string]types.TaskExecutor
 	logger         types.Logger
+	events         *events.Bus
 	dryRun         bool
 	maxConcurrency int
 }
@@ -27,6 +32,10 @@ type Config struct {
 	DryRun         bool
 	MaxConcurrency int
 	Logger         types.Logger
+
+	// Events receives task lifecycle events. When nil the executor creates its
+	// own bus and logs events to Logger.
+	Events *events.Bus
 }
 
 // New creates a new executor with the given context manager
@@ -44,15 +53,29 @@ func New(contextManager types.ContextManager, config *Config) (*Executor, error)
 		return nil, fmt.Errorf("invalid executor configuration: %w", err)
 	}
 
+	bus := config.Events
+	if bus == nil {
+		bus = events.NewBus()
+		if config.Logger != nil {
+			bus.Subscribe(events.NewLogObserver(config.Logger))
+		}
+	}
+
 	return &Executor{
 		contextManager: contextManager,
 		taskRegistry:   make(map[string]types.TaskExecutor),
 		logger:         config.Logger,
+		events:         bus,
 		dryRun:         config.DryRun,
 		maxConcurrency: maxConcurrency,
 	}, nil
 }
 
+// Events returns the bus task lifecycle events are published on
+func (e *Executor) Events() *events.Bus {
+	return e.events
+}
+
 // RegisterTask registers a task executor for a specific task type
 func (e *Executor) RegisterTask(taskType string, executor types.TaskExecutor) {
 	e.taskRegistry[taskType] = executor
@@ -75,27 +98,23 @@ func (e *Executor) ExecuteWorkflow(ctx context.Context, workflow *types.Workflow
 		Status:    types.WorkflowRunning,
 	}
 
-	// Execute layers sequentially, tasks within layers in parallel
-	for layerNum, layer := range layers {
-		e.logf("Executing layer %d with %d tasks", layerNum, len(layer.Tasks))
+	// Tasks publish their output on the execution's bus
+	if events.BusFromContext(ctx) == nil {
+		ctx = events.WithBus(ctx, e.events)
+	}
 
-		if workflow.Mode == types.SequentialMode {
-			// Execute tasks sequentially within the layer
-			if err := e.executeLayerSequential(ctx, layer, result); err != nil {
-				result.Status = types.WorkflowFailed
-				result.EndTime = time.Now()
-				result.Duration = result.EndTime.Sub(startTime)
-				return result, err
-			}
-		} else {
-			// Execute tasks in parallel within the layer
-			if err := e.executeLayerParallel(ctx, layer, result); err != nil {
-				result.Status = types.WorkflowFailed
-				result.EndTime = time.Now()
-				result.Duration = result.EndTime.Sub(startTime)
-				return result, err
-			}
-		}
+	var execErr error
+	if workflow.Mode == types.SequentialMode {
+		execErr = e.executeLayersSequential(ctx, layers, result)
+	} else {
+		execErr = e.executeGraphParallel(ctx, layers, result)
+	}
+
+	if execErr != nil {
+		result.Status = types.WorkflowFailed
+		result.EndTime = time.Now()
+		result.Duration = result.EndTime.Sub(startTime)
+		return result, execErr
 	}
 
 	// Determine final status
@@ -146,15 +165,32 @@ func (e *Executor) ExecuteTask(ctx context.Context, task *types.TaskConfig) (*ty
 		Status:    types.TaskRunning,
 	}
 
-	e.logf("Executing task '%s' (%s)", task.Name, task.Type)
+	e.publish(ctx, taskEvent(events.TaskStarted, task))
 
 	// Check if task should be skipped based on conditions
-	if shouldSkip, reason := e.shouldSkipTask(task); shouldSkip {
+	shouldSkip, reason, condErr := e.shouldSkipTask(task)
+	if condErr != nil && !e.dryRun {
+		result.Status = types.TaskFailed
+		result.Message = fmt.Sprintf("failed to evaluate condition: %v", condErr)
+		result.EndTime = time.Now()
+		result.Duration = result.EndTime.Sub(result.StartTime)
+		e.publishResult(ctx, events.TaskFinished, task, result)
+		if err := e.contextManager.RegisterTaskResult(result); err != nil {
+			e.logf("Warning: failed to register task result for '%s': %v", task.ID, err)
+		}
+		return result, nil
+	}
+	if condErr != nil {
+		// Upstream tasks produce no output in dry-run mode, so a condition that
+		// cannot be evaluated is reported rather than failing the plan
+		shouldSkip, reason = true, fmt.Sprintf("condition could not be evaluated in dry run: %v", condErr)
+	}
+	if shouldSkip {
 		result.Status = types.TaskSkipped
 		result.Message = reason
 		result.EndTime = time.Now()
 		result.Duration = result.EndTime.Sub(result.StartTime)
-		e.logf("Task '%s' skipped: %s", task.Name, reason)
+		e.publishResult(ctx, events.TaskSkipped, task, result)
 		return result, nil
 	}
 
@@ -164,13 +200,20 @@ func (e *Executor) ExecuteTask(ctx context.Context, task *types.TaskConfig) (*ty
 		result.Message = "Dry run mode - task would be executed"
 		result.EndTime = time.Now()
 		result.Duration = result.EndTime.Sub(result.StartTime)
-		e.logf("Task '%s' dry run completed", task.Name)
+		e.publishResult(ctx, events.TaskSkipped, task, result)
 	} else {
+		e.trackSecretFields(ctx, executor, task)
+
 		// Execute the actual task
 		execResult := executor.Execute(ctx, task, e.contextManager)
+		result.AttemptCount = 1
 
 		// Update result with execution details
 		result.Status = execResult.Status
+		result.Changed = execResult.Changed
+		if reporter, ok := executor.(types.ChangeReporter); ok {
+			result.ChangeTracked = reporter.ReportsChange()
+		}
 		result.Message = execResult.Message
 		result.Stdout = execResult.Stdout
 		result.Stderr = execResult.Stderr
@@ -179,12 +222,11 @@ func (e *Executor) ExecuteTask(ctx context.Context, task *types.TaskConfig) (*ty
 		result.EndTime = time.Now()
 		result.Duration = result.EndTime.Sub(result.StartTime)
 
-		// Log completion
-		if result.Status == types.TaskSuccess {
-			e.logf("Task '%s' completed successfully", task.Name)
-		} else {
-			e.logf("Task '%s' failed: %s", task.Name, result.Message)
-		}
+		// Mask credentials before the result is published or registered
+		// where other tasks, reports and history can read it
+		redact.FromContext(ctx).TaskResult(result)
+
+		e.publishResult(ctx, events.TaskFinished, task, result)
 	}
 
 	// Register task result in context for other tasks to use
@@ -195,8 +237,62 @@ func (e *Executor) ExecuteTask(ctx context.Context, task *types.TaskConfig) (*ty
 	return result, nil
 }
 
+// trackSecretFields adds the rendered values of the task's credential
+// fields to the run's redactor before the task can print them
+func (e *Executor) trackSecretFields(ctx context.Context, executor types.TaskExecutor, task *types.TaskConfig) {
+	redactor := redact.FromContext(ctx)
+	describer, ok := executor.(types.SecretFieldDescriber)
+	if redactor == nil || !ok {
+		return
+	}
+
+	for _, field := range describer.SecretFields() {
+		value, ok := task.Config[field].(string)
+		if !ok || value == "" {
+			continue
+		}
+		if strings.Contains(value, "{{") {
+			rendered, err := e.contextManager.EvaluateString(value)
+			if err != nil {
+				continue
+			}
+			value = rendered
+		}
+		redactor.Add(value)
+	}
+}
+
+// executeLayersSequential runs the layers one task at a time. Once a required
+// task has failed, later layers only run tasks whose trigger rule explicitly
+// reacts to failures.
+func (e *Executor) executeLayersSequential(ctx context.Context, layers []*resolver.ExecutionLayer, workflowResult *types.WorkflowResult) error {
+	var requiredFailure error
+
+	for layerNum, layer := range layers {
+		e.logf("Executing layer %d with %d tasks", layerNum, len(layer.Tasks))
+		for _, node := range layer.Tasks {
+			e.publish(ctx, queuedEvent(node))
+		}
+
+		err := e.executeLayerSequential(ctx, layer, workflowResult, requiredFailure != nil)
+		if err != nil {
+			var reqErr *requiredTaskError
+			if !errors.As(err, &reqErr) {
+				return err
+			}
+			if requiredFailure == nil {
+				requiredFailure = err
+			}
+		}
+	}
+
+	return requiredFailure
+}
+
 // executeLayerSequential executes all tasks in a layer sequentially
-func (e *Executor) executeLayerSequential(ctx context.Context, layer *resolver.ExecutionLayer, workflowResult *types.WorkflowResult) error {
+func (e *Executor) executeLayerSequential(ctx context.Context, layer *resolver.ExecutionLayer, workflowResult *types.WorkflowResult, aborting bool) error {
+	var firstError error
+
 	for _, taskNode := range layer.Tasks {
 		select {
 		case <-ctx.Done():
@@ -204,7 +300,11 @@ func (e *Executor) executeLayerSequential(ctx context.Context, layer *resolver.E
 		default:
 		}
 
-		result, err := e.ExecuteTask(ctx, taskNode.Task)
+		if (aborting || firstError != nil) && !runsAfterFailure(taskNode.Task) {
+			continue
+		}
+
+		result, err := e.executeNode(ctx, taskNode, workflowResult.Tasks)
 		if err != nil {
 			return fmt.Errorf("task '%s' execution failed: %w", taskNode.Task.ID, err)
 		}
@@ -212,88 +312,269 @@ func (e *Executor) executeLayerSequential(ctx context.Context, layer *resolver.E
 		workflowResult.Tasks[taskNode.Task.ID] = result
 
 		// Stop execution if task failed and it's required
-		if result.Status == types.TaskFailed && taskNode.Task.IsRequired() {
-			return fmt.Errorf("required task '%s' failed: %s", taskNode.Task.Name, result.Message)
+		if result.Status == types.TaskFailed && taskNode.Task.IsRequired() && firstError == nil {
+			firstError = &requiredTaskError{name: taskNode.Task.Name, message: result.Message}
 		}
 	}
 
-	return nil
+	return firstError
 }
 
-// executeLayerParallel executes all tasks in a layer in parallel
-func (e *Executor) executeLayerParallel(ctx context.Context, layer *resolver.ExecutionLayer, workflowResult *types.WorkflowResult) error {
-	// Limit concurrency
-	semaphore := make(chan struct{}, e.maxConcurrency)
-	// Pre-fill semaphore with available slots
-	for i := 0; i < e.maxConcurrency; i++ {
-		semaphore <- struct{}{}
+// taskOutcome carries a finished task back to the scheduler
+type taskOutcome struct {
+	node   *resolver.TaskNode
+	result *types.TaskResult
+	err    error
+}
+
+// executeGraphParallel schedules tasks as soon as their own dependencies have
+// finished, rather than waiting for a whole layer, so a task that blocks for a
+// long time (such as an approval) only holds up the tasks that depend on it.
+func (e *Executor) executeGraphParallel(ctx context.Context, layers []*resolver.ExecutionLayer, workflowResult *types.WorkflowResult) error {
+	remaining := make(map[*resolver.TaskNode]int)
+	var ready []*resolver.TaskNode
+	for _, layer := range layers {
+		for _, node := range layer.Tasks {
+			remaining[node] = len(node.Dependencies)
+			if len(node.Dependencies) == 0 {
+				ready = append(ready, node)
+				e.publish(ctx, queuedEvent(node))
+			}
+		}
 	}
 
-	var wg sync.WaitGroup
-	var mu sync.Mutex
-	var firstError error
+	outcomes := make(chan taskOutcome)
+	running := 0
+	var firstError, requiredFailure error
 
-	for _, taskNode := range layer.Tasks {
-		wg.Add(1)
-
-		go func(node *resolver.TaskNode) {
-			defer wg.Done()
-
-			// Acquire semaphore
-			<-semaphore
-			defer func() { semaphore <- struct{}{} }()
-
-			select {
-			case <-ctx.Done():
-				mu.Lock()
-				if firstError == nil {
-					firstError = ctx.Err()
-				}
-				mu.Unlock()
-				return
-			default:
+	// resolve marks a node as finished and queues dependents whose
+	// dependencies are now all finished
+	resolve := func(node *resolver.TaskNode) {
+		for _, dependent := range node.Dependents {
+			if _, tracked := remaining[dependent]; !tracked {
+				continue
+			}
+			remaining[dependent]--
+			if remaining[dependent] == 0 {
+				ready = append(ready, dependent)
+				e.publish(ctx, queuedEvent(dependent))
 			}
+		}
+	}
 
-			result, err := e.ExecuteTask(ctx, node.Task)
+	for {
+		for len(ready) > 0 && running < e.maxConcurrency {
+			node := ready[0]
+			ready = ready[1:]
+
+			if firstError == nil && ctx.Err() != nil {
+				firstError = ctx.Err()
+			}
 
-			mu.Lock()
-			defer mu.Unlock()
+			// Tasks that will not run still resolve, so downstream all_done
+			// and one_failed tasks can see them as skipped
+			if firstError != nil || (requiredFailure != nil && !runsAfterFailure(node.Task)) {
+				resolve(node)
+				continue
+			}
 
-			if err != nil && firstError == nil {
-				firstError = fmt.Errorf("task '%s' execution failed: %w", node.Task.ID, err)
-				return
+			completed := make(map[string]*types.TaskResult, len(workflowResult.Tasks))
+			for id, r := range workflowResult.Tasks {
+				completed[id] = r
 			}
 
-			workflowResult.Tasks[node.Task.ID] = result
+			running++
+			go func(node *resolver.TaskNode) {
+				result, err := e.executeNode(ctx, node, completed)
+				outcomes <- taskOutcome{node: node, result: result, err: err}
+			}(node)
+		}
+
+		if running == 0 {
+			break
+		}
+
+		outcome := <-outcomes
+		running--
+
+		if outcome.err != nil {
+			if firstError == nil {
+				firstError = fmt.Errorf("task '%s' execution failed: %w", outcome.node.Task.ID, outcome.err)
+			}
+		} else {
+			workflowResult.Tasks[outcome.node.Task.ID] = outcome.result
 
 			// Check if required task failed
-			if result.Status == types.TaskFailed && node.Task.IsRequired() && firstError == nil {
-				firstError = fmt.Errorf("required task '%s' failed: %s", node.Task.Name, result.Message)
+			if outcome.result.Status == types.TaskFailed && outcome.node.Task.IsRequired() && requiredFailure == nil {
+				requiredFailure = &requiredTaskError{name: outcome.node.Task.Name, message: outcome.result.Message}
 			}
-		}(taskNode)
+		}
+
+		resolve(outcome.node)
 	}
 
-	wg.Wait()
-	return firstError
+	if firstError != nil {
+		return firstError
+	}
+	return requiredFailure
+}
+
+// requiredTaskError reports that a required task failed. Layers keep running
+// failure-tolerant tasks after it is raised rather than aborting immediately.
+type requiredTaskError struct {
+	name    string
+	message string
+}
+
+func (e *requiredTaskError) Error() string {
+	return fmt.Sprintf("required task '%s' failed: %s", e.name, e.message)
 }
 
-// shouldSkipTask determines if a task should be skipped based on conditions
-func (e *Executor) shouldSkipTask(task *types.TaskConfig) (bool, string) {
-	// Check if task has a condition
-	if task.When != "" {
+// executeNode runs a task after checking its trigger rule against the results
+// of its dependencies. Tasks whose rule is not met are recorded as skipped.
+func (e *Executor) executeNode(ctx context.Context, node *resolver.TaskNode, completed map[string]*types.TaskResult) (*types.TaskResult, error) {
+	if !e.dryRun {
+		if ok, reason := evaluateTriggerRule(node, completed); !ok {
+			result := &types.TaskResult{
+				ID:        node.Task.ID,
+				Name:      node.Task.Name,
+				Type:      node.Task.Type,
+				Status:    types.TaskSkipped,
+				Message:   reason,
+				StartTime: time.Now(),
+			}
+			result.EndTime = result.StartTime
+			e.publishResult(ctx, events.TaskSkipped, node.Task, result)
+
+			if err := e.contextManager.RegisterTaskResult(result); err != nil {
+				e.logf("Warning: failed to register task result for '%s': %v", node.Task.ID, err)
+			}
+			return result, nil
+		}
+	}
+
+	return e.ExecuteTask(ctx, node.Task)
+}
+
+// runsAfterFailure reports whether a task should still be considered once a
+// required task has failed and the workflow is winding down
+func runsAfterFailure(task *types.TaskConfig) bool {
+	return task.TriggerRule == types.TriggerAllDone || task.TriggerRule == types.TriggerOneFailed
+}
+
+// evaluateTriggerRule checks a task's trigger rule against its dependencies'
+// statuses. Dependencies without a recorded result never ran and count as skipped.
+func evaluateTriggerRule(node *resolver.TaskNode, completed map[string]*types.TaskResult) (bool, string) {
+	rule := node.Task.TriggerRule
+	if rule == "" || len(node.Dependencies) == 0 {
+		return true, ""
+	}
+
+	var failed, skipped []string
+	for _, dep := range node.Dependencies {
+		status := types.TaskSkipped
+		if r, ok := completed[dep.Task.ID]; ok {
+			status = r.Status
+		}
+
+		switch status {
+		case types.TaskFailed:
+			failed = append(failed, dep.Task.ID)
+		case types.TaskSkipped:
+			skipped = append(skipped, dep.Task.ID)
+		}
+	}
+
+	switch rule {
+	case types.TriggerAllSuccess:
+		if len(failed) > 0 || len(skipped) > 0 {
+			return false, fmt.Sprintf("trigger rule '%s' not met: %s", rule, describeDependencyOutcomes(failed, skipped))
+		}
+	case types.TriggerNoneFailed:
+		if len(failed) > 0 {
+			return false, fmt.Sprintf("trigger rule '%s' not met: %s", rule, describeDependencyOutcomes(failed, nil))
+		}
+	case types.TriggerOneFailed:
+		if len(failed) == 0 {
+			return false, fmt.Sprintf("trigger rule '%s' not met: no dependency failed", rule)
+		}
+	}
+
+	return true, ""
+}
+
+// describeDependencyOutcomes lists the dependencies that prevented a rule from matching
+func describeDependencyOutcomes(failed, skipped []string) string {
+	var parts []string
+	if len(failed) > 0 {
+		parts = append(parts, fmt.Sprintf("failed dependencies [%s]", strings.Join(failed, ", ")))
+	}
+	if len(skipped) > 0 {
+		parts = append(parts, fmt.Sprintf("skipped dependencies [%s]", strings.Join(skipped, ", ")))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// shouldSkipTask determines if a task should be skipped based on conditions.
+// Conditions written as Go templates keep their historical behaviour of being
+// skipped when they fail to render; expression conditions return an error instead
+// so that typos and type mismatches are not mistaken for a false condition.
+func (e *Executor) shouldSkipTask(task *types.TaskConfig) (bool, string, error) {
+	if task.When == "" {
+		return false, "", nil
+	}
+
+	if expression.IsTemplate(task.When) {
 		// Evaluate the condition using the template engine
 		result, err := e.contextManager.EvaluateString(task.When)
 		if err != nil {
-			return true, fmt.Sprintf("failed to evaluate condition: %v", err)
+			return true, fmt.Sprintf("failed to evaluate condition: %v", err), nil
 		}
 
 		// Check if result is truthy
 		if !isTruthy(result) {
-			return true, fmt.Sprintf("condition '%s' evaluated to false", task.When)
+			return true, fmt.Sprintf("condition '%s' evaluated to false", task.When), nil
 		}
+		return false, "", nil
+	}
+
+	// A bare word such as yes or enabled is taken as it was before conditions
+	// were expressions
+	if expression.IsLiteral(task.When) {
+		if !isTruthy(strings.TrimSpace(task.When)) {
+			return true, fmt.Sprintf("condition '%s' evaluated to false", task.When), nil
+		}
+		return false, "", nil
+	}
+
+	expr, err := expression.Parse(task.When)
+	if err != nil {
+		return false, "", err
+	}
+
+	ok, err := expr.EvaluateBool(expression.Data(e.conditionContext()))
+	if err != nil {
+		return false, "", fmt.Errorf("condition '%s': %w", task.When, err)
+	}
+	if !ok {
+		return true, fmt.Sprintf("condition '%s' evaluated to false", task.When), nil
 	}
 
-	return false, ""
+	return false, "", nil
+}
+
+// contextSnapshotter is implemented by context managers that can hand out a
+// consistent copy of their state to concurrent readers
+type contextSnapshotter interface {
+	Snapshot() *types.WorkflowContext
+}
+
+// conditionContext returns the workflow context used to evaluate conditions
+func (e *Executor) conditionContext() *types.WorkflowContext {
+	if s, ok := e.contextManager.(contextSnapshotter); ok {
+		return s.Snapshot()
+	}
+	return e.contextManager.GetContext()
 }
 
 // isTruthy determines if a string represents a truthy value
@@ -306,6 +587,42 @@ func isTruthy(value string) bool {
 	}
 }
 
+// taskEvent creates an event of the given type describing the task
+func taskEvent(eventType events.Type, task *types.TaskConfig) events.Event {
+	return events.Event{
+		Type:     eventType,
+		TaskID:   task.ID,
+		TaskName: task.Name,
+		TaskType: task.Type,
+	}
+}
+
+// queuedEvent creates a TaskQueued event listing the task's dependencies
+func queuedEvent(node *resolver.TaskNode) events.Event {
+	event := taskEvent(events.TaskQueued, node.Task)
+	for _, dep := range node.Dependencies {
+		event.Dependencies = append(event.Dependencies, dep.Task.ID)
+	}
+	return event
+}
+
+// publishResult publishes a finished or skipped event carrying the task's result
+func (e *Executor) publishResult(ctx context.Context, eventType events.Type, task *types.TaskConfig, result *types.TaskResult) {
+	event := taskEvent(eventType, task)
+	event.Status = result.Status
+	event.Message = result.Message
+	event.Attempt = result.AttemptCount
+	event.Duration = result.Duration
+	event.TaskResult = result
+	e.publish(ctx, event)
+}
+
+// publish stamps the event with the execution ID and publishes it
+func (e *Executor) publish(ctx context.Context, event events.Event) {
+	event.ExecutionID = types.ExecutionIDFromContext(ctx)
+	e.events.Publish(event)
+}
+
 // logf logs a formatted message if logger is available
 func (e *Executor) logf(format string, args ...interface{}) {
 	if e.logger != nil {
@@ -7,9 +7,12 @@ import (
 	"context"
 	"errors"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/sarlalian/ritual/internal/events"
+	"github.com/sarlalian/ritual/internal/redact"
 	"github.com/sarlalian/ritual/internal/workflow/resolver"
 	"github.com/sarlalian/ritual/pkg/types"
 )
@@ -18,6 +21,7 @@ import (
 type MockTaskExecutor struct {
 	shouldFail   bool
 	shouldSkip   bool
+	changed      bool
 	executionLog []string
 	delay        time.Duration
 }
@@ -46,6 +50,7 @@ func (m *MockTaskExecutor) Execute(ctx context.Context, task *types.TaskConfig,
 		result.Status = types.TaskSuccess
 		result.Message = "Mock task completed"
 		result.Stdout = "mock output"
+		result.Changed = m.changed
 	}
 
 	result.EndTime = time.Now()
@@ -72,6 +77,7 @@ func (m *MockTaskExecutor) SupportsDryRun() bool {
 
 // MockContextManager implements ContextManager for testing
 type MockContextManager struct {
+	mu          sync.Mutex
 	variables   map[string]interface{}
 	environment map[string]string
 	taskResults map[string]*types.TaskResult
@@ -90,10 +96,17 @@ func (m *MockContextManager) Initialize(workflow *types.Workflow, envVars []stri
 }
 
 func (m *MockContextManager) GetContext() *types.WorkflowContext {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tasks := make(map[string]*types.TaskResult, len(m.taskResults))
+	for k, v := range m.taskResults {
+		tasks[k] = v
+	}
 	return &types.WorkflowContext{
 		Variables:   m.variables,
 		Environment: m.environment,
-		Tasks:       m.taskResults,
+		Tasks:       tasks,
 	}
 }
 
@@ -122,6 +135,8 @@ func (m *MockContextManager) SetEnvironment(name, value string) error {
 }
 
 func (m *MockContextManager) RegisterTaskResult(taskResult *types.TaskResult) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.taskResults[taskResult.ID] = taskResult
 	if taskResult.Name != taskResult.ID {
 		m.taskResults[taskResult.Name] = taskResult
@@ -130,6 +145,8 @@ func (m *MockContextManager) RegisterTaskResult(taskResult *types.TaskResult) er
 }
 
 func (m *MockContextManager) GetTaskResult(identifier string) (*types.TaskResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if result, exists := m.taskResults[identifier]; exists {
 		return result, nil
 	}
@@ -281,6 +298,67 @@ func TestExecutor_ExecuteTask_Success(t *testing.T) {
 	}
 }
 
+func TestExecutor_ExecuteTask_Changed(t *testing.T) {
+	contextManager := NewMockContextManager()
+	executor, err := New(contextManager, nil)
+	if err != nil {
+		t.Fatalf("Failed to create executor: %v", err)
+	}
+
+	executor.RegisterTask("test", &MockTaskExecutor{changed: true})
+
+	task := &types.TaskConfig{
+		ID:   "task1",
+		Name: "Test Task",
+		Type: "test",
+	}
+
+	result, err := executor.ExecuteTask(context.Background(), task)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if !result.Changed {
+		t.Error("Expected changed flag to be carried onto the task result")
+	}
+
+	registeredResult, err := contextManager.GetTaskResult("task1")
+	if err != nil {
+		t.Fatalf("Expected task result to be registered: %v", err)
+	}
+	if !registeredResult.Changed {
+		t.Error("Expected registered task result to be marked changed")
+	}
+}
+
+// changeReportingExecutor is a MockTaskExecutor whose results say whether it
+// changed anything
+type changeReportingExecutor struct {
+	MockTaskExecutor
+}
+
+func (c *changeReportingExecutor) ReportsChange() bool { return true }
+
+func TestExecutor_ExecuteTask_ChangeTracked(t *testing.T) {
+	executor, err := New(NewMockContextManager(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create executor: %v", err)
+	}
+	executor.RegisterTask("tracked", &changeReportingExecutor{})
+	executor.RegisterTask("untracked", &MockTaskExecutor{})
+
+	for taskType, want := range map[string]bool{"tracked": true, "untracked": false} {
+		task := &types.TaskConfig{ID: taskType, Name: taskType, Type: taskType}
+		result, err := executor.ExecuteTask(context.Background(), task)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if result.ChangeTracked != want {
+			t.Errorf("%s: expected ChangeTracked %v, got %v", taskType, want, result.ChangeTracked)
+		}
+	}
+}
+
 func TestExecutor_ExecuteTask_Failure(t *testing.T) {
 	contextManager := NewMockContextManager()
 	executor, err := New(contextManager, nil)
@@ -396,6 +474,50 @@ func TestExecutor_ExecuteTask_Condition_Skip(t *testing.T) {
 	}
 }
 
+func TestExecutor_ExecuteTask_ExpressionCondition(t *testing.T) {
+	contextManager := NewMockContextManager()
+	contextManager.environment["ENVIRONMENT"] = "staging"
+	contextManager.taskResults["build"] = &types.TaskResult{ID: "build", Status: types.TaskSuccess, Changed: true}
+
+	executor, err := New(contextManager, nil)
+	if err != nil {
+		t.Fatalf("Failed to create executor: %v", err)
+	}
+	executor.RegisterTask("test", &MockTaskExecutor{})
+
+	tests := []struct {
+		when     string
+		expected types.TaskStatus
+	}{
+		{"tasks.build.Changed and env.ENVIRONMENT in ['prod', 'staging']", types.TaskSuccess},
+		{"tasks.build.Status == 'success' and env.ENVIRONMENT == 'prod'", types.TaskSkipped},
+		{"env.ENVIRONMENT == 1", types.TaskFailed},
+		{"vars.undefined_flag", types.TaskFailed},
+		// Bare words are checked for truthiness as they were before expressions
+		{"yes", types.TaskSuccess},
+		{"enabled", types.TaskSuccess},
+		{"off", types.TaskSkipped},
+		{"0", types.TaskSkipped},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.when, func(t *testing.T) {
+			task := &types.TaskConfig{ID: "deploy", Name: "Deploy", Type: "test", When: tt.when}
+
+			result, err := executor.ExecuteTask(context.Background(), task)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if result.Status != tt.expected {
+				t.Errorf("Expected status %s, got %s: %s", tt.expected, result.Status, result.Message)
+			}
+			if tt.expected == types.TaskFailed && !strings.Contains(result.Message, "condition") {
+				t.Errorf("Expected condition error message, got: %s", result.Message)
+			}
+		})
+	}
+}
+
 func TestExecutor_ExecuteWorkflow_Parallel(t *testing.T) {
 	contextManager := NewMockContextManager()
 	executor, err := New(contextManager, nil)
@@ -508,6 +630,278 @@ func TestExecutor_ExecuteWorkflow_RequiredTaskFailure(t *testing.T) {
 	}
 }
 
+func TestExecutor_ExecuteWorkflow_TriggerRules(t *testing.T) {
+	contextManager := NewMockContextManager()
+	executor, err := New(contextManager, nil)
+	if err != nil {
+		t.Fatalf("Failed to create executor: %v", err)
+	}
+
+	executor.RegisterTask("test", &MockTaskExecutor{})
+	executor.RegisterTask("fail", &MockTaskExecutor{shouldFail: true})
+
+	optional := false
+	workflow := &types.Workflow{
+		Name: "Trigger Rules",
+		Mode: types.ParallelMode,
+		Tasks: []types.TaskConfig{
+			{ID: "flaky", Name: "Flaky", Type: "fail", Required: &optional},
+			{ID: "stable", Name: "Stable", Type: "test"},
+			{ID: "gated", Name: "Gated", Type: "test", When: "false"},
+			{ID: "all_success", Name: "All Success", Type: "test", DependsOn: []string{"flaky", "stable"}, TriggerRule: types.TriggerAllSuccess},
+			{ID: "none_failed", Name: "None Failed", Type: "test", DependsOn: []string{"flaky", "stable"}, TriggerRule: types.TriggerNoneFailed},
+			{ID: "one_failed", Name: "One Failed", Type: "test", DependsOn: []string{"flaky", "stable"}, TriggerRule: types.TriggerOneFailed},
+			{ID: "all_done", Name: "All Done", Type: "test", DependsOn: []string{"flaky", "stable"}, TriggerRule: types.TriggerAllDone},
+			{ID: "legacy", Name: "Legacy", Type: "test", DependsOn: []string{"flaky"}},
+			{ID: "after_skip_none_failed", Name: "After Skip None Failed", Type: "test", DependsOn: []string{"gated", "stable"}, TriggerRule: types.TriggerNoneFailed},
+			{ID: "after_skip_all_success", Name: "After Skip All Success", Type: "test", DependsOn: []string{"gated", "stable"}, TriggerRule: types.TriggerAllSuccess},
+			{ID: "no_failure", Name: "No Failure", Type: "test", DependsOn: []string{"stable"}, TriggerRule: types.TriggerOneFailed},
+		},
+	}
+
+	result, err := executor.ExecuteWorkflow(context.Background(), workflow, NewMockResolver(workflow.Tasks))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expected := map[string]types.TaskStatus{
+		"all_success":            types.TaskSkipped,
+		"none_failed":            types.TaskSkipped,
+		"one_failed":             types.TaskSuccess,
+		"all_done":               types.TaskSuccess,
+		"legacy":                 types.TaskSuccess,
+		"after_skip_none_failed": types.TaskSuccess,
+		"after_skip_all_success": types.TaskSkipped,
+		"no_failure":             types.TaskSkipped,
+	}
+	for id, status := range expected {
+		task, ok := result.Tasks[id]
+		if !ok {
+			t.Errorf("Expected result for task '%s'", id)
+			continue
+		}
+		if task.Status != status {
+			t.Errorf("Task '%s': expected %s, got %s (%s)", id, status, task.Status, task.Message)
+		}
+	}
+
+	if msg := result.Tasks["all_success"].Message; !strings.Contains(msg, "trigger rule 'all_success' not met") || !strings.Contains(msg, "flaky") {
+		t.Errorf("Expected skip reason to name the rule and failed dependency, got: %s", msg)
+	}
+	if msg := result.Tasks["after_skip_all_success"].Message; !strings.Contains(msg, "skipped dependencies [gated]") {
+		t.Errorf("Expected skip reason to name the skipped dependency, got: %s", msg)
+	}
+}
+
+func TestExecutor_ExecuteWorkflow_TriggerRulesAfterRequiredFailure(t *testing.T) {
+	for _, mode := range []types.ExecutionMode{types.ParallelMode, types.SequentialMode} {
+		t.Run(string(mode), func(t *testing.T) {
+			contextManager := NewMockContextManager()
+			executor, err := New(contextManager, nil)
+			if err != nil {
+				t.Fatalf("Failed to create executor: %v", err)
+			}
+
+			executor.RegisterTask("test", &MockTaskExecutor{})
+			executor.RegisterTask("fail", &MockTaskExecutor{shouldFail: true})
+
+			workflow := &types.Workflow{
+				Name: "Cleanup After Failure",
+				Mode: mode,
+				Tasks: []types.TaskConfig{
+					{ID: "deploy", Name: "Deploy", Type: "fail"},
+					{ID: "verify", Name: "Verify", Type: "test", DependsOn: []string{"deploy"}},
+					{ID: "rollback", Name: "Rollback", Type: "test", DependsOn: []string{"deploy"}, TriggerRule: types.TriggerOneFailed},
+					{ID: "report", Name: "Report", Type: "test", DependsOn: []string{"verify"}, TriggerRule: types.TriggerAllDone},
+				},
+			}
+
+			result, err := executor.ExecuteWorkflow(context.Background(), workflow, NewMockResolver(workflow.Tasks))
+			if err == nil || !strings.Contains(err.Error(), "required task") {
+				t.Fatalf("Expected required task error, got: %v", err)
+			}
+			if result.Status != types.WorkflowFailed {
+				t.Errorf("Expected workflow failed, got %s", result.Status)
+			}
+
+			if _, ran := result.Tasks["verify"]; ran {
+				t.Error("Expected task without a trigger rule not to run after a required failure")
+			}
+			if r := result.Tasks["rollback"]; r == nil || r.Status != types.TaskSuccess {
+				t.Errorf("Expected one_failed task to run after a required failure, got %+v", r)
+			}
+			if r := result.Tasks["report"]; r == nil || r.Status != types.TaskSuccess {
+				t.Errorf("Expected all_done task to run after a required failure, got %+v", r)
+			}
+		})
+	}
+}
+
+// blockingTaskExecutor waits for release to be closed, like an approval task
+// waiting on a human, and fails if that never happens
+type blockingTaskExecutor struct {
+	release chan struct{}
+}
+
+func (b *blockingTaskExecutor) Execute(ctx context.Context, task *types.TaskConfig, contextManager types.ContextManager) *types.TaskResult {
+	result := &types.TaskResult{ID: task.ID, Name: task.Name, Type: task.Type, StartTime: time.Now()}
+
+	select {
+	case <-b.release:
+		result.Status = types.TaskSuccess
+	case <-time.After(2 * time.Second):
+		result.Status = types.TaskFailed
+		result.Message = "never released"
+	}
+
+	result.EndTime = time.Now()
+	return result
+}
+
+func (b *blockingTaskExecutor) Validate(task *types.TaskConfig) error { return nil }
+func (b *blockingTaskExecutor) SupportsDryRun() bool                  { return true }
+
+// releasingTaskExecutor unblocks a blockingTaskExecutor when it runs
+type releasingTaskExecutor struct {
+	release chan struct{}
+}
+
+func (r *releasingTaskExecutor) Execute(ctx context.Context, task *types.TaskConfig, contextManager types.ContextManager) *types.TaskResult {
+	close(r.release)
+	return &types.TaskResult{ID: task.ID, Name: task.Name, Type: task.Type, Status: types.TaskSuccess}
+}
+
+func (r *releasingTaskExecutor) Validate(task *types.TaskConfig) error { return nil }
+func (r *releasingTaskExecutor) SupportsDryRun() bool                  { return true }
+
+func TestExecutor_ExecuteWorkflow_IndependentBranchesRunWhileBlocked(t *testing.T) {
+	contextManager := NewMockContextManager()
+	executor, err := New(contextManager, nil)
+	if err != nil {
+		t.Fatalf("Failed to create executor: %v", err)
+	}
+
+	release := make(chan struct{})
+	executor.RegisterTask("test", &MockTaskExecutor{})
+	executor.RegisterTask("block", &blockingTaskExecutor{release: release})
+	executor.RegisterTask("release", &releasingTaskExecutor{release: release})
+
+	// "gate" blocks until "verify" runs. verify sits in a later dependency layer
+	// than gate, so it only gets to run if the scheduler does not wait for
+	// whole layers to finish.
+	workflow := &types.Workflow{
+		Name: "Test Workflow",
+		Mode: types.ParallelMode,
+		Tasks: []types.TaskConfig{
+			{ID: "gate", Name: "Gate", Type: "block"},
+			{ID: "promote", Name: "Promote", Type: "test", DependsOn: []string{"gate"}},
+			{ID: "build", Name: "Build", Type: "test"},
+			{ID: "verify", Name: "Verify", Type: "release", DependsOn: []string{"build"}},
+		},
+	}
+
+	resolver := NewMockResolver(workflow.Tasks)
+	result, err := executor.ExecuteWorkflow(context.Background(), workflow, resolver)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	for _, id := range []string{"gate", "promote", "build", "verify"} {
+		if status := result.Tasks[id].Status; status != types.TaskSuccess {
+			t.Errorf("Expected task %s to succeed, got %s: %s", id, status, result.Tasks[id].Message)
+		}
+	}
+}
+
+// failingTaskExecutor fails every time and counts its calls
+type failingTaskExecutor struct {
+	calls int
+}
+
+func (f *failingTaskExecutor) Execute(ctx context.Context, task *types.TaskConfig, contextManager types.ContextManager) *types.TaskResult {
+	f.calls++
+	return &types.TaskResult{ID: task.ID, Name: task.Name, Type: task.Type, Status: types.TaskFailed, Message: "failed"}
+}
+
+func (f *failingTaskExecutor) Validate(task *types.TaskConfig) error { return nil }
+func (f *failingTaskExecutor) SupportsDryRun() bool                  { return true }
+
+func TestExecutor_ExecuteTask_SingleAttempt(t *testing.T) {
+	executor, err := New(NewMockContextManager(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create executor: %v", err)
+	}
+
+	failing := &failingTaskExecutor{}
+	executor.RegisterTask("failing", failing)
+
+	// retry_count is not acted on; a task runs once
+	task := &types.TaskConfig{ID: "failing", Name: "Failing", Type: "failing", RetryCount: 2, RetryDelay: time.Millisecond}
+	result, err := executor.ExecuteTask(context.Background(), task)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.Status != types.TaskFailed {
+		t.Errorf("Expected status %s, got %s", types.TaskFailed, result.Status)
+	}
+	if result.AttemptCount != 1 || failing.calls != 1 {
+		t.Errorf("Expected a single attempt, got AttemptCount=%d calls=%d", result.AttemptCount, failing.calls)
+	}
+}
+
+func TestExecutor_ExecuteWorkflow_PublishesTaskEvents(t *testing.T) {
+	executor, err := New(NewMockContextManager(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create executor: %v", err)
+	}
+	executor.RegisterTask("test", &MockTaskExecutor{})
+
+	var mu sync.Mutex
+	byTask := make(map[string][]events.Type)
+	executor.Events().Subscribe(events.ObserverFunc(func(event events.Event) {
+		if event.ExecutionID != "exec_1" {
+			t.Errorf("Expected execution ID on %s event, got %q", event.Type, event.ExecutionID)
+		}
+		mu.Lock()
+		byTask[event.TaskID] = append(byTask[event.TaskID], event.Type)
+		mu.Unlock()
+	}))
+
+	skip := false
+	workflow := &types.Workflow{
+		Name: "Test Workflow",
+		Mode: types.ParallelMode,
+		Tasks: []types.TaskConfig{
+			{ID: "build", Name: "Build", Type: "test"},
+			{ID: "deploy", Name: "Deploy", Type: "test", DependsOn: []string{"build"}, When: "false", Required: &skip},
+		},
+	}
+
+	ctx := types.WithExecutionID(context.Background(), "exec_1")
+	if _, err := executor.ExecuteWorkflow(ctx, workflow, NewMockResolver(workflow.Tasks)); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	want := map[string][]events.Type{
+		"build":  {events.TaskQueued, events.TaskStarted, events.TaskFinished},
+		"deploy": {events.TaskQueued, events.TaskStarted, events.TaskSkipped},
+	}
+	for id, expected := range want {
+		got := byTask[id]
+		if len(got) != len(expected) {
+			t.Errorf("Expected events %v for %s, got %v", expected, id, got)
+			continue
+		}
+		for i := range expected {
+			if got[i] != expected[i] {
+				t.Errorf("Expected events %v for %s, got %v", expected, id, got)
+				break
+			}
+		}
+	}
+}
+
 func TestExecutor_ExecuteWorkflow_OptionalTaskFailure(t *testing.T) {
 	contextManager := NewMockContextManager()
 	executor, err := New(contextManager, nil)
@@ -567,3 +961,52 @@ func TestExecutor_IsTruthy(t *testing.T) {
 		})
 	}
 }
+
+// leakyTaskExecutor prints its token config field, as a misbehaving tool might
+type leakyTaskExecutor struct{}
+
+func (l *leakyTaskExecutor) Execute(ctx context.Context, task *types.TaskConfig, contextManager types.ContextManager) *types.TaskResult {
+	token, _ := task.Config["token"].(string)
+	return &types.TaskResult{
+		Status:  types.TaskSuccess,
+		Message: "authenticated with " + token,
+		Stdout:  "db password is hunter22\n",
+		Output:  map[string]interface{}{"api_token": "xyz", "user": "deploy"},
+	}
+}
+
+func (l *leakyTaskExecutor) Validate(task *types.TaskConfig) error { return nil }
+func (l *leakyTaskExecutor) SupportsDryRun() bool                  { return true }
+func (l *leakyTaskExecutor) SecretFields() []string                { return []string{"token"} }
+
+func TestExecutor_ExecuteTask_RedactsResult(t *testing.T) {
+	contextManager := NewMockContextManager()
+	executor, err := New(contextManager, nil)
+	if err != nil {
+		t.Fatalf("Failed to create executor: %v", err)
+	}
+	executor.RegisterTask("leaky", &leakyTaskExecutor{})
+
+	redactor := redact.New(redact.DefaultPatterns)
+	redactor.Add("hunter22")
+	ctx := redact.WithRedactor(context.Background(), redactor)
+
+	task := &types.TaskConfig{ID: "leaky", Name: "Leaky", Type: "leaky", Config: map[string]interface{}{"token": "tok-12345"}}
+	if _, err := executor.ExecuteTask(ctx, task); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	registered, err := contextManager.GetTaskResult("leaky")
+	if err != nil {
+		t.Fatalf("Expected task result to be registered: %v", err)
+	}
+	if strings.Contains(registered.Message, "tok-12345") {
+		t.Errorf("Expected secret task field to be masked, got: %s", registered.Message)
+	}
+	if strings.Contains(registered.Stdout, "hunter22") {
+		t.Errorf("Expected tracked value to be masked in stdout, got: %s", registered.Stdout)
+	}
+	if registered.Output["api_token"] != redact.Mask || registered.Output["user"] != "deploy" {
+		t.Errorf("Expected only the sensitive output key to be masked, got: %v", registered.Output)
+	}
+}
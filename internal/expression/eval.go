@@ -0,0 +1,607 @@
+// ABOUTME: Evaluator for parsed condition expressions against the workflow context
+// ABOUTME: Applies strict typing so mismatched comparisons and missing values surface as errors
+
+package expression
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+// UndefinedError reports a reference to a variable, key or field that does not exist
+type UndefinedError struct {
+	Path string
+}
+
+func (e *UndefinedError) Error() string {
+	return fmt.Sprintf("'%s' is not defined", e.Path)
+}
+
+// Data builds the evaluation roots from a workflow context
+func Data(ctx *types.WorkflowContext) map[string]interface{} {
+	data := map[string]interface{}{
+		"tasks":    map[string]*types.TaskResult{},
+		"vars":     map[string]interface{}{},
+		"env":      map[string]string{},
+		"metadata": map[string]interface{}{},
+		"workflow": map[string]interface{}{},
+	}
+
+	if ctx == nil {
+		return data
+	}
+
+	if ctx.Tasks != nil {
+		data["tasks"] = ctx.Tasks
+	}
+	if ctx.Variables != nil {
+		data["vars"] = ctx.Variables
+	}
+	if ctx.Environment != nil {
+		data["env"] = ctx.Environment
+	}
+	if ctx.Metadata != nil {
+		data["metadata"] = ctx.Metadata
+		if workflow, exists := ctx.Metadata["workflow"]; exists {
+			data["workflow"] = workflow
+		}
+	}
+
+	return data
+}
+
+// Evaluate computes the value of the expression using the given roots
+func (e *Expression) Evaluate(data map[string]interface{}) (interface{}, error) {
+	return eval(e.root, data)
+}
+
+// EvaluateBool evaluates the expression and requires the result to be a boolean
+func (e *Expression) EvaluateBool(data map[string]interface{}) (bool, error) {
+	value, err := e.Evaluate(data)
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression must evaluate to a boolean, got %s", typeName(value))
+	}
+	return b, nil
+}
+
+// TaskReferences returns the task identifiers the expression refers to by name
+func (e *Expression) TaskReferences() []string {
+	return e.References("tasks")
+}
+
+// References returns the names the expression reads from a root such as
+// tasks or vars, like build in tasks.build.status
+func (e *Expression) References(root string) []string {
+	return e.references(root, false)
+}
+
+// RequiredReferences returns the names References does, except those only
+// tested with defined(), which may be missing without an error
+func (e *Expression) RequiredReferences(root string) []string {
+	return e.references(root, true)
+}
+
+func (e *Expression) references(root string, skipDefined bool) []string {
+	var refs []string
+	seen := make(map[string]bool)
+
+	var walk func(n node)
+	walk = func(n node) {
+		switch v := n.(type) {
+		case *pathNode:
+			if v.root == root && len(v.segments) > 0 && v.segments[0].index == nil {
+				if name := v.segments[0].name; !seen[name] {
+					seen[name] = true
+					refs = append(refs, name)
+				}
+			}
+			for _, seg := range v.segments {
+				if seg.index != nil {
+					walk(seg.index)
+				}
+			}
+		case *listNode:
+			for _, item := range v.items {
+				walk(item)
+			}
+		case *unaryNode:
+			walk(v.operand)
+		case *binaryNode:
+			walk(v.left)
+			walk(v.right)
+		case *callNode:
+			if skipDefined && v.name == "defined" {
+				return
+			}
+			for _, arg := range v.args {
+				walk(arg)
+			}
+		}
+	}
+	walk(e.root)
+
+	return refs
+}
+
+// IsConstant reports whether the expression reads nothing from the context,
+// so it always evaluates to the same value
+func (e *Expression) IsConstant() bool {
+	var constant func(n node) bool
+	constant = func(n node) bool {
+		switch v := n.(type) {
+		case *pathNode:
+			return false
+		case *listNode:
+			for _, item := range v.items {
+				if !constant(item) {
+					return false
+				}
+			}
+		case *unaryNode:
+			return constant(v.operand)
+		case *binaryNode:
+			return constant(v.left) && constant(v.right)
+		case *callNode:
+			for _, arg := range v.args {
+				if !constant(arg) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return constant(e.root)
+}
+
+func eval(n node, data map[string]interface{}) (interface{}, error) {
+	switch v := n.(type) {
+	case *literalNode:
+		return v.value, nil
+
+	case *listNode:
+		items := make([]interface{}, 0, len(v.items))
+		for _, item := range v.items {
+			value, err := eval(item, data)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, value)
+		}
+		return items, nil
+
+	case *pathNode:
+		return resolvePath(v, data)
+
+	case *unaryNode:
+		value, err := eval(v.operand, data)
+		if err != nil {
+			return nil, err
+		}
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("'not' requires a boolean operand, got %s", typeName(value))
+		}
+		return !b, nil
+
+	case *binaryNode:
+		return evalBinary(v, data)
+
+	case *callNode:
+		return evalCall(v, data)
+	}
+
+	return nil, fmt.Errorf("unsupported expression node %T", n)
+}
+
+// evalBinary evaluates boolean connectives with short-circuiting, then comparisons
+func evalBinary(n *binaryNode, data map[string]interface{}) (interface{}, error) {
+	left, err := eval(n.left, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.op == "and" || n.op == "or" {
+		lb, ok := left.(bool)
+		if !ok {
+			return nil, fmt.Errorf("'%s' requires boolean operands, got %s", n.op, typeName(left))
+		}
+		if (n.op == "and" && !lb) || (n.op == "or" && lb) {
+			return lb, nil
+		}
+		right, err := eval(n.right, data)
+		if err != nil {
+			return nil, err
+		}
+		rb, ok := right.(bool)
+		if !ok {
+			return nil, fmt.Errorf("'%s' requires boolean operands, got %s", n.op, typeName(right))
+		}
+		return rb, nil
+	}
+
+	right, err := eval(n.right, data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(left, right)
+	case "!=":
+		eq, err := equal(left, right)
+		return !eq, err
+	case "<", "<=", ">", ">=":
+		return compare(n.op, left, right)
+	case "in":
+		return contains(right, left)
+	case "not in":
+		found, err := contains(right, left)
+		return !found, err
+	case "=~", "!~":
+		matched, err := match(n, left, right)
+		if n.op == "!~" {
+			return !matched, err
+		}
+		return matched, err
+	}
+
+	return nil, fmt.Errorf("unsupported operator '%s'", n.op)
+}
+
+// evalCall evaluates the built-in functions
+func evalCall(n *callNode, data map[string]interface{}) (interface{}, error) {
+	if n.name == "defined" {
+		_, err := eval(n.args[0], data)
+		if err == nil {
+			return true, nil
+		}
+		if _, ok := err.(*UndefinedError); ok {
+			return false, nil
+		}
+		return nil, err
+	}
+
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		value, err := eval(arg, data)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = value
+	}
+
+	switch n.name {
+	case "len":
+		if s, ok := args[0].(string); ok {
+			return float64(len(s)), nil
+		}
+		rv := reflect.ValueOf(args[0])
+		if rv.IsValid() && (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array || rv.Kind() == reflect.Map) {
+			return float64(rv.Len()), nil
+		}
+		return nil, fmt.Errorf("len() requires a string, list or map, got %s", typeName(args[0]))
+
+	case "lower", "upper":
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("%s() requires a string, got %s", n.name, typeName(args[0]))
+		}
+		if n.name == "lower" {
+			return strings.ToLower(s), nil
+		}
+		return strings.ToUpper(s), nil
+
+	case "contains":
+		return contains(args[0], args[1])
+
+	case "startsWith", "endsWith":
+		s, ok1 := args[0].(string)
+		affix, ok2 := args[1].(string)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("%s() requires string arguments, got %s and %s", n.name, typeName(args[0]), typeName(args[1]))
+		}
+		if n.name == "startsWith" {
+			return strings.HasPrefix(s, affix), nil
+		}
+		return strings.HasSuffix(s, affix), nil
+	}
+
+	return nil, fmt.Errorf("unknown function '%s'", n.name)
+}
+
+// resolvePath walks a path through maps, slices and structs
+func resolvePath(n *pathNode, data map[string]interface{}) (interface{}, error) {
+	current := normalize(data[n.root])
+	traversed := n.root
+
+	for _, seg := range n.segments {
+		key := seg.name
+		var index interface{} = key
+		if seg.index != nil {
+			value, err := eval(seg.index, data)
+			if err != nil {
+				return nil, err
+			}
+			index = value
+		}
+
+		next, err := lookup(current, index, traversed)
+		if err != nil {
+			return nil, err
+		}
+
+		switch idx := index.(type) {
+		case string:
+			traversed += "." + idx
+		default:
+			traversed += fmt.Sprintf("[%v]", idx)
+		}
+		current = normalize(next)
+	}
+
+	return current, nil
+}
+
+// lookup returns the member of container named by key
+func lookup(container interface{}, key interface{}, path string) (interface{}, error) {
+	if container == nil {
+		return nil, fmt.Errorf("cannot access '%v' on null value '%s'", key, path)
+	}
+
+	rv := reflect.ValueOf(container)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, fmt.Errorf("cannot access '%v' on null value '%s'", key, path)
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Map:
+		name, ok := key.(string)
+		if !ok || rv.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("map '%s' must be indexed by a string, got %s", path, typeName(key))
+		}
+		value := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()))
+		if !value.IsValid() {
+			return nil, &UndefinedError{Path: path + "." + name}
+		}
+		return value.Interface(), nil
+
+	case reflect.Slice, reflect.Array:
+		f, ok := key.(float64)
+		if !ok || f != float64(int(f)) {
+			return nil, fmt.Errorf("list '%s' must be indexed by an integer, got %s", path, typeName(key))
+		}
+		i := int(f)
+		if i < 0 || i >= rv.Len() {
+			return nil, fmt.Errorf("index %d out of range for '%s' (length %d)", i, path, rv.Len())
+		}
+		return rv.Index(i).Interface(), nil
+
+	case reflect.Struct:
+		name, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("field of '%s' must be named by a string, got %s", path, typeName(key))
+		}
+		if field, ok := structField(rv, name); ok {
+			return field.Interface(), nil
+		}
+		return nil, &UndefinedError{Path: path + "." + name}
+	}
+
+	return nil, fmt.Errorf("cannot access '%v' on %s value '%s'", key, typeName(container), path)
+}
+
+// structField finds an exported field by exact name, json tag or case-insensitive name
+func structField(rv reflect.Value, name string) (reflect.Value, bool) {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := strings.Split(field.Tag.Get("json"), ",")[0]
+		if field.Name == name || tag == name || strings.EqualFold(field.Name, name) {
+			return rv.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// normalize converts scalar values into the evaluator's canonical types:
+// numbers become float64, string-kinded types become string and durations
+// are expressed in seconds
+func normalize(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	if d, ok := value.(time.Duration); ok {
+		return d.Seconds()
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return nil
+		}
+		if rv.Elem().Kind() != reflect.Struct {
+			return normalize(rv.Elem().Interface())
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	case reflect.Bool:
+		return rv.Bool()
+	}
+
+	return value
+}
+
+// equal compares two values, refusing to compare values of different types
+func equal(left, right interface{}) (bool, error) {
+	if left == nil || right == nil {
+		return left == nil && right == nil, nil
+	}
+
+	switch l := left.(type) {
+	case float64:
+		if r, ok := right.(float64); ok {
+			return l == r, nil
+		}
+	case string:
+		if r, ok := right.(string); ok {
+			return l == r, nil
+		}
+	case bool:
+		if r, ok := right.(bool); ok {
+			return l == r, nil
+		}
+	default:
+		if typeName(left) == typeName(right) {
+			return reflect.DeepEqual(left, right), nil
+		}
+	}
+
+	return false, fmt.Errorf("cannot compare %s with %s", typeName(left), typeName(right))
+}
+
+// compare orders two numbers or two strings
+func compare(op string, left, right interface{}) (bool, error) {
+	var cmp int
+
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return false, fmt.Errorf("cannot compare number with %s using '%s'", typeName(right), op)
+		}
+		switch {
+		case l < r:
+			cmp = -1
+		case l > r:
+			cmp = 1
+		}
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return false, fmt.Errorf("cannot compare string with %s using '%s'", typeName(right), op)
+		}
+		cmp = strings.Compare(l, r)
+	default:
+		return false, fmt.Errorf("'%s' requires numbers or strings, got %s", op, typeName(left))
+	}
+
+	switch op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+// contains implements 'in': substring for strings, membership for lists and key lookup for maps
+func contains(container, item interface{}) (bool, error) {
+	if s, ok := container.(string); ok {
+		sub, ok := item.(string)
+		if !ok {
+			return false, fmt.Errorf("cannot search string for %s", typeName(item))
+		}
+		return strings.Contains(s, sub), nil
+	}
+
+	rv := reflect.ValueOf(container)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if eq, err := equal(normalize(rv.Index(i).Interface()), item); err == nil && eq {
+				return true, nil
+			}
+		}
+		return false, nil
+
+	case reflect.Map:
+		key, ok := item.(string)
+		if !ok || rv.Type().Key().Kind() != reflect.String {
+			return false, fmt.Errorf("map keys must be strings, got %s", typeName(item))
+		}
+		return rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key())).IsValid(), nil
+	}
+
+	return false, fmt.Errorf("'in' requires a list, map or string on the right, got %s", typeName(container))
+}
+
+// match applies a regular expression, using the precompiled pattern when available
+func match(n *binaryNode, left, right interface{}) (bool, error) {
+	s, ok := left.(string)
+	if !ok {
+		return false, fmt.Errorf("'%s' requires a string on the left, got %s", n.op, typeName(left))
+	}
+
+	re := n.re
+	if re == nil {
+		pattern, ok := right.(string)
+		if !ok {
+			return false, fmt.Errorf("'%s' requires a string pattern, got %s", n.op, typeName(right))
+		}
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return false, fmt.Errorf("invalid regular expression %q: %w", pattern, err)
+		}
+		re = compiled
+	}
+
+	return re.MatchString(s), nil
+}
+
+// typeName describes a value's type in the terms used by the expression language
+func typeName(value interface{}) string {
+	if value == nil {
+		return "null"
+	}
+
+	switch value.(type) {
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	}
+
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		return "list"
+	case reflect.Map:
+		return "map"
+	case reflect.Struct:
+		return "object"
+	}
+
+	return rv.Kind().String()
+}
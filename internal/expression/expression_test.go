@@ -0,0 +1,258 @@
+// ABOUTME: Tests for the condition expression language
+// ABOUTME: Covers parsing, evaluation semantics, type errors and task reference extraction
+
+package expression
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+func testData() map[string]interface{} {
+	ctx := types.NewWorkflowContext()
+	ctx.Environment["ENVIRONMENT"] = "staging"
+	ctx.Variables["replicas"] = 3
+	ctx.Variables["regions"] = []interface{}{"us-east-1", "eu-west-1"}
+	ctx.Variables["enabled"] = true
+	ctx.Variables["app"] = map[string]interface{}{"name": "ritual", "version": "1.2.0"}
+	ctx.Tasks["build"] = &types.TaskResult{
+		ID:       "build",
+		Status:   types.TaskSuccess,
+		Changed:  true,
+		Stdout:   "build ok",
+		Duration: 1500 * time.Millisecond,
+		Output:   map[string]interface{}{"artifact": "app.tar.gz"},
+	}
+	ctx.Tasks["Build App"] = ctx.Tasks["build"]
+	return Data(ctx)
+}
+
+func TestEvaluateBool(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected bool
+	}{
+		{"true", true},
+		{"false", false},
+		{"tasks.build.Status == 'success'", true},
+		{"tasks.build.status == \"success\"", true},
+		{"tasks.build.Changed and env.ENVIRONMENT in ['prod', 'staging']", true},
+		{"tasks.build.Status == 'success' && (env.ENVIRONMENT == 'prod' || env.ENVIRONMENT == 'staging')", true},
+		{"not tasks.build.changed", false},
+		{"!vars.enabled", false},
+		{"vars.replicas >= 3", true},
+		{"vars.replicas < 2", false},
+		{"tasks.build.Duration > 1", true},
+		{"'eu-west-1' in vars.regions", true},
+		{"'ap-south-1' not in vars.regions", true},
+		{"'ok' in tasks.build.Stdout", true},
+		{"'artifact' in tasks.build.Output", true},
+		{"vars.app.version =~ '^1\\\\.'", true},
+		{"env.ENVIRONMENT !~ 'prod'", true},
+		{"tasks['Build App'].Changed", true},
+		{"vars.regions[1] == 'eu-west-1'", true},
+		{".vars.app.name == 'ritual'", true},
+		{"variables.replicas == 3 and environment.ENVIRONMENT == 'staging'", true},
+		{"defined(vars.missing)", false},
+		{"defined(tasks.build.Output.artifact)", true},
+		{"len(vars.regions) == 2", true},
+		{"startsWith(lower(tasks.build.Stdout), 'build')", true},
+	}
+
+	data := testData()
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			result, err := expr.EvaluateBool(data)
+			if err != nil {
+				t.Fatalf("EvaluateBool failed: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestEvaluate_ShortCircuit(t *testing.T) {
+	data := testData()
+
+	expr, err := Parse("false and vars.missing == 1")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	result, err := expr.EvaluateBool(data)
+	if err != nil {
+		t.Fatalf("Expected short-circuit to avoid evaluating the right side, got: %v", err)
+	}
+	if result {
+		t.Error("Expected false")
+	}
+}
+
+func TestEvaluate_Errors(t *testing.T) {
+	tests := []struct {
+		expr     string
+		contains string
+	}{
+		{"vars.missing == 1", "'vars.missing' is not defined"},
+		{"env.ENVIRONMENT == 1", "cannot compare string with number"},
+		{"vars.replicas > 'two'", "cannot compare number with string"},
+		{"vars.replicas and true", "requires boolean operands"},
+		{"env.ENVIRONMENT", "must evaluate to a boolean"},
+		{"tasks.deploy.Status == 'success'", "'tasks.deploy' is not defined"},
+		{"tasks.build.Nope", "'tasks.build.Nope' is not defined"},
+		{"vars.regions[5] == 'x'", "out of range"},
+	}
+
+	data := testData()
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			_, err = expr.EvaluateBool(data)
+			if err == nil {
+				t.Fatal("Expected evaluation error")
+			}
+			if !strings.Contains(err.Error(), tt.contains) {
+				t.Errorf("Expected error containing %q, got: %v", tt.contains, err)
+			}
+		})
+	}
+}
+
+func TestParse_SyntaxErrors(t *testing.T) {
+	tests := []struct {
+		expr     string
+		contains string
+		pos      int
+	}{
+		{"", "empty expression", 0},
+		{"tasks.build.Status = 'success'", "use '=='", 19},
+		{"tasks.build.Status == ", "unexpected end of expression", 22},
+		{"foo == 1", "unknown identifier 'foo'", 0},
+		{"vars.a == 1 and", "unexpected end of expression", 15},
+		{"(vars.a == 1", "expected ')'", 12},
+		{"vars.a == 'unterminated", "unterminated string literal", 10},
+		{"vars.a =~ '('", "invalid regular expression", 10},
+		{"1 < vars.a < 3", "cannot be chained", 11},
+		{"frobnicate(vars.a)", "unknown function 'frobnicate'", 0},
+		{"len(vars.a, vars.b)", "takes 1 argument(s)", 0},
+		{"vars.a == 1 vars.b", "unexpected 'vars'", 12},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Parse(tt.expr)
+			if err == nil {
+				t.Fatal("Expected syntax error")
+			}
+
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Expected *SyntaxError, got %T", err)
+			}
+			if !strings.Contains(syntaxErr.Message, tt.contains) {
+				t.Errorf("Expected message containing %q, got %q", tt.contains, syntaxErr.Message)
+			}
+			if syntaxErr.Pos != tt.pos {
+				t.Errorf("Expected position %d, got %d", tt.pos, syntaxErr.Pos)
+			}
+		})
+	}
+}
+
+func TestSyntaxError_Caret(t *testing.T) {
+	_, err := Parse("vars.a = 1")
+	if err == nil {
+		t.Fatal("Expected syntax error")
+	}
+
+	lines := strings.Split(err.Error(), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected message, source and caret lines, got: %q", err.Error())
+	}
+	if !strings.Contains(lines[0], "column 8") {
+		t.Errorf("Expected column 8 in message, got %q", lines[0])
+	}
+	if strings.Index(lines[2], "^") != strings.Index(lines[1], "=") {
+		t.Errorf("Expected caret under '=', got:\n%s\n%s", lines[1], lines[2])
+	}
+}
+
+func TestTaskReferences(t *testing.T) {
+	expr, err := Parse("tasks.build.Changed and (tasks['Deploy App'].Status == 'success' or tasks.build.Stdout != '')")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	refs := expr.TaskReferences()
+	expected := []string{"build", "Deploy App"}
+	if !reflect.DeepEqual(refs, expected) {
+		t.Errorf("Expected references %v, got %v", expected, refs)
+	}
+}
+
+func TestReferences(t *testing.T) {
+	expr, err := Parse("vars.region == 'eu' and defined(vars.zone) and env.HOME != ''")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if refs := expr.References("vars"); !reflect.DeepEqual(refs, []string{"region", "zone"}) {
+		t.Errorf("Expected references [region zone], got %v", refs)
+	}
+	if refs := expr.RequiredReferences("vars"); !reflect.DeepEqual(refs, []string{"region"}) {
+		t.Errorf("Expected required references [region], got %v", refs)
+	}
+}
+
+func TestIsConstant(t *testing.T) {
+	tests := map[string]bool{
+		"false":                    true,
+		"1 == 2 or 'a' in ['b']":   true,
+		"not defined(vars.region)": false,
+		"tasks.build.Changed":      false,
+	}
+	for src, want := range tests {
+		expr, err := Parse(src)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", src, err)
+		}
+		if got := expr.IsConstant(); got != want {
+			t.Errorf("Expected IsConstant(%q) to be %v, got %v", src, want, got)
+		}
+	}
+}
+
+func TestIsTemplate(t *testing.T) {
+	if !IsTemplate("{{ eq .env.ENVIRONMENT \"production\" }}") {
+		t.Error("Expected template condition to be detected")
+	}
+	if IsTemplate("env.ENVIRONMENT == 'production'") {
+		t.Error("Expected expression condition not to be treated as a template")
+	}
+}
+
+func TestIsLiteral(t *testing.T) {
+	for _, condition := range []string{"yes", "enabled", " off ", "0", "1", "production"} {
+		if !IsLiteral(condition) {
+			t.Errorf("Expected %q to be a literal", condition)
+		}
+	}
+	for _, condition := range []string{"true", "false", "null", "vars", "'yes'", "not yes", "vars.enabled", ""} {
+		if IsLiteral(condition) {
+			t.Errorf("Expected %q not to be a literal", condition)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+// ABOUTME: Tokenizer for the condition expression language used by task 'when' clauses
+// ABOUTME: Produces identifiers, literals and operators annotated with their source offsets
+
+package expression
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tokenKind identifies the lexical class of a token
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOperator
+)
+
+// token is a single lexical unit of an expression
+type token struct {
+	kind  tokenKind
+	text  string // raw text for identifiers, numbers and operators; decoded value for strings
+	pos   int    // byte offset of the token within the source
+	value float64
+}
+
+// operators lists the recognised punctuation, longest first so that
+// two-character operators win over their one-character prefixes
+var operators = []string{
+	"==", "!=", "<=", ">=", "=~", "!~", "&&", "||",
+	"<", ">", "!", "(", ")", "[", "]", ",", ".",
+}
+
+// lex splits the source into tokens, always terminating the slice with an EOF token
+func lex(src string) ([]token, error) {
+	var tokens []token
+	i := 0
+
+	for i < len(src) {
+		c := src[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case isIdentStart(c):
+			start := i
+			for i < len(src) && (isIdentPart(src[i]) || (src[i] == '-' && i+1 < len(src) && isIdentPart(src[i+1]))) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: src[start:i], pos: start})
+
+		case isDigit(c) || (c == '-' && i+1 < len(src) && isDigit(src[i+1])):
+			start := i
+			i++
+			for i < len(src) && isDigit(src[i]) {
+				i++
+			}
+			if i+1 < len(src) && src[i] == '.' && isDigit(src[i+1]) {
+				i++
+				for i < len(src) && isDigit(src[i]) {
+					i++
+				}
+			}
+			var value float64
+			if _, err := fmt.Sscanf(src[start:i], "%g", &value); err != nil {
+				return nil, newSyntaxError(src, start, fmt.Sprintf("invalid number '%s'", src[start:i]))
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: src[start:i], pos: start, value: value})
+
+		case c == '"' || c == '\'':
+			value, end, err := lexString(src, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenString, text: value, pos: i})
+			i = end
+
+		default:
+			op := matchOperator(src[i:])
+			if op == "" {
+				if c == '=' {
+					return nil, newSyntaxError(src, i, "unexpected '=' (use '==' for comparison)")
+				}
+				return nil, newSyntaxError(src, i, fmt.Sprintf("unexpected character '%c'", c))
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+			i += len(op)
+		}
+	}
+
+	tokens = append(tokens, token{kind: tokenEOF, pos: len(src)})
+	return tokens, nil
+}
+
+// lexString decodes a quoted string starting at start and returns the offset just past it
+func lexString(src string, start int) (string, int, error) {
+	quote := src[start]
+	var b strings.Builder
+
+	for i := start + 1; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == quote:
+			return b.String(), i + 1, nil
+		case c == '\\':
+			if i+1 >= len(src) {
+				return "", 0, newSyntaxError(src, i, "unterminated escape sequence")
+			}
+			i++
+			switch src[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case '\\', '"', '\'':
+				b.WriteByte(src[i])
+			default:
+				return "", 0, newSyntaxError(src, i-1, fmt.Sprintf("unknown escape sequence '\\%c'", src[i]))
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return "", 0, newSyntaxError(src, start, "unterminated string literal")
+}
+
+// matchOperator returns the operator at the start of s, or "" if there is none
+func matchOperator(s string) string {
+	for _, op := range operators {
+		if strings.HasPrefix(s, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,523 @@
+// ABOUTME: Recursive descent parser for 'when' condition expressions
+// ABOUTME: Builds an AST and reports syntax errors with a caret pointing at the offending column
+
+package expression
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// rootAliases lists the top-level identifiers an expression may reference. The long
+// forms mirror the template data aliases and are normalised to the short form.
+var rootAliases = map[string]string{
+	"tasks":       "tasks",
+	"vars":        "vars",
+	"variables":   "vars",
+	"env":         "env",
+	"environment": "env",
+	"metadata":    "metadata",
+	"workflow":    "workflow",
+}
+
+// functions maps the built-in function names to their arity
+var functions = map[string]int{
+	"len":        1,
+	"lower":      1,
+	"upper":      1,
+	"contains":   2,
+	"startsWith": 2,
+	"endsWith":   2,
+	"defined":    1,
+}
+
+// SyntaxError describes a problem found while parsing an expression
+type SyntaxError struct {
+	Source  string
+	Pos     int
+	Message string
+}
+
+func newSyntaxError(src string, pos int, message string) *SyntaxError {
+	return &SyntaxError{Source: src, Pos: pos, Message: message}
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at column %d\n    %s\n    %s^", e.Message, e.Pos+1, e.Source, strings.Repeat(" ", e.Pos))
+}
+
+// Expression is a parsed condition ready for evaluation
+type Expression struct {
+	source string
+	root   node
+}
+
+// String returns the original source of the expression
+func (e *Expression) String() string {
+	return e.source
+}
+
+// node is an element of the expression AST
+type node interface {
+	position() int
+}
+
+type literalNode struct {
+	pos   int
+	value interface{}
+}
+
+type listNode struct {
+	pos   int
+	items []node
+}
+
+// segment is one step of a path: either a static name or a computed index
+type segment struct {
+	pos   int
+	name  string
+	index node
+}
+
+type pathNode struct {
+	pos      int
+	root     string
+	segments []segment
+}
+
+type unaryNode struct {
+	pos     int
+	op      string
+	operand node
+}
+
+type binaryNode struct {
+	pos   int
+	op    string
+	left  node
+	right node
+	re    *regexp.Regexp // precompiled pattern when the right side of =~ or !~ is a literal
+}
+
+type callNode struct {
+	pos  int
+	name string
+	args []node
+}
+
+func (n *literalNode) position() int { return n.pos }
+func (n *listNode) position() int    { return n.pos }
+func (n *pathNode) position() int    { return n.pos }
+func (n *unaryNode) position() int   { return n.pos }
+func (n *binaryNode) position() int  { return n.pos }
+func (n *callNode) position() int    { return n.pos }
+
+// parser holds the token stream for a single Parse call
+type parser struct {
+	src    string
+	tokens []token
+	cur    int
+}
+
+// Parse compiles an expression, returning a *SyntaxError if it is malformed
+func Parse(src string) (*Expression, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{src: src, tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, newSyntaxError(src, 0, "empty expression")
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.unexpected(tok)
+	}
+
+	return &Expression{source: src, root: root}, nil
+}
+
+// IsTemplate reports whether a condition uses Go template syntax rather than
+// the expression language
+func IsTemplate(condition string) bool {
+	return strings.Contains(condition, "{{")
+}
+
+// IsLiteral reports whether a condition is a single bare word or number, such
+// as yes, enabled or 0, rather than an expression. Such conditions predate the
+// expression language and are still checked for truthiness as text.
+func IsLiteral(condition string) bool {
+	tokens, err := lex(condition)
+	if err != nil || len(tokens) != 2 {
+		return false
+	}
+	switch tok := tokens[0]; tok.kind {
+	case tokenNumber:
+		return true
+	case tokenIdent:
+		switch tok.text {
+		case "true", "false", "null", "nil":
+			return false
+		}
+		_, isRoot := rootAliases[tok.text]
+		return !isRoot
+	}
+	return false
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.cur]
+}
+
+func (p *parser) peekAt(offset int) token {
+	if p.cur+offset >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.cur+offset]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.cur]
+	if tok.kind != tokenEOF {
+		p.cur++
+	}
+	return tok
+}
+
+// isOp reports whether the current token is one of the given operators or keywords
+func (p *parser) isOp(ops ...string) bool {
+	tok := p.peek()
+	if tok.kind != tokenOperator && tok.kind != tokenIdent {
+		return false
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) expect(op string) (token, error) {
+	tok := p.peek()
+	if tok.kind != tokenOperator || tok.text != op {
+		return tok, newSyntaxError(p.src, tok.pos, fmt.Sprintf("expected '%s' but found %s", op, describe(tok)))
+	}
+	return p.next(), nil
+}
+
+func (p *parser) unexpected(tok token) error {
+	return newSyntaxError(p.src, tok.pos, fmt.Sprintf("unexpected %s", describe(tok)))
+}
+
+// parseOr handles the lowest precedence level: or / ||
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOp("or", "||") {
+		tok := p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{pos: tok.pos, op: "or", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+// parseAnd handles and / &&
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOp("and", "&&") {
+		tok := p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{pos: tok.pos, op: "and", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+// parseNot handles prefix negation
+func (p *parser) parseNot() (node, error) {
+	if p.isOp("not", "!") {
+		tok := p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{pos: tok.pos, op: "not", operand: operand}, nil
+	}
+
+	return p.parseComparison()
+}
+
+// parseComparison handles a single, non-associative comparison
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	tok := p.peek()
+	var op string
+	switch {
+	case p.isOp("==", "!=", "<", "<=", ">", ">=", "=~", "!~", "in"):
+		op = tok.text
+		p.next()
+	case tok.kind == tokenIdent && tok.text == "not" && p.peekAt(1).kind == tokenIdent && p.peekAt(1).text == "in":
+		op = "not in"
+		p.next()
+		p.next()
+	default:
+		return left, nil
+	}
+
+	right, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	bin := &binaryNode{pos: tok.pos, op: op, left: left, right: right}
+
+	if op == "=~" || op == "!~" {
+		if lit, ok := right.(*literalNode); ok {
+			pattern, ok := lit.value.(string)
+			if !ok {
+				return nil, newSyntaxError(p.src, right.position(), fmt.Sprintf("'%s' requires a string pattern", op))
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, newSyntaxError(p.src, right.position(), fmt.Sprintf("invalid regular expression: %v", err))
+			}
+			bin.re = re
+		}
+	}
+
+	if next := p.peek(); p.isOp("==", "!=", "<", "<=", ">", ">=", "=~", "!~", "in") {
+		return nil, newSyntaxError(p.src, next.pos, "comparisons cannot be chained; combine them with 'and'")
+	}
+
+	return bin, nil
+}
+
+// parsePrimary handles literals, lists, paths, function calls and parentheses
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.peek()
+
+	switch tok.kind {
+	case tokenNumber:
+		p.next()
+		return &literalNode{pos: tok.pos, value: tok.value}, nil
+
+	case tokenString:
+		p.next()
+		return &literalNode{pos: tok.pos, value: tok.text}, nil
+
+	case tokenIdent:
+		switch tok.text {
+		case "true":
+			p.next()
+			return &literalNode{pos: tok.pos, value: true}, nil
+		case "false":
+			p.next()
+			return &literalNode{pos: tok.pos, value: false}, nil
+		case "null", "nil":
+			p.next()
+			return &literalNode{pos: tok.pos, value: nil}, nil
+		case "and", "or", "not", "in":
+			return nil, p.unexpected(tok)
+		}
+
+		if next := p.peekAt(1); next.kind == tokenOperator && next.text == "(" {
+			return p.parseCall()
+		}
+		return p.parsePath()
+
+	case tokenOperator:
+		switch tok.text {
+		case "(":
+			p.next()
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return inner, nil
+
+		case "[":
+			return p.parseList()
+
+		case ".":
+			// Accept template-style leading dots such as .vars.name
+			if next := p.peekAt(1); next.kind == tokenIdent && next.pos == tok.pos+1 {
+				p.next()
+				return p.parsePath()
+			}
+		}
+	}
+
+	if tok.kind == tokenEOF {
+		return nil, newSyntaxError(p.src, tok.pos, "unexpected end of expression")
+	}
+	return nil, p.unexpected(tok)
+}
+
+// parseList handles [a, b, c] literals
+func (p *parser) parseList() (node, error) {
+	open := p.next()
+	list := &listNode{pos: open.pos}
+
+	if p.isOp("]") {
+		p.next()
+		return list, nil
+	}
+
+	for {
+		item, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		list.items = append(list.items, item)
+
+		if p.isOp(",") {
+			p.next()
+			continue
+		}
+		if _, err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return list, nil
+	}
+}
+
+// parseCall handles built-in function calls
+func (p *parser) parseCall() (node, error) {
+	name := p.next()
+	arity, ok := functions[name.text]
+	if !ok {
+		return nil, newSyntaxError(p.src, name.pos, fmt.Sprintf("unknown function '%s' (available: %s)", name.text, strings.Join(functionNames(), ", ")))
+	}
+	p.next() // (
+
+	call := &callNode{pos: name.pos, name: name.text}
+	if !p.isOp(")") {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			if p.isOp(",") {
+				p.next()
+				continue
+			}
+			break
+		}
+	}
+	if _, err := p.expect(")"); err != nil {
+		return nil, err
+	}
+
+	if len(call.args) != arity {
+		return nil, newSyntaxError(p.src, name.pos, fmt.Sprintf("function '%s' takes %d argument(s), got %d", name.text, arity, len(call.args)))
+	}
+	if call.name == "defined" {
+		if _, ok := call.args[0].(*pathNode); !ok {
+			return nil, newSyntaxError(p.src, call.args[0].position(), "function 'defined' expects a variable path")
+		}
+	}
+
+	return call, nil
+}
+
+// parsePath handles dotted and indexed references rooted at a known identifier
+func (p *parser) parsePath() (node, error) {
+	rootTok := p.next()
+	root, ok := rootAliases[rootTok.text]
+	if !ok {
+		return nil, newSyntaxError(p.src, rootTok.pos, fmt.Sprintf("unknown identifier '%s' (expected one of %s, or a quoted string)", rootTok.text, strings.Join(rootNames(), ", ")))
+	}
+
+	path := &pathNode{pos: rootTok.pos, root: root}
+	for {
+		switch {
+		case p.isOp("."):
+			p.next()
+			tok := p.next()
+			switch tok.kind {
+			case tokenIdent:
+				path.segments = append(path.segments, segment{pos: tok.pos, name: tok.text})
+			case tokenNumber:
+				path.segments = append(path.segments, segment{pos: tok.pos, index: &literalNode{pos: tok.pos, value: tok.value}})
+			default:
+				return nil, newSyntaxError(p.src, tok.pos, fmt.Sprintf("expected a field name after '.' but found %s", describe(tok)))
+			}
+
+		case p.isOp("["):
+			open := p.next()
+			index, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			if lit, ok := index.(*literalNode); ok {
+				if name, ok := lit.value.(string); ok {
+					path.segments = append(path.segments, segment{pos: open.pos, name: name})
+					continue
+				}
+			}
+			path.segments = append(path.segments, segment{pos: open.pos, index: index})
+
+		default:
+			return path, nil
+		}
+	}
+}
+
+// describe renders a token for use in error messages
+func describe(tok token) string {
+	switch tok.kind {
+	case tokenEOF:
+		return "end of expression"
+	case tokenString:
+		return fmt.Sprintf("string %q", tok.text)
+	default:
+		return fmt.Sprintf("'%s'", tok.text)
+	}
+}
+
+func rootNames() []string {
+	return []string{"tasks", "vars", "env", "metadata", "workflow"}
+}
+
+func functionNames() []string {
+	names := make([]string, 0, len(functions))
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
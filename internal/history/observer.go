@@ -0,0 +1,39 @@
+// ABOUTME: Event observer that records finished workflow executions in the history store
+// ABOUTME: Uses the execution ID from the event so history matches server and event IDs
+
+package history
+
+import (
+	"github.com/sarlalian/ritual/internal/events"
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+// Observer records executions when their WorkflowFinished event is published
+type Observer struct {
+	store  *Store
+	logger types.Logger
+}
+
+// NewObserver creates an observer that writes to store and logs failures to logger
+func NewObserver(store *Store, logger types.Logger) *Observer {
+	return &Observer{store: store, logger: logger}
+}
+
+// OnEvent records finished executions. Runs that stopped before execution
+// started, such as on validation errors, are not recorded.
+func (o *Observer) OnEvent(event events.Event) {
+	if event.Type != events.WorkflowFinished || event.Result == nil {
+		return
+	}
+	if event.Result.WorkflowResult == nil && event.Result.ExecutionError == nil {
+		return
+	}
+
+	triggerData := map[string]interface{}{
+		"env_vars": event.EnvVars,
+	}
+	err := o.store.RecordExecutionWithID(event.ExecutionID, event.Result, event.Workflow, event.WorkflowPath, "manual", triggerData)
+	if err != nil && o.logger != nil {
+		o.logger.Info().Msgf("Failed to record execution history: %v", err)
+	}
+}
@@ -14,6 +14,7 @@ import (
 
 	"github.com/spf13/afero"
 
+	"github.com/sarlalian/ritual/internal/redact"
 	"github.com/sarlalian/ritual/pkg/types"
 )
 
@@ -22,6 +23,7 @@ type Store struct {
 	fs         afero.Fs
 	dataDir    string
 	maxEntries int
+	redactor   *redact.Redactor
 }
 
 // ExecutionRecord represents a complete execution record
@@ -37,12 +39,25 @@ type ExecutionRecord struct {
 	TriggerData      map[string]interface{}       `json:"trigger_data,omitempty"`
 	Environment      map[string]string            `json:"environment,omitempty"`
 	Variables        map[string]interface{}       `json:"variables,omitempty"`
+	Outputs          map[string]interface{}       `json:"outputs,omitempty"`
 	TaskResults      map[string]*types.TaskResult `json:"task_results"`
 	ErrorMessage     string                       `json:"error_message,omitempty"`
 	ValidationErrors []string                     `json:"validation_errors,omitempty"`
+	Approvals        []ApprovalRecord             `json:"approvals,omitempty"`
 	Metadata         map[string]interface{}       `json:"metadata,omitempty"`
 }
 
+// ApprovalRecord captures who resolved an approval task and how
+type ApprovalRecord struct {
+	TaskID    string `json:"task_id"`
+	TaskName  string `json:"task_name"`
+	Decision  string `json:"decision"`
+	Approver  string `json:"approver,omitempty"`
+	Comment   string `json:"comment,omitempty"`
+	Source    string `json:"source,omitempty"`
+	DecidedAt string `json:"decided_at,omitempty"`
+}
+
 // ExecutionSummary provides a lightweight summary of an execution
 type ExecutionSummary struct {
 	ID           string               `json:"id"`
@@ -53,6 +68,7 @@ type ExecutionSummary struct {
 	TaskCount    int                  `json:"task_count"`
 	SuccessTasks int                  `json:"success_tasks"`
 	FailedTasks  int                  `json:"failed_tasks"`
+	ChangedTasks int                  `json:"changed_tasks"`
 	TriggerType  string               `json:"trigger_type"`
 }
 
@@ -73,6 +89,10 @@ type HistoryStats struct {
 	SuccessfulRuns  int                          `json:"successful_runs"`
 	FailedRuns      int                          `json:"failed_runs"`
 	PartialRuns     int                          `json:"partial_runs"`
+	ChangedRuns     int                          `json:"changed_runs"` // runs where at least one task changed something
+	ChangedTasks    int                          `json:"changed_tasks"`
+	UnchangedTasks  int                          `json:"unchanged_tasks"`
+	UntrackedTasks  int                          `json:"untracked_tasks"` // successful tasks whose executors do not report changes
 	SuccessRate     float64                      `json:"success_rate"`
 	AverageDuration time.Duration                `json:"average_duration"`
 	WorkflowCounts  map[string]int               `json:"workflow_counts"`
@@ -96,6 +116,12 @@ func New(fs afero.Fs, dataDir string, maxEntries int) *Store {
 	}
 }
 
+// SetRedactor sets the redactor used to mask sensitive values in records
+// before they are written
+func (s *Store) SetRedactor(redactor *redact.Redactor) {
+	s.redactor = redactor
+}
+
 // Initialize creates the data directory if it doesn't exist
 func (s *Store) Initialize() error {
 	if err := s.fs.MkdirAll(s.dataDir, 0755); err != nil {
@@ -107,7 +133,11 @@ func (s *Store) Initialize() error {
 // RecordExecution stores a workflow execution result
 func (s *Store) RecordExecution(result *types.Result, workflowName, workflowPath, triggerType string, triggerData map[string]interface{}) error {
 	executionID := fmt.Sprintf("exec_%d", time.Now().UnixNano())
+	return s.RecordExecutionWithID(executionID, result, workflowName, workflowPath, triggerType, triggerData)
+}
 
+// RecordExecutionWithID stores a workflow execution result under a known execution ID
+func (s *Store) RecordExecutionWithID(executionID string, result *types.Result, workflowName, workflowPath, triggerType string, triggerData map[string]interface{}) error {
 	record := &ExecutionRecord{
 		ID:           executionID,
 		WorkflowName: workflowName,
@@ -126,6 +156,8 @@ func (s *Store) RecordExecution(result *types.Result, workflowName, workflowPath
 		record.EndTime = result.WorkflowResult.EndTime
 		record.Duration = result.WorkflowResult.Duration
 		record.TaskResults = result.WorkflowResult.Tasks
+		record.Outputs = result.WorkflowResult.Outputs
+		record.Approvals = extractApprovals(result.WorkflowResult.Tasks)
 	}
 
 	// Handle errors
@@ -149,7 +181,114 @@ func (s *Store) RecordExecution(result *types.Result, workflowName, workflowPath
 	}
 
 	// Store execution record
-	return s.storeRecord(record)
+	return s.storeRecord(s.redactRecord(record))
+}
+
+// redactRecord returns a copy of record with sensitive values masked. Task
+// results are copied rather than changed, since they belong to the live run.
+func (s *Store) redactRecord(record *ExecutionRecord) *ExecutionRecord {
+	if s.redactor == nil {
+		return record
+	}
+	r := s.redactor
+
+	masked := *record
+	masked.ErrorMessage = r.String(record.ErrorMessage)
+	masked.TriggerData = r.Map(record.TriggerData)
+	if envVars, ok := record.TriggerData["env_vars"].([]string); ok {
+		masked.TriggerData["env_vars"] = redactAssignments(r, envVars)
+	}
+	if record.Environment != nil {
+		masked.Environment = r.Value(record.Environment).(map[string]string)
+	}
+	masked.Variables = r.Map(record.Variables)
+	masked.Outputs = r.Map(record.Outputs)
+	masked.Metadata = r.Map(record.Metadata)
+
+	if record.ValidationErrors != nil {
+		masked.ValidationErrors = r.Value(record.ValidationErrors).([]string)
+	}
+
+	if record.TaskResults != nil {
+		masked.TaskResults = make(map[string]*types.TaskResult, len(record.TaskResults))
+		copies := make(map[*types.TaskResult]*types.TaskResult)
+		for key, result := range record.TaskResults {
+			if result == nil {
+				masked.TaskResults[key] = nil
+				continue
+			}
+			// Results are keyed by both ID and name; keep them shared
+			if copied, ok := copies[result]; ok {
+				masked.TaskResults[key] = copied
+				continue
+			}
+			copied := *result
+			r.TaskResult(&copied)
+			copies[result] = &copied
+			masked.TaskResults[key] = &copied
+		}
+	}
+
+	masked.Approvals = append([]ApprovalRecord(nil), record.Approvals...)
+	for i := range masked.Approvals {
+		masked.Approvals[i].Comment = r.String(masked.Approvals[i].Comment)
+	}
+
+	return &masked
+}
+
+// redactAssignments masks KEY=value pairs whose key is sensitive or whose
+// value contains a tracked value
+func redactAssignments(r *redact.Redactor, assignments []string) []string {
+	masked := make([]string, len(assignments))
+	for i, assignment := range assignments {
+		key, value, found := strings.Cut(assignment, "=")
+		if found && r.SensitiveName(key) && value != "" {
+			masked[i] = key + "=" + redact.Mask
+		} else {
+			masked[i] = r.String(assignment)
+		}
+	}
+	return masked
+}
+
+// extractApprovals collects the decisions made on approval tasks. Task results
+// are keyed by both ID and name, so each task is only recorded once.
+func extractApprovals(tasks map[string]*types.TaskResult) []ApprovalRecord {
+	seen := make(map[*types.TaskResult]bool)
+	var approvals []ApprovalRecord
+
+	for _, task := range tasks {
+		if task == nil || task.Type != "approval" || seen[task] {
+			continue
+		}
+		seen[task] = true
+
+		decision, _ := task.Output["decision"].(string)
+		if decision == "" {
+			continue
+		}
+
+		approver, _ := task.Output["approver"].(string)
+		comment, _ := task.Output["comment"].(string)
+		source, _ := task.Output["source"].(string)
+		decidedAt, _ := task.Output["decided_at"].(string)
+
+		approvals = append(approvals, ApprovalRecord{
+			TaskID:    task.ID,
+			TaskName:  task.Name,
+			Decision:  decision,
+			Approver:  approver,
+			Comment:   comment,
+			Source:    source,
+			DecidedAt: decidedAt,
+		})
+	}
+
+	sort.Slice(approvals, func(i, j int) bool {
+		return approvals[i].DecidedAt < approvals[j].DecidedAt
+	})
+	return approvals
 }
 
 // storeRecord saves an execution record to disk
@@ -344,6 +483,9 @@ func (s *Store) createSummary(record *ExecutionRecord) *ExecutionSummary {
 		case types.TaskFailed:
 			summary.FailedTasks++
 		}
+		if task.Changed {
+			summary.ChangedTasks++
+		}
 	}
 
 	return summary
@@ -390,6 +532,25 @@ func (s *Store) GetStats() (*HistoryStats, error) {
 			stats.PartialRuns++
 		}
 
+		// Changed/unchanged task counts
+		runChanged := false
+		for _, task := range record.TaskResults {
+			switch {
+			case task.Changed:
+				stats.ChangedTasks++
+				runChanged = true
+			case task.Status != types.TaskSuccess && task.Status != types.TaskWarning:
+				// Failed and skipped tasks are neither changed nor unchanged
+			case task.ChangeTracked:
+				stats.UnchangedTasks++
+			default:
+				stats.UntrackedTasks++
+			}
+		}
+		if runChanged {
+			stats.ChangedRuns++
+		}
+
 		// Workflow counts
 		stats.WorkflowCounts[record.WorkflowName]++
 
@@ -0,0 +1,226 @@
+// ABOUTME: Typed workflow inputs declared in a workflow's inputs section
+// ABOUTME: Coerces values from --var, --var-file and webhooks and reports missing or invalid ones
+
+package inputs
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sarlalian/ritual/pkg/coerce"
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+// Input types. An input without a type is a string.
+const (
+	String = "string"
+	Int    = "int"
+	Bool   = "bool"
+	List   = "list"
+	Map    = "map"
+	Enum   = "enum"
+)
+
+// Types lists the supported input types
+var Types = []string{String, Int, Bool, List, Map, Enum}
+
+// Check validates a workflow's input declarations: known types, enum values,
+// defaults of the right type, and names that are not also set in vars
+func Check(workflow *types.Workflow) []error {
+	var errs []error
+	for _, name := range names(workflow.Inputs) {
+		input := workflow.Inputs[name]
+		field := "inputs." + name
+
+		switch {
+		case !knownType(input.Type):
+			errs = append(errs, types.NewValidationError(field, input.Type,
+				fmt.Sprintf("unknown type '%s' (expected one of %s)", input.Type, strings.Join(Types, ", "))))
+			continue
+		case input.Type == Enum && len(input.Values) == 0:
+			errs = append(errs, types.NewValidationError(field, nil, "enum inputs must list their allowed values"))
+			continue
+		case input.Type != Enum && len(input.Values) > 0:
+			errs = append(errs, types.NewValidationError(field, input.Values, "values only apply to enum inputs"))
+		}
+
+		if input.Required && input.Default != nil {
+			errs = append(errs, types.NewValidationError(field, input.Default, "a required input cannot have a default"))
+		} else if input.Default != nil {
+			if _, err := Coerce(input, input.Default); err != nil {
+				errs = append(errs, types.NewValidationError(field, input.Default, fmt.Sprintf("invalid default: %v", err)))
+			}
+		}
+
+		if _, ok := workflow.Variables[name]; ok {
+			errs = append(errs, types.NewValidationError(field, nil, "name is also defined in vars"))
+		}
+	}
+	return errs
+}
+
+// Resolve returns the value of every declared input from provided, falling
+// back to defaults. Optional inputs without a default get their type's zero
+// value so templates can test them. Every missing or invalid input is
+// reported; values for undeclared names are ignored.
+func Resolve(declared map[string]types.InputConfig, provided map[string]interface{}) (map[string]interface{}, []error) {
+	values := make(map[string]interface{}, len(declared))
+	var errs []error
+
+	for _, name := range names(declared) {
+		input := declared[name]
+		field := "inputs." + name
+
+		raw, ok := provided[name]
+		if !ok {
+			switch {
+			case input.Required:
+				message := "missing required input"
+				if input.Description != "" {
+					message += " (" + input.Description + ")"
+				}
+				errs = append(errs, types.NewValidationError(field, nil, message))
+				continue
+			case input.Default != nil:
+				raw = input.Default
+			default:
+				values[name] = zero(input.Type)
+				continue
+			}
+		}
+
+		value, err := Coerce(input, raw)
+		if err != nil {
+			errs = append(errs, types.NewValidationError(field, raw, err.Error()))
+			continue
+		}
+		values[name] = value
+	}
+
+	return values, errs
+}
+
+// Unknown returns the provided names that are not declared inputs
+func Unknown(declared map[string]types.InputConfig, provided map[string]interface{}) []string {
+	var unknown []string
+	for name := range provided {
+		if _, ok := declared[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// Coerce converts value to the input's type. Strings from the command line
+// are parsed, so "3" is accepted for an int, "a,b" or a JSON array for a
+// list, and a JSON object for a map.
+func Coerce(input types.InputConfig, value interface{}) (interface{}, error) {
+	switch input.Type {
+	case "", String:
+		return coerce.String(value)
+	case Int:
+		return coerce.Int(value)
+	case Bool:
+		return coerce.Bool(value)
+	case List:
+		return toList(value)
+	case Map:
+		m, err := coerce.Map(value)
+		if err == nil && m == nil {
+			m = map[string]interface{}{}
+		}
+		return m, err
+	case Enum:
+		s, err := coerce.String(value)
+		if err != nil {
+			return nil, err
+		}
+		allowed := make([]string, len(input.Values))
+		for i, candidate := range input.Values {
+			allowed[i] = fmt.Sprint(candidate)
+			if allowed[i] == s {
+				return candidate, nil
+			}
+		}
+		return nil, fmt.Errorf("must be one of %s, got %q", strings.Join(allowed, ", "), s)
+	default:
+		return nil, fmt.Errorf("unknown type '%s'", input.Type)
+	}
+}
+
+// toList converts lists, JSON arrays and comma-separated strings to a list
+func toList(value interface{}) ([]interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return []interface{}{}, nil
+	case []interface{}:
+		return v, nil
+	case string:
+		trimmed := strings.TrimSpace(v)
+		if trimmed == "" {
+			return []interface{}{}, nil
+		}
+		if strings.HasPrefix(trimmed, "[") {
+			var list []interface{}
+			if err := json.Unmarshal([]byte(trimmed), &list); err != nil {
+				return nil, fmt.Errorf("invalid JSON list: %w", err)
+			}
+			return list, nil
+		}
+		parts := strings.Split(trimmed, ",")
+		list := make([]interface{}, len(parts))
+		for i, part := range parts {
+			list[i] = strings.TrimSpace(part)
+		}
+		return list, nil
+	}
+
+	items, err := coerce.StringSlice(value)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]interface{}, len(items))
+	for i, item := range items {
+		list[i] = item
+	}
+	return list, nil
+}
+
+func zero(inputType string) interface{} {
+	switch inputType {
+	case Int:
+		return 0
+	case Bool:
+		return false
+	case List:
+		return []interface{}{}
+	case Map:
+		return map[string]interface{}{}
+	default:
+		return ""
+	}
+}
+
+func knownType(inputType string) bool {
+	if inputType == "" {
+		return true
+	}
+	for _, known := range Types {
+		if inputType == known {
+			return true
+		}
+	}
+	return false
+}
+
+func names(declared map[string]types.InputConfig) []string {
+	sorted := make([]string, 0, len(declared))
+	for name := range declared {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
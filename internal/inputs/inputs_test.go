@@ -0,0 +1,120 @@
+// ABOUTME: Tests for typed workflow inputs
+// ABOUTME: Covers declaration checks, coercion per type, defaults and error reporting
+
+package inputs
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+func TestCoerce(t *testing.T) {
+	tests := []struct {
+		input types.InputConfig
+		value interface{}
+		want  interface{}
+	}{
+		{types.InputConfig{}, "plain", "plain"},
+		{types.InputConfig{Type: String}, 42, "42"},
+		{types.InputConfig{Type: Int}, "3", 3},
+		{types.InputConfig{Type: Int}, float64(5), 5},
+		{types.InputConfig{Type: Bool}, "true", true},
+		{types.InputConfig{Type: List}, "a, b,c", []interface{}{"a", "b", "c"}},
+		{types.InputConfig{Type: List}, `["x", 1]`, []interface{}{"x", float64(1)}},
+		{types.InputConfig{Type: List}, []interface{}{1, 2}, []interface{}{1, 2}},
+		{types.InputConfig{Type: List}, "", []interface{}{}},
+		{types.InputConfig{Type: Map}, `{"a": 1}`, map[string]interface{}{"a": float64(1)}},
+		{types.InputConfig{Type: Enum, Values: []interface{}{"dev", "prod"}}, "prod", "prod"},
+		{types.InputConfig{Type: Enum, Values: []interface{}{1, 2}}, "2", 2},
+	}
+
+	for _, test := range tests {
+		got, err := Coerce(test.input, test.value)
+		if err != nil {
+			t.Errorf("Expected no error coercing %v to %s, got: %v", test.value, test.input.Type, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Expected %v to coerce to %#v, got %#v", test.value, test.want, got)
+		}
+	}
+
+	invalid := []struct {
+		input types.InputConfig
+		value interface{}
+	}{
+		{types.InputConfig{Type: Int}, "many"},
+		{types.InputConfig{Type: Bool}, "maybe"},
+		{types.InputConfig{Type: String}, []interface{}{"a"}},
+		{types.InputConfig{Type: Map}, "not a map"},
+		{types.InputConfig{Type: Enum, Values: []interface{}{"dev", "prod"}}, "qa"},
+	}
+	for _, test := range invalid {
+		if _, err := Coerce(test.input, test.value); err == nil {
+			t.Errorf("Expected error coercing %v to %s", test.value, test.input.Type)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	declared := map[string]types.InputConfig{
+		"version":  {Required: true, Description: "release to deploy"},
+		"replicas": {Type: Int, Default: 2},
+		"regions":  {Type: List},
+		"debug":    {Type: Bool},
+	}
+
+	values, errs := Resolve(declared, map[string]interface{}{"version": "1.2.0", "extra": "ignored"})
+	if len(errs) > 0 {
+		t.Fatalf("Expected no errors, got: %v", errs)
+	}
+	want := map[string]interface{}{"version": "1.2.0", "replicas": 2, "regions": []interface{}{}, "debug": false}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("Expected %v, got %v", want, values)
+	}
+
+	_, errs = Resolve(declared, map[string]interface{}{"replicas": "lots", "debug": "perhaps"})
+	if len(errs) != 3 {
+		t.Fatalf("Expected 3 errors, got: %v", errs)
+	}
+	for i, want := range []string{"inputs.debug", "inputs.replicas", "inputs.version': missing required input (release to deploy)"} {
+		if !strings.Contains(errs[i].Error(), want) {
+			t.Errorf("Expected error %d to contain %q, got: %v", i, want, errs[i])
+		}
+	}
+
+	if unknown := Unknown(declared, map[string]interface{}{"version": "1", "verison": "1"}); len(unknown) != 1 || unknown[0] != "verison" {
+		t.Errorf("Expected verison to be unknown, got: %v", unknown)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	workflow := &types.Workflow{
+		Inputs: map[string]types.InputConfig{
+			"ok":        {Type: Enum, Values: []interface{}{"a", "b"}, Default: "a"},
+			"bad_type":  {Type: "number"},
+			"no_values": {Type: Enum},
+			"bad_def":   {Type: Int, Default: "ten"},
+			"both":      {Required: true, Default: "x"},
+			"shadowed":  {},
+		},
+		Variables: map[string]interface{}{"shadowed": "value"},
+	}
+
+	errs := Check(workflow)
+	if len(errs) != 5 {
+		t.Fatalf("Expected 5 errors, got: %v", errs)
+	}
+	joined := ""
+	for _, err := range errs {
+		joined += err.Error() + "\n"
+	}
+	for _, want := range []string{"unknown type 'number'", "must list their allowed values", "invalid default", "cannot have a default", "also defined in vars"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("Expected %q in errors, got:\n%s", want, joined)
+		}
+	}
+}
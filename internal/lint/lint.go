@@ -0,0 +1,275 @@
+// ABOUTME: Workflow linter running registered rules over parsed workflow files
+// ABOUTME: Locates findings in the YAML source and honours ritual-lint disable comments
+
+package lint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sarlalian/ritual/internal/tasks"
+	"github.com/sarlalian/ritual/internal/workflow/parser"
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+// Severity is how serious a finding is
+type Severity string
+
+// Severities, most serious first
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+// rank orders severities so thresholds can be compared
+func (s Severity) rank() int {
+	switch s {
+	case SeverityError:
+		return 3
+	case SeverityWarning:
+		return 2
+	case SeverityInfo:
+		return 1
+	}
+	return 0
+}
+
+// AtLeast reports whether s is as serious as threshold
+func (s Severity) AtLeast(threshold Severity) bool {
+	return s.rank() >= threshold.rank()
+}
+
+// ParseSeverity parses a severity name
+func ParseSeverity(name string) (Severity, error) {
+	switch s := Severity(strings.ToLower(name)); s {
+	case SeverityError, SeverityWarning, SeverityInfo:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown severity %q (expected error, warning or info)", name)
+}
+
+// Finding is one problem found in a workflow file
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	File     string   `json:"file"`
+	Path     string   `json:"path,omitempty"` // such as tasks[2].command
+	Line     int      `json:"line,omitempty"`
+	Column   int      `json:"column,omitempty"`
+}
+
+// Rule is a check run over every linted workflow. Check returns findings
+// with a Message and a Path; the linter fills in the rest.
+type Rule struct {
+	ID          string
+	Description string
+	Severity    Severity
+	Check       func(w *Workflow) []Finding
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Rule)
+)
+
+func init() {
+	for _, rule := range builtinRules {
+		Register(rule)
+	}
+}
+
+// Register adds a rule, replacing any rule already registered with its ID
+func Register(rule Rule) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[rule.ID] = rule
+}
+
+// Rules returns the registered rules sorted by ID
+func Rules() []Rule {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	rules := make([]Rule, 0, len(registry))
+	for _, rule := range registry {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return rules
+}
+
+// Options control a lint run
+type Options struct {
+	// Disabled rule IDs, in addition to those disabled by comments
+	Disabled []string
+
+	// Environment, when set, adds the variables of its overlay file to those
+	// templates may use, as --environment does for run
+	Environment string
+}
+
+// Workflow is what rules check: the parsed workflow and its source
+type Workflow struct {
+	*types.Workflow
+	File     string
+	Dir      string
+	Registry *tasks.Registry
+
+	// Defined holds the names available as .vars when the workflow runs.
+	// Unreadable lists variable files whose names could not be read, so
+	// Defined may be incomplete.
+	Defined    map[string]bool
+	Unreadable []string
+}
+
+// TaskLists returns the workflow's task lists by section name
+func (w *Workflow) TaskLists() map[string][]types.TaskConfig {
+	return map[string][]types.TaskConfig{
+		"tasks":      w.Tasks,
+		"on_success": w.OnSuccess,
+		"on_failure": w.OnFailure,
+	}
+}
+
+// CheckFile lints one workflow file with every registered rule that is not
+// disabled. A file holding several workflows has each of them linted, unless
+// path names one, as in pipelines.yaml#deploy. Findings are sorted by line.
+func CheckFile(path string, registry *tasks.Registry, opts Options) ([]Finding, error) {
+	path, name := parser.ResolveWorkflowRef(nil, path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{name}
+	if name == "" {
+		if all, err := parser.WorkflowNames(data); err == nil && len(all) > 1 {
+			names = all
+		}
+	}
+
+	var findings []Finding
+	for _, name := range names {
+		workflowFindings, err := checkWorkflow(path, name, data, registry, opts)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, workflowFindings...)
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Line != findings[j].Line {
+			return findings[i].Line < findings[j].Line
+		}
+		return findings[i].Rule < findings[j].Rule
+	})
+	return findings, nil
+}
+
+// checkWorkflow lints the workflow called name in a file, or its only
+// workflow when name is ""
+func checkWorkflow(path, name string, data []byte, registry *tasks.Registry, opts Options) ([]Finding, error) {
+	ref := path
+	if name != "" {
+		ref += "#" + name
+	}
+	workflow, err := parser.New(nil).ParseFile(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Workflow{Workflow: workflow, File: path, Dir: filepath.Dir(path), Registry: registry}
+	w.defineVariables(opts.Environment)
+
+	disabled := disableComments(data, workflow.Source)
+	for _, id := range opts.Disabled {
+		disabled[""] = append(disabled[""], id)
+	}
+
+	var findings []Finding
+	for _, rule := range Rules() {
+		for _, finding := range rule.Check(w) {
+			finding.Rule = rule.ID
+			if finding.Severity == "" {
+				finding.Severity = rule.Severity
+			}
+			finding.File = path
+			if isDisabled(disabled, finding.Path, rule.ID) {
+				continue
+			}
+			pos := workflow.Source.Lookup(finding.Path)
+			finding.Line, finding.Column = pos.Line, pos.Column
+			findings = append(findings, finding)
+		}
+	}
+	return findings, nil
+}
+
+var disablePattern = regexp.MustCompile(`(^|\s)#\s*ritual-lint\s+(disable|disable-file)=([\w-]+(?:\s*,\s*[\w-]+)*)`)
+
+// disableComments reads "# ritual-lint disable=rule,..." comments. A comment
+// at the end of a line disables the rules for the key or item on that line
+// and everything under it; a comment on a line of its own does the same for
+// the next line. "disable-file" disables the rules for the whole file. The
+// result maps paths, or "" for the file, to disabled rule IDs.
+func disableComments(data []byte, source types.SourceMap) map[string][]string {
+	lines := strings.Split(string(data), "\n")
+	pathsByLine := make(map[int][]string)
+	for path, pos := range source {
+		pathsByLine[pos.Line] = append(pathsByLine[pos.Line], path)
+	}
+
+	disabled := make(map[string][]string)
+	for i, line := range lines {
+		match := disablePattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		var ids []string
+		for _, id := range strings.Split(match[3], ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+		if match[2] == "disable-file" {
+			disabled[""] = append(disabled[""], ids...)
+			continue
+		}
+
+		target := i + 1
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			// The next line that is not blank or a comment
+			for target = i + 2; target <= len(lines); target++ {
+				if next := strings.TrimSpace(lines[target-1]); next != "" && !strings.HasPrefix(next, "#") {
+					break
+				}
+			}
+		}
+		for _, path := range pathsByLine[target] {
+			disabled[path] = append(disabled[path], ids...)
+		}
+	}
+	return disabled
+}
+
+// isDisabled reports whether rule is disabled for path, the file or an
+// ancestor of path
+func isDisabled(disabled map[string][]string, path, rule string) bool {
+	for {
+		for _, id := range disabled[path] {
+			if id == rule || id == "all" {
+				return true
+			}
+		}
+		if path == "" {
+			return false
+		}
+		path = types.ParentPath(path)
+	}
+}
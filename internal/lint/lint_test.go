@@ -0,0 +1,291 @@
+// ABOUTME: Tests for the workflow linter
+// ABOUTME: Verifies each built-in rule, finding positions, disable comments and output formats
+
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sarlalian/ritual/internal/tasks"
+)
+
+const lintWorkflow = `name: Lint Test
+vars:
+  region: eu-west-1
+  unused_name: nobody
+  legacy: old  # ritual-lint disable=unused-variable
+tasks:
+  - name: Build
+    command: echo "building in {{ .vars.region }}
+    timout: 10s
+  - name: Deploy
+    command: echo {{ .vars.regoin }}
+    when: vars.region == 'eu' and defined(vars.zone)
+  - name: Never
+    command: echo never
+    when: "false"
+  - name: After Never
+    command: echo after
+    depends_on: [Never]
+  - name: Optional
+    command: echo optional
+    required: false
+  - name: After Optional
+    command: echo done
+    depends_on: [Optional]
+  # ritual-lint disable=shell-quoting
+  - name: Quoted
+    command: echo 'unterminated
+`
+
+func writeWorkflow(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "workflow.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write workflow: %v", err)
+	}
+	return path
+}
+
+func findingsByRule(findings []Finding) map[string][]Finding {
+	byRule := make(map[string][]Finding)
+	for _, f := range findings {
+		byRule[f.Rule] = append(byRule[f.Rule], f)
+	}
+	return byRule
+}
+
+func TestCheckFile(t *testing.T) {
+	path := writeWorkflow(t, lintWorkflow)
+	findings, err := CheckFile(path, tasks.New(), Options{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	byRule := findingsByRule(findings)
+
+	tests := []struct {
+		rule     string
+		path     string
+		line     int
+		severity Severity
+		contains string
+	}{
+		{"unknown-config-key", "tasks[0].timout", 9, SeverityError, "unknown config key 'timout'"},
+		{"shell-quoting", "tasks[0].command", 8, SeverityWarning, "an unterminated double quote"},
+		{"undefined-variable", "tasks[1].command", 11, SeverityError, "variable 'regoin' is not defined; did you mean 'region'?"},
+		{"unused-variable", "vars.unused_name", 4, SeverityWarning, "'unused_name' is never used"},
+		{"optional-dependency", "tasks[5].depends_on[0]", 24, SeverityWarning, "depends on optional task 'Optional'"},
+	}
+	for _, tt := range tests {
+		got := byRule[tt.rule]
+		if len(got) != 1 {
+			t.Errorf("Expected 1 %s finding, got %d: %v", tt.rule, len(got), got)
+			continue
+		}
+		f := got[0]
+		if f.Path != tt.path || f.Line != tt.line || f.Severity != tt.severity || f.File != path {
+			t.Errorf("Expected %s at %s line %d with severity %s, got %+v", tt.rule, tt.path, tt.line, tt.severity, f)
+		}
+		if !strings.Contains(f.Message, tt.contains) {
+			t.Errorf("Expected %s message to contain %q, got %q", tt.rule, tt.contains, f.Message)
+		}
+	}
+
+	// The always-false condition and the task that depends on it
+	constant := byRule["constant-condition"]
+	if len(constant) != 2 {
+		t.Fatalf("Expected 2 constant-condition findings, got %v", constant)
+	}
+	if constant[0].Path != "tasks[2].when" || !strings.Contains(constant[0].Message, "always false") {
+		t.Errorf("Unexpected first constant-condition finding: %+v", constant[0])
+	}
+	if constant[1].Path != "tasks[3].depends_on" || !strings.Contains(constant[1].Message, "never runs because it depends on 'Never'") {
+		t.Errorf("Unexpected second constant-condition finding: %+v", constant[1])
+	}
+
+	for i := 1; i < len(findings); i++ {
+		if findings[i].Line < findings[i-1].Line {
+			t.Errorf("Expected findings sorted by line, got %d after %d", findings[i].Line, findings[i-1].Line)
+		}
+	}
+}
+
+func TestCheckFileDisabled(t *testing.T) {
+	path := writeWorkflow(t, "# ritual-lint disable-file=unknown-config-key\n"+lintWorkflow)
+	findings, err := CheckFile(path, tasks.New(), Options{Disabled: []string{"optional-dependency"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	byRule := findingsByRule(findings)
+	for _, rule := range []string{"unknown-config-key", "optional-dependency"} {
+		if len(byRule[rule]) != 0 {
+			t.Errorf("Expected %s to be disabled, got %v", rule, byRule[rule])
+		}
+	}
+	if len(byRule["undefined-variable"]) != 1 {
+		t.Errorf("Expected undefined-variable to still run, got %v", byRule["undefined-variable"])
+	}
+}
+
+func TestCheckFileVariableFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "common.yaml"), []byte("zone: a\n"), 0644); err != nil {
+		t.Fatalf("Failed to write variable file: %v", err)
+	}
+	path := filepath.Join(dir, "workflow.yaml")
+	content := `name: Vars
+variable_files:
+  - common.yaml
+tasks:
+  - name: Use
+    command: echo {{ .vars.zone }}
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write workflow: %v", err)
+	}
+
+	findings, err := CheckFile(path, tasks.New(), Options{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(findings) != 0 {
+		t.Errorf("Expected no findings, got %v", findings)
+	}
+
+	// An environment without an overlay file leaves the names unknown
+	findings, err = CheckFile(path, tasks.New(), Options{Environment: "missing"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(findings) != 1 || findings[0].Severity != SeverityInfo {
+		t.Errorf("Expected one info finding for the unreadable overlay, got %v", findings)
+	}
+}
+
+func TestCheckFileMultipleWorkflows(t *testing.T) {
+	path := writeWorkflow(t, `name: build
+tasks:
+  - name: Compile
+    command: echo {{ .vars.target }}
+---
+name: deploy
+vars:
+  region: eu-west-1
+tasks:
+  - name: Ship
+    command: echo shipping
+`)
+
+	findings, err := CheckFile(path, tasks.New(), Options{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(findings) != 2 {
+		t.Fatalf("Expected a finding from each workflow, got %v", findings)
+	}
+	if findings[0].Rule != "undefined-variable" || findings[0].Line != 4 || findings[0].File != path {
+		t.Errorf("Expected undefined-variable at line 4 of %s, got %+v", path, findings[0])
+	}
+	if findings[1].Rule != "unused-variable" || findings[1].Line != 8 {
+		t.Errorf("Expected unused-variable at line 8, got %+v", findings[1])
+	}
+
+	// Naming a workflow lints only that one
+	findings, err = CheckFile(path+"#deploy", tasks.New(), Options{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(findings) != 1 || findings[0].Rule != "unused-variable" {
+		t.Errorf("Expected only the deploy finding, got %v", findings)
+	}
+}
+
+func TestCheckFileParseError(t *testing.T) {
+	path := writeWorkflow(t, "name: Broken\ntasks:\n  - name: No Type\n")
+	if _, err := CheckFile(path, tasks.New(), Options{}); err == nil {
+		t.Error("Expected parse error for invalid workflow")
+	}
+}
+
+func TestRules(t *testing.T) {
+	rules := Rules()
+	if len(rules) != len(builtinRules) {
+		t.Errorf("Expected %d rules, got %d", len(builtinRules), len(rules))
+	}
+	for i := 1; i < len(rules); i++ {
+		if rules[i].ID < rules[i-1].ID {
+			t.Errorf("Expected rules sorted by ID, got %s after %s", rules[i].ID, rules[i-1].ID)
+		}
+	}
+	for _, rule := range rules {
+		if rule.Description == "" || rule.Severity == "" || rule.Check == nil {
+			t.Errorf("Rule %s is incomplete", rule.ID)
+		}
+	}
+}
+
+func TestSeverity(t *testing.T) {
+	if !SeverityError.AtLeast(SeverityWarning) || SeverityInfo.AtLeast(SeverityWarning) || !SeverityWarning.AtLeast(SeverityWarning) {
+		t.Error("Expected severities ordered error > warning > info")
+	}
+	if s, err := ParseSeverity("Warning"); err != nil || s != SeverityWarning {
+		t.Errorf("Expected warning, got %q, %v", s, err)
+	}
+	if _, err := ParseSeverity("fatal"); err == nil {
+		t.Error("Expected error for unknown severity")
+	}
+}
+
+func TestWriteFormats(t *testing.T) {
+	findings := []Finding{
+		{Rule: "unused-variable", Severity: SeverityWarning, Message: "variable 'x' is never used", File: "wf.yaml", Path: "vars.x", Line: 3, Column: 3},
+		{Rule: "undefined-variable", Severity: SeverityInfo, Message: "not checked", File: "wf.yaml"},
+	}
+
+	var text bytes.Buffer
+	if err := WriteText(&text, findings); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := "wf.yaml:3:3: warning [unused-variable] variable 'x' is never used\nwf.yaml: info [undefined-variable] not checked\n"
+	if text.String() != expected {
+		t.Errorf("Expected text %q, got %q", expected, text.String())
+	}
+
+	var empty bytes.Buffer
+	if err := WriteJSON(&empty, nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if strings.TrimSpace(empty.String()) != "[]" {
+		t.Errorf("Expected empty JSON array, got %q", empty.String())
+	}
+
+	var sarif bytes.Buffer
+	if err := WriteSARIF(&sarif, findings); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(sarif.Bytes(), &log); err != nil {
+		t.Fatalf("Expected valid SARIF JSON, got %v", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("Unexpected SARIF log: %+v", log)
+	}
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != len(Rules()) {
+		t.Errorf("Expected %d SARIF rules, got %d", len(Rules()), len(run.Tool.Driver.Rules))
+	}
+	if len(run.Results) != 2 {
+		t.Fatalf("Expected 2 SARIF results, got %d", len(run.Results))
+	}
+	if region := run.Results[0].Locations[0].PhysicalLocation.Region; region == nil || region.StartLine != 3 {
+		t.Errorf("Expected region at line 3, got %+v", region)
+	}
+	if run.Results[1].Level != "note" || run.Results[1].Locations[0].PhysicalLocation.Region != nil {
+		t.Errorf("Expected info finding as a note without a region, got %+v", run.Results[1])
+	}
+}
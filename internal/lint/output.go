@@ -0,0 +1,137 @@
+// ABOUTME: Text, JSON and SARIF rendering of lint findings
+// ABOUTME: SARIF output lets code review bots annotate the lines findings point at
+
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// WriteText renders findings one per line as file:line:col: severity [rule] message
+func WriteText(w io.Writer, findings []Finding) error {
+	for _, f := range findings {
+		location := f.File
+		if f.Line > 0 {
+			location = fmt.Sprintf("%s:%d:%d", f.File, f.Line, f.Column)
+		}
+		if _, err := fmt.Fprintf(w, "%s: %s [%s] %s\n", location, f.Severity, f.Rule, f.Message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON renders findings as an indented JSON array
+func WriteJSON(w io.Writer, findings []Finding) error {
+	if findings == nil {
+		findings = []Finding{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(findings)
+}
+
+// SARIF 2.1.0 documents, with only the properties findings need
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string       `json:"id"`
+	ShortDescription     sarifMessage `json:"shortDescription"`
+	DefaultConfiguration struct {
+		Level string `json:"level"`
+	} `json:"defaultConfiguration"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+		Region *sarifRegion `json:"region,omitempty"`
+	} `json:"physicalLocation"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// sarifLevel maps a severity to a SARIF result level
+func sarifLevel(s Severity) string {
+	if s == SeverityInfo {
+		return "note"
+	}
+	return string(s)
+}
+
+// WriteSARIF renders findings as a SARIF 2.1.0 log describing every
+// registered rule
+func WriteSARIF(w io.Writer, findings []Finding) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "ritual",
+			InformationURI: "https://github.com/sarlalian/ritual",
+		}},
+		Results: []sarifResult{},
+	}
+	for _, rule := range Rules() {
+		r := sarifRule{ID: rule.ID, ShortDescription: sarifMessage{Text: rule.Description}}
+		r.DefaultConfiguration.Level = sarifLevel(rule.Severity)
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, r)
+	}
+
+	for _, f := range findings {
+		var location sarifLocation
+		location.PhysicalLocation.ArtifactLocation.URI = filepath.ToSlash(f.File)
+		if f.Line > 0 {
+			location.PhysicalLocation.Region = &sarifRegion{StartLine: f.Line, StartColumn: f.Column}
+		}
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    f.Rule,
+			Level:     sarifLevel(f.Severity),
+			Message:   sarifMessage{Text: f.Message},
+			Locations: []sarifLocation{location},
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
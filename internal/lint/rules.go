@@ -0,0 +1,465 @@
+// ABOUTME: Built-in lint rules for config keys, variables, conditions, dependencies and shell quoting
+// ABOUTME: Each rule reports findings by path so they can be located and disabled in the YAML source
+
+package lint
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sarlalian/ritual/internal/expression"
+	"github.com/sarlalian/ritual/internal/template"
+	"github.com/sarlalian/ritual/internal/variables"
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+var builtinRules = []Rule{
+	{
+		ID:          "unknown-config-key",
+		Description: "A task sets a config key its task type does not read",
+		Severity:    SeverityError,
+		Check:       checkConfigKeys,
+	},
+	{
+		ID:          "undefined-variable",
+		Description: "A template reads a variable that no input, vars entry or variable file defines",
+		Severity:    SeverityError,
+		Check:       checkUndefinedVariables,
+	},
+	{
+		ID:          "unused-variable",
+		Description: "A vars entry is never read by a template or condition",
+		Severity:    SeverityWarning,
+		Check:       checkUnusedVariables,
+	},
+	{
+		ID:          "constant-condition",
+		Description: "A task's when condition is always false, so the task never runs, or always true",
+		Severity:    SeverityWarning,
+		Check:       checkConstantConditions,
+	},
+	{
+		ID:          "optional-dependency",
+		Description: "A task depends on a non-required task without a trigger_rule, so it is skipped when that task fails",
+		Severity:    SeverityWarning,
+		Check:       checkOptionalDependencies,
+	},
+	{
+		ID:          "shell-quoting",
+		Description: "A command or script has an unterminated quote",
+		Severity:    SeverityWarning,
+		Check:       checkShellQuoting,
+	},
+}
+
+// taskSections are the workflow's task lists, in file order
+var taskSections = []string{"tasks", "on_success", "on_failure"}
+
+// forEachTask calls fn with the path and config of every task
+func (w *Workflow) forEachTask(fn func(path string, task *types.TaskConfig)) {
+	lists := w.TaskLists()
+	for _, section := range taskSections {
+		list := lists[section]
+		for i := range list {
+			fn(fmt.Sprintf("%s[%d]", section, i), &list[i])
+		}
+	}
+}
+
+// taskType is a task's type, inferring command for handlers the parser
+// validated without setting one
+func taskType(task *types.TaskConfig) string {
+	if task.Type == "" {
+		if _, ok := task.Config["command"]; ok {
+			return "command"
+		}
+	}
+	return task.Type
+}
+
+// forEachString calls fn with the path of every string in value
+func forEachString(path string, value interface{}, fn func(path, s string)) {
+	switch v := value.(type) {
+	case string:
+		fn(path, v)
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			forEachString(path+"."+key, v[key], fn)
+		}
+	case []interface{}:
+		for i, item := range v {
+			forEachString(fmt.Sprintf("%s[%d]", path, i), item, fn)
+		}
+	}
+}
+
+// forEachTemplate calls fn with every template in the workflow, other than
+// task conditions
+func (w *Workflow) forEachTemplate(fn func(path, tmpl string)) {
+	templates := func(path, s string) {
+		if strings.Contains(s, "{{") {
+			fn(path, s)
+		}
+	}
+	for i, file := range w.VariableFiles {
+		templates(fmt.Sprintf("variable_files[%d]", i), file)
+	}
+	for _, name := range sortedKeys(w.Environment) {
+		templates("environment."+name, w.Environment[name])
+	}
+	for _, name := range sortedKeys(w.Variables) {
+		forEachString("vars."+name, w.Variables[name], templates)
+	}
+	w.forEachTask(func(path string, task *types.TaskConfig) {
+		for _, key := range sortedKeys(task.Config) {
+			forEachString(path+"."+key, task.Config[key], templates)
+		}
+	})
+	for _, name := range sortedKeys(w.Outputs) {
+		forEachString("outputs."+name, w.Outputs[name], templates)
+	}
+}
+
+// defineVariables collects the names templates may read from .vars
+func (w *Workflow) defineVariables(environment string) {
+	w.Defined = make(map[string]bool)
+	for name := range w.Inputs {
+		w.Defined[name] = true
+	}
+	for name := range w.Variables {
+		w.Defined[name] = true
+	}
+	// Imported workflows' vars are nested under the import's name
+	for _, path := range w.Imports {
+		w.Defined[strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))] = true
+	}
+
+	loader := variables.New(w.Dir)
+	files := append([]string{}, w.VariableFiles...)
+	if environment != "" {
+		overlay, err := loader.EnvironmentOverlay(environment)
+		if err != nil {
+			w.Unreadable = append(w.Unreadable, environment)
+		} else {
+			files = append(files, overlay)
+		}
+	}
+	for _, file := range files {
+		if strings.Contains(file, "{{") || variables.IsRemote(file) {
+			w.Unreadable = append(w.Unreadable, file)
+			continue
+		}
+		values, err := loader.LoadVariableFile(file)
+		if err != nil {
+			w.Unreadable = append(w.Unreadable, file)
+			continue
+		}
+		for name := range values {
+			w.Defined[name] = true
+		}
+	}
+}
+
+func checkConfigKeys(w *Workflow) []Finding {
+	var findings []Finding
+	w.forEachTask(func(path string, task *types.TaskConfig) {
+		typ := taskType(task)
+		schema, ok := w.Registry.ConfigSchema(typ)
+		if !ok {
+			return
+		}
+		known := []string{"description"}
+		for _, field := range schema.Fields {
+			known = append(known, field.Name)
+		}
+		for _, key := range sortedKeys(task.Config) {
+			if contains(known, key) {
+				continue
+			}
+			message := fmt.Sprintf("%s task '%s' has unknown config key '%s'", typ, task.Name, key)
+			if suggestion := template.Suggest(key, known); suggestion != "" {
+				message += "; " + suggestion
+			}
+			findings = append(findings, Finding{Path: path + "." + key, Message: message})
+		}
+	})
+	return findings
+}
+
+func checkUndefinedVariables(w *Workflow) []Finding {
+	// Names from unreadable variable files are unknown, so say so once
+	// instead of reporting every use of them
+	if len(w.Unreadable) > 0 {
+		var findings []Finding
+		for _, file := range w.Unreadable {
+			path := "variable_files"
+			for i, declared := range w.VariableFiles {
+				if declared == file {
+					path = fmt.Sprintf("variable_files[%d]", i)
+				}
+			}
+			findings = append(findings, Finding{
+				Path:     path,
+				Severity: SeverityInfo,
+				Message:  fmt.Sprintf("variables from '%s' could not be read, so undefined variables were not checked", file),
+			})
+		}
+		return findings
+	}
+
+	var findings []Finding
+	report := func(path string, names []string) {
+		seen := make(map[string]bool)
+		for _, name := range names {
+			if w.Defined[name] || seen[name] {
+				continue
+			}
+			seen[name] = true
+			message := fmt.Sprintf("variable '%s' is not defined", name)
+			if suggestion := template.Suggest(name, sortedKeys(w.Defined)); suggestion != "" {
+				message += "; " + suggestion
+			}
+			findings = append(findings, Finding{Path: path, Message: message})
+		}
+	}
+
+	w.forEachTemplate(func(path, tmpl string) {
+		if refs, err := template.ReferencedVariables(tmpl); err == nil {
+			report(path, refs.Names)
+		}
+	})
+	w.forEachTask(func(path string, task *types.TaskConfig) {
+		report(path+".when", conditionVariables(task.When).Names)
+	})
+	return findings
+}
+
+func checkUnusedVariables(w *Workflow) []Finding {
+	// Tasks of imported workflows may read the importing workflow's vars
+	if len(w.Imports) > 0 {
+		return nil
+	}
+
+	used := make(map[string]bool)
+	all := false
+	use := func(refs *template.VariableReferences) {
+		all = all || refs.All
+		for _, name := range append(refs.Names, refs.Lookups...) {
+			used[name] = true
+		}
+	}
+	w.forEachTemplate(func(path, tmpl string) {
+		if refs, err := template.ReferencedVariables(tmpl); err == nil {
+			use(refs)
+		}
+	})
+	w.forEachTask(func(path string, task *types.TaskConfig) {
+		use(conditionVariables(task.When))
+	})
+	if all {
+		return nil
+	}
+
+	var findings []Finding
+	for _, name := range sortedKeys(w.Variables) {
+		if used[name] {
+			continue
+		}
+		findings = append(findings, Finding{
+			Path:    "vars." + name,
+			Message: fmt.Sprintf("variable '%s' is never used", name),
+		})
+	}
+	return findings
+}
+
+// conditionVariables returns the variables a when condition reads. Conditions
+// that fail to parse are reported by validate, so they read nothing here.
+func conditionVariables(when string) *template.VariableReferences {
+	if when == "" {
+		return &template.VariableReferences{}
+	}
+	if expression.IsTemplate(when) {
+		if refs, err := template.ReferencedVariables(when); err == nil {
+			return refs
+		}
+		return &template.VariableReferences{}
+	}
+	expr, err := expression.Parse(when)
+	if err != nil {
+		return &template.VariableReferences{}
+	}
+	// Variables only tested with defined() may be missing
+	return &template.VariableReferences{Names: expr.RequiredReferences("vars"), Lookups: expr.References("vars")}
+}
+
+func checkConstantConditions(w *Workflow) []Finding {
+	var findings []Finding
+
+	// Tasks that never run, and the tasks that depend on them with the
+	// default trigger rule, which never run either
+	never := make(map[string]bool)
+	paths := make(map[string]string)
+	for i := range w.Tasks {
+		task := &w.Tasks[i]
+		path := fmt.Sprintf("tasks[%d]", i)
+		paths[task.ID] = path
+		value, constant := constantCondition(task.When)
+		if !constant {
+			continue
+		}
+		if value {
+			findings = append(findings, Finding{
+				Path:     path + ".when",
+				Severity: SeverityInfo,
+				Message:  fmt.Sprintf("condition of task '%s' is always true", task.Name),
+			})
+			continue
+		}
+		never[task.ID] = true
+		findings = append(findings, Finding{
+			Path:    path + ".when",
+			Message: fmt.Sprintf("condition of task '%s' is always false, so it never runs", task.Name),
+		})
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for i := range w.Tasks {
+			task := &w.Tasks[i]
+			if never[task.ID] || (task.TriggerRule != "" && task.TriggerRule != types.TriggerAllSuccess) {
+				continue
+			}
+			for _, dep := range task.DependsOn {
+				depTask := w.findTask(dep)
+				if depTask == nil || !never[depTask.ID] {
+					continue
+				}
+				never[task.ID] = true
+				changed = true
+				findings = append(findings, Finding{
+					Path:    paths[task.ID] + ".depends_on",
+					Message: fmt.Sprintf("task '%s' never runs because it depends on '%s', which never runs", task.Name, depTask.Name),
+				})
+				break
+			}
+		}
+	}
+	return findings
+}
+
+// constantCondition evaluates a when condition that reads nothing from the
+// context. constant is false when the condition depends on the run.
+func constantCondition(when string) (value, constant bool) {
+	if when == "" {
+		return false, false
+	}
+	if expression.IsLiteral(when) {
+		return truthyText(when), true
+	}
+	if expression.IsTemplate(when) {
+		rendered, ok := template.RenderConstant(when)
+		if !ok {
+			return false, false
+		}
+		return truthyText(rendered), true
+	}
+	expr, err := expression.Parse(when)
+	if err != nil || !expr.IsConstant() {
+		return false, false
+	}
+	value, err = expr.EvaluateBool(expression.Data(nil))
+	if err != nil {
+		return false, false
+	}
+	return value, true
+}
+
+// truthyText reports whether the executor treats a rendered or literal
+// condition as true
+func truthyText(text string) bool {
+	switch strings.TrimSpace(text) {
+	case "", "false", "0", "no", "off":
+		return false
+	}
+	return true
+}
+
+func checkOptionalDependencies(w *Workflow) []Finding {
+	var findings []Finding
+	for i := range w.Tasks {
+		task := &w.Tasks[i]
+		if task.TriggerRule != "" {
+			continue
+		}
+		for j, dep := range task.DependsOn {
+			depTask := w.findTask(dep)
+			if depTask == nil || depTask.IsRequired() {
+				continue
+			}
+			findings = append(findings, Finding{
+				Path: fmt.Sprintf("tasks[%d].depends_on[%d]", i, j),
+				Message: fmt.Sprintf("task '%s' depends on optional task '%s' and is skipped if it fails; set trigger_rule to %s or %s to run anyway",
+					task.Name, depTask.Name, types.TriggerNoneFailed, types.TriggerAllDone),
+			})
+		}
+	}
+	return findings
+}
+
+func checkShellQuoting(w *Workflow) []Finding {
+	var findings []Finding
+	w.forEachTask(func(path string, task *types.TaskConfig) {
+		var keys []string
+		switch taskType(task) {
+		case "command", "shell", "script":
+			keys = []string{"command", "script"}
+			// With args, command is a program run without a shell
+			if _, ok := task.Config["args"]; ok {
+				keys = []string{"script"}
+			}
+		case "ssh", "remote":
+			keys = []string{"command"}
+		}
+		for _, key := range keys {
+			script, ok := task.Config[key].(string)
+			if !ok {
+				continue
+			}
+			if problem := shellQuotingProblem(script); problem != "" {
+				findings = append(findings, Finding{Path: path + "." + key, Message: fmt.Sprintf("%s of task '%s' has %s", key, task.Name, problem)})
+			}
+		}
+	})
+	return findings
+}
+
+// findTask finds a task of the main list by ID or name
+func (w *Workflow) findTask(ref string) *types.TaskConfig {
+	for i := range w.Tasks {
+		if w.Tasks[i].ID == ref || w.Tasks[i].Name == ref {
+			return &w.Tasks[i]
+		}
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
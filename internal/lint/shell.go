@@ -0,0 +1,89 @@
+// ABOUTME: Shell quoting check for command and ssh task scripts
+// ABOUTME: Finds quotes and backticks left open, skipping templates, comments and heredocs
+
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// templateAction matches a template action, whose quotes belong to the
+// template rather than the shell
+var templateAction = regexp.MustCompile(`(?s)\{\{.*?\}\}`)
+
+// heredocStart matches the start of a heredoc such as <<EOF, <<-'EOF' or << "EOF"
+var heredocStart = regexp.MustCompile(`<<(-?)\s*(['"]?)([A-Za-z_][A-Za-z0-9_]*)(['"]?)`)
+
+var quoteNames = map[byte]string{
+	'\'': "single quote",
+	'"':  "double quote",
+	'`':  "backtick",
+}
+
+// shellQuotingProblem describes the first quote left open in a script, or
+// returns "" when every quote is closed
+func shellQuotingProblem(script string) string {
+	lines := strings.Split(templateAction.ReplaceAllString(script, "x"), "\n")
+
+	var quote byte
+	quoteLine := 0
+	var heredocs []heredoc
+	for n := 0; n < len(lines); n++ {
+		line := lines[n]
+
+		// Heredoc bodies are data, not shell
+		if quote == 0 && len(heredocs) > 0 {
+			doc := heredocs[0]
+			body := line
+			if doc.indented {
+				body = strings.TrimLeft(body, "\t")
+			}
+			if body == doc.marker {
+				heredocs = heredocs[1:]
+			}
+			continue
+		}
+
+		for i := 0; i < len(line); i++ {
+			c := line[i]
+			switch {
+			case quote == '\'':
+				if c == '\'' {
+					quote = 0
+				}
+			case c == '\\':
+				i++
+			case quote != 0:
+				if c == quote {
+					quote = 0
+				}
+			case c == '\'' || c == '"' || c == '`':
+				quote, quoteLine = c, n+1
+			case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+				// A comment runs to the end of the line
+				i = len(line)
+			case c == '<' && strings.HasPrefix(line[i:], "<<") && !strings.HasPrefix(line[i:], "<<<"):
+				if match := heredocStart.FindStringSubmatch(line[i:]); match != nil && match[2] == match[4] {
+					heredocs = append(heredocs, heredoc{marker: match[3], indented: match[1] == "-"})
+					i += len(match[0]) - 1
+				}
+			}
+		}
+	}
+
+	if quote == 0 {
+		return ""
+	}
+	if len(lines) == 1 {
+		return fmt.Sprintf("an unterminated %s", quoteNames[quote])
+	}
+	return fmt.Sprintf("an unterminated %s on line %d", quoteNames[quote], quoteLine)
+}
+
+// heredoc is a pending heredoc whose body starts on the next line
+type heredoc struct {
+	marker   string
+	indented bool
+}
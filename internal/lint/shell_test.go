@@ -0,0 +1,35 @@
+// ABOUTME: Tests for the shell quoting check
+// ABOUTME: Verifies open quotes are found while templates, comments and heredocs are skipped
+
+package lint
+
+import "testing"
+
+func TestShellQuotingProblem(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{"balanced", `echo "a" 'b' ` + "`date`", ""},
+		{"escaped quote", `echo \"hello`, ""},
+		{"apostrophe in double quotes", `echo "it's fine"`, ""},
+		{"template quotes", `echo {{ getVar "name" }}`, ""},
+		{"comment", "echo hi # don't worry", ""},
+		{"heredoc", "cat <<EOF\nit's data\nEOF\necho done", ""},
+		{"indented heredoc", "cat <<-'EOF'\n\tit's data\n\tEOF", ""},
+		{"here string", `cat <<< "text"`, ""},
+		{"open double quote", `echo "hello`, "an unterminated double quote"},
+		{"open single quote", `echo 'hello`, "an unterminated single quote"},
+		{"open backtick", "echo `date", "an unterminated backtick"},
+		{"multi-line", "echo ok\necho 'oops\necho more", "an unterminated single quote on line 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shellQuotingProblem(tt.script); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
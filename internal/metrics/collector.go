@@ -0,0 +1,147 @@
+// ABOUTME: Ritual's execution metrics, collected from the event bus
+// ABOUTME: Serves them on /metrics or writes them as a node-exporter textfile for one-shot runs
+
+package metrics
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sarlalian/ritual/internal/events"
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+// Collector records workflow and task metrics from execution events
+type Collector struct {
+	registry *Registry
+
+	workflowRuns      *CounterVec
+	workflowDuration  *HistogramVec
+	workflowLastRun   *GaugeVec
+	taskDuration      *HistogramVec
+	queueDepth        *GaugeVec
+	inProgress        *GaugeVec
+	webhookRejections *CounterVec
+
+	mu     sync.Mutex
+	queued map[string]map[string]bool // task IDs queued but not yet started, by execution
+}
+
+// NewCollector creates a collector with all ritual metrics registered
+func NewCollector() *Collector {
+	r := NewRegistry()
+	return &Collector{
+		registry: r,
+		workflowRuns: r.Counter("ritual_workflow_runs_total",
+			"Workflow executions by final status.", "workflow", "status"),
+		workflowDuration: r.Histogram("ritual_workflow_duration_seconds",
+			"Workflow execution duration in seconds.", DefaultBuckets, "workflow"),
+		workflowLastRun: r.Gauge("ritual_workflow_last_run_timestamp_seconds",
+			"Unix time the workflow last finished.", "workflow", "status"),
+		taskDuration: r.Histogram("ritual_task_duration_seconds",
+			"Task duration in seconds by task type and status.", DefaultBuckets, "type", "status"),
+		queueDepth: r.Gauge("ritual_task_queue_depth",
+			"Tasks whose dependencies are met and are waiting for a concurrency slot."),
+		inProgress: r.Gauge("ritual_executions_in_progress",
+			"Workflow executions currently running."),
+		webhookRejections: r.Counter("ritual_webhook_rejections_total",
+			"Webhook requests rejected before a workflow was started, by reason.", "reason"),
+		queued: make(map[string]map[string]bool),
+	}
+}
+
+// Registry returns the underlying registry so callers can add their own metrics
+func (c *Collector) Registry() *Registry {
+	return c.registry
+}
+
+// OnEvent updates metrics from an execution event
+func (c *Collector) OnEvent(event events.Event) {
+	switch event.Type {
+	case events.WorkflowStarted:
+		c.inProgress.Add(1)
+
+	case events.WorkflowFinished:
+		c.inProgress.Add(-1)
+		status := workflowStatus(event.Result)
+		c.workflowRuns.Inc(event.Workflow, status)
+		c.workflowDuration.Observe(event.Duration.Seconds(), event.Workflow)
+		c.workflowLastRun.Set(float64(time.Now().Unix()), event.Workflow, status)
+
+		// Tasks that were queued but never started, e.g. after a required
+		// failure, no longer count as waiting
+		c.mu.Lock()
+		delete(c.queued, event.ExecutionID)
+		c.updateQueueDepth()
+		c.mu.Unlock()
+
+	case events.TaskQueued:
+		c.setQueued(event.ExecutionID, event.TaskID, true)
+
+	case events.TaskStarted:
+		c.setQueued(event.ExecutionID, event.TaskID, false)
+
+	case events.TaskFinished, events.TaskSkipped:
+		// Tasks skipped by their trigger rule finish without being started
+		c.setQueued(event.ExecutionID, event.TaskID, false)
+		c.taskDuration.Observe(event.Duration.Seconds(), event.TaskType, string(event.Status))
+	}
+}
+
+// WebhookRejected counts a rejected webhook request
+func (c *Collector) WebhookRejected(reason string) {
+	c.webhookRejections.Inc(reason)
+}
+
+// Handler serves the metrics in the Prometheus text format
+func (c *Collector) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = c.registry.WriteText(w)
+	})
+}
+
+// WriteTextFile writes the metrics for the node exporter textfile collector
+func (c *Collector) WriteTextFile(path string) error {
+	return c.registry.WriteTextFile(path)
+}
+
+// setQueued marks a task of an execution as waiting or no longer waiting
+func (c *Collector) setQueued(executionID, taskID string, waiting bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tasks := c.queued[executionID]
+	if waiting {
+		if tasks == nil {
+			tasks = make(map[string]bool)
+			c.queued[executionID] = tasks
+		}
+		tasks[taskID] = true
+	} else {
+		delete(tasks, taskID)
+		if len(tasks) == 0 {
+			delete(c.queued, executionID)
+		}
+	}
+	c.updateQueueDepth()
+}
+
+// updateQueueDepth publishes the total queue depth; callers hold c.mu
+func (c *Collector) updateQueueDepth() {
+	total := 0
+	for _, tasks := range c.queued {
+		total += len(tasks)
+	}
+	c.queueDepth.Set(float64(total))
+}
+
+// workflowStatus reduces a run's result to a status label
+func workflowStatus(result *types.Result) string {
+	if result == nil || result.ParseError != nil || result.DependencyError != nil ||
+		len(result.ValidationErrors) > 0 || result.ExecutionError != nil || result.WorkflowResult == nil {
+		return string(types.WorkflowFailed)
+	}
+	return string(result.WorkflowResult.Status)
+}
@@ -0,0 +1,169 @@
+// ABOUTME: Tests for the metric registry text format and the event-driven collector
+// ABOUTME: Checks exposition output, histogram buckets, queue depth and textfile writes
+
+package metrics
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sarlalian/ritual/internal/events"
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+func render(t *testing.T, r *Registry) string {
+	t.Helper()
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+	return b.String()
+}
+
+func assertContains(t *testing.T, text string, lines ...string) {
+	t.Helper()
+	for _, line := range lines {
+		if !strings.Contains(text, line+"\n") {
+			t.Errorf("Expected output to contain %q, got:\n%s", line, text)
+		}
+	}
+}
+
+func TestRegistry_Counter(t *testing.T) {
+	r := NewRegistry()
+	c := r.Counter("jobs_total", "Jobs run.", "status")
+
+	c.Inc("ok")
+	c.Add(2, "ok")
+	c.Inc(`we"ird`)
+	c.Add(-5, "ok") // counters never decrease
+
+	assertContains(t, render(t, r),
+		"# HELP jobs_total Jobs run.",
+		"# TYPE jobs_total counter",
+		`jobs_total{status="ok"} 3`,
+		`jobs_total{status="we\"ird"} 1`,
+	)
+}
+
+func TestRegistry_GaugeWithoutLabels(t *testing.T) {
+	r := NewRegistry()
+	g := r.Gauge("depth", "Queue depth.")
+
+	g.Set(4)
+	g.Add(-1.5)
+
+	assertContains(t, render(t, r), "# TYPE depth gauge", "depth 2.5")
+}
+
+func TestRegistry_Histogram(t *testing.T) {
+	r := NewRegistry()
+	h := r.Histogram("latency_seconds", "Latency.", []float64{1, 0.1}, "op")
+
+	h.Observe(0.05, "read")
+	h.Observe(0.5, "read")
+	h.Observe(7, "read")
+
+	assertContains(t, render(t, r),
+		"# TYPE latency_seconds histogram",
+		`latency_seconds_bucket{op="read",le="0.1"} 1`,
+		`latency_seconds_bucket{op="read",le="1"} 2`,
+		`latency_seconds_bucket{op="read",le="+Inf"} 3`,
+		`latency_seconds_sum{op="read"} 7.55`,
+		`latency_seconds_count{op="read"} 3`,
+	)
+}
+
+func TestRegistry_WriteTextFile(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("runs_total", "Runs.").Inc()
+
+	path := filepath.Join(t.TempDir(), "ritual.prom")
+	if err := r.WriteTextFile(path); err != nil {
+		t.Fatalf("WriteTextFile failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read metrics file: %v", err)
+	}
+	assertContains(t, string(data), "runs_total 1")
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("Expected only the metrics file to remain, got %d entries", len(entries))
+	}
+}
+
+func TestCollector_RecordsExecution(t *testing.T) {
+	c := NewCollector()
+	bus := events.NewBus()
+	bus.Subscribe(c)
+
+	task := func(eventType events.Type, id string) events.Event {
+		return events.Event{Type: eventType, ExecutionID: "exec_1", TaskID: id, TaskType: "command"}
+	}
+
+	bus.Publish(events.Event{Type: events.WorkflowStarted, ExecutionID: "exec_1", Workflow: "deploy"})
+	bus.Publish(task(events.TaskQueued, "build"))
+	bus.Publish(task(events.TaskQueued, "test"))
+	bus.Publish(task(events.TaskQueued, "lint"))
+	bus.Publish(task(events.TaskStarted, "build"))
+
+	assertContains(t, render(t, c.Registry()), "ritual_task_queue_depth 2", "ritual_executions_in_progress 1")
+
+	finished := task(events.TaskFinished, "build")
+	finished.Status = types.TaskSuccess
+	finished.Duration = 2 * time.Second
+	bus.Publish(finished)
+
+	bus.Publish(events.Event{
+		Type:        events.WorkflowFinished,
+		ExecutionID: "exec_1",
+		Workflow:    "deploy",
+		Duration:    3 * time.Second,
+		Result:      &types.Result{WorkflowResult: &types.WorkflowResult{Status: types.WorkflowSuccess}},
+	})
+
+	c.WebhookRejected("invalid_payload")
+
+	text := render(t, c.Registry())
+	assertContains(t, text,
+		`ritual_workflow_runs_total{workflow="deploy",status="success"} 1`,
+		`ritual_workflow_duration_seconds_count{workflow="deploy"} 1`,
+		`ritual_task_duration_seconds_bucket{type="command",status="success",le="5"} 1`,
+		`ritual_webhook_rejections_total{reason="invalid_payload"} 1`,
+		// Tasks left queued when the workflow ends no longer count
+		"ritual_task_queue_depth 0",
+		"ritual_executions_in_progress 0",
+	)
+}
+
+func TestCollector_FailedRunStatus(t *testing.T) {
+	c := NewCollector()
+	c.OnEvent(events.Event{Type: events.WorkflowStarted, Workflow: "deploy"})
+	c.OnEvent(events.Event{
+		Type:     events.WorkflowFinished,
+		Workflow: "deploy",
+		Result:   &types.Result{ValidationErrors: []error{os.ErrInvalid}},
+	})
+
+	assertContains(t, render(t, c.Registry()), `ritual_workflow_runs_total{workflow="deploy",status="failed"} 1`)
+}
+
+func TestCollector_Handler(t *testing.T) {
+	c := NewCollector()
+	c.WebhookRejected("method_not_allowed")
+
+	rec := httptest.NewRecorder()
+	c.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Unexpected content type %q", ct)
+	}
+	assertContains(t, rec.Body.String(), `ritual_webhook_rejections_total{reason="method_not_allowed"} 1`)
+}
@@ -0,0 +1,256 @@
+// ABOUTME: Minimal metric registry that renders the Prometheus text exposition format
+// ABOUTME: Provides labelled counters, gauges and histograms without external dependencies
+
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are histogram upper bounds in seconds, suited to task durations
+var DefaultBuckets = []float64{0.1, 0.5, 1, 5, 15, 30, 60, 300, 900, 1800, 3600}
+
+// Registry holds metric families and renders them for scraping
+type Registry struct {
+	mu       sync.Mutex
+	families []*family
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+type metricKind string
+
+const (
+	kindCounter   metricKind = "counter"
+	kindGauge     metricKind = "gauge"
+	kindHistogram metricKind = "histogram"
+)
+
+// family is one named metric and its labelled series
+type family struct {
+	mu      sync.Mutex
+	name    string
+	help    string
+	kind    metricKind
+	labels  []string
+	buckets []float64
+	series  map[string]*series
+}
+
+// series is the state of one label combination
+type series struct {
+	labelValues []string
+	value       float64  // counter and gauge value, histogram sum
+	count       uint64   // histogram observation count
+	bucketCount []uint64 // histogram observations per bucket (non-cumulative)
+}
+
+func (r *Registry) register(f *family) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f.series = make(map[string]*series)
+	r.families = append(r.families, f)
+	return f
+}
+
+// CounterVec is a counter partitioned by labels
+type CounterVec struct{ f *family }
+
+// Counter registers a counter with the given label names
+func (r *Registry) Counter(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{r.register(&family{name: name, help: help, kind: kindCounter, labels: labels})}
+}
+
+// Add increases the counter for the label values by delta, which must not be negative
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		return
+	}
+	c.f.with(labelValues, func(s *series) { s.value += delta })
+}
+
+// Inc increases the counter for the label values by one
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// GaugeVec is a gauge partitioned by labels
+type GaugeVec struct{ f *family }
+
+// Gauge registers a gauge with the given label names
+func (r *Registry) Gauge(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{r.register(&family{name: name, help: help, kind: kindGauge, labels: labels})}
+}
+
+// Set sets the gauge for the label values
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+	g.f.with(labelValues, func(s *series) { s.value = value })
+}
+
+// Add adjusts the gauge for the label values by delta
+func (g *GaugeVec) Add(delta float64, labelValues ...string) {
+	g.f.with(labelValues, func(s *series) { s.value += delta })
+}
+
+// HistogramVec is a histogram partitioned by labels
+type HistogramVec struct{ f *family }
+
+// Histogram registers a histogram with the given bucket upper bounds and label names
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	return &HistogramVec{r.register(&family{name: name, help: help, kind: kindHistogram, labels: labels, buckets: sorted})}
+}
+
+// Observe records a value for the label values
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	h.f.with(labelValues, func(s *series) {
+		if s.bucketCount == nil {
+			s.bucketCount = make([]uint64, len(h.f.buckets))
+		}
+		for i, bound := range h.f.buckets {
+			if value <= bound {
+				s.bucketCount[i]++
+				break
+			}
+		}
+		s.value += value
+		s.count++
+	})
+}
+
+// with runs update on the series for the label values, creating it if needed.
+// Missing label values are treated as empty strings.
+func (f *family) with(labelValues []string, update func(*series)) {
+	values := make([]string, len(f.labels))
+	copy(values, labelValues)
+	key := strings.Join(values, "\xff")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, exists := f.series[key]
+	if !exists {
+		s = &series{labelValues: values}
+		f.series[key] = s
+	}
+	update(s)
+}
+
+// WriteText renders every metric in the Prometheus text exposition format
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	families := append([]*family(nil), r.families...)
+	r.mu.Unlock()
+
+	var b strings.Builder
+	for _, f := range families {
+		f.write(&b)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteTextFile writes the metrics to path atomically, as expected by the
+// node exporter textfile collector
+func (r *Registry) WriteTextFile(path string) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create metrics file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if err := r.WriteText(tmp); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	return nil
+}
+
+func (f *family) write(b *strings.Builder) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fmt.Fprintf(b, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+	fmt.Fprintf(b, "# TYPE %s %s\n", f.name, f.kind)
+
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := f.series[key]
+		switch f.kind {
+		case kindHistogram:
+			var cumulative uint64
+			for i, bound := range f.buckets {
+				if s.bucketCount != nil {
+					cumulative += s.bucketCount[i]
+				}
+				fmt.Fprintf(b, "%s_bucket%s %d\n", f.name, f.labelString(s.labelValues, "le", formatFloat(bound)), cumulative)
+			}
+			fmt.Fprintf(b, "%s_bucket%s %d\n", f.name, f.labelString(s.labelValues, "le", "+Inf"), s.count)
+			fmt.Fprintf(b, "%s_sum%s %s\n", f.name, f.labelString(s.labelValues, "", ""), formatFloat(s.value))
+			fmt.Fprintf(b, "%s_count%s %d\n", f.name, f.labelString(s.labelValues, "", ""), s.count)
+		default:
+			fmt.Fprintf(b, "%s%s %s\n", f.name, f.labelString(s.labelValues, "", ""), formatFloat(s.value))
+		}
+	}
+}
+
+// labelString renders {name="value",...}, optionally with one extra label
+func (f *family) labelString(values []string, extraName, extraValue string) string {
+	var pairs []string
+	for i, name := range f.labels {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", name, escapeLabel(values[i])))
+	}
+	if extraName != "" {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", extraName, extraValue))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
@@ -5,18 +5,27 @@ package orchestrator
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/spf13/afero"
 
+	"github.com/sarlalian/ritual/internal/approval"
 	contextManager "github.com/sarlalian/ritual/internal/context"
+	"github.com/sarlalian/ritual/internal/events"
 	"github.com/sarlalian/ritual/internal/executor"
 	"github.com/sarlalian/ritual/internal/filesystem"
 	"github.com/sarlalian/ritual/internal/history"
+	"github.com/sarlalian/ritual/internal/inputs"
+	"github.com/sarlalian/ritual/internal/output"
+	"github.com/sarlalian/ritual/internal/redact"
 	"github.com/sarlalian/ritual/internal/tasks"
 	"github.com/sarlalian/ritual/internal/template"
+	"github.com/sarlalian/ritual/internal/variables"
 	"github.com/sarlalian/ritual/internal/workflow/imports"
 	"github.com/sarlalian/ritual/internal/workflow/parser"
 	"github.com/sarlalian/ritual/internal/workflow/resolver"
@@ -32,8 +41,15 @@ type Orchestrator struct {
 	taskRegistry   *tasks.Registry
 	importResolver *imports.Resolver
 	historyStore   *history.Store
+	approvalGate   *approval.Gate
+	events         *events.Bus
+	redactor       *redact.Redactor
 	logger         types.Logger
 	config         *Config
+
+	// overflowDir holds output over the limit when no OutputDir is set; it
+	// is empty when the history is not on the local filesystem
+	overflowDir string
 }
 
 // Config holds orchestrator configuration
@@ -43,6 +59,33 @@ type Config struct {
 	Logger         types.Logger
 	Verbose        bool
 	HistoryDir     string
+
+	// OutputLimit is how many bytes of each task's stdout/stderr are kept in
+	// results and history; zero keeps output.DefaultMaxBytes
+	OutputLimit int
+
+	// OutputDir receives the full output of command and ssh tasks. When empty,
+	// output is only spooled once it exceeds OutputLimit, to an output
+	// directory beside a local history, where it is kept for OutputRetention.
+	OutputDir string
+
+	// OutputRetention is how long output spooled beside the history is kept;
+	// zero keeps output.DefaultRetention
+	OutputRetention time.Duration
+
+	// Redactor masks secrets and sensitive variables in logs, task results
+	// and history. When nil, one using redact.DefaultPatterns is created.
+	Redactor *redact.Redactor
+
+	// KeyFile and PassphraseEnv say where the passphrase for encrypted
+	// variable files is read from. When both are empty, RITUAL_PASSPHRASE
+	// and RITUAL_KEY_FILE are used.
+	KeyFile       string
+	PassphraseEnv string
+
+	// Environment selects the overlay in variables/environments that is
+	// layered over the workflow's variable files
+	Environment string
 }
 
 // New creates a new workflow orchestrator
@@ -66,20 +109,38 @@ func New(config *Config) (*Orchestrator, error) {
 		config.HistoryDir = "./history"
 	}
 
+	// Every log line passes through the redactor, which learns secret values
+	// as the context is initialised and as secrets are fetched
+	redactor := config.Redactor
+	if redactor == nil {
+		redactor = redact.New(redact.DefaultPatterns)
+	}
+	logger := redact.NewLogger(config.Logger, redactor)
+
 	// Initialize template engine
 	templateEngine := template.New()
 
 	// Initialize context manager
 	ctxManager := contextManager.New(templateEngine)
+	ctxManager.SetRedactor(redactor)
+	ctxManager.SetVariableKeySource(config.KeyFile, config.PassphraseEnv)
+	ctxManager.SetVariableEnvironment(config.Environment)
 
 	// Initialize task registry
 	taskRegistry := tasks.New()
 
+	// Initialize the event bus shared by the executor and the orchestrator
+	bus := events.NewBus()
+	if logger != nil {
+		bus.Subscribe(events.NewLogObserver(logger))
+	}
+
 	// Initialize executor
 	executorConfig := &executor.Config{
 		DryRun:         config.DryRun,
 		MaxConcurrency: config.MaxConcurrency,
-		Logger:         config.Logger,
+		Logger:         logger,
+		Events:         bus,
 	}
 	exec, err := executor.New(ctxManager, executorConfig)
 	if err != nil {
@@ -104,6 +165,20 @@ func New(config *Config) (*Orchestrator, error) {
 	}
 	historyStore := history.New(historyFS, historyPath, 10000) // Keep up to 10k records
 	_ = historyStore.Initialize()                              // Create directory if needed
+	historyStore.SetRedactor(redactor)
+
+	// Record execution history (regardless of success or failure)
+	bus.Subscribe(history.NewObserver(historyStore, logger))
+
+	// Output over the limit is kept beside a local history, so the files
+	// results point at outlive the run
+	var overflowDir string
+	if _, local := historyFS.(*afero.OsFs); local {
+		overflowDir = filepath.Join(historyPath, "output")
+	}
+	if config.OutputRetention <= 0 {
+		config.OutputRetention = output.DefaultRetention
+	}
 
 	return &Orchestrator{
 		parser:         parserInstance,
@@ -113,41 +188,136 @@ func New(config *Config) (*Orchestrator, error) {
 		taskRegistry:   taskRegistry,
 		importResolver: importResolver,
 		historyStore:   historyStore,
-		logger:         config.Logger,
+		approvalGate:   approval.NewGate(),
+		events:         bus,
+		redactor:       redactor,
+		logger:         logger,
 		config:         config,
+		overflowDir:    overflowDir,
 	}, nil
 }
 
 // ExecuteWorkflowFile executes a workflow from a YAML file
 func (o *Orchestrator) ExecuteWorkflowFile(ctx context.Context, filename string, envVars []string) (*types.Result, error) {
+	workflow, err := o.loadWorkflowFile(filename)
+	if err != nil {
+		return &types.Result{ParseError: err}, nil
+	}
+
+	return o.ExecuteWorkflowWithPath(ctx, workflow, envVars, filename)
+}
+
+// ResolveContext loads a workflow file and initialises its context, inputs,
+// variable files, vars and environment included, without running any task.
+// The context manager then holds the resolved values and where each came from.
+func (o *Orchestrator) ResolveContext(ctx context.Context, filename string, envVars []string) (*types.Result, error) {
+	workflow, err := o.loadWorkflowFile(filename)
+	if err != nil {
+		return &types.Result{ParseError: err}, nil
+	}
+
+	result := &types.Result{}
+	if len(workflow.Imports) > 0 {
+		workflow, err = o.importResolver.ResolveImports(ctx, workflow, filename)
+		if err != nil {
+			result.ParseError = fmt.Errorf("failed to resolve imports: %w", err)
+			return result, nil
+		}
+	}
+
+	result.ValidationErrors = append(result.ValidationErrors, inputs.Check(workflow)...)
+	result.ValidationErrors = append(result.ValidationErrors, variables.CheckMergeConfig(workflow.VariableMerge)...)
+	result.ValidationErrors = append(result.ValidationErrors, contextManager.CheckVariableSources(workflow.Variables)...)
+	if len(result.ValidationErrors) > 0 {
+		locateErrors(workflow, result.ValidationErrors)
+		return result, nil
+	}
+
+	o.initializeContext(ctx, workflow, envVars, result)
+	return result, nil
+}
+
+// loadWorkflowFile parses a workflow file and points the context manager at
+// it, for variable file loading and value provenance
+func (o *Orchestrator) loadWorkflowFile(filename string) (*types.Workflow, error) {
 	o.logf("Loading workflow from file: %s", filename)
 
 	// Parse workflow from file
 	workflow, err := o.parser.ParseFile(filename)
 	if err != nil {
-		return &types.Result{
-			ParseError: fmt.Errorf("failed to parse workflow file '%s': %w", filename, err),
-		}, nil
+		return nil, fmt.Errorf("failed to parse workflow file '%s': %w", filename, err)
 	}
 
-	// Update context manager with workflow directory for variable file loading
-	workflowDir := filepath.Dir(filename)
-	if workflowDir != "." {
-		if mgr, ok := o.contextManager.(*contextManager.Manager); ok {
+	if mgr, ok := o.contextManager.(*contextManager.Manager); ok {
+		// A reference such as pipelines.yaml#deploy names a workflow in the file
+		path, _ := parser.ResolveWorkflowRef(nil, filename)
+		mgr.SetWorkflowFile(path)
+
+		// Update context manager with workflow directory for variable file loading
+		if workflowDir := filepath.Dir(path); workflowDir != "." {
 			mgr.SetWorkflowDir(workflowDir)
 		}
 	}
 
-	return o.ExecuteWorkflowWithPath(ctx, workflow, envVars, filename)
+	return workflow, nil
 }
 
 // ExecuteWorkflowWithPath executes a workflow with file path context for imports
 func (o *Orchestrator) ExecuteWorkflowWithPath(ctx context.Context, workflow *types.Workflow, envVars []string, workflowPath string) (*types.Result, error) {
 	result := &types.Result{}
-
-	o.logf("Starting workflow execution: %s", workflow.Name)
 	startTime := time.Now()
 
+	// Approval tasks need a gate to wait on, and events and approvals need an
+	// execution ID to be addressed by. Callers such as the webhook server
+	// supply their own; otherwise use ours.
+	if approval.GateFromContext(ctx) == nil {
+		ctx = approval.WithGate(ctx, o.approvalGate)
+	}
+	executionID := types.ExecutionIDFromContext(ctx)
+	if executionID == "" {
+		executionID = fmt.Sprintf("exec_%d", time.Now().UnixNano())
+		ctx = types.WithExecutionID(ctx, executionID)
+	}
+
+	// Without an output directory, streams over the limit are spooled beside
+	// the history, where output of runs past their retention is pruned
+	settings := output.Settings{
+		MaxBytes: o.config.OutputLimit,
+		SpoolDir: o.config.OutputDir,
+		Redactor: o.redactor,
+	}
+	if settings.SpoolDir == "" && o.overflowDir != "" {
+		settings.OverflowDir = o.overflowDir
+		if removed, err := output.Prune(o.overflowDir, o.config.OutputRetention); err != nil {
+			o.logf("Failed to prune spooled output: %v", err)
+		} else if removed > 0 {
+			o.logf("Pruned spooled output of %d executions", removed)
+		}
+	}
+	ctx = output.WithSettings(ctx, settings)
+	ctx = redact.WithRedactor(ctx, o.redactor)
+
+	o.events.Publish(events.Event{
+		Type:         events.WorkflowStarted,
+		ExecutionID:  executionID,
+		Workflow:     workflow.Name,
+		WorkflowPath: workflowPath,
+		EnvVars:      envVars,
+		TraceParent:  types.TraceParentFromContext(ctx),
+	})
+
+	defer func() {
+		o.events.Publish(events.Event{
+			Type:         events.WorkflowFinished,
+			ExecutionID:  executionID,
+			Workflow:     workflow.Name,
+			WorkflowPath: workflowPath,
+			EnvVars:      envVars,
+			Duration:     time.Since(startTime),
+			Result:       result,
+		})
+	}()
+
 	// Resolve imports if present
 	if len(workflow.Imports) > 0 {
 		o.logf("Resolving %d workflow imports", len(workflow.Imports))
@@ -161,7 +331,7 @@ func (o *Orchestrator) ExecuteWorkflowWithPath(ctx context.Context, workflow *ty
 	}
 
 	// Continue with the rest of the execution logic
-	return o.executeResolvedWorkflow(ctx, workflow, envVars, workflowPath, startTime, result)
+	return o.executeResolvedWorkflow(ctx, workflow, envVars, result)
 }
 
 // ExecuteWorkflowYAML executes a workflow from YAML content
@@ -185,13 +355,17 @@ func (o *Orchestrator) ExecuteWorkflow(ctx context.Context, workflow *types.Work
 }
 
 // executeResolvedWorkflow handles the actual workflow execution after imports are resolved
-func (o *Orchestrator) executeResolvedWorkflow(ctx context.Context, workflow *types.Workflow, envVars []string, workflowPath string, startTime time.Time, result *types.Result) (*types.Result, error) {
+func (o *Orchestrator) executeResolvedWorkflow(ctx context.Context, workflow *types.Workflow, envVars []string, result *types.Result) (*types.Result, error) {
 	// Validate workflow
 	o.logf("Validating workflow and tasks")
 	if err := o.parser.Validate(workflow); err != nil {
 		result.ValidationErrors = append(result.ValidationErrors, fmt.Errorf("workflow validation failed: %w", err))
 	}
 
+	result.ValidationErrors = append(result.ValidationErrors, inputs.Check(workflow)...)
+	result.ValidationErrors = append(result.ValidationErrors, variables.CheckMergeConfig(workflow.VariableMerge)...)
+	result.ValidationErrors = append(result.ValidationErrors, contextManager.CheckVariableSources(workflow.Variables)...)
+
 	// Validate all tasks
 	taskErrors := o.taskRegistry.ValidateAll(workflow.Tasks)
 	result.ValidationErrors = append(result.ValidationErrors, taskErrors...)
@@ -200,6 +374,8 @@ func (o *Orchestrator) executeResolvedWorkflow(ctx context.Context, workflow *ty
 	templateEngine := o.contextManager.GetTemplateEngine()
 	templateErrors := template.ValidateTaskTemplates(workflow.Tasks, templateEngine)
 	result.ValidationErrors = append(result.ValidationErrors, templateErrors...)
+	result.ValidationErrors = append(result.ValidationErrors, template.ValidateOutputTemplates(workflow.Outputs, workflow.Tasks)...)
+	locateErrors(workflow, result.ValidationErrors)
 
 	// Stop if we have validation errors
 	if len(result.ValidationErrors) > 0 {
@@ -207,10 +383,16 @@ func (o *Orchestrator) executeResolvedWorkflow(ctx context.Context, workflow *ty
 		return result, nil
 	}
 
-	// Initialize context
-	o.logf("Initializing workflow context")
-	if err := o.contextManager.Initialize(workflow, envVars); err != nil {
-		result.ExecutionError = fmt.Errorf("failed to initialize context: %w", err)
+	if !o.initializeContext(ctx, workflow, envVars, result) {
+		return result, nil
+	}
+
+	// Render templates now that vars and env are known, so a typo fails the
+	// run before any task has side effects
+	preflightErrors := template.Preflight(workflow.Tasks, templateEngine, o.contextManager.GetContext(), o.taskRegistry.OutputFields)
+	if len(preflightErrors) > 0 {
+		result.ValidationErrors = append(result.ValidationErrors, preflightErrors...)
+		o.logf("Template pre-flight failed with %d errors", len(preflightErrors))
 		return result, nil
 	}
 
@@ -238,6 +420,21 @@ func (o *Orchestrator) executeResolvedWorkflow(ctx context.Context, workflow *ty
 	}
 
 	workflowResult, err := o.executor.ExecuteWorkflow(ctx, workflow, o.resolver)
+	if workflowResult != nil && len(workflow.Outputs) > 0 {
+		outputErr := o.evaluateOutputs(workflow.Outputs, workflowResult)
+		if outputErr != nil && err == nil && workflowResult.Status != types.WorkflowFailed {
+			workflowResult.Status = types.WorkflowFailed
+			workflowResult.Error = outputErr.Error()
+			result.ExecutionError = outputErr
+			result.WorkflowResult = workflowResult
+			return result, nil
+		}
+		if outputErr != nil {
+			// The run already failed, so outputs that depend on tasks that
+			// did not finish are expected to be missing
+			o.logf("Some outputs could not be evaluated: %v", outputErr)
+		}
+	}
 	if err != nil {
 		result.ExecutionError = fmt.Errorf("workflow execution failed: %w", err)
 		result.WorkflowResult = workflowResult // Include partial results
@@ -245,26 +442,11 @@ func (o *Orchestrator) executeResolvedWorkflow(ctx context.Context, workflow *ty
 		result.WorkflowResult = workflowResult
 	}
 
-	// Record execution history (regardless of success or failure)
-	if o.historyStore != nil {
-		triggerData := map[string]interface{}{
-			"env_vars": envVars,
-		}
-		if historyErr := o.historyStore.RecordExecution(result, workflow.Name, workflowPath, "manual", triggerData); historyErr != nil {
-			o.logf("Failed to record execution history: %v", historyErr)
-		}
-	}
-
 	// Return early if execution failed
 	if err != nil {
 		return result, nil
 	}
 
-	// Log completion
-	duration := time.Since(startTime)
-	o.logf("Workflow '%s' completed with status %s in %v",
-		workflow.Name, workflowResult.Status, duration)
-
 	if o.config.Verbose {
 		o.logWorkflowSummary(workflowResult)
 	}
@@ -272,16 +454,96 @@ func (o *Orchestrator) executeResolvedWorkflow(ctx context.Context, workflow *ty
 	return result, nil
 }
 
+// initializeContext resolves the workflow's inputs and initialises the
+// context. Problems are recorded in result and reported by returning false.
+func (o *Orchestrator) initializeContext(ctx context.Context, workflow *types.Workflow, envVars []string, result *types.Result) bool {
+	// Coerce the caller's values for declared inputs, reporting every
+	// missing or invalid one at once
+	provided := types.InputsFromContext(ctx)
+	inputValues, inputErrors := inputs.Resolve(workflow.Inputs, provided)
+	if len(inputErrors) > 0 {
+		result.ValidationErrors = append(result.ValidationErrors, inputErrors...)
+		o.logf("Workflow inputs are invalid: %d errors", len(inputErrors))
+		return false
+	}
+	if len(workflow.Inputs) > 0 {
+		if unknown := inputs.Unknown(workflow.Inputs, provided); len(unknown) > 0 {
+			o.logf("Ignoring values for undeclared inputs: %s", strings.Join(unknown, ", "))
+		}
+	}
+	if mgr, ok := o.contextManager.(*contextManager.Manager); ok {
+		mgr.SetInputs(inputValues)
+	}
+
+	// Initialize context
+	o.logf("Initializing workflow context")
+	if err := o.contextManager.Initialize(workflow, envVars); err != nil {
+		result.ExecutionError = fmt.Errorf("failed to initialize context: %w", err)
+		return false
+	}
+	return true
+}
+
+// evaluateOutputs evaluates the workflow's declared outputs against the final
+// context and stores those that succeed, masked, in the workflow result
+func (o *Orchestrator) evaluateOutputs(declared map[string]interface{}, workflowResult *types.WorkflowResult) error {
+	names := make([]string, 0, len(declared))
+	for name := range declared {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	outputs := make(map[string]interface{}, len(declared))
+	var errs []error
+	for _, name := range names {
+		// Evaluated one at a time so every failing output is reported
+		evaluated, err := o.contextManager.EvaluateMap(map[string]interface{}{name: declared[name]})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("output '%s': %w", name, err))
+			continue
+		}
+		outputs[name] = evaluated[name]
+	}
+
+	workflowResult.Outputs = o.redactor.Map(outputs)
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to evaluate workflow outputs: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
 // GetTaskRegistry returns the task registry for custom task registration
 func (o *Orchestrator) GetTaskRegistry() *tasks.Registry {
 	return o.taskRegistry
 }
 
+// GetApprovalGate returns the gate used by approval tasks when the caller does
+// not supply one through the context
+func (o *Orchestrator) GetApprovalGate() *approval.Gate {
+	return o.approvalGate
+}
+
+// GetEventBus returns the bus workflow and task events are published on
+func (o *Orchestrator) GetEventBus() *events.Bus {
+	return o.events
+}
+
 // GetContextManager returns the context manager for inspection
 func (o *Orchestrator) GetContextManager() types.ContextManager {
 	return o.contextManager
 }
 
+// locateErrors gives validation errors that name a workflow path, such as
+// inputs.region or vars.build, the position that path was written at
+func locateErrors(workflow *types.Workflow, errs []error) {
+	for _, err := range errs {
+		var validationErr *types.ValidationError
+		if errors.As(err, &validationErr) && !validationErr.Position.IsValid() {
+			validationErr.Position = workflow.Source.Lookup(validationErr.Field)
+		}
+	}
+}
+
 // ValidateWorkflowFile validates a workflow file without executing it
 func (o *Orchestrator) ValidateWorkflowFile(filename string) (*types.Result, error) {
 	o.logf("Validating workflow file: %s", filename)
@@ -307,6 +569,9 @@ func (o *Orchestrator) ValidateWorkflow(workflow *types.Workflow) (*types.Result
 	if err := o.parser.Validate(workflow); err != nil {
 		result.ValidationErrors = append(result.ValidationErrors, fmt.Errorf("workflow validation failed: %w", err))
 	}
+	result.ValidationErrors = append(result.ValidationErrors, inputs.Check(workflow)...)
+	result.ValidationErrors = append(result.ValidationErrors, variables.CheckMergeConfig(workflow.VariableMerge)...)
+	result.ValidationErrors = append(result.ValidationErrors, contextManager.CheckVariableSources(workflow.Variables)...)
 
 	// Validate all tasks
 	taskErrors := o.taskRegistry.ValidateAll(workflow.Tasks)
@@ -316,6 +581,8 @@ func (o *Orchestrator) ValidateWorkflow(workflow *types.Workflow) (*types.Result
 	templateEngine := o.contextManager.GetTemplateEngine()
 	templateErrors := template.ValidateTaskTemplates(workflow.Tasks, templateEngine)
 	result.ValidationErrors = append(result.ValidationErrors, templateErrors...)
+	result.ValidationErrors = append(result.ValidationErrors, template.ValidateOutputTemplates(workflow.Outputs, workflow.Tasks)...)
+	locateErrors(workflow, result.ValidationErrors)
 
 	// Validate dependencies
 	if err := o.resolver.BuildGraph(workflow.Tasks); err != nil {
@@ -383,8 +650,12 @@ func (o *Orchestrator) logWorkflowSummary(result *types.WorkflowResult) {
 	successCount := 0
 	failedCount := 0
 	skippedCount := 0
+	changedCount := 0
 
 	for _, taskResult := range result.Tasks {
+		if taskResult.Changed {
+			changedCount++
+		}
 		switch taskResult.Status {
 		case types.TaskSuccess:
 			successCount++
@@ -403,6 +674,7 @@ func (o *Orchestrator) logWorkflowSummary(result *types.WorkflowResult) {
 		Int("successful", successCount).
 		Int("failed", failedCount).
 		Int("skipped", skippedCount).
+		Int("changed", changedCount).
 		Msg("Workflow execution summary")
 
 	// Log failed tasks
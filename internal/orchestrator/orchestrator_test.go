@@ -8,9 +8,11 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/sarlalian/ritual/internal/events"
 	"github.com/sarlalian/ritual/pkg/types"
 )
 
@@ -221,6 +223,66 @@ tasks:
 	}
 }
 
+func TestOrchestrator_PublishesWorkflowEvents(t *testing.T) {
+	historyDir := t.TempDir()
+	orchestrator, err := New(&Config{DryRun: true, HistoryDir: historyDir})
+	if err != nil {
+		t.Fatalf("Failed to create orchestrator: %v", err)
+	}
+
+	var mu sync.Mutex
+	var received []events.Event
+	orchestrator.GetEventBus().Subscribe(events.ObserverFunc(func(event events.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, event)
+	}))
+
+	yamlContent := []byte(`
+name: Event Workflow
+tasks:
+  - id: task1
+    name: Echo Hello
+    type: command
+    command: echo "Hello World"
+`)
+
+	ctx := types.WithExecutionID(context.Background(), "exec_events")
+	if _, err := orchestrator.ExecuteWorkflowYAML(ctx, yamlContent, nil); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(received) < 2 {
+		t.Fatalf("Expected workflow and task events, got %d", len(received))
+	}
+
+	first, last := received[0], received[len(received)-1]
+	if first.Type != events.WorkflowStarted || last.Type != events.WorkflowFinished {
+		t.Errorf("Expected events to be bracketed by workflow started/finished, got %s ... %s", first.Type, last.Type)
+	}
+	if last.Result == nil || last.Result.WorkflowResult == nil {
+		t.Error("Expected the finished event to carry the result")
+	}
+
+	sawTask := false
+	for _, event := range received {
+		if event.ExecutionID != "exec_events" {
+			t.Errorf("Expected execution ID on %s event, got %q", event.Type, event.ExecutionID)
+		}
+		if event.TaskID == "task1" {
+			sawTask = true
+		}
+	}
+	if !sawTask {
+		t.Error("Expected task events for task1")
+	}
+
+	// History is recorded from the finished event under the same execution ID
+	if _, err := orchestrator.historyStore.GetExecution("exec_events"); err != nil {
+		t.Errorf("Expected history record for exec_events: %v", err)
+	}
+}
+
 func TestOrchestrator_ExecuteWorkflowYAML_ParseError(t *testing.T) {
 	orchestrator, err := New(nil)
 	if err != nil {
@@ -364,6 +426,257 @@ func TestOrchestrator_ExecuteWorkflow_WithVariables(t *testing.T) {
 	}
 }
 
+func TestOrchestrator_ExecuteWorkflow_PreflightStopsBeforeTasks(t *testing.T) {
+	orchestrator, err := New(nil)
+	if err != nil {
+		t.Fatalf("Failed to create orchestrator: %v", err)
+	}
+
+	marker := filepath.Join(t.TempDir(), "marker")
+	workflow := &types.Workflow{
+		Name:      "Preflight Test Workflow",
+		Variables: map[string]interface{}{"region": "us-east-1"},
+		Tasks: []types.TaskConfig{
+			{
+				ID:     "first",
+				Name:   "Create Marker",
+				Type:   "file",
+				Config: map[string]interface{}{"path": marker, "state": "touch"},
+			},
+			{
+				ID:        "second",
+				Name:      "Use Misspelled Variable",
+				Type:      "command",
+				DependsOn: []string{"first"},
+				Config:    map[string]interface{}{"command": "echo {{ .vars.regoin }}"},
+			},
+		},
+	}
+
+	result, err := orchestrator.ExecuteWorkflow(context.Background(), workflow, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(result.ValidationErrors) != 1 {
+		t.Fatalf("Expected 1 validation error, got: %v", result.ValidationErrors)
+	}
+	if !strings.Contains(result.ValidationErrors[0].Error(), "did you mean 'region'?") {
+		t.Errorf("Expected suggestion in error, got: %v", result.ValidationErrors[0])
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Errorf("Expected no task to run, but %s exists", marker)
+	}
+}
+
+func TestOrchestrator_ExecuteWorkflow_Inputs(t *testing.T) {
+	orchestrator, err := New(nil)
+	if err != nil {
+		t.Fatalf("Failed to create orchestrator: %v", err)
+	}
+
+	newWorkflow := func() *types.Workflow {
+		return &types.Workflow{
+			Name: "Inputs Test Workflow",
+			Inputs: map[string]types.InputConfig{
+				"version":  {Type: "string", Required: true, Description: "release to deploy"},
+				"replicas": {Type: "int", Default: 2},
+				"target":   {Type: "enum", Values: []interface{}{"staging", "production"}, Default: "staging"},
+			},
+			Variables: map[string]interface{}{"total": "{{ add .vars.replicas 1 }}"},
+			Tasks: []types.TaskConfig{
+				{
+					ID:     "show",
+					Name:   "Show Inputs",
+					Type:   "command",
+					Config: map[string]interface{}{"command": "echo {{ .vars.version }}-{{ .vars.replicas }}-{{ .vars.target }}-{{ .vars.total }}"},
+				},
+			},
+		}
+	}
+
+	// Every missing or invalid input is reported before any task runs
+	ctx := types.WithInputs(context.Background(), map[string]interface{}{"replicas": "many", "target": "qa"})
+	result, err := orchestrator.ExecuteWorkflow(ctx, newWorkflow(), nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(result.ValidationErrors) != 3 || result.WorkflowResult != nil {
+		t.Fatalf("Expected 3 input errors and no execution, got: %v", result.ValidationErrors)
+	}
+	for i, want := range []string{"inputs.replicas", "inputs.target", "inputs.version"} {
+		if !strings.Contains(result.ValidationErrors[i].Error(), want) {
+			t.Errorf("Expected error %d to name %s, got: %v", i, want, result.ValidationErrors[i])
+		}
+	}
+
+	// Strings from the command line are coerced to the declared types
+	ctx = types.WithInputs(context.Background(), map[string]interface{}{"version": "1.4.0", "replicas": "3"})
+	result, err = orchestrator.ExecuteWorkflow(ctx, newWorkflow(), nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(result.ValidationErrors) > 0 || result.ExecutionError != nil || result.WorkflowResult == nil {
+		t.Fatalf("Expected the workflow to run, got: %v %v", result.ValidationErrors, result.ExecutionError)
+	}
+	if stdout := result.WorkflowResult.Tasks["show"].Stdout; strings.TrimSpace(stdout) != "1.4.0-3-staging-4" {
+		t.Errorf("Expected coerced inputs in output, got %q", stdout)
+	}
+}
+
+func TestOrchestrator_ExecuteWorkflow_Outputs(t *testing.T) {
+	run := func(outputs map[string]interface{}) *types.Result {
+		t.Helper()
+		// A fresh orchestrator per run, since each run builds its own graph
+		orchestrator, err := New(&Config{MaxConcurrency: 1})
+		if err != nil {
+			t.Fatalf("Failed to create orchestrator: %v", err)
+		}
+		workflow := &types.Workflow{
+			Name:      "Outputs Test Workflow",
+			Variables: map[string]interface{}{"region": "eu-west-1", "replicas": 3},
+			Tasks: []types.TaskConfig{
+				{
+					ID:     "build",
+					Name:   "Build",
+					Type:   "command",
+					Config: map[string]interface{}{"command": "echo 1.4.0"},
+				},
+			},
+			Outputs: outputs,
+		}
+		result, err := orchestrator.ExecuteWorkflow(context.Background(), workflow, nil)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		return result
+	}
+
+	result := run(map[string]interface{}{
+		"version":  "{{ .tasks.build.Stdout | trim }}",
+		"replicas": "{{ .vars.replicas }}",
+		"target":   "{{ .vars.region }}/{{ .tasks.build.Status }}",
+	})
+	if result.ExecutionError != nil || result.WorkflowResult == nil {
+		t.Fatalf("Expected the workflow to run, got: %v", result.ExecutionError)
+	}
+	outputs := result.WorkflowResult.Outputs
+	if outputs["version"] != "1.4.0" || outputs["target"] != "eu-west-1/success" {
+		t.Errorf("Expected evaluated outputs, got %v", outputs)
+	}
+	if outputs["replicas"] != 3 {
+		t.Errorf("Expected single-expression outputs to keep their type, got %#v", outputs["replicas"])
+	}
+
+	// Output templates are checked before any task runs
+	result = run(map[string]interface{}{"version": "{{ .tasks.biuld.Stdout }}"})
+	if len(result.ValidationErrors) != 1 || result.WorkflowResult != nil {
+		t.Fatalf("Expected 1 validation error and no execution, got: %v", result.ValidationErrors)
+	}
+
+	// An output that fails to evaluate fails an otherwise successful run
+	result = run(map[string]interface{}{
+		"version": "{{ .tasks.build.Stdout | trim }}",
+		"size":    "{{ .vars.size }}",
+	})
+	if result.ExecutionError == nil || !strings.Contains(result.ExecutionError.Error(), "output 'size'") {
+		t.Fatalf("Expected an error naming the failed output, got: %v", result.ExecutionError)
+	}
+	if result.WorkflowResult.Status != types.WorkflowFailed || result.WorkflowResult.Outputs["version"] != "1.4.0" {
+		t.Errorf("Expected a failed workflow that kept its other outputs, got %s %v", result.WorkflowResult.Status, result.WorkflowResult.Outputs)
+	}
+}
+
+func TestOrchestrator_ExecuteWorkflow_RedactsSensitiveValues(t *testing.T) {
+	historyDir := t.TempDir()
+	orchestrator, err := New(&Config{MaxConcurrency: 1, HistoryDir: historyDir})
+	if err != nil {
+		t.Fatalf("Failed to create orchestrator: %v", err)
+	}
+
+	workflow := &types.Workflow{
+		Name:      "Redaction Test Workflow",
+		Variables: map[string]interface{}{"db_password": "hunter22"},
+		Tasks: []types.TaskConfig{
+			{
+				ID:     "leak",
+				Name:   "Print Password",
+				Type:   "command",
+				Config: map[string]interface{}{"command": "echo 'password is {{ .vars.db_password }}'"},
+			},
+		},
+	}
+
+	result, err := orchestrator.ExecuteWorkflow(context.Background(), workflow, []string{"api_token=tok-12345"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	taskResult := result.WorkflowResult.Tasks["leak"]
+	if taskResult == nil {
+		t.Fatal("Expected leak task result")
+	}
+	if strings.Contains(taskResult.Stdout, "hunter22") || !strings.Contains(taskResult.Stdout, "password is ********") {
+		t.Errorf("Expected password to be masked in stdout, got: %q", taskResult.Stdout)
+	}
+
+	files, err := filepath.Glob(filepath.Join(historyDir, "*.json"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Expected one history record, got %v (%v)", files, err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "hunter22") || strings.Contains(string(data), "tok-12345") {
+		t.Errorf("Expected history record to be masked, got: %s", data)
+	}
+}
+
+func TestOrchestrator_ExecuteWorkflow_SpoolsOverflowBesideHistory(t *testing.T) {
+	historyDir := t.TempDir()
+	orchestrator, err := New(&Config{MaxConcurrency: 1, HistoryDir: historyDir, OutputLimit: 16})
+	if err != nil {
+		t.Fatalf("Failed to create orchestrator: %v", err)
+	}
+
+	// Output of runs past their retention is pruned when the next run starts
+	stale := filepath.Join(historyDir, "output", "exec_stale")
+	if err := os.MkdirAll(stale, 0700); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * orchestrator.config.OutputRetention)
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	workflow := &types.Workflow{
+		Name: "Overflow",
+		Tasks: []types.TaskConfig{
+			{ID: "chatty", Name: "Chatty", Type: "command", Config: map[string]interface{}{"command": "seq 1 100"}},
+		},
+	}
+	result, err := orchestrator.ExecuteWorkflow(context.Background(), workflow, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	file, _ := result.WorkflowResult.Tasks["chatty"].Output["stdout_file"].(string)
+	if !strings.HasPrefix(file, filepath.Join(historyDir, "output")+string(filepath.Separator)) {
+		t.Fatalf("Expected the spool file under the history directory, got %q", file)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("Expected the spool file to outlive the run: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "1\n2\n") || !strings.HasSuffix(string(data), "100\n") {
+		t.Errorf("Expected the full output in the spool file, got %d bytes", len(data))
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("Expected stale output to be pruned, got %v", err)
+	}
+}
+
 func TestOrchestrator_ExecuteWorkflow_WithEnvironment(t *testing.T) {
 	orchestrator, err := New(&Config{DryRun: true})
 	if err != nil {
@@ -0,0 +1,258 @@
+// ABOUTME: Bounded capture of task output with spooling of the full stream to a log file
+// ABOUTME: Keeps the tail of stdout/stderr in memory so results and history stay small
+
+package output
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/sarlalian/ritual/internal/redact"
+)
+
+// DefaultMaxBytes is how much of each output stream is kept in a task result
+const DefaultMaxBytes = 1 << 20
+
+// Settings controls how task output is captured
+type Settings struct {
+	// MaxBytes is how much of each stream is kept in memory; zero means DefaultMaxBytes
+	MaxBytes int
+
+	// SpoolDir receives the full output of every stream when set
+	SpoolDir string
+
+	// OverflowDir receives the full output of streams that exceed MaxBytes
+	// when SpoolDir is empty. When both are empty output beyond MaxBytes is
+	// dropped.
+	OverflowDir string
+
+	// Redactor masks sensitive values in spooled output, which is written
+	// before the task's result is redacted
+	Redactor *redact.Redactor
+}
+
+// settingsKey is the context key for capture settings
+type settingsKey struct{}
+
+// WithSettings returns a context carrying output capture settings
+func WithSettings(ctx context.Context, settings Settings) context.Context {
+	return context.WithValue(ctx, settingsKey{}, settings)
+}
+
+// SettingsFromContext returns the capture settings carried by ctx, or defaults
+func SettingsFromContext(ctx context.Context) Settings {
+	settings, _ := ctx.Value(settingsKey{}).(Settings)
+	return settings
+}
+
+// Capture is an io.Writer that keeps the last MaxBytes written to it and
+// spools the complete stream to a file
+type Capture struct {
+	mu       sync.Mutex
+	limit    int
+	buf      []byte
+	total    int64
+	path     string
+	always   bool
+	spooled  bool
+	file     *os.File
+	spoolErr error
+	redactor *redact.Redactor
+
+	// pending holds the start of a line not yet spooled, so values are
+	// masked even when a write splits them
+	pending []byte
+}
+
+// NewCapture creates a capture for one stream of a task. The spool file is
+// named after the execution, task and stream.
+func NewCapture(settings Settings, executionID, taskID, stream string) *Capture {
+	limit := settings.MaxBytes
+	if limit <= 0 {
+		limit = DefaultMaxBytes
+	}
+
+	dir := settings.SpoolDir
+	if dir == "" {
+		dir = settings.OverflowDir
+	}
+	if executionID == "" {
+		executionID = "adhoc"
+	}
+
+	c := &Capture{
+		limit:    limit,
+		always:   settings.SpoolDir != "",
+		redactor: settings.Redactor,
+	}
+	if dir != "" {
+		c.path = filepath.Join(dir, sanitize(executionID), fmt.Sprintf("%s.%s.log", sanitize(taskID), stream))
+	}
+	return c
+}
+
+// Write records p. It never fails, so a full disk cannot kill the task; spool
+// errors are reported by Err.
+func (c *Capture) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.total += int64(len(p))
+
+	if !c.spooled && c.spoolErr == nil && c.path != "" && (c.always || len(c.buf)+len(p) > c.limit) {
+		// Everything written so far is still in buf, so the file gets it all
+		c.openSpool(p)
+	} else if c.file != nil {
+		c.spool(p, false)
+	}
+
+	c.buf = append(c.buf, p...)
+	if len(c.buf) > 2*c.limit {
+		c.buf = append(c.buf[:0:0], c.buf[len(c.buf)-c.limit:]...)
+	}
+
+	return len(p), nil
+}
+
+// openSpool creates the spool file and writes the buffered output and p to it
+func (c *Capture) openSpool(p []byte) {
+	// Output may hold anything the task printed, so only the owner may read it
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		c.spoolErr = err
+		return
+	}
+
+	file, err := os.OpenFile(c.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		c.spoolErr = err
+		return
+	}
+	c.file = file
+	c.spooled = true
+	c.spool(append(c.buf[:len(c.buf):len(c.buf)], p...), false)
+}
+
+// spool writes the complete lines of p, masked, to the spool file and keeps
+// the rest for later. flush writes the rest too. A line longer than the
+// limit is written without waiting for its end.
+func (c *Capture) spool(p []byte, flush bool) {
+	c.pending = append(c.pending, p...)
+	n := bytes.LastIndexByte(c.pending, '\n') + 1
+	if flush || len(c.pending) > c.limit {
+		n = len(c.pending)
+	}
+	if n == 0 {
+		return
+	}
+
+	data := c.pending[:n]
+	if c.redactor != nil {
+		data = []byte(c.redactor.String(string(data)))
+	}
+	c.pending = append(c.pending[:0], c.pending[n:]...)
+	if _, err := c.file.Write(data); err != nil {
+		c.spoolErr = err
+		_ = c.file.Close()
+		c.file = nil
+	}
+}
+
+// String returns the retained output: everything, or the last MaxBytes once
+// the stream has been truncated
+func (c *Capture) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.buf) > c.limit {
+		return string(c.buf[len(c.buf)-c.limit:])
+	}
+	return string(c.buf)
+}
+
+// Truncated reports whether more was written than is retained
+func (c *Capture) Truncated() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.total > int64(c.limit)
+}
+
+// Total returns the number of bytes written
+func (c *Capture) Total() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.total
+}
+
+// File returns the path of the spool file, or "" if nothing was spooled
+func (c *Capture) File() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.spooled || c.spoolErr != nil {
+		return ""
+	}
+	return c.path
+}
+
+// Err returns the error that stopped spooling, if any
+func (c *Capture) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.spoolErr
+}
+
+// Close spools what is left of the stream and closes the spool file
+func (c *Capture) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.file == nil {
+		return nil
+	}
+	c.spool(nil, true)
+	if c.file == nil {
+		return c.spoolErr
+	}
+	err := c.file.Close()
+	c.file = nil
+	return err
+}
+
+// Annotate records where the full stream went in a task's output map:
+// <stream>_file, <stream>_bytes and <stream>_truncated
+func (c *Capture) Annotate(output map[string]interface{}, stream string) {
+	if file := c.File(); file != "" {
+		output[stream+"_file"] = file
+	}
+	if c.Truncated() {
+		output[stream+"_truncated"] = true
+		output[stream+"_bytes"] = c.Total()
+	}
+}
+
+// AnnotationFields lists every output key Annotate may set
+func AnnotationFields() []string {
+	var fields []string
+	for _, stream := range []string{"stdout", "stderr"} {
+		fields = append(fields, stream+"_file", stream+"_bytes", stream+"_truncated")
+	}
+	return fields
+}
+
+// sanitize makes an identifier safe to use as a path component
+func sanitize(name string) string {
+	if name == "" {
+		return "task"
+	}
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|', 0:
+			return '_'
+		}
+		return r
+	}, name)
+}
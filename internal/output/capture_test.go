@@ -0,0 +1,195 @@
+// ABOUTME: Tests for bounded output capture and spooling to log files
+// ABOUTME: Covers tail retention, lazy and eager spooling, redaction, annotations and pruning
+
+package output
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sarlalian/ritual/internal/redact"
+)
+
+func TestCapture_UnderLimit(t *testing.T) {
+	c := NewCapture(Settings{MaxBytes: 64, SpoolDir: ""}, "exec_1", "build", "stdout")
+	defer func() { _ = c.Close() }()
+
+	_, _ = c.Write([]byte("hello\n"))
+
+	if c.String() != "hello\n" {
+		t.Errorf("Expected full output, got %q", c.String())
+	}
+	if c.Truncated() {
+		t.Error("Expected output under the limit not to be truncated")
+	}
+	if c.File() != "" {
+		t.Errorf("Expected no spool file for small output, got %q", c.File())
+	}
+
+	annotations := make(map[string]interface{})
+	c.Annotate(annotations, "stdout")
+	if len(annotations) != 0 {
+		t.Errorf("Expected no annotations, got %v", annotations)
+	}
+}
+
+func TestCapture_OverLimitKeepsTailAndSpools(t *testing.T) {
+	dir := t.TempDir()
+	c := NewCapture(Settings{MaxBytes: 10, OverflowDir: dir}, "exec_1", "dump", "stdout")
+
+	var full strings.Builder
+	for i := 0; i < 20; i++ {
+		line := strings.Repeat(string(rune('a'+i)), 3) + "\n"
+		full.WriteString(line)
+		_, _ = c.Write([]byte(line))
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	want := full.String()
+	if got := c.String(); got != want[len(want)-10:] {
+		t.Errorf("Expected last 10 bytes %q, got %q", want[len(want)-10:], got)
+	}
+	if !c.Truncated() || c.Total() != int64(len(want)) {
+		t.Errorf("Expected truncation of %d bytes, got truncated=%v total=%d", len(want), c.Truncated(), c.Total())
+	}
+
+	spooled, err := os.ReadFile(c.File())
+	if err != nil {
+		t.Fatalf("Failed to read spool file: %v", err)
+	}
+	if string(spooled) != want {
+		t.Errorf("Expected spool file to hold the complete output")
+	}
+	if c.File() != filepath.Join(dir, "exec_1", "dump.stdout.log") {
+		t.Errorf("Expected spool file in the temporary directory, got %s", c.File())
+	}
+	if info, err := os.Stat(c.File()); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected spool file readable by its owner only, got %v (%v)", info.Mode(), err)
+	}
+	if info, err := os.Stat(filepath.Dir(c.File())); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("Expected spool directory private to its owner, got %v (%v)", info.Mode(), err)
+	}
+
+	annotations := make(map[string]interface{})
+	c.Annotate(annotations, "stdout")
+	if annotations["stdout_file"] != c.File() || annotations["stdout_truncated"] != true || annotations["stdout_bytes"] != int64(len(want)) {
+		t.Errorf("Unexpected annotations: %v", annotations)
+	}
+}
+
+func TestCapture_SpoolDirSpoolsEverything(t *testing.T) {
+	dir := t.TempDir()
+	c := NewCapture(Settings{MaxBytes: 1024, SpoolDir: dir}, "exec/1", "deploy", "stderr")
+
+	_, _ = c.Write([]byte("warning: small output\n"))
+	_ = c.Close()
+
+	expected := filepath.Join(dir, "exec_1", "deploy.stderr.log")
+	if c.File() != expected {
+		t.Errorf("Expected spool file %q, got %q", expected, c.File())
+	}
+
+	data, err := os.ReadFile(expected)
+	if err != nil || string(data) != "warning: small output\n" {
+		t.Errorf("Expected spool file with output, got %q (%v)", data, err)
+	}
+}
+
+func TestCapture_NoSpoolDirDropsOverflow(t *testing.T) {
+	c := NewCapture(Settings{MaxBytes: 4}, "exec_1", "task", "stdout")
+	_, _ = c.Write([]byte("more than four bytes"))
+	_ = c.Close()
+
+	if c.File() != "" || c.Err() != nil {
+		t.Errorf("Expected nothing spooled without a directory, got %q (%v)", c.File(), c.Err())
+	}
+	if !c.Truncated() || c.String() != "ytes" {
+		t.Errorf("Expected retained tail, got %q", c.String())
+	}
+}
+
+func TestCapture_SpoolIsRedacted(t *testing.T) {
+	dir := t.TempDir()
+	redactor := redact.New(nil)
+	redactor.Add("s3cr3t-value")
+	c := NewCapture(Settings{MaxBytes: 1024, SpoolDir: dir, Redactor: redactor}, "exec_1", "deploy", "stdout")
+
+	// The secret is split across writes, and the last line has no newline
+	_, _ = c.Write([]byte("token=s3cr"))
+	_, _ = c.Write([]byte("3t-value\nagain s3cr3t-"))
+	_, _ = c.Write([]byte("value"))
+	_ = c.Close()
+
+	data, err := os.ReadFile(c.File())
+	if err != nil {
+		t.Fatalf("Expected spool file, got %v", err)
+	}
+	expected := "token=" + redact.Mask + "\nagain " + redact.Mask
+	if string(data) != expected {
+		t.Errorf("Expected spool %q, got %q", expected, data)
+	}
+}
+
+func TestCapture_UnwritableSpoolDir(t *testing.T) {
+	blocker := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewCapture(Settings{MaxBytes: 4, SpoolDir: blocker}, "exec_1", "task", "stdout")
+	n, err := c.Write([]byte("more than four bytes"))
+	if err != nil || n != 20 {
+		t.Errorf("Expected writes to succeed despite spool failure, got n=%d err=%v", n, err)
+	}
+	if c.Err() == nil {
+		t.Error("Expected the spool error to be reported")
+	}
+	if c.File() != "" {
+		t.Error("Expected no spool file reference after a spool failure")
+	}
+	if c.String() != "ytes" {
+		t.Errorf("Expected retained tail, got %q", c.String())
+	}
+}
+
+func TestSettingsFromContext(t *testing.T) {
+	if got := SettingsFromContext(context.Background()); got != (Settings{}) {
+		t.Errorf("Expected zero settings, got %+v", got)
+	}
+
+	ctx := WithSettings(context.Background(), Settings{MaxBytes: 5, SpoolDir: "/tmp/x"})
+	if got := SettingsFromContext(ctx); got.MaxBytes != 5 || got.SpoolDir != "/tmp/x" {
+		t.Errorf("Unexpected settings: %+v", got)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"exec_old", "exec_new"} {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "exec_old"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := Prune(dir, 24*time.Hour)
+	if err != nil || removed != 1 {
+		t.Fatalf("Expected 1 directory removed, got %d (%v)", removed, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "exec_new")); err != nil {
+		t.Errorf("Expected recent output to be kept, got %v", err)
+	}
+
+	if removed, err := Prune(filepath.Join(dir, "missing"), time.Hour); err != nil || removed != 0 {
+		t.Errorf("Expected nothing to prune in a missing directory, got %d (%v)", removed, err)
+	}
+}
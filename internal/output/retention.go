@@ -0,0 +1,45 @@
+// ABOUTME: Retention of spooled output kept alongside execution history
+// ABOUTME: Removes the spool directories of executions older than a cut-off
+
+package output
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultRetention is how long spooled output is kept when no retention is set
+const DefaultRetention = 7 * 24 * time.Hour
+
+// Prune removes the per-execution directories under dir that have not been
+// written to for longer than olderThan, and returns how many it removed. A
+// missing dir holds nothing to prune.
+func Prune(dir string, olderThan time.Duration) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	removed := 0
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
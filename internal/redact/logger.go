@@ -0,0 +1,108 @@
+// ABOUTME: Logger wrapper that masks tracked sensitive values in messages and fields
+// ABOUTME: Wraps any types.Logger so every log line passes through the redactor
+
+package redact
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+// NewLogger returns a logger that masks r's tracked values before passing
+// messages and fields to base. It returns base when either is nil.
+func NewLogger(base types.Logger, r *Redactor) types.Logger {
+	if base == nil || r == nil {
+		return base
+	}
+	if existing, ok := base.(*logger); ok && existing.redactor == r {
+		return base
+	}
+	return &logger{base: base, redactor: r}
+}
+
+type logger struct {
+	base     types.Logger
+	redactor *Redactor
+}
+
+func (l *logger) Debug() types.LogEvent { return &logEvent{l.base.Debug(), l.redactor} }
+func (l *logger) Info() types.LogEvent  { return &logEvent{l.base.Info(), l.redactor} }
+func (l *logger) Warn() types.LogEvent  { return &logEvent{l.base.Warn(), l.redactor} }
+func (l *logger) Error() types.LogEvent { return &logEvent{l.base.Error(), l.redactor} }
+func (l *logger) With() types.LogContext {
+	return &logContext{l.base.With(), l.redactor}
+}
+
+type logEvent struct {
+	event    types.LogEvent
+	redactor *Redactor
+}
+
+func (e *logEvent) Str(key, val string) types.LogEvent {
+	if e.redactor.SensitiveName(key) && val != "" {
+		val = Mask
+	}
+	e.event = e.event.Str(key, e.redactor.String(val))
+	return e
+}
+
+func (e *logEvent) Int(key string, val int) types.LogEvent {
+	e.event = e.event.Int(key, val)
+	return e
+}
+
+func (e *logEvent) Dur(key string, val time.Duration) types.LogEvent {
+	e.event = e.event.Dur(key, val)
+	return e
+}
+
+func (e *logEvent) Err(err error) types.LogEvent {
+	if err != nil {
+		if masked := e.redactor.String(err.Error()); masked != err.Error() {
+			err = errors.New(masked)
+		}
+	}
+	e.event = e.event.Err(err)
+	return e
+}
+
+func (e *logEvent) Bool(key string, val bool) types.LogEvent {
+	e.event = e.event.Bool(key, val)
+	return e
+}
+
+func (e *logEvent) Any(key string, val interface{}) types.LogEvent {
+	if e.redactor.SensitiveName(key) && val != nil {
+		val = Mask
+	}
+	e.event = e.event.Any(key, e.redactor.Value(val))
+	return e
+}
+
+func (e *logEvent) Msg(msg string) {
+	e.event.Msg(e.redactor.String(msg))
+}
+
+func (e *logEvent) Msgf(format string, args ...interface{}) {
+	e.event.Msg(e.redactor.String(fmt.Sprintf(format, args...)))
+}
+
+type logContext struct {
+	context  types.LogContext
+	redactor *Redactor
+}
+
+func (c *logContext) Str(key, val string) types.LogContext {
+	if c.redactor.SensitiveName(key) && val != "" {
+		val = Mask
+	}
+	c.context = c.context.Str(key, c.redactor.String(val))
+	return c
+}
+
+func (c *logContext) Logger() types.Logger {
+	return &logger{base: c.context.Logger(), redactor: c.redactor}
+}
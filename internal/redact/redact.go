@@ -0,0 +1,286 @@
+// ABOUTME: Tracks sensitive values and masks them in logs, task results and history
+// ABOUTME: Values come from secret providers, sensitive variable names and secret task fields
+
+package redact
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+// Mask replaces every sensitive value
+const Mask = "********"
+
+// MinLength is the shortest value that is tracked. Masking shorter values
+// such as "1" or "no" would mangle unrelated text.
+const MinLength = 4
+
+// DefaultPatterns match the names of variables, environment variables and
+// map keys whose values are sensitive. Matching is case-insensitive.
+var DefaultPatterns = []string{
+	"*password*",
+	"*passwd*",
+	"*passphrase*",
+	"*secret*",
+	"*token*",
+	"*api_key*",
+	"*apikey*",
+	"*private_key*",
+	"*credential*",
+	"*webhook_url*",
+}
+
+// Redactor masks tracked values. A nil *Redactor masks nothing, so callers
+// do not need to check whether redaction is configured.
+type Redactor struct {
+	mu       sync.RWMutex
+	patterns []string
+	values   map[string]struct{}
+	replacer *strings.Replacer
+}
+
+// New creates a redactor matching sensitive names against patterns, which
+// are path.Match globs such as "*_password"
+func New(patterns []string) *Redactor {
+	lowered := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		lowered[i] = strings.ToLower(pattern)
+	}
+	return &Redactor{patterns: lowered, values: make(map[string]struct{})}
+}
+
+// Add tracks values so they are masked from now on. Values shorter than
+// MinLength are ignored, as are the individual lines of multi-line values
+// that are too short.
+func (r *Redactor) Add(values ...string) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changed := false
+	track := func(value string) {
+		if len(value) < MinLength {
+			return
+		}
+		if _, ok := r.values[value]; !ok {
+			r.values[value] = struct{}{}
+			changed = true
+		}
+	}
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		track(value)
+		// Multi-line secrets such as keys are often printed one line at a time
+		if strings.Contains(value, "\n") {
+			for _, line := range strings.Split(value, "\n") {
+				track(strings.TrimSpace(line))
+			}
+		}
+	}
+	if changed {
+		r.replacer = nil
+	}
+}
+
+// SensitiveName reports whether name matches one of the patterns
+func (r *Redactor) SensitiveName(name string) bool {
+	if r == nil {
+		return false
+	}
+	name = strings.ToLower(name)
+	for _, pattern := range r.patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// AddVariables tracks the values of variables with sensitive names,
+// including strings nested in lists and maps
+func (r *Redactor) AddVariables(vars map[string]interface{}) {
+	if r == nil {
+		return
+	}
+	for name, value := range vars {
+		if r.SensitiveName(name) {
+			r.Add(stringsIn(value)...)
+		} else if nested, ok := value.(map[string]interface{}); ok {
+			r.AddVariables(nested)
+		}
+	}
+}
+
+// AddEnvironment tracks the values of environment variables with sensitive names
+func (r *Redactor) AddEnvironment(env map[string]string) {
+	if r == nil {
+		return
+	}
+	for name, value := range env {
+		if r.SensitiveName(name) {
+			r.Add(value)
+		}
+	}
+}
+
+// String masks every tracked value in s
+func (r *Redactor) String(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	replacer := r.currentReplacer()
+	if replacer == nil {
+		return s
+	}
+	return replacer.Replace(s)
+}
+
+// Value returns a masked copy of v. Strings have tracked values masked, and
+// map entries whose keys are sensitive names are masked entirely.
+func (r *Redactor) Value(v interface{}) interface{} {
+	if r == nil {
+		return v
+	}
+	switch value := v.(type) {
+	case string:
+		return r.String(value)
+	case map[string]interface{}:
+		return r.Map(value)
+	case map[string]string:
+		masked := make(map[string]string, len(value))
+		for key, item := range value {
+			if r.SensitiveName(key) && item != "" {
+				masked[key] = Mask
+			} else {
+				masked[key] = r.String(item)
+			}
+		}
+		return masked
+	case []interface{}:
+		masked := make([]interface{}, len(value))
+		for i, item := range value {
+			masked[i] = r.Value(item)
+		}
+		return masked
+	case []string:
+		masked := make([]string, len(value))
+		for i, item := range value {
+			masked[i] = r.String(item)
+		}
+		return masked
+	default:
+		return v
+	}
+}
+
+// Map returns a masked copy of m, as Value does
+func (r *Redactor) Map(m map[string]interface{}) map[string]interface{} {
+	if r == nil || m == nil {
+		return m
+	}
+	masked := make(map[string]interface{}, len(m))
+	for key, item := range m {
+		if r.SensitiveName(key) && item != nil && item != "" {
+			masked[key] = Mask
+		} else {
+			masked[key] = r.Value(item)
+		}
+	}
+	return masked
+}
+
+// TaskResult masks the text fields and output of result in place
+func (r *Redactor) TaskResult(result *types.TaskResult) {
+	if r == nil || result == nil {
+		return
+	}
+	result.Message = r.String(result.Message)
+	result.Stdout = r.String(result.Stdout)
+	result.Stderr = r.String(result.Stderr)
+	result.Error = r.String(result.Error)
+	if result.Output != nil {
+		result.Output = r.Map(result.Output)
+	}
+}
+
+// currentReplacer returns a replacer for the tracked values, rebuilding it
+// after values were added. Longer values are replaced first so a value
+// containing another is masked whole.
+func (r *Redactor) currentReplacer() *strings.Replacer {
+	r.mu.RLock()
+	replacer := r.replacer
+	empty := len(r.values) == 0
+	r.mu.RUnlock()
+	if replacer != nil || empty {
+		return replacer
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.replacer == nil {
+		values := make([]string, 0, len(r.values))
+		for value := range r.values {
+			values = append(values, value)
+		}
+		sort.Slice(values, func(i, j int) bool {
+			if len(values[i]) != len(values[j]) {
+				return len(values[i]) > len(values[j])
+			}
+			return values[i] < values[j]
+		})
+		pairs := make([]string, 0, len(values)*2)
+		for _, value := range values {
+			pairs = append(pairs, value, Mask)
+		}
+		r.replacer = strings.NewReplacer(pairs...)
+	}
+	return r.replacer
+}
+
+// stringsIn returns the strings in v, looking inside lists and maps
+func stringsIn(v interface{}) []string {
+	switch value := v.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		var out []string
+		for _, item := range value {
+			out = append(out, stringsIn(item)...)
+		}
+		return out
+	case map[string]interface{}:
+		var out []string
+		for _, item := range value {
+			out = append(out, stringsIn(item)...)
+		}
+		return out
+	case int, int64, uint64, float64:
+		// Numeric passwords and PINs are printed as text
+		return []string{fmt.Sprint(value)}
+	default:
+		return nil
+	}
+}
+
+// redactorKey is the context key for the run's redactor
+type redactorKey struct{}
+
+// WithRedactor returns a context carrying r
+func WithRedactor(ctx context.Context, r *Redactor) context.Context {
+	return context.WithValue(ctx, redactorKey{}, r)
+}
+
+// FromContext returns the redactor carried by ctx, or nil
+func FromContext(ctx context.Context) *Redactor {
+	r, _ := ctx.Value(redactorKey{}).(*Redactor)
+	return r
+}
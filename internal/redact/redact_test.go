@@ -0,0 +1,148 @@
+// ABOUTME: Tests for tracking and masking sensitive values
+// ABOUTME: Covers name patterns, nested values, task results and the logger wrapper
+
+package redact
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sarlalian/ritual/pkg/types"
+	"github.com/sarlalian/ritual/pkg/utils"
+)
+
+func TestRedactor_String(t *testing.T) {
+	r := New(DefaultPatterns)
+	r.Add("hunter22", "abc", "hunter22-extended")
+
+	got := r.String("pw=hunter22 long=hunter22-extended short=abc")
+	want := "pw=" + Mask + " long=" + Mask + " short=abc"
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	var nilRedactor *Redactor
+	if nilRedactor.String("hunter22") != "hunter22" {
+		t.Error("Expected nil redactor to leave text unchanged")
+	}
+}
+
+func TestRedactor_MultiLineValues(t *testing.T) {
+	r := New(nil)
+	r.Add("-----BEGIN KEY-----\nMIIEvQIBADANBg\n-----END KEY-----\n")
+
+	if got := r.String("line: MIIEvQIBADANBg"); got != "line: "+Mask {
+		t.Errorf("Expected each line of a multi-line secret to be masked, got %q", got)
+	}
+}
+
+func TestRedactor_SensitiveNames(t *testing.T) {
+	r := New([]string{"*_password", "*token*"})
+
+	tests := map[string]bool{
+		"db_password":  true,
+		"DB_PASSWORD":  true,
+		"GITHUB_TOKEN": true,
+		"tokenizer":    true,
+		"password":     false,
+		"region":       false,
+	}
+	for name, want := range tests {
+		if got := r.SensitiveName(name); got != want {
+			t.Errorf("Expected SensitiveName(%q) to be %v, got %v", name, want, got)
+		}
+	}
+}
+
+func TestRedactor_AddVariablesAndEnvironment(t *testing.T) {
+	r := New(DefaultPatterns)
+	r.AddVariables(map[string]interface{}{
+		"db_password": "hunter22",
+		"region":      "us-east-1",
+		"service": map[string]interface{}{
+			"api_key": "key-98765",
+		},
+		"deploy_tokens": []interface{}{"tok-aaaa", "tok-bbbb"},
+		"pin_secret":    123456,
+	})
+	r.AddEnvironment(map[string]string{"GITHUB_TOKEN": "ghp_abcdef", "HOME": "/home/ritual"})
+
+	text := "hunter22 us-east-1 key-98765 tok-aaaa tok-bbbb 123456 ghp_abcdef /home/ritual"
+	want := strings.Join([]string{Mask, "us-east-1", Mask, Mask, Mask, Mask, Mask, "/home/ritual"}, " ")
+	if got := r.String(text); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestRedactor_ValueAndTaskResult(t *testing.T) {
+	r := New(DefaultPatterns)
+	r.Add("hunter22")
+
+	masked := r.Map(map[string]interface{}{
+		"password": "x",
+		"note":     "uses hunter22",
+		"list":     []interface{}{"hunter22", 3},
+		"empty":    nil,
+	})
+	if masked["password"] != Mask || masked["note"] != "uses "+Mask || masked["empty"] != nil {
+		t.Errorf("Unexpected masked map: %v", masked)
+	}
+	if list := masked["list"].([]interface{}); list[0] != Mask || list[1] != 3 {
+		t.Errorf("Unexpected masked list: %v", list)
+	}
+
+	result := &types.TaskResult{
+		Message: "login hunter22",
+		Stdout:  "hunter22\n",
+		Stderr:  "bad hunter22",
+		Error:   "hunter22 rejected",
+		Output:  map[string]interface{}{"auth_token": "t", "host": "example"},
+	}
+	r.TaskResult(result)
+	for _, field := range []string{result.Message, result.Stdout, result.Stderr, result.Error} {
+		if strings.Contains(field, "hunter22") {
+			t.Errorf("Expected task result fields to be masked, got %q", field)
+		}
+	}
+	if result.Output["auth_token"] != Mask || result.Output["host"] != "example" {
+		t.Errorf("Unexpected masked output: %v", result.Output)
+	}
+}
+
+func TestRedactor_Context(t *testing.T) {
+	if FromContext(context.Background()) != nil {
+		t.Error("Expected no redactor in an empty context")
+	}
+	r := New(nil)
+	if FromContext(WithRedactor(context.Background(), r)) != r {
+		t.Error("Expected the redactor carried by the context")
+	}
+}
+
+func TestNewLogger_MasksMessagesAndFields(t *testing.T) {
+	var buf bytes.Buffer
+	r := New(DefaultPatterns)
+	logger := NewLogger(utils.NewJSONLogger(utils.InfoLevel, &buf), r)
+
+	// Values added after the logger was created are masked too
+	r.Add("hunter22")
+
+	logger.Info().
+		Str("db_password", "short").
+		Str("note", "was hunter22").
+		Err(errors.New("auth hunter22 failed")).
+		Any("config", map[string]interface{}{"token": "t"}).
+		Msgf("connecting with %s", "hunter22")
+	logger.With().Str("user", "hunter22").Logger().Info().Msg("context field")
+
+	out := buf.String()
+	if strings.Contains(out, "hunter22") || strings.Contains(out, "short") {
+		t.Errorf("Expected sensitive values to be masked, got: %s", out)
+	}
+	if strings.Count(out, Mask) < 6 {
+		t.Errorf("Expected masks in every field, got: %s", out)
+	}
+}
@@ -0,0 +1,167 @@
+// ABOUTME: JUnit XML rendering of run reports
+// ABOUTME: Maps tasks to test cases with failures, skips and captured stdout/stderr
+
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Errors   int          `xml:"errors,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr,omitempty"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Cases      []junitCase     `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+	SystemErr string        `xml:"system-err,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
+	Body    string `xml:",chardata"`
+}
+
+// WriteJUnit renders the report as a JUnit XML document with one test suite
+// for the workflow and one test case per task. Workflow-level errors such as
+// parse or validation failures become an errored test case of their own.
+func (r *Report) WriteJUnit(w io.Writer) error {
+	suite := junitSuite{
+		Name: r.Workflow,
+		Time: seconds(r.Duration),
+		Properties: []junitProperty{
+			{Name: "status", Value: r.Status},
+		},
+	}
+	if r.Path != "" {
+		suite.Properties = append(suite.Properties, junitProperty{Name: "path", Value: r.Path})
+	}
+	if !r.StartTime.IsZero() {
+		suite.Timestamp = r.StartTime.UTC().Format("2006-01-02T15:04:05")
+	}
+
+	for _, e := range r.Errors {
+		suite.Cases = append(suite.Cases, junitCase{
+			Name:      e.Kind,
+			Classname: r.Workflow,
+			Time:      seconds(0),
+			Error:     &junitMessage{Message: firstLine(e.Message), Type: e.Kind + "_error", Body: e.Message},
+		})
+		suite.Errors++
+	}
+
+	for _, task := range r.Tasks {
+		tc := junitCase{
+			Name:      task.Name,
+			Classname: r.Workflow + "." + task.Type,
+			Time:      seconds(task.Duration),
+			SystemOut: task.Stdout,
+			SystemErr: task.Stderr,
+		}
+
+		switch task.Status {
+		case types.TaskSuccess:
+		case types.TaskWarning:
+			// JUnit has no warning outcome; keep the case passing and surface the message
+			if task.Message != "" {
+				tc.SystemOut = joinNonEmpty("warning: "+task.Message, tc.SystemOut)
+			}
+		case types.TaskFailed:
+			message := task.Error
+			if message == "" {
+				message = task.Message
+			}
+			body := message
+			if task.ReturnCode != 0 {
+				body = fmt.Sprintf("%s\nreturn code: %d", body, task.ReturnCode)
+			}
+			if task.Attempts > 1 {
+				body = fmt.Sprintf("%s\nattempts: %d", body, task.Attempts)
+			}
+			tc.Failure = &junitMessage{Message: firstLine(message), Type: task.Type, Body: body}
+			suite.Failures++
+		default:
+			tc.Skipped = &junitMessage{Message: task.Message}
+			suite.Skipped++
+		}
+
+		suite.Cases = append(suite.Cases, tc)
+	}
+	suite.Tests = len(suite.Cases)
+
+	doc := junitSuites{
+		Name:     r.Workflow,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Errors:   suite.Errors,
+		Skipped:  suite.Skipped,
+		Time:     suite.Time,
+		Suites:   []junitSuite{suite},
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
+
+func joinNonEmpty(parts ...string) string {
+	var kept []string
+	for _, p := range parts {
+		if p != "" {
+			kept = append(kept, p)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
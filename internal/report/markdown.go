@@ -0,0 +1,125 @@
+// ABOUTME: Markdown rendering of run reports for CI job summaries and PR comments
+// ABOUTME: Shows a status header, a task table and collapsible output for failed tasks
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+// maxMarkdownOutput bounds the stdout/stderr embedded per task so summaries
+// stay within CI size limits
+const maxMarkdownOutput = 4096
+
+// WriteMarkdown renders the report as GitHub-flavoured Markdown
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "## %s %s\n\n", statusIcon(r.Status), escapeMarkdown(r.Workflow))
+	fmt.Fprintf(&b, "**Status:** %s", r.Status)
+	if r.Duration > 0 {
+		fmt.Fprintf(&b, " · **Duration:** %s", r.Duration.Round(time.Millisecond))
+	}
+	b.WriteString("\n\n")
+
+	if len(r.Errors) > 0 {
+		b.WriteString("### Errors\n\n")
+		for _, e := range r.Errors {
+			fmt.Fprintf(&b, "- **%s:** %s\n", e.Kind, escapeMarkdown(e.Message))
+		}
+		b.WriteString("\n")
+	}
+
+	if len(r.Tasks) == 0 {
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	s := r.Summary
+	fmt.Fprintf(&b, "%d tasks: %d passed, %d warnings, %d failed, %d skipped, %d changed\n\n",
+		s.Total, s.Passed, s.Warnings, s.Failed, s.Skipped, s.Changed)
+
+	b.WriteString("| | Task | Type | Status | Duration | Changed |\n")
+	b.WriteString("|---|---|---|---|---|---|\n")
+	for _, task := range r.Tasks {
+		changed := ""
+		if task.Changed {
+			changed = "yes"
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s |\n",
+			taskIcon(task.Status), escapeMarkdown(task.Name), task.Type, task.Status,
+			task.Duration.Round(time.Millisecond), changed)
+	}
+
+	for _, task := range r.Tasks {
+		if task.Status != types.TaskFailed {
+			continue
+		}
+		fmt.Fprintf(&b, "\n### ❌ %s\n\n", escapeMarkdown(task.Name))
+		msg := task.Error
+		if msg == "" {
+			msg = task.Message
+		}
+		if msg != "" {
+			fmt.Fprintf(&b, "%s\n", escapeMarkdown(msg))
+		}
+		if task.ReturnCode != 0 {
+			fmt.Fprintf(&b, "\nReturn code: `%d`\n", task.ReturnCode)
+		}
+		writeOutputBlock(&b, "stdout", task.Stdout)
+		writeOutputBlock(&b, "stderr", task.Stderr)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeOutputBlock(b *strings.Builder, label, output string) {
+	if output == "" {
+		return
+	}
+	if len(output) > maxMarkdownOutput {
+		output = "…" + output[len(output)-maxMarkdownOutput:]
+	}
+	// Pick a fence longer than any backtick run in the output
+	fence := "```"
+	for strings.Contains(output, fence) {
+		fence += "`"
+	}
+	fmt.Fprintf(b, "\n<details><summary>%s</summary>\n\n%s\n%s\n%s\n\n</details>\n", label, fence, strings.TrimRight(output, "\n"), fence)
+}
+
+func statusIcon(status string) string {
+	switch types.WorkflowStatus(status) {
+	case types.WorkflowSuccess:
+		return "✅"
+	case types.WorkflowPartialSuccess:
+		return "⚠️"
+	default:
+		return "❌"
+	}
+}
+
+func taskIcon(status types.TaskStatus) string {
+	switch status {
+	case types.TaskSuccess:
+		return "✅"
+	case types.TaskWarning:
+		return "⚠️"
+	case types.TaskFailed:
+		return "❌"
+	default:
+		return "⏭️"
+	}
+}
+
+// escapeMarkdown keeps table cells and inline text from breaking the layout
+func escapeMarkdown(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(s, "\n", " ")
+}
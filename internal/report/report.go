@@ -0,0 +1,243 @@
+// ABOUTME: Run reports built from workflow results for CI systems and humans
+// ABOUTME: Normalises types.Result into one model rendered as JUnit XML, Markdown or JSON
+
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+// Format identifies a report encoding
+type Format string
+
+// Supported report formats
+const (
+	FormatJUnit    Format = "junit"
+	FormatMarkdown Format = "markdown"
+	FormatJSON     Format = "json"
+)
+
+// Report is a format-neutral summary of a workflow run, dry run or validation
+type Report struct {
+	Workflow  string        `json:"workflow"`
+	Path      string        `json:"path,omitempty"`
+	Status    string        `json:"status"`
+	StartTime time.Time     `json:"start_time,omitempty"`
+	EndTime   time.Time     `json:"end_time,omitempty"`
+	Duration  time.Duration `json:"duration"`
+	Errors    []Error       `json:"errors,omitempty"`
+	Summary   Summary       `json:"summary"`
+	Tasks     []Task        `json:"tasks"`
+}
+
+// Error is a workflow-level problem that stopped or failed the run
+type Error struct {
+	Kind    string `json:"kind"` // parse, validation, dependency or execution
+	Message string `json:"message"`
+}
+
+// Summary counts tasks by outcome
+type Summary struct {
+	Total    int `json:"total"`
+	Passed   int `json:"passed"`
+	Warnings int `json:"warnings"`
+	Failed   int `json:"failed"`
+	Skipped  int `json:"skipped"`
+	Changed  int `json:"changed"`
+}
+
+// Task is one task's outcome
+type Task struct {
+	ID         string                 `json:"id"`
+	Name       string                 `json:"name"`
+	Type       string                 `json:"type"`
+	Status     types.TaskStatus       `json:"status"`
+	Changed    bool                   `json:"changed"`
+	Message    string                 `json:"message,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	ReturnCode int                    `json:"return_code"`
+	Attempts   int                    `json:"attempts,omitempty"`
+	StartTime  time.Time              `json:"start_time,omitempty"`
+	Duration   time.Duration          `json:"duration"`
+	Stdout     string                 `json:"stdout,omitempty"`
+	Stderr     string                 `json:"stderr,omitempty"`
+	Output     map[string]interface{} `json:"output,omitempty"`
+}
+
+// New builds a report from a result. workflowPath names the report when the
+// result has no workflow result, as with parse and validation failures.
+func New(result *types.Result, workflowPath string) *Report {
+	r := &Report{
+		Workflow: strings.TrimSuffix(filepath.Base(workflowPath), filepath.Ext(workflowPath)),
+		Path:     workflowPath,
+		Tasks:    []Task{},
+	}
+	if result == nil {
+		r.Status = string(types.WorkflowFailed)
+		r.Errors = append(r.Errors, Error{Kind: "execution", Message: "no result"})
+		return r
+	}
+
+	if result.ParseError != nil {
+		r.Errors = append(r.Errors, Error{Kind: "parse", Message: result.ParseError.Error()})
+	}
+	for _, err := range result.ValidationErrors {
+		r.Errors = append(r.Errors, Error{Kind: "validation", Message: err.Error()})
+	}
+	if result.DependencyError != nil {
+		r.Errors = append(r.Errors, Error{Kind: "dependency", Message: result.DependencyError.Error()})
+	}
+	if result.ExecutionError != nil {
+		r.Errors = append(r.Errors, Error{Kind: "execution", Message: result.ExecutionError.Error()})
+	}
+
+	r.Status = string(types.WorkflowSuccess)
+	if len(r.Errors) > 0 {
+		r.Status = string(types.WorkflowFailed)
+	}
+
+	wr := result.WorkflowResult
+	if wr == nil {
+		return r
+	}
+
+	if wr.Name != "" {
+		r.Workflow = wr.Name
+	}
+	if wr.Status != "" && len(r.Errors) == 0 {
+		r.Status = string(wr.Status)
+	}
+	r.StartTime = wr.StartTime
+	r.EndTime = wr.EndTime
+	r.Duration = wr.Duration
+
+	for id, tr := range wr.Tasks {
+		task := Task{
+			ID:         id,
+			Name:       tr.Name,
+			Type:       tr.Type,
+			Status:     tr.Status,
+			Changed:    tr.Changed,
+			Message:    tr.Message,
+			Error:      tr.Error,
+			ReturnCode: tr.ReturnCode,
+			Attempts:   tr.AttemptCount,
+			StartTime:  tr.StartTime,
+			Duration:   tr.Duration,
+			Stdout:     tr.Stdout,
+			Stderr:     tr.Stderr,
+			Output:     tr.Output,
+		}
+		if task.Name == "" {
+			task.Name = id
+		}
+		r.Tasks = append(r.Tasks, task)
+
+		r.Summary.Total++
+		switch tr.Status {
+		case types.TaskSuccess:
+			r.Summary.Passed++
+		case types.TaskWarning:
+			r.Summary.Warnings++
+		case types.TaskFailed:
+			r.Summary.Failed++
+		default:
+			// Pending and running tasks never ran, so they count as skipped
+			r.Summary.Skipped++
+		}
+		if tr.Changed {
+			r.Summary.Changed++
+		}
+	}
+
+	// Tasks run in dependency order, so start time is the most readable order
+	sort.Slice(r.Tasks, func(i, j int) bool {
+		a, b := r.Tasks[i], r.Tasks[j]
+		if !a.StartTime.Equal(b.StartTime) {
+			return a.StartTime.Before(b.StartTime)
+		}
+		return a.ID < b.ID
+	})
+
+	return r
+}
+
+// Write renders the report in the given format
+func (r *Report) Write(w io.Writer, format Format) error {
+	switch format {
+	case FormatJUnit:
+		return r.WriteJUnit(w)
+	case FormatMarkdown:
+		return r.WriteMarkdown(w)
+	case FormatJSON:
+		return r.WriteJSON(w)
+	default:
+		return fmt.Errorf("unknown report format: %s", format)
+	}
+}
+
+// WriteJSON renders the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// Spec is a requested report: a format and a destination path, where "-" or
+// an empty path means stdout
+type Spec struct {
+	Format Format
+	Path   string
+}
+
+// ParseSpec parses a --report value of the form "format=path" or "format"
+func ParseSpec(value string) (Spec, error) {
+	name, path, _ := strings.Cut(value, "=")
+	spec := Spec{Format: Format(strings.ToLower(strings.TrimSpace(name))), Path: strings.TrimSpace(path)}
+
+	switch spec.Format {
+	case FormatJUnit, FormatMarkdown, FormatJSON:
+		return spec, nil
+	case "md":
+		spec.Format = FormatMarkdown
+		return spec, nil
+	case "xml":
+		spec.Format = FormatJUnit
+		return spec, nil
+	default:
+		return Spec{}, fmt.Errorf("unknown report format %q (expected junit, markdown or json)", name)
+	}
+}
+
+// WriteTo writes the report to the spec's destination, creating parent
+// directories as needed
+func (s Spec) WriteTo(r *Report, stdout io.Writer) error {
+	if s.Path == "" || s.Path == "-" {
+		return r.Write(stdout, s.Format)
+	}
+
+	if dir := filepath.Dir(s.Path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create report directory: %w", err)
+		}
+	}
+
+	file, err := os.Create(s.Path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s report: %w", s.Format, err)
+	}
+	if err := r.Write(file, s.Format); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write %s report: %w", s.Format, err)
+	}
+	return file.Close()
+}
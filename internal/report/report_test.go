@@ -0,0 +1,202 @@
+// ABOUTME: Tests for building run reports and rendering JUnit, Markdown and JSON
+// ABOUTME: Covers task status mapping, workflow-level errors and --report spec parsing
+
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+func sampleResult() *types.Result {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return &types.Result{
+		WorkflowResult: &types.WorkflowResult{
+			Name:      "release",
+			Status:    types.WorkflowFailed,
+			StartTime: start,
+			Duration:  3 * time.Second,
+			Tasks: map[string]*types.TaskResult{
+				"build": {ID: "build", Name: "Build", Type: "command", Status: types.TaskSuccess, Changed: true,
+					Stdout: "compiled <ok>\n", StartTime: start, Duration: time.Second},
+				"test": {ID: "test", Name: "Test", Type: "command", Status: types.TaskFailed, ReturnCode: 2,
+					Error: "exit status 2", Stderr: "FAIL pkg\n", AttemptCount: 3,
+					StartTime: start.Add(time.Second), Duration: time.Second},
+				"deploy": {ID: "deploy", Name: "Deploy", Type: "ssh", Status: types.TaskSkipped,
+					Message: "dependency failed", StartTime: start.Add(2 * time.Second)},
+				"lint": {ID: "lint", Name: "Lint", Type: "command", Status: types.TaskWarning,
+					Message: "2 warnings", StartTime: start.Add(time.Second)},
+			},
+		},
+	}
+}
+
+func TestNew_SummarisesTasks(t *testing.T) {
+	r := New(sampleResult(), "workflows/release.yaml")
+
+	if r.Workflow != "release" || r.Status != "failed" {
+		t.Errorf("Unexpected report header: %s %s", r.Workflow, r.Status)
+	}
+	want := Summary{Total: 4, Passed: 1, Warnings: 1, Failed: 1, Skipped: 1, Changed: 1}
+	if r.Summary != want {
+		t.Errorf("Expected summary %+v, got %+v", want, r.Summary)
+	}
+
+	var order []string
+	for _, task := range r.Tasks {
+		order = append(order, task.ID)
+	}
+	if strings.Join(order, ",") != "build,lint,test,deploy" {
+		t.Errorf("Expected tasks in start order, got %v", order)
+	}
+}
+
+func TestNew_WorkflowErrorsWithoutResult(t *testing.T) {
+	result := &types.Result{
+		ValidationErrors: []error{errors.New("task a: missing command"), errors.New("task b: unknown type")},
+	}
+	r := New(result, "ci/check.yaml")
+
+	if r.Workflow != "check" || r.Status != "failed" || len(r.Errors) != 2 {
+		t.Fatalf("Unexpected report: %+v", r)
+	}
+	if r.Errors[0].Kind != "validation" {
+		t.Errorf("Expected validation error kind, got %s", r.Errors[0].Kind)
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := New(sampleResult(), "release.yaml").WriteJUnit(&buf); err != nil {
+		t.Fatalf("WriteJUnit failed: %v", err)
+	}
+
+	var doc junitSuites
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Invalid XML: %v\n%s", err, buf.String())
+	}
+	suite := doc.Suites[0]
+	if suite.Tests != 4 || suite.Failures != 1 || suite.Skipped != 1 || suite.Errors != 0 {
+		t.Errorf("Unexpected suite counts: %+v", suite)
+	}
+
+	cases := map[string]junitCase{}
+	for _, tc := range suite.Cases {
+		cases[tc.Name] = tc
+	}
+	if cases["Build"].SystemOut != "compiled <ok>\n" || cases["Build"].Classname != "release.command" {
+		t.Errorf("Unexpected build case: %+v", cases["Build"])
+	}
+	if f := cases["Test"].Failure; f == nil || f.Message != "exit status 2" || !strings.Contains(f.Body, "return code: 2") {
+		t.Errorf("Unexpected test failure: %+v", f)
+	}
+	if cases["Test"].SystemErr != "FAIL pkg\n" {
+		t.Errorf("Expected stderr attached, got %q", cases["Test"].SystemErr)
+	}
+	if s := cases["Deploy"].Skipped; s == nil || s.Message != "dependency failed" {
+		t.Errorf("Unexpected skip: %+v", s)
+	}
+	if cases["Lint"].Failure != nil || !strings.Contains(cases["Lint"].SystemOut, "2 warnings") {
+		t.Errorf("Warnings should pass with the message in system-out: %+v", cases["Lint"])
+	}
+}
+
+func TestWriteJUnit_WorkflowErrors(t *testing.T) {
+	result := &types.Result{ParseError: errors.New("yaml: line 3: did not find expected key")}
+
+	var buf bytes.Buffer
+	if err := New(result, "broken.yaml").WriteJUnit(&buf); err != nil {
+		t.Fatalf("WriteJUnit failed: %v", err)
+	}
+
+	var doc junitSuites
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Invalid XML: %v", err)
+	}
+	if doc.Errors != 1 || doc.Suites[0].Cases[0].Error == nil || doc.Suites[0].Cases[0].Error.Type != "parse_error" {
+		t.Errorf("Expected a parse error test case, got:\n%s", buf.String())
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := New(sampleResult(), "release.yaml").WriteMarkdown(&buf); err != nil {
+		t.Fatalf("WriteMarkdown failed: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"## ❌ release",
+		"4 tasks: 1 passed, 1 warnings, 1 failed, 1 skipped, 1 changed",
+		"| ✅ | Build | command | success | 1s | yes |",
+		"### ❌ Test",
+		"Return code: `2`",
+		"<details><summary>stderr</summary>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected markdown to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	result := sampleResult()
+	result.ExecutionError = errors.New("context canceled")
+
+	var buf bytes.Buffer
+	if err := New(result, "release.yaml").WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+
+	var decoded Report
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if len(decoded.Errors) != 1 || decoded.Errors[0].Message != "context canceled" {
+		t.Errorf("Expected errors to be serialised as messages, got %+v", decoded.Errors)
+	}
+	if len(decoded.Tasks) != 4 {
+		t.Errorf("Expected 4 tasks, got %d", len(decoded.Tasks))
+	}
+}
+
+func TestParseSpec(t *testing.T) {
+	tests := map[string]Spec{
+		"junit=out/report.xml": {Format: FormatJUnit, Path: "out/report.xml"},
+		"markdown=summary.md":  {Format: FormatMarkdown, Path: "summary.md"},
+		"md":                   {Format: FormatMarkdown},
+		"json=-":               {Format: FormatJSON, Path: "-"},
+	}
+	for value, want := range tests {
+		got, err := ParseSpec(value)
+		if err != nil || got != want {
+			t.Errorf("ParseSpec(%q) = %+v, %v; want %+v", value, got, err, want)
+		}
+	}
+
+	if _, err := ParseSpec("html=out.html"); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+func TestSpecWriteTo_CreatesDirectories(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reports", "junit.xml")
+	spec := Spec{Format: FormatJUnit, Path: path}
+
+	if err := spec.WriteTo(New(sampleResult(), "release.yaml"), nil); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !bytes.HasPrefix(data, []byte("<?xml")) {
+		t.Errorf("Expected XML report at %s, got %q (%v)", path, data, err)
+	}
+}
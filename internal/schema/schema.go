@@ -0,0 +1,311 @@
+// ABOUTME: JSON Schema generation for workflow files
+// ABOUTME: Combines the workflow and task structure with the config schema of every registered task type
+
+package schema
+
+import (
+	"sort"
+
+	contextManager "github.com/sarlalian/ritual/internal/context"
+	"github.com/sarlalian/ritual/internal/inputs"
+	"github.com/sarlalian/ritual/internal/secrets"
+	"github.com/sarlalian/ritual/internal/tasks"
+	"github.com/sarlalian/ritual/internal/variables"
+	"github.com/sarlalian/ritual/internal/workflow/parser"
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+// Draft is the JSON Schema dialect of generated schemas, the newest one
+// yaml-language-server fully supports
+const Draft = "http://json-schema.org/draft-07/schema#"
+
+// object is a JSON Schema, or part of one
+type object = map[string]interface{}
+
+// Generate returns a JSON Schema for workflow files. Each task is checked
+// against the config schema of its type; task types whose executor does not
+// describe its config accept any keys.
+func Generate(registry *tasks.Registry) map[string]interface{} {
+	taskTypes := registry.GetAvailableTypes()
+	sort.Strings(taskTypes)
+
+	definitions := object{
+		"template":        object{"type": "string", "pattern": `\{\{.*\}\}`, "description": "A template rendered when the task runs"},
+		"task":            taskSchema(registry, taskTypes),
+		"variable":        variableSchema(),
+		"variable_source": variableSourceSchema(),
+	}
+	for _, taskType := range taskTypes {
+		if config, ok := registry.ConfigSchema(taskType); ok {
+			definitions["config_"+taskType] = configSchema(config)
+		}
+	}
+
+	tasksList := object{"type": "array", "items": ref("task")}
+	return object{
+		"$schema":              Draft,
+		"title":                "Ritual workflow",
+		"type":                 "object",
+		"required":             []string{"name", "tasks"},
+		"additionalProperties": false,
+		"properties": object{
+			"name":        object{"type": "string"},
+			"version":     object{"type": "string"},
+			"description": object{"type": "string"},
+			"mode":        object{"enum": []string{string(types.ParallelMode), string(types.SequentialMode)}, "default": string(types.ParallelMode)},
+			"environment": stringMap("Environment variables for every task"),
+			"imports":     object{"type": "array", "items": object{"type": "string"}, "description": "Workflows or libraries to import"},
+			"inputs": object{
+				"type":                 "object",
+				"description":          "Values expected from whoever runs the workflow",
+				"additionalProperties": inputSchema(),
+			},
+			"variable_files": object{"type": "array", "items": object{"type": "string"}, "description": "Variable files loaded in order; later files override earlier ones"},
+			"variable_merge": mergeSchema(),
+			"vars":           object{"type": "object", "additionalProperties": ref("variable")},
+			"secrets": object{
+				"type": "object",
+				"additionalProperties": object{
+					"type":     "object",
+					"required": []string{"provider"},
+					"properties": object{
+						"provider": object{"enum": secrets.Providers()},
+					},
+				},
+			},
+			"tasks":      tasksList,
+			"outputs":    object{"type": "object", "description": "Values evaluated after all tasks finish"},
+			"on_success": tasksList,
+			"on_failure": tasksList,
+		},
+		"patternProperties": object{
+			"^" + parser.ExtensionPrefix: object{"description": "Ignored by ritual; a place for anchors to merge into tasks with <<"},
+		},
+		"definitions": definitions,
+	}
+}
+
+// taskProperties are the keys every task accepts, whatever its type
+func taskProperties(taskTypes []string) object {
+	return object{
+		"id":           object{"type": "string"},
+		"name":         object{"type": "string"},
+		"type":         object{"enum": taskTypes},
+		"description":  object{"type": "string"},
+		"depends_on":   object{"type": "array", "items": object{"type": "string"}},
+		"when":         object{"type": "string", "description": "Condition that must hold for the task to run"},
+		"trigger_rule": object{"enum": []string{string(types.TriggerAllSuccess), string(types.TriggerAllDone), string(types.TriggerOneFailed), string(types.TriggerNoneFailed)}, "default": string(types.TriggerAllSuccess)},
+		"required":     object{"type": "boolean", "default": true, "description": "Whether the workflow fails when this task fails"},
+		"always_run":   object{"type": "boolean"},
+		"register":     object{"type": "string", "description": "Variable that receives the task's result"},
+		"retry_count":  object{"type": "integer", "minimum": 0},
+		"retry_delay":  object{"type": []string{"string", "integer"}, "description": "Duration such as 5s"},
+	}
+}
+
+// taskSchema is a task whose config keys are checked against the schema of
+// its type. A task without a type that has a command is a command task.
+func taskSchema(registry *tasks.Registry, taskTypes []string) object {
+	var branches []interface{}
+	for _, taskType := range taskTypes {
+		if _, ok := registry.ConfigSchema(taskType); !ok {
+			continue
+		}
+		branches = append(branches, object{
+			"if": object{
+				"required":   []string{"type"},
+				"properties": object{"type": object{"const": taskType}},
+			},
+			"then": ref("config_" + taskType),
+		})
+	}
+	if _, ok := registry.ConfigSchema("command"); ok {
+		branches = append(branches, object{
+			"if":   object{"not": object{"required": []string{"type"}}, "required": []string{"command"}},
+			"then": ref("config_command"),
+		})
+	}
+
+	return object{
+		"type":       "object",
+		"required":   []string{"name"},
+		"properties": taskProperties(taskTypes),
+		"allOf":      branches,
+	}
+}
+
+// configSchema checks the keys of a task of one type: the common task keys
+// and the fields of its config, and nothing else
+func configSchema(config *types.ConfigSchema) object {
+	properties := object{}
+	for name := range taskProperties(nil) {
+		properties[name] = true
+	}
+	var required []string
+	for _, field := range config.Fields {
+		properties[field.Name] = fieldSchema(field)
+		if field.Required {
+			required = append(required, field.Name)
+		}
+	}
+
+	result := object{
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if config.Description != "" {
+		result["description"] = config.Description
+	}
+	if len(required) > 0 {
+		result["required"] = required
+	}
+	var groups []interface{}
+	for _, group := range config.OneOf {
+		var choices []interface{}
+		for _, name := range group {
+			choices = append(choices, object{"required": []string{name}})
+		}
+		groups = append(groups, object{"oneOf": choices})
+	}
+	if len(groups) > 0 {
+		result["allOf"] = groups
+	}
+	return result
+}
+
+// fieldSchema converts a field to JSON Schema. Scalars other than strings
+// may also be templates, and strings may be given as any scalar.
+func fieldSchema(field types.FieldSchema) object {
+	var result object
+	switch field.Type {
+	case types.FieldString:
+		result = object{"type": scalarTypes()}
+	case types.FieldInteger, types.FieldNumber, types.FieldBoolean:
+		result = object{"anyOf": []interface{}{object{"type": field.Type}, ref("template")}}
+	case types.FieldDuration:
+		result = object{"type": []string{"string", "number"}}
+	case types.FieldStringList:
+		result = object{"anyOf": []interface{}{
+			object{"type": scalarTypes()},
+			object{"type": "array", "items": object{"type": scalarTypes()}},
+		}}
+	case types.FieldStringMap:
+		result = stringMap("")
+	case types.FieldList:
+		result = object{"type": "array"}
+		if field.Items != nil {
+			result["items"] = fieldSchema(*field.Items)
+		}
+	case types.FieldObject:
+		result = object{"type": "object"}
+		if len(field.Fields) > 0 {
+			properties := object{}
+			for _, nested := range field.Fields {
+				properties[nested.Name] = fieldSchema(nested)
+			}
+			result["properties"] = properties
+			result["additionalProperties"] = false
+		}
+	default:
+		result = object{}
+	}
+
+	if len(field.Enum) > 0 {
+		result = object{"anyOf": []interface{}{object{"enum": field.Enum}, ref("template")}}
+	}
+	if field.Description != "" {
+		result["description"] = field.Description
+	}
+	if field.Default != nil {
+		result["default"] = field.Default
+	}
+	return result
+}
+
+// inputSchema is the declaration of one workflow input
+func inputSchema() object {
+	return object{
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties": object{
+			"type":        object{"enum": inputs.Types, "default": inputs.String},
+			"default":     true,
+			"required":    object{"type": "boolean"},
+			"description": object{"type": "string"},
+			"values":      object{"type": "array", "description": "Allowed values of an enum input"},
+		},
+	}
+}
+
+// mergeSchema is the variable_merge section
+func mergeSchema() object {
+	lists := []string{variables.ListReplace, variables.ListAppend, variables.ListMergeByKey}
+	return object{
+		"type":                 "object",
+		"description":          "How later variable files are merged into earlier ones",
+		"additionalProperties": false,
+		"properties": object{
+			"mode":      object{"enum": []string{variables.MergeDeep, variables.MergeShallow}, "default": variables.MergeDeep},
+			"lists":     object{"enum": lists, "default": variables.ListReplace},
+			"merge_key": object{"type": "string", "default": variables.DefaultMergeKey},
+			"paths":     object{"type": "object", "additionalProperties": object{"enum": lists}},
+		},
+	}
+}
+
+// variableSchema is a vars entry: any value, checked as a source when it is
+// a map with a from_* key
+func variableSchema() object {
+	return object{
+		"if":   object{"type": "object", "anyOf": sourceKinds()},
+		"then": ref("variable_source"),
+	}
+}
+
+// variableSourceSchema is a vars entry read from a command, file, HTTP
+// endpoint or environment variable
+func variableSourceSchema() object {
+	return object{
+		"type":                 "object",
+		"additionalProperties": false,
+		"oneOf":                sourceKinds(),
+		"properties": object{
+			contextManager.FromCommand: object{"type": "string", "description": "Shell command whose output is the value"},
+			contextManager.FromFile:    object{"type": "string", "description": "File whose content is the value"},
+			contextManager.FromHTTP:    object{"type": "string", "description": "URL whose response body is the value"},
+			contextManager.FromEnv:     object{"type": "string", "description": "Environment variable holding the value"},
+			"required":                 object{"type": "boolean"},
+			"trim":                     object{"type": "boolean", "default": true},
+			"default":                  true,
+			"timeout":                  object{"type": "string", "default": contextManager.DefaultSourceTimeout.String()},
+			"cache":                    object{"type": "string", "description": "How long to reuse the value, such as 10m"},
+			"parse":                    object{"enum": []string{"json", "yaml"}},
+			"headers":                  stringMap("Request headers, for from_http"),
+		},
+	}
+}
+
+// sourceKinds matches each of the keys that make a vars entry a source
+func sourceKinds() []interface{} {
+	var kinds []interface{}
+	for _, key := range []string{contextManager.FromCommand, contextManager.FromFile, contextManager.FromHTTP, contextManager.FromEnv} {
+		kinds = append(kinds, object{"required": []string{key}})
+	}
+	return kinds
+}
+
+func ref(name string) object {
+	return object{"$ref": "#/definitions/" + name}
+}
+
+func scalarTypes() []string {
+	return []string{"string", "number", "boolean"}
+}
+
+func stringMap(description string) object {
+	result := object{"type": "object", "additionalProperties": object{"type": scalarTypes()}}
+	if description != "" {
+		result["description"] = description
+	}
+	return result
+}
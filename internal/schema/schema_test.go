@@ -0,0 +1,186 @@
+// ABOUTME: Tests for workflow JSON Schema generation
+// ABOUTME: Checks the schema covers the workflow structure, every task type and the keys the examples use
+
+package schema
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/sarlalian/ritual/internal/tasks"
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+func generate(t *testing.T) map[string]interface{} {
+	t.Helper()
+	// Round trip through JSON so the schema is checked as editors see it
+	data, err := json.Marshal(Generate(tasks.New()))
+	if err != nil {
+		t.Fatalf("Expected the schema to marshal, got: %v", err)
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+// lookup follows a path of keys through nested maps
+func lookup(t *testing.T, value interface{}, path ...string) map[string]interface{} {
+	t.Helper()
+	for _, key := range path {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			t.Fatalf("Expected an object at %s", strings.Join(path, "."))
+		}
+		value = m[key]
+	}
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected an object at %s, got %v", strings.Join(path, "."), value)
+	}
+	return m
+}
+
+// yamlKeys returns the yaml keys of a struct's fields, skipping inline and
+// ignored ones
+func yamlKeys(v interface{}) []string {
+	var keys []string
+	typ := reflect.TypeOf(v)
+	for i := 0; i < typ.NumField(); i++ {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("yaml"), ",")
+		if name != "" && name != "-" {
+			keys = append(keys, name)
+		}
+	}
+	return keys
+}
+
+func TestGenerate_CoversWorkflowAndTaskKeys(t *testing.T) {
+	schema := generate(t)
+
+	properties := lookup(t, schema, "properties")
+	for _, key := range yamlKeys(types.Workflow{}) {
+		if _, ok := properties[key]; !ok {
+			t.Errorf("Expected workflow key %s in the schema", key)
+		}
+	}
+
+	taskProperties := lookup(t, schema, "definitions", "task", "properties")
+	for _, key := range yamlKeys(types.TaskConfig{}) {
+		if _, ok := taskProperties[key]; !ok {
+			t.Errorf("Expected task key %s in the schema", key)
+		}
+	}
+
+	// Top-level extension keys hold anchors and are allowed
+	if _, ok := lookup(t, schema, "patternProperties")["^x-"]; !ok {
+		t.Error("Expected x- extension keys to be allowed at the top level")
+	}
+}
+
+func TestGenerate_CoversEveryTaskType(t *testing.T) {
+	schema := generate(t)
+	registry := tasks.New()
+
+	enum, _ := lookup(t, schema, "definitions", "task", "properties", "type")["enum"].([]interface{})
+	if len(enum) != len(registry.GetAvailableTypes()) {
+		t.Errorf("Expected %d task types in the enum, got %v", len(registry.GetAvailableTypes()), enum)
+	}
+
+	for _, taskType := range registry.GetAvailableTypes() {
+		if _, ok := registry.ConfigSchema(taskType); !ok {
+			t.Errorf("Expected task type %s to describe its config", taskType)
+			continue
+		}
+		config := lookup(t, schema, "definitions", "config_"+taskType)
+		if config["additionalProperties"] != false {
+			t.Errorf("Expected %s to reject unknown keys", taskType)
+		}
+		if _, ok := lookup(t, config, "properties")["depends_on"]; !ok {
+			t.Errorf("Expected %s to accept the common task keys", taskType)
+		}
+	}
+
+	// Aliases share the schema of the type they alias
+	command := lookup(t, schema, "definitions", "config_command")
+	shell := lookup(t, schema, "definitions", "config_shell")
+	if !reflect.DeepEqual(command, shell) {
+		t.Error("Expected the shell alias to have the command schema")
+	}
+	if _, ok := command["allOf"]; !ok {
+		t.Error("Expected command and script to be mutually exclusive")
+	}
+}
+
+func TestGenerate_FieldTypes(t *testing.T) {
+	schema := generate(t)
+	file := lookup(t, schema, "definitions", "config_file")
+
+	if required, _ := file["required"].([]interface{}); len(required) != 1 || required[0] != "path" {
+		t.Errorf("Expected path to be required, got %v", file["required"])
+	}
+	state := lookup(t, file, "properties", "state")
+	if state["default"] != "file" {
+		t.Errorf("Expected state to default to file, got %v", state["default"])
+	}
+	if enum, _ := state["anyOf"].([]interface{})[0].(map[string]interface{})["enum"].([]interface{}); len(enum) != 6 {
+		t.Errorf("Expected 6 states, got %v", enum)
+	}
+
+	// Booleans may be templates
+	backup, _ := lookup(t, file, "properties", "backup")["anyOf"].([]interface{})
+	if len(backup) != 2 || backup[1].(map[string]interface{})["$ref"] != "#/definitions/template" {
+		t.Errorf("Expected a boolean or a template, got %v", backup)
+	}
+
+	capture := lookup(t, schema, "definitions", "config_command", "properties", "capture")
+	if _, ok := lookup(t, capture, "properties")["combined"]; !ok || capture["additionalProperties"] != false {
+		t.Errorf("Expected capture to list its fields, got %v", capture)
+	}
+}
+
+// TestGenerate_ExampleTaskKeys checks every key of every task in the
+// examples is known to the schema of its type
+func TestGenerate_ExampleTaskKeys(t *testing.T) {
+	schema := generate(t)
+	files, err := filepath.Glob("../../examples/*.yaml")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("Expected example workflows, got %v", err)
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var workflow struct {
+			Tasks     []map[string]interface{} `yaml:"tasks"`
+			OnSuccess []map[string]interface{} `yaml:"on_success"`
+			OnFailure []map[string]interface{} `yaml:"on_failure"`
+		}
+		if err := yaml.Unmarshal(data, &workflow); err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+
+		all := append(append(workflow.Tasks, workflow.OnSuccess...), workflow.OnFailure...)
+		for _, task := range all {
+			taskType, _ := task["type"].(string)
+			if taskType == "" {
+				taskType = "command"
+			}
+			properties := lookup(t, schema, "definitions", "config_"+taskType, "properties")
+			for key := range task {
+				if _, ok := properties[key]; !ok {
+					t.Errorf("%s: task '%v' has key %s unknown to the %s schema", filepath.Base(file), task["name"], key, taskType)
+				}
+			}
+		}
+	}
+}
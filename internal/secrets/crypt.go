@@ -0,0 +1,303 @@
+// ABOUTME: Passphrase-based encryption for secrets and variable files at rest
+// ABOUTME: AES-256-GCM with an scrypt-derived key, armored as a header line plus base64
+
+package secrets
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// EncryptedHeader is the first line of every encrypted file
+const EncryptedHeader = "$RITUAL_ENCRYPTED;1;AES256-GCM;scrypt"
+
+// Environment variables consulted for the passphrase when none is configured
+const (
+	PassphraseEnv = "RITUAL_PASSPHRASE"
+	KeyFileEnv    = "RITUAL_KEY_FILE"
+)
+
+const (
+	saltSize  = 16
+	keySize   = 32
+	scryptN   = 1 << 15
+	scryptR   = 8
+	scryptP   = 1
+	lineWidth = 76
+)
+
+// ErrNoPassphrase is returned when no passphrase or key file is available
+var ErrNoPassphrase = fmt.Errorf("no passphrase: set %s or %s", PassphraseEnv, KeyFileEnv)
+
+// IsEncrypted reports whether data starts with the encrypted file header
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte(EncryptedHeader))
+}
+
+// Encrypt encrypts plaintext with a key derived from passphrase and returns
+// the armored result
+func Encrypt(plaintext, passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, ErrNoPassphrase
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	payload := append(append(salt, nonce...), gcm.Seal(nil, nonce, plaintext, []byte(EncryptedHeader))...)
+	encoded := base64.StdEncoding.EncodeToString(payload)
+
+	var out bytes.Buffer
+	out.WriteString(EncryptedHeader)
+	out.WriteByte('\n')
+	for len(encoded) > lineWidth {
+		out.WriteString(encoded[:lineWidth])
+		out.WriteByte('\n')
+		encoded = encoded[lineWidth:]
+	}
+	out.WriteString(encoded)
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+// Decrypt reverses Encrypt. A wrong passphrase and a tampered file are
+// indistinguishable and both fail authentication.
+func Decrypt(data, passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, ErrNoPassphrase
+	}
+
+	text := strings.TrimSpace(string(data))
+	header, body, _ := strings.Cut(text, "\n")
+	if strings.TrimSpace(header) != EncryptedHeader {
+		return nil, errors.New("not an encrypted file: missing header")
+	}
+
+	payload, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(body), ""))
+	if err != nil {
+		return nil, fmt.Errorf("malformed encrypted data: %w", err)
+	}
+	if len(payload) < saltSize {
+		return nil, errors.New("malformed encrypted data: too short")
+	}
+
+	salt := payload[:saltSize]
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	rest := payload[saltSize:]
+	if len(rest) < gcm.NonceSize() {
+		return nil, errors.New("malformed encrypted data: too short")
+	}
+
+	plaintext, err := gcm.Open(nil, rest[:gcm.NonceSize()], rest[gcm.NonceSize():], []byte(EncryptedHeader))
+	if err != nil {
+		return nil, errors.New("decryption failed: wrong passphrase or corrupted data")
+	}
+	return plaintext, nil
+}
+
+func newGCM(passphrase, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, salt, scryptN, scryptR, scryptP, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// LoadPassphrase returns the passphrase to use. An explicit key file wins,
+// then the named environment variable, then RITUAL_PASSPHRASE and
+// RITUAL_KEY_FILE. Key file contents are used with surrounding whitespace
+// trimmed.
+func LoadPassphrase(keyFile, passphraseEnv string) ([]byte, error) {
+	if keyFile != "" {
+		return readKeyFile(keyFile)
+	}
+	if passphraseEnv != "" {
+		if value := os.Getenv(passphraseEnv); value != "" {
+			return []byte(value), nil
+		}
+		return nil, fmt.Errorf("no passphrase: %s is not set", passphraseEnv)
+	}
+	if value := os.Getenv(PassphraseEnv); value != "" {
+		return []byte(value), nil
+	}
+	if path := os.Getenv(KeyFileEnv); path != "" {
+		return readKeyFile(path)
+	}
+	return nil, ErrNoPassphrase
+}
+
+func readKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	key := bytes.TrimSpace(data)
+	if len(key) == 0 {
+		return nil, fmt.Errorf("key file %s is empty", path)
+	}
+	return key, nil
+}
+
+// encryptedValuePrefix starts every value encrypted in place by a ValueCipher
+const encryptedValuePrefix = "ENC[AES256_GCM,"
+
+// IsEncryptedValue reports whether s is a single value encrypted by a ValueCipher
+func IsEncryptedValue(s string) bool {
+	return strings.HasPrefix(s, encryptedValuePrefix) && strings.HasSuffix(s, "]")
+}
+
+// ValueCipher encrypts individual values in a file whose keys stay readable,
+// in the style of sops. Each value is bound to its key path and type, so
+// values cannot be moved between keys unnoticed. Values encrypted by one
+// cipher share a salt so the key is derived only once per file.
+type ValueCipher struct {
+	passphrase []byte
+	salt       []byte
+	keys       map[string]cipher.AEAD
+	known      map[string]knownValue
+}
+
+// knownValue remembers a decrypted value so that re-encrypting it unchanged
+// returns the original ciphertext and leaves the file's diff clean
+type knownValue struct {
+	plaintext, typ, ciphertext string
+}
+
+// NewValueCipher creates a cipher using passphrase
+func NewValueCipher(passphrase []byte) *ValueCipher {
+	return &ValueCipher{
+		passphrase: passphrase,
+		keys:       make(map[string]cipher.AEAD),
+		known:      make(map[string]knownValue),
+	}
+}
+
+// EncryptValue encrypts plaintext for the value at path. typ records the
+// value's original type (str, int, float or bool) so it can be restored.
+func (c *ValueCipher) EncryptValue(plaintext, typ, path string) (string, error) {
+	if known, ok := c.known[path]; ok && known.plaintext == plaintext && known.typ == typ {
+		return known.ciphertext, nil
+	}
+	if len(c.passphrase) == 0 {
+		return "", ErrNoPassphrase
+	}
+
+	if c.salt == nil {
+		c.salt = make([]byte, saltSize)
+		if _, err := rand.Read(c.salt); err != nil {
+			return "", fmt.Errorf("failed to generate salt: %w", err)
+		}
+	}
+	gcm, err := c.gcm(c.salt)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := gcm.Seal(nil, nonce, []byte(plaintext), valueAAD(typ, path))
+	encode := base64.StdEncoding.EncodeToString
+	return fmt.Sprintf("%sdata:%s,iv:%s,salt:%s,type:%s]",
+		encryptedValuePrefix, encode(sealed), encode(nonce), encode(c.salt), typ), nil
+}
+
+// DecryptValue reverses EncryptValue, returning the plaintext and its type.
+// Later values are encrypted with the salt of the first one decrypted.
+func (c *ValueCipher) DecryptValue(value, path string) (string, string, error) {
+	if !IsEncryptedValue(value) {
+		return "", "", errors.New("not an encrypted value")
+	}
+	if len(c.passphrase) == 0 {
+		return "", "", ErrNoPassphrase
+	}
+
+	fields := make(map[string]string)
+	for _, field := range strings.Split(strings.TrimSuffix(strings.TrimPrefix(value, encryptedValuePrefix), "]"), ",") {
+		name, val, _ := strings.Cut(field, ":")
+		fields[name] = val
+	}
+	decode := func(name string) ([]byte, error) {
+		data, err := base64.StdEncoding.DecodeString(fields[name])
+		if err != nil || len(data) == 0 {
+			return nil, fmt.Errorf("malformed encrypted value: bad %s", name)
+		}
+		return data, nil
+	}
+
+	sealed, err := decode("data")
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := decode("iv")
+	if err != nil {
+		return "", "", err
+	}
+	salt, err := decode("salt")
+	if err != nil {
+		return "", "", err
+	}
+	typ := fields["type"]
+
+	gcm, err := c.gcm(salt)
+	if err != nil {
+		return "", "", err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return "", "", errors.New("malformed encrypted value: bad iv")
+	}
+	plaintext, err := gcm.Open(nil, nonce, sealed, valueAAD(typ, path))
+	if err != nil {
+		return "", "", errors.New("decryption failed: wrong passphrase or corrupted data")
+	}
+
+	if c.salt == nil {
+		c.salt = salt
+	}
+	c.known[path] = knownValue{plaintext: string(plaintext), typ: typ, ciphertext: value}
+	return string(plaintext), typ, nil
+}
+
+// gcm returns the AEAD for salt, deriving the key on first use
+func (c *ValueCipher) gcm(salt []byte) (cipher.AEAD, error) {
+	if gcm, ok := c.keys[string(salt)]; ok {
+		return gcm, nil
+	}
+	gcm, err := newGCM(c.passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	c.keys[string(salt)] = gcm
+	return gcm, nil
+}
+
+func valueAAD(typ, path string) []byte {
+	return []byte(typ + ":" + path)
+}
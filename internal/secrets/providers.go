@@ -0,0 +1,223 @@
+// ABOUTME: Built-in secret providers: environment, mounted files, external commands
+// ABOUTME: and the encrypted local secrets file
+
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/sarlalian/ritual/pkg/coerce"
+)
+
+// DefaultCommandTimeout bounds how long a command provider may run
+const DefaultCommandTimeout = 30 * time.Second
+
+// stringOption returns a string option, or fallback when it is absent
+func stringOption(ref Reference, key, fallback string) (string, error) {
+	value, ok, err := coerce.Field(ref.Options, key, coerce.String)
+	if err != nil {
+		return "", err
+	}
+	if !ok || value == "" {
+		return fallback, nil
+	}
+	return value, nil
+}
+
+// resolvePath makes a relative path relative to the workflow directory
+func resolvePath(ref Reference, path string) string {
+	if path == "" || filepath.IsAbs(path) || ref.Dir == "" {
+		return path
+	}
+	return filepath.Join(ref.Dir, path)
+}
+
+// trimNewline drops a single trailing newline, as left by editors and echo
+func trimNewline(value string) string {
+	value = strings.TrimSuffix(value, "\n")
+	return strings.TrimSuffix(value, "\r")
+}
+
+// envProvider reads an environment variable, `key` or the secret's name
+type envProvider struct{}
+
+func (p *envProvider) Resolve(ctx context.Context, ref Reference) (string, error) {
+	key, err := stringOption(ref, "key", ref.Name)
+	if err != nil {
+		return "", err
+	}
+
+	if ref.Env != nil {
+		if value, ok := ref.Env(key); ok {
+			return value, nil
+		}
+	} else if value, ok := os.LookupEnv(key); ok {
+		return value, nil
+	}
+	return "", fmt.Errorf("environment variable %s is not set", key)
+}
+
+// fileProvider reads a whole file: `path`, or `key` (default the secret's
+// name) inside `dir`, the layout of Docker and Kubernetes mounted secrets
+type fileProvider struct{}
+
+func (p *fileProvider) Resolve(ctx context.Context, ref Reference) (string, error) {
+	path, err := stringOption(ref, "path", "")
+	if err != nil {
+		return "", err
+	}
+	if path == "" {
+		dir, err := stringOption(ref, "dir", "")
+		if err != nil {
+			return "", err
+		}
+		if dir == "" {
+			return "", errors.New("either path or dir is required")
+		}
+		key, err := stringOption(ref, "key", ref.Name)
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(dir, key)
+	}
+
+	data, err := os.ReadFile(resolvePath(ref, path))
+	if err != nil {
+		return "", err
+	}
+	return trimNewline(string(data)), nil
+}
+
+// commandProvider runs `command` through the shell and uses its stdout, as
+// with `pass show deploy/key`. Stdout is never included in errors.
+type commandProvider struct{}
+
+func (p *commandProvider) Resolve(ctx context.Context, ref Reference) (string, error) {
+	command, err := stringOption(ref, "command", "")
+	if err != nil {
+		return "", err
+	}
+	if command == "" {
+		return "", errors.New("command is required")
+	}
+	timeout, ok, err := coerce.Field(ref.Options, "timeout", coerce.Duration)
+	if err != nil {
+		return "", err
+	}
+	if !ok || timeout <= 0 {
+		timeout = DefaultCommandTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = ref.Dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("command timed out after %s", timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("command failed: %w: %s", err, msg)
+		}
+		return "", fmt.Errorf("command failed: %w", err)
+	}
+	return trimNewline(stdout.String()), nil
+}
+
+// encryptedFileProvider reads `key` (default the secret's name) from a YAML
+// map encrypted with Encrypt. The passphrase comes from `key_file`, the
+// environment variable named by `passphrase_env`, or RITUAL_PASSPHRASE /
+// RITUAL_KEY_FILE. Each file is decrypted once per run.
+type encryptedFileProvider struct {
+	mu    sync.Mutex
+	files map[string]map[string]interface{}
+}
+
+func newEncryptedFileProvider() *encryptedFileProvider {
+	return &encryptedFileProvider{files: make(map[string]map[string]interface{})}
+}
+
+func (p *encryptedFileProvider) Resolve(ctx context.Context, ref Reference) (string, error) {
+	path, err := stringOption(ref, "path", "")
+	if err != nil {
+		return "", err
+	}
+	if path == "" {
+		return "", errors.New("path is required")
+	}
+	key, err := stringOption(ref, "key", ref.Name)
+	if err != nil {
+		return "", err
+	}
+
+	values, err := p.load(ref, resolvePath(ref, path))
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := values[key]
+	if !ok || value == nil {
+		return "", fmt.Errorf("key '%s' not found in %s", key, path)
+	}
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		return "", fmt.Errorf("key '%s' in %s is not a scalar value", key, path)
+	}
+	return fmt.Sprint(value), nil
+}
+
+func (p *encryptedFileProvider) load(ref Reference, path string) (map[string]interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if values, ok := p.files[path]; ok {
+		return values, nil
+	}
+
+	keyFile, err := stringOption(ref, "key_file", "")
+	if err != nil {
+		return nil, err
+	}
+	passphraseEnv, err := stringOption(ref, "passphrase_env", "")
+	if err != nil {
+		return nil, err
+	}
+	passphrase, err := LoadPassphrase(resolvePath(ref, keyFile), passphraseEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := Decrypt(data, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	values := make(map[string]interface{})
+	if err := yaml.Unmarshal(plaintext, &values); err != nil {
+		// Deliberately not wrapping the YAML error, which can quote the plaintext
+		return nil, fmt.Errorf("%s: decrypted content is not a YAML map", path)
+	}
+
+	p.files[path] = values
+	return values, nil
+}
@@ -0,0 +1,162 @@
+// ABOUTME: Secret providers and the per-run store that resolves declared secrets
+// ABOUTME: Values are fetched lazily on first use and cached in memory for the run only
+
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+// Reference is what a provider needs to fetch one secret
+type Reference struct {
+	Name    string                 // declared secret name
+	Options map[string]interface{} // provider options from the secrets section
+	Dir     string                 // workflow directory, for relative paths
+	Env     func(string) (string, bool)
+}
+
+// Provider fetches secret values from one kind of backend
+type Provider interface {
+	Resolve(ctx context.Context, ref Reference) (string, error)
+}
+
+// Factory creates a provider for a single run, so providers may cache state
+// such as a decrypted file without sharing it between runs
+type Factory func() Provider
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{
+		"env":            func() Provider { return &envProvider{} },
+		"file":           func() Provider { return &fileProvider{} },
+		"command":        func() Provider { return &commandProvider{} },
+		"encrypted_file": func() Provider { return newEncryptedFileProvider() },
+	}
+)
+
+// Register makes a provider available to the secrets section under name,
+// replacing any provider already registered with that name
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = factory
+}
+
+// Providers returns the registered provider names
+func Providers() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookupFactory(name string) (Factory, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	factory, ok := registry[name]
+	return factory, ok
+}
+
+// Store resolves the secrets declared by one workflow run. It implements
+// types.SecretResolver.
+type Store struct {
+	declared  map[string]types.SecretConfig
+	providers map[string]Provider
+	dir       string
+	env       func(string) (string, bool)
+
+	mu        sync.Mutex
+	cache     map[string]string
+	onResolve func(value string)
+}
+
+// NewStore checks that every declared secret names a registered provider.
+// Nothing is fetched until Resolve is called.
+func NewStore(declared map[string]types.SecretConfig, dir string, env func(string) (string, bool)) (*Store, error) {
+	store := &Store{
+		declared:  declared,
+		providers: make(map[string]Provider),
+		dir:       dir,
+		env:       env,
+		cache:     make(map[string]string),
+	}
+
+	for _, name := range sortedNames(declared) {
+		providerName := declared[name].Provider
+		if providerName == "" {
+			return nil, fmt.Errorf("secret '%s': provider is required", name)
+		}
+		if _, ok := store.providers[providerName]; ok {
+			continue
+		}
+		factory, ok := lookupFactory(providerName)
+		if !ok {
+			return nil, fmt.Errorf("secret '%s': unknown provider '%s' (available: %v)", name, providerName, Providers())
+		}
+		store.providers[providerName] = factory()
+	}
+
+	return store, nil
+}
+
+// Resolve returns the named secret, fetching it on first use
+func (s *Store) Resolve(name string) (string, error) {
+	config, ok := s.declared[name]
+	if !ok {
+		return "", fmt.Errorf("secret '%s' is not declared in the workflow's secrets section", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if value, ok := s.cache[name]; ok {
+		return value, nil
+	}
+
+	value, err := s.providers[config.Provider].Resolve(context.Background(), Reference{
+		Name:    name,
+		Options: config.Options,
+		Dir:     s.dir,
+		Env:     s.env,
+	})
+	if err != nil {
+		return "", fmt.Errorf("secret '%s' (%s): %w", name, config.Provider, err)
+	}
+
+	s.cache[name] = value
+	if s.onResolve != nil {
+		s.onResolve(value)
+	}
+	return value, nil
+}
+
+// OnResolve registers fn to be called with each secret value the first time
+// it is fetched, so the value can be masked wherever it later appears
+func (s *Store) OnResolve(fn func(value string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onResolve = fn
+}
+
+// Names returns the declared secret names
+func (s *Store) Names() []string {
+	return sortedNames(s.declared)
+}
+
+func sortedNames(declared map[string]types.SecretConfig) []string {
+	names := make([]string, 0, len(declared))
+	for name := range declared {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,221 @@
+// ABOUTME: Tests for secret providers, the per-run store and file encryption
+// ABOUTME: Covers env, file, command and encrypted_file providers and lazy caching
+
+package secrets
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+func TestEncryptDecrypt_RoundTrip(t *testing.T) {
+	plaintext := []byte("db_password: hunter2\n")
+
+	encrypted, err := Encrypt(plaintext, []byte("correct horse"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !IsEncrypted(encrypted) {
+		t.Errorf("Expected encrypted output to carry the header, got: %s", encrypted)
+	}
+	if strings.Contains(string(encrypted), "hunter2") {
+		t.Error("Expected ciphertext not to contain the plaintext")
+	}
+
+	decrypted, err := Decrypt(encrypted, []byte("correct horse"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if string(decrypted) != string(plaintext) {
+		t.Errorf("Expected %q, got %q", plaintext, decrypted)
+	}
+
+	if _, err := Decrypt(encrypted, []byte("wrong")); err == nil {
+		t.Error("Expected error for wrong passphrase")
+	}
+	if _, err := Decrypt([]byte("db_password: hunter2"), []byte("correct horse")); err == nil {
+		t.Error("Expected error for plaintext input")
+	}
+	if _, err := Encrypt(plaintext, nil); err == nil {
+		t.Error("Expected error for empty passphrase")
+	}
+}
+
+func TestStore_Providers(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "token"), "mounted-token\n")
+	if err := os.Mkdir(filepath.Join(dir, "run"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, "run", "api_key"), "from-dir")
+
+	encrypted, err := Encrypt([]byte("smtp: s3cret\nport: 2525\n"), []byte("pw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, "secrets.enc"), string(encrypted))
+	t.Setenv("TEST_SECRETS_PASSPHRASE", "pw")
+
+	declared := map[string]types.SecretConfig{
+		"db_password": {Provider: "env", Options: map[string]interface{}{"key": "DB_PASSWORD"}},
+		"token":       {Provider: "file", Options: map[string]interface{}{"path": "token"}},
+		"api_key":     {Provider: "file", Options: map[string]interface{}{"dir": "run"}},
+		"deploy_key":  {Provider: "command", Options: map[string]interface{}{"command": "printf 'from-command\\n'"}},
+		"smtp":        {Provider: "encrypted_file", Options: map[string]interface{}{"path": "secrets.enc", "passphrase_env": "TEST_SECRETS_PASSPHRASE"}},
+		"smtp_port":   {Provider: "encrypted_file", Options: map[string]interface{}{"path": "secrets.enc", "key": "port", "passphrase_env": "TEST_SECRETS_PASSPHRASE"}},
+	}
+	env := map[string]string{"DB_PASSWORD": "hunter2"}
+
+	store, err := NewStore(declared, dir, func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expected := map[string]string{
+		"db_password": "hunter2",
+		"token":       "mounted-token",
+		"api_key":     "from-dir",
+		"deploy_key":  "from-command",
+		"smtp":        "s3cret",
+		"smtp_port":   "2525",
+	}
+	for name, want := range expected {
+		got, err := store.Resolve(name)
+		if err != nil {
+			t.Errorf("Expected no error resolving %s, got: %v", name, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("Expected %s to be %q, got %q", name, want, got)
+		}
+	}
+
+	if names := store.Names(); len(names) != len(declared) || names[0] != "api_key" {
+		t.Errorf("Expected sorted declared names, got: %v", names)
+	}
+}
+
+func TestStore_ResolvesLazilyAndCaches(t *testing.T) {
+	dir := t.TempDir()
+	counter := filepath.Join(dir, "count")
+
+	store, err := NewStore(map[string]types.SecretConfig{
+		"counted": {Provider: "command", Options: map[string]interface{}{"command": "echo x >> " + counter + "; echo value"}},
+	}, dir, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := os.Stat(counter); !os.IsNotExist(err) {
+		t.Fatal("Expected the provider not to run before the secret is used")
+	}
+
+	for i := 0; i < 3; i++ {
+		if value, err := store.Resolve("counted"); err != nil || value != "value" {
+			t.Fatalf("Expected value, got %q (%v)", value, err)
+		}
+	}
+
+	data, err := os.ReadFile(counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runs := strings.Count(string(data), "x"); runs != 1 {
+		t.Errorf("Expected the command to run once, got %d", runs)
+	}
+}
+
+func TestStore_Errors(t *testing.T) {
+	if _, err := NewStore(map[string]types.SecretConfig{"x": {Provider: "vault"}}, "", nil); err == nil || !strings.Contains(err.Error(), "unknown provider 'vault'") {
+		t.Errorf("Expected unknown provider error, got: %v", err)
+	}
+	if _, err := NewStore(map[string]types.SecretConfig{"x": {}}, "", nil); err == nil {
+		t.Error("Expected error for missing provider")
+	}
+
+	store, err := NewStore(map[string]types.SecretConfig{
+		"missing_env": {Provider: "env"},
+		"failing":     {Provider: "command", Options: map[string]interface{}{"command": "echo leaked; echo denied >&2; exit 3"}},
+	}, "", func(string) (string, bool) { return "", false })
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if _, err := store.Resolve("undeclared"); err == nil || !strings.Contains(err.Error(), "not declared") {
+		t.Errorf("Expected not declared error, got: %v", err)
+	}
+	if _, err := store.Resolve("missing_env"); err == nil || !strings.Contains(err.Error(), "environment variable missing_env is not set") {
+		t.Errorf("Expected unset variable error, got: %v", err)
+	}
+
+	_, err = store.Resolve("failing")
+	if err == nil || !strings.Contains(err.Error(), "denied") {
+		t.Errorf("Expected stderr in command error, got: %v", err)
+	}
+	if err != nil && strings.Contains(err.Error(), "leaked") {
+		t.Errorf("Expected stdout to stay out of errors, got: %v", err)
+	}
+}
+
+type staticProvider struct{ value string }
+
+func (p *staticProvider) Resolve(ctx context.Context, ref Reference) (string, error) {
+	return p.value + ":" + ref.Name, nil
+}
+
+func TestRegister_CustomProvider(t *testing.T) {
+	Register("test-static", func() Provider { return &staticProvider{value: "static"} })
+
+	store, err := NewStore(map[string]types.SecretConfig{"name": {Provider: "test-static"}}, "", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if value, err := store.Resolve("name"); err != nil || value != "static:name" {
+		t.Errorf("Expected static:name, got %q (%v)", value, err)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValueCipher_RoundTrip(t *testing.T) {
+	c := NewValueCipher([]byte("pw"))
+
+	encrypted, err := c.EncryptValue("hunter2", "str", "db.password")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !IsEncryptedValue(encrypted) || strings.Contains(encrypted, "hunter2") {
+		t.Errorf("Expected an opaque encrypted value, got: %s", encrypted)
+	}
+
+	fresh := NewValueCipher([]byte("pw"))
+	plaintext, typ, err := fresh.DecryptValue(encrypted, "db.password")
+	if err != nil || plaintext != "hunter2" || typ != "str" {
+		t.Fatalf("Expected hunter2 (str), got %q (%s), %v", plaintext, typ, err)
+	}
+
+	// Unchanged values re-encrypt to the same ciphertext
+	again, err := fresh.EncryptValue("hunter2", "str", "db.password")
+	if err != nil || again != encrypted {
+		t.Errorf("Expected the original ciphertext to be reused, got %s (%v)", again, err)
+	}
+
+	if _, _, err := fresh.DecryptValue(encrypted, "db.user"); err == nil {
+		t.Error("Expected error when a value is moved to another key")
+	}
+	if _, _, err := NewValueCipher([]byte("wrong")).DecryptValue(encrypted, "db.password"); err == nil {
+		t.Error("Expected error for wrong passphrase")
+	}
+}
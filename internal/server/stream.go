@@ -0,0 +1,222 @@
+// ABOUTME: Per-execution event buffer and Server-Sent Events handler for live progress
+// ABOUTME: Buffers recent events with sequential IDs so clients can resume via Last-Event-ID
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sarlalian/ritual/internal/events"
+)
+
+// maxStreamEvents bounds how many events are kept per execution for replay
+const maxStreamEvents = 10000
+
+// streamRetention is how long the events of a finished execution are kept
+// for clients that connect or reconnect late
+const streamRetention = 5 * time.Minute
+
+// sseHeartbeatInterval is how often an idle stream sends a keep-alive comment
+const sseHeartbeatInterval = 15 * time.Second
+
+// streamEvent is an event with its position in the execution's stream
+type streamEvent struct {
+	id    int
+	event events.Event
+}
+
+// executionStream buffers the events of one execution and wakes waiting readers
+type executionStream struct {
+	mu      sync.Mutex
+	events  []streamEvent
+	nextID  int
+	closed  bool
+	waiters map[chan struct{}]struct{}
+}
+
+func newExecutionStream() *executionStream {
+	return &executionStream{
+		nextID:  1,
+		waiters: make(map[chan struct{}]struct{}),
+	}
+}
+
+// append adds an event, dropping the oldest once the buffer is full
+func (s *executionStream) append(event events.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	s.events = append(s.events, streamEvent{id: s.nextID, event: event})
+	s.nextID++
+	if len(s.events) > maxStreamEvents {
+		s.events = append(s.events[:0:0], s.events[len(s.events)-maxStreamEvents:]...)
+	}
+	s.notify()
+}
+
+// close marks the stream finished; readers drain what is buffered and stop
+func (s *executionStream) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	s.notify()
+}
+
+// notify wakes every waiting reader; callers hold s.mu
+func (s *executionStream) notify() {
+	for ch := range s.waiters {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// since returns buffered events after lastID, whether earlier events were
+// dropped from the buffer, and whether the stream is closed
+func (s *executionStream) since(lastID int) ([]streamEvent, bool, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var pending []streamEvent
+	for _, e := range s.events {
+		if e.id > lastID {
+			pending = append(pending, e)
+		}
+	}
+	dropped := len(s.events) > 0 && s.events[0].id > lastID+1
+	return pending, dropped, s.closed
+}
+
+// wait registers a channel that is signalled when the stream changes
+func (s *executionStream) wait() (chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	s.mu.Lock()
+	s.waiters[ch] = struct{}{}
+	s.mu.Unlock()
+
+	return ch, func() {
+		s.mu.Lock()
+		delete(s.waiters, ch)
+		s.mu.Unlock()
+	}
+}
+
+// closeStream ends an execution's stream and forgets it once the retention
+// period has passed, so a long-running server does not keep every event of
+// every execution. Callers hold ws.mu.
+func (ws *WebhookServer) closeStream(executionID string) {
+	stream, exists := ws.streams[executionID]
+	if !exists {
+		return
+	}
+	stream.close()
+
+	time.AfterFunc(ws.streamRetention, func() {
+		ws.mu.Lock()
+		defer ws.mu.Unlock()
+		if ws.streams[executionID] == stream {
+			delete(ws.streams, executionID)
+		}
+	})
+}
+
+// handleExecutionEvents streams an execution's events as Server-Sent Events.
+// Clients resume after a disconnect by sending the last ID they saw in the
+// Last-Event-ID header (or the last_event_id query parameter).
+func (ws *WebhookServer) handleExecutionEvents(w http.ResponseWriter, r *http.Request, executionID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ws.mu.RLock()
+	stream, exists := ws.streams[executionID]
+	ws.mu.RUnlock()
+
+	if !exists {
+		// The execution finished longer ago than streams are retained
+		http.Error(w, "Events of this execution are no longer available", http.StatusGone)
+		return
+	}
+
+	lastID := 0
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	if lastEventID != "" {
+		id, err := strconv.Atoi(lastEventID)
+		if err != nil || id < 0 {
+			http.Error(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		lastID = id
+	}
+
+	// Streams outlive the server's write timeout
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	changed, stop := stream.wait()
+	defer stop()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		pending, dropped, closed := stream.since(lastID)
+		if dropped {
+			_, _ = fmt.Fprint(w, ": earlier events are no longer buffered\n\n")
+		}
+		for _, e := range pending {
+			if err := writeSSE(w, e); err != nil {
+				return
+			}
+			lastID = e.id
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		if closed {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-changed:
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeSSE writes one event in Server-Sent Events framing
+func writeSSE(w http.ResponseWriter, e streamEvent) error {
+	data, err := json.Marshal(e.event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.id, e.event.Type, data)
+	return err
+}
@@ -0,0 +1,200 @@
+// ABOUTME: Tests for the Server-Sent Events stream of execution events
+// ABOUTME: Covers live delivery, Last-Event-ID replay, reconnecting after the end and eviction
+
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sarlalian/ritual/internal/events"
+)
+
+// sseEvent is one parsed Server-Sent Event
+type sseEvent struct {
+	id    int
+	event events.Event
+}
+
+// openStream connects to an execution's event stream
+func openStream(t *testing.T, url, lastEventID string) (*http.Response, *bufio.Reader) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp, bufio.NewReader(resp.Body)
+}
+
+// readEvent reads the next event, skipping comments. ok is false once the
+// server has ended the stream.
+func readEvent(t *testing.T, reader *bufio.Reader) (sseEvent, bool) {
+	t.Helper()
+	var e sseEvent
+	var data string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return e, false
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			if data == "" {
+				continue
+			}
+			if err := json.Unmarshal([]byte(data), &e.event); err != nil {
+				t.Fatalf("Invalid event data %q: %v", data, err)
+			}
+			return e, true
+		case strings.HasPrefix(line, "id: "):
+			e.id, _ = strconv.Atoi(strings.TrimPrefix(line, "id: "))
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+// readAll reads events until the server ends the stream
+func readAll(t *testing.T, reader *bufio.Reader) []sseEvent {
+	t.Helper()
+	var all []sseEvent
+	for {
+		e, ok := readEvent(t, reader)
+		if !ok {
+			return all
+		}
+		all = append(all, e)
+	}
+}
+
+// onlyExecution waits for the server's single execution and returns its ID
+func onlyExecution(t *testing.T, ws *WebhookServer) string {
+	t.Helper()
+	var executionID string
+	waitFor(t, "the execution to start", func() bool {
+		ws.mu.RLock()
+		defer ws.mu.RUnlock()
+		for id := range ws.executions {
+			executionID = id
+		}
+		return executionID != ""
+	})
+	return executionID
+}
+
+func TestExecutionEvents(t *testing.T) {
+	release := filepath.Join(t.TempDir(), "release")
+	workflow := fmt.Sprintf(`name: Stream
+tasks:
+  - id: talk
+    name: Talk
+    type: command
+    script: echo one; while [ ! -f %s ]; do sleep 0.02; done; echo two
+`, release)
+	ws, httpServer := newTestServer(t, map[string]string{"stream.yaml": workflow}, nil)
+
+	post(t, httpServer.URL+"/webhook", `{"event": "manual", "workflow": "stream.yaml"}`, nil)
+	executionID := onlyExecution(t, ws)
+	url := httpServer.URL + "/executions/" + executionID + "/events"
+
+	// Lines arrive while the task is still running
+	resp, reader := openStream(t, url, "")
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %s", resp.Header.Get("Content-Type"))
+	}
+	var live []sseEvent
+	for {
+		e, ok := readEvent(t, reader)
+		if !ok {
+			t.Fatalf("Stream ended before the first line (execution %s)", executionStatus(ws, executionID))
+		}
+		live = append(live, e)
+		if e.event.Type == events.LogLine && e.event.Line == "one" {
+			break
+		}
+	}
+	if status := executionStatus(ws, executionID); status != "running" {
+		t.Fatalf("Expected the first line while running, got status %s", status)
+	}
+
+	if err := os.WriteFile(release, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	live = append(live, readAll(t, reader)...)
+
+	last := live[len(live)-1]
+	if last.event.Type != events.WorkflowFinished {
+		t.Errorf("Expected the stream to end with %s, got %s", events.WorkflowFinished, last.event.Type)
+	}
+	var sawTwo bool
+	for i, e := range live {
+		if e.id != i+1 {
+			t.Fatalf("Expected event %d to have ID %d, got %d", i, i+1, e.id)
+		}
+		if e.event.ExecutionID != executionID {
+			t.Errorf("Expected events of %s, got %s", executionID, e.event.ExecutionID)
+		}
+		sawTwo = sawTwo || (e.event.Type == events.LogLine && e.event.Line == "two")
+	}
+	if !sawTwo {
+		t.Error("Expected the second line in the stream")
+	}
+
+	// Reconnecting after the end replays everything and ends
+	_, reader = openStream(t, url, "")
+	if replayed := readAll(t, reader); len(replayed) != len(live) {
+		t.Errorf("Expected %d replayed events, got %d", len(live), len(replayed))
+	}
+
+	// Last-Event-ID resumes after the given event
+	_, reader = openStream(t, url, "2")
+	resumed := readAll(t, reader)
+	if len(resumed) != len(live)-2 || resumed[0].id != 3 {
+		t.Errorf("Expected events from ID 3, got %d events starting at %v", len(resumed), resumed)
+	}
+
+	resp, _ = openStream(t, url, "oops")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid Last-Event-ID, got %d", resp.StatusCode)
+	}
+}
+
+func TestExecutionEvents_Evicted(t *testing.T) {
+	ws, httpServer := newTestServer(t, map[string]string{"quick.yaml": "name: Quick\ntasks:\n  - name: Hi\n    command: echo hi\n"}, nil)
+	ws.streamRetention = 50 * time.Millisecond
+
+	post(t, httpServer.URL+"/webhook?wait=true", `{"event": "manual", "workflow": "quick.yaml"}`, nil)
+	executionID := onlyExecution(t, ws)
+
+	waitFor(t, "the stream to be evicted", func() bool {
+		ws.mu.RLock()
+		defer ws.mu.RUnlock()
+		_, exists := ws.streams[executionID]
+		return !exists
+	})
+
+	resp, _ := openStream(t, httpServer.URL+"/executions/"+executionID+"/events", "")
+	if resp.StatusCode != http.StatusGone {
+		t.Errorf("Expected 410 for an evicted stream, got %d", resp.StatusCode)
+	}
+	if executionStatus(ws, executionID) != "completed" {
+		t.Errorf("Expected the execution itself to be kept, got %q", executionStatus(ws, executionID))
+	}
+}
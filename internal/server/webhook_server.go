@@ -5,16 +5,23 @@ package server
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/sarlalian/ritual/internal/approval"
+	"github.com/sarlalian/ritual/internal/events"
+	"github.com/sarlalian/ritual/internal/metrics"
 	"github.com/sarlalian/ritual/internal/orchestrator"
+	"github.com/sarlalian/ritual/internal/tracing"
 	"github.com/sarlalian/ritual/pkg/types"
 )
 
@@ -26,6 +33,18 @@ type WebhookServer struct {
 	logger       types.Logger
 	mu           sync.RWMutex
 	executions   map[string]*ExecutionStatus
+	streams      map[string]*executionStream
+	gate         *approval.Gate
+	metrics      *metrics.Collector
+	approvers    map[string]string
+
+	// streamRetention is how long a finished execution's events stay
+	// available for replay
+	streamRetention time.Duration
+
+	// waitTimeout is how long a ?wait=true request is held open before it
+	// is answered while the workflow still runs
+	waitTimeout time.Duration
 }
 
 // Config holds webhook server configuration
@@ -34,6 +53,15 @@ type Config struct {
 	WorkflowDir  string
 	Logger       types.Logger
 	Orchestrator *orchestrator.Orchestrator
+
+	// TraceExporter, when set, receives an OTLP trace for every execution
+	TraceExporter tracing.Exporter
+
+	// ApproverTokens maps bearer tokens to the approver each identifies.
+	// Approvals resolved through the API are attributed to the token's
+	// approver; without a token the caller is anonymous and may only resolve
+	// approvals that do not list approvers.
+	ApproverTokens map[string]string
 }
 
 // WebhookPayload represents an incoming webhook payload
@@ -58,6 +86,22 @@ type ExecutionStatus struct {
 	Result    *types.Result   `json:"result,omitempty"`
 	Payload   *WebhookPayload `json:"payload,omitempty"`
 	Error     string          `json:"error,omitempty"`
+
+	// Outputs holds the workflow's declared outputs once the execution ends
+	Outputs map[string]interface{} `json:"outputs,omitempty"`
+
+	// Tasks holds the latest known status of each task while the execution runs
+	Tasks map[string]types.TaskStatus `json:"tasks,omitempty"`
+
+	// PendingApprovals lists approval tasks currently waiting on a decision
+	PendingApprovals []approval.Request `json:"pending_approvals,omitempty"`
+}
+
+// ApprovalPayload is the body accepted by the approval endpoint. The
+// approver is taken from the request's bearer token, not the body.
+type ApprovalPayload struct {
+	Decision string `json:"decision"`
+	Comment  string `json:"comment,omitempty"`
 }
 
 // New creates a new webhook server
@@ -71,6 +115,25 @@ func New(config *Config) *WebhookServer {
 		workflowDir:  config.WorkflowDir,
 		logger:       config.Logger,
 		executions:   make(map[string]*ExecutionStatus),
+		streams:      make(map[string]*executionStream),
+		gate:         approval.NewGate(),
+		metrics:      metrics.NewCollector(),
+		approvers:    config.ApproverTokens,
+
+		streamRetention: streamRetention,
+		waitTimeout:     maxWait,
+	}
+
+	// Approvals for webhook-triggered executions are resolved through the API
+	ws.gate.EnableRemote()
+
+	// Track task progress and stream events for executions started by this server
+	if ws.orchestrator != nil {
+		ws.orchestrator.GetEventBus().Subscribe(events.ObserverFunc(ws.onEvent))
+		ws.orchestrator.GetEventBus().Subscribe(ws.metrics)
+		if config.TraceExporter != nil {
+			ws.orchestrator.GetEventBus().Subscribe(tracing.NewTracer(config.TraceExporter, config.Logger))
+		}
 	}
 
 	mux := http.NewServeMux()
@@ -86,6 +149,7 @@ func New(config *Config) *WebhookServer {
 	mux.HandleFunc("/executions", ws.handleExecutions)
 	mux.HandleFunc("/executions/", ws.handleExecutionDetails)
 	mux.HandleFunc("/health", ws.handleHealth)
+	mux.Handle("/metrics", ws.metrics.Handler())
 
 	ws.server = &http.Server{
 		Addr:         fmt.Sprintf(":%d", config.Port),
@@ -113,26 +177,31 @@ func (ws *WebhookServer) Stop(ctx context.Context) error {
 // handleWebhook handles generic webhook requests
 func (ws *WebhookServer) handleWebhook(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		ws.reject(w, "method_not_allowed", "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		ws.logf("Failed to read webhook body: %v", err)
-		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		ws.reject(w, "read_error", "Failed to read request body", http.StatusBadRequest)
 		return
 	}
 
 	var payload WebhookPayload
 	if err := json.Unmarshal(body, &payload); err != nil {
 		ws.logf("Failed to parse webhook payload: %v", err)
-		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		ws.reject(w, "invalid_payload", "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	if waitRequested(r) {
+		ws.respondWhenFinished(w, r, &payload)
 		return
 	}
 
 	// Execute workflow asynchronously
-	go ws.executeWorkflow(&payload)
+	go ws.executeWorkflow(&payload, r.Header.Get("traceparent"))
 
 	// Return immediate response
 	response := map[string]interface{}{
@@ -148,32 +217,32 @@ func (ws *WebhookServer) handleWebhook(w http.ResponseWriter, r *http.Request) {
 // handleGitHubWebhook handles GitHub-specific webhook events
 func (ws *WebhookServer) handleGitHubWebhook(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		ws.reject(w, "method_not_allowed", "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	eventType := r.Header.Get("X-GitHub-Event")
 	if eventType == "" {
-		http.Error(w, "Missing X-GitHub-Event header", http.StatusBadRequest)
+		ws.reject(w, "missing_event_header", "Missing X-GitHub-Event header", http.StatusBadRequest)
 		return
 	}
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		ws.logf("Failed to read GitHub webhook body: %v", err)
-		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		ws.reject(w, "read_error", "Failed to read request body", http.StatusBadRequest)
 		return
 	}
 
 	// Parse GitHub payload and convert to standard format
 	payload := ws.parseGitHubPayload(eventType, body)
 	if payload == nil {
-		http.Error(w, "Unsupported GitHub event type", http.StatusBadRequest)
+		ws.reject(w, "unsupported_event", "Unsupported GitHub event type", http.StatusBadRequest)
 		return
 	}
 
 	// Execute workflow asynchronously
-	go ws.executeWorkflow(payload)
+	go ws.executeWorkflow(payload, r.Header.Get("traceparent"))
 
 	// Return GitHub-expected response
 	w.WriteHeader(http.StatusOK)
@@ -183,32 +252,32 @@ func (ws *WebhookServer) handleGitHubWebhook(w http.ResponseWriter, r *http.Requ
 // handleGitLabWebhook handles GitLab-specific webhook events
 func (ws *WebhookServer) handleGitLabWebhook(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		ws.reject(w, "method_not_allowed", "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	eventType := r.Header.Get("X-Gitlab-Event")
 	if eventType == "" {
-		http.Error(w, "Missing X-Gitlab-Event header", http.StatusBadRequest)
+		ws.reject(w, "missing_event_header", "Missing X-Gitlab-Event header", http.StatusBadRequest)
 		return
 	}
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		ws.logf("Failed to read GitLab webhook body: %v", err)
-		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		ws.reject(w, "read_error", "Failed to read request body", http.StatusBadRequest)
 		return
 	}
 
 	// Parse GitLab payload and convert to standard format
 	payload := ws.parseGitLabPayload(eventType, body)
 	if payload == nil {
-		http.Error(w, "Unsupported GitLab event type", http.StatusBadRequest)
+		ws.reject(w, "unsupported_event", "Unsupported GitLab event type", http.StatusBadRequest)
 		return
 	}
 
 	// Execute workflow asynchronously
-	go ws.executeWorkflow(payload)
+	go ws.executeWorkflow(payload, r.Header.Get("traceparent"))
 
 	// Return GitLab-expected response
 	w.WriteHeader(http.StatusOK)
@@ -218,14 +287,14 @@ func (ws *WebhookServer) handleGitLabWebhook(w http.ResponseWriter, r *http.Requ
 // handleCustomWebhook handles custom webhook events with flexible payload
 func (ws *WebhookServer) handleCustomWebhook(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		ws.reject(w, "method_not_allowed", "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		ws.logf("Failed to read custom webhook body: %v", err)
-		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		ws.reject(w, "read_error", "Failed to read request body", http.StatusBadRequest)
 		return
 	}
 
@@ -247,8 +316,13 @@ func (ws *WebhookServer) handleCustomWebhook(w http.ResponseWriter, r *http.Requ
 		payload.Event = customEvent
 	}
 
+	if waitRequested(r) {
+		ws.respondWhenFinished(w, r, &payload)
+		return
+	}
+
 	// Execute workflow asynchronously
-	go ws.executeWorkflow(&payload)
+	go ws.executeWorkflow(&payload, r.Header.Get("traceparent"))
 
 	// Return response
 	response := map[string]interface{}{
@@ -261,6 +335,75 @@ func (ws *WebhookServer) handleCustomWebhook(w http.ResponseWriter, r *http.Requ
 	_ = json.NewEncoder(w).Encode(response)
 }
 
+// maxWait is how long a synchronous webhook request waits for its workflow
+const maxWait = 10 * time.Minute
+
+// waitRequested reports whether the caller asked, with ?wait=true, to hold
+// the request open until the workflow finishes
+func waitRequested(r *http.Request) bool {
+	wait, _ := strconv.ParseBool(r.URL.Query().Get("wait"))
+	return wait
+}
+
+// respondWhenFinished runs the workflow within the request and answers with
+// its final status and outputs. Failed executions get a 500. If the workflow
+// is still running after the server's wait timeout, the caller gets a 202
+// with the execution ID to follow up on instead.
+func (ws *WebhookServer) respondWhenFinished(w http.ResponseWriter, r *http.Request, payload *WebhookPayload) {
+	// Workflows routinely outlast the server's write timeout
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	executionID := ws.startExecution(payload)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ws.runExecution(executionID, payload, r.Header.Get("traceparent"))
+	}()
+
+	timer := time.NewTimer(ws.waitTimeout)
+	defer timer.Stop()
+
+	finished := true
+	select {
+	case <-done:
+	case <-timer.C:
+		finished = false
+	case <-r.Context().Done():
+		// The caller went away; the execution carries on
+		return
+	}
+
+	ws.mu.RLock()
+	execution := ws.executions[executionID].snapshot()
+	ws.mu.RUnlock()
+
+	response := map[string]interface{}{
+		"status":       execution.Status,
+		"event":        payload.Event,
+		"execution_id": execution.ID,
+		"outputs":      execution.Outputs,
+	}
+	if execution.Error != "" {
+		response["error"] = execution.Error
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case !finished:
+		ws.logf("Execution %s still running after %s; answering without waiting", executionID, ws.waitTimeout)
+		w.WriteHeader(http.StatusAccepted)
+	case execution.Status != "completed":
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// reject answers a webhook request with an error and counts the rejection
+func (ws *WebhookServer) reject(w http.ResponseWriter, reason, message string, code int) {
+	ws.metrics.WebhookRejected(reason)
+	http.Error(w, message, code)
+}
+
 // handleStatus returns server status information
 func (ws *WebhookServer) handleStatus(w http.ResponseWriter, r *http.Request) {
 	ws.mu.RLock()
@@ -282,7 +425,7 @@ func (ws *WebhookServer) handleExecutions(w http.ResponseWriter, r *http.Request
 	ws.mu.RLock()
 	executions := make([]*ExecutionStatus, 0, len(ws.executions))
 	for _, exec := range ws.executions {
-		executions = append(executions, exec)
+		executions = append(executions, exec.snapshot())
 	}
 	ws.mu.RUnlock()
 
@@ -294,7 +437,8 @@ func (ws *WebhookServer) handleExecutions(w http.ResponseWriter, r *http.Request
 func (ws *WebhookServer) handleExecutionDetails(w http.ResponseWriter, r *http.Request) {
 	// Extract execution ID from URL path
 	path := strings.TrimPrefix(r.URL.Path, "/executions/")
-	executionID := strings.Split(path, "/")[0]
+	parts := strings.Split(path, "/")
+	executionID := parts[0]
 
 	if executionID == "" {
 		http.Error(w, "Missing execution ID", http.StatusBadRequest)
@@ -303,6 +447,10 @@ func (ws *WebhookServer) handleExecutionDetails(w http.ResponseWriter, r *http.R
 
 	ws.mu.RLock()
 	execution, exists := ws.executions[executionID]
+	var snapshot *ExecutionStatus
+	if exists {
+		snapshot = execution.snapshot()
+	}
 	ws.mu.RUnlock()
 
 	if !exists {
@@ -310,8 +458,182 @@ func (ws *WebhookServer) handleExecutionDetails(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	if len(parts) > 1 && parts[1] == "events" {
+		ws.handleExecutionEvents(w, r, executionID)
+		return
+	}
+
+	if len(parts) > 1 && parts[1] == "approvals" {
+		taskID := ""
+		if len(parts) > 2 {
+			taskID = parts[2]
+		}
+		ws.handleApprovals(w, r, executionID, taskID)
+		return
+	}
+
+	snapshot.PendingApprovals = ws.gate.Pending(executionID)
+
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(execution)
+	_ = json.NewEncoder(w).Encode(snapshot)
+}
+
+// snapshot copies the execution so it can be encoded outside the server lock
+func (e *ExecutionStatus) snapshot() *ExecutionStatus {
+	copied := *e
+	if e.Tasks != nil {
+		copied.Tasks = make(map[string]types.TaskStatus, len(e.Tasks))
+		for id, status := range e.Tasks {
+			copied.Tasks[id] = status
+		}
+	}
+	return &copied
+}
+
+// onEvent streams events of executions started by this server and keeps their
+// task statuses current
+func (ws *WebhookServer) onEvent(event events.Event) {
+	ws.mu.RLock()
+	stream, exists := ws.streams[event.ExecutionID]
+	ws.mu.RUnlock()
+	if !exists {
+		return
+	}
+	stream.append(event)
+
+	if event.TaskID == "" {
+		return
+	}
+
+	var status types.TaskStatus
+	switch event.Type {
+	case events.TaskQueued:
+		status = types.TaskPending
+	case events.TaskStarted:
+		status = types.TaskRunning
+	case events.TaskFinished, events.TaskSkipped:
+		status = event.Status
+	default:
+		return
+	}
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	execution, exists := ws.executions[event.ExecutionID]
+	if !exists {
+		return
+	}
+	if execution.Tasks == nil {
+		execution.Tasks = make(map[string]types.TaskStatus)
+	}
+	execution.Tasks[event.TaskID] = status
+}
+
+// handleApprovals lists pending approvals for an execution (GET) or resolves
+// the approval for one task (POST /executions/{id}/approvals/{task})
+func (ws *WebhookServer) handleApprovals(w http.ResponseWriter, r *http.Request, executionID, taskID string) {
+	switch r.Method {
+	case http.MethodGet:
+		pending := ws.gate.Pending(executionID)
+		if pending == nil {
+			pending = []approval.Request{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(pending)
+
+	case http.MethodPost:
+		if taskID == "" {
+			http.Error(w, "Missing task ID", http.StatusBadRequest)
+			return
+		}
+
+		approver, ok := ws.approver(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="ritual"`)
+			http.Error(w, "Invalid approver token", http.StatusUnauthorized)
+			return
+		}
+
+		var payload ApprovalPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+
+		var approved bool
+		switch strings.ToLower(payload.Decision) {
+		case "approve", "approved":
+			approved = true
+		case "reject", "rejected":
+			approved = false
+		default:
+			http.Error(w, "Decision must be 'approve' or 'reject'", http.StatusBadRequest)
+			return
+		}
+
+		err := ws.gate.Resolve(executionID, taskID, approval.Decision{
+			Approved: approved,
+			Approver: approver,
+			Comment:  payload.Comment,
+			Source:   approval.SourceAPI,
+		})
+		switch {
+		case errors.Is(err, approval.ErrNotPending):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case errors.Is(err, approval.ErrNotAuthorized) && approver == "":
+			http.Error(w, "This approval is restricted to its approvers; authenticate with an approver token", http.StatusUnauthorized)
+			return
+		case errors.Is(err, approval.ErrNotAuthorized):
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		ws.logf("Approval for task %s in execution %s resolved by %s: %s", taskID, executionID, approverName(approver), payload.Decision)
+
+		response := map[string]interface{}{
+			"status":    "resolved",
+			"execution": executionID,
+			"task":      taskID,
+			"approved":  approved,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// approver returns the approver identified by the request's bearer token,
+// or "" for a request without one. ok is false when the token is unknown.
+func (ws *WebhookServer) approver(r *http.Request) (name string, ok bool) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return "", true
+	}
+	token, found := strings.CutPrefix(header, "Bearer ")
+	if !found {
+		return "", false
+	}
+	for known, name := range ws.approvers {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(known)) == 1 {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// approverName names an approver in logs
+func approverName(approver string) string {
+	if approver == "" {
+		return "an anonymous caller"
+	}
+	return approver
 }
 
 // handleHealth returns health status
@@ -325,8 +647,18 @@ func (ws *WebhookServer) handleHealth(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(health)
 }
 
-// executeWorkflow executes a workflow based on webhook payload
-func (ws *WebhookServer) executeWorkflow(payload *WebhookPayload) {
+// executeWorkflow executes a workflow based on webhook payload and returns
+// the execution's ID once it has finished. A non-empty traceParent makes the
+// execution's trace a child of the caller's span.
+func (ws *WebhookServer) executeWorkflow(payload *WebhookPayload, traceParent string) string {
+	executionID := ws.startExecution(payload)
+	ws.runExecution(executionID, payload, traceParent)
+	return executionID
+}
+
+// startExecution registers a running execution for payload and its event
+// stream, and returns its ID
+func (ws *WebhookServer) startExecution(payload *WebhookPayload) string {
 	executionID := fmt.Sprintf("exec_%d", time.Now().UnixNano())
 
 	execution := &ExecutionStatus{
@@ -339,36 +671,77 @@ func (ws *WebhookServer) executeWorkflow(payload *WebhookPayload) {
 	// Register execution
 	ws.mu.Lock()
 	ws.executions[executionID] = execution
+	ws.streams[executionID] = newExecutionStream()
 	ws.mu.Unlock()
 
 	ws.logf("Starting workflow execution %s for event %s", executionID, payload.Event)
+	return executionID
+}
+
+// runExecution runs the workflow of a registered execution and records how
+// it finished
+func (ws *WebhookServer) runExecution(executionID string, payload *WebhookPayload, traceParent string) {
+	ws.mu.RLock()
+	execution := ws.executions[executionID]
+	ws.mu.RUnlock()
 
 	// Determine workflow file
 	workflowFile := ws.determineWorkflowFile(payload)
 	if workflowFile == "" {
+		ws.metrics.WebhookRejected("no_workflow")
 		ws.finishExecution(executionID, "failed", fmt.Errorf("no workflow file determined for event %s", payload.Event))
 		return
 	}
 
+	ws.mu.Lock()
 	execution.Workflow = workflowFile
+	ws.mu.Unlock()
 
 	// Build environment variables from payload
 	envVars := ws.buildEnvironmentVars(payload)
 
-	// Execute workflow
-	ctx := context.Background()
+	// Execute workflow, letting approval tasks be addressed by this execution ID
+	ctx := types.WithExecutionID(approval.WithGate(context.Background(), ws.gate), executionID)
+	if traceParent != "" {
+		ctx = types.WithTraceParent(ctx, traceParent)
+	}
+	// The payload's variables fill the workflow's declared inputs
+	ctx = types.WithInputs(ctx, payload.Variables)
 	result, err := ws.orchestrator.ExecuteWorkflowFile(ctx, workflowFile, envVars)
 
+	if result != nil && result.WorkflowResult != nil {
+		ws.mu.Lock()
+		execution.Outputs = result.WorkflowResult.Outputs
+		ws.mu.Unlock()
+	}
+
 	// Update execution status
 	if err != nil {
 		ws.finishExecution(executionID, "failed", err)
-	} else if result.WorkflowResult != nil && result.WorkflowResult.Status == types.WorkflowFailed {
-		ws.finishExecution(executionID, "failed", fmt.Errorf("workflow failed"))
+	} else if resultErr := resultError(result); resultErr != nil {
+		ws.finishExecution(executionID, "failed", resultErr)
 	} else {
 		ws.finishExecutionSuccess(executionID, result)
 	}
 }
 
+// resultError returns why a workflow did not run to success, or nil if it did
+func resultError(result *types.Result) error {
+	switch {
+	case result.ParseError != nil:
+		return result.ParseError
+	case len(result.ValidationErrors) > 0:
+		return errors.Join(result.ValidationErrors...)
+	case result.DependencyError != nil:
+		return result.DependencyError
+	case result.ExecutionError != nil:
+		return result.ExecutionError
+	case result.WorkflowResult != nil && result.WorkflowResult.Status == types.WorkflowFailed:
+		return fmt.Errorf("workflow failed")
+	}
+	return nil
+}
+
 // determineWorkflowFile determines which workflow file to execute based on payload
 func (ws *WebhookServer) determineWorkflowFile(payload *WebhookPayload) string {
 	// If workflow is explicitly specified
@@ -445,6 +818,8 @@ func (ws *WebhookServer) finishExecution(executionID, status string, err error)
 			ws.logf("Execution %s failed: %v", executionID, err)
 		}
 	}
+
+	ws.closeStream(executionID)
 }
 
 // finishExecutionSuccess marks an execution as successfully completed
@@ -463,6 +838,8 @@ func (ws *WebhookServer) finishExecutionSuccess(executionID string, result *type
 
 		ws.logf("Execution %s completed successfully", executionID)
 	}
+
+	ws.closeStream(executionID)
 }
 
 // parseGitHubPayload parses GitHub webhook payload
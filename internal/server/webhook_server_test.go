@@ -0,0 +1,303 @@
+// ABOUTME: Tests for the webhook server's HTTP API
+// ABOUTME: Drives executions through httptest and checks approvals, streams and synchronous responses
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sarlalian/ritual/internal/approval"
+	"github.com/sarlalian/ritual/internal/orchestrator"
+	"github.com/sarlalian/ritual/internal/tracing"
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+// newTestServer serves the given workflows, by file name, from a temporary
+// directory. configure may adjust the server's config before it is created.
+func newTestServer(t *testing.T, workflows map[string]string, configure func(*Config)) (*WebhookServer, *httptest.Server) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range workflows {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write workflow: %v", err)
+		}
+	}
+
+	orch, err := orchestrator.New(&orchestrator.Config{
+		MaxConcurrency: types.DefaultConcurrency,
+		HistoryDir:     t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create orchestrator: %v", err)
+	}
+
+	config := &Config{WorkflowDir: dir, Orchestrator: orch}
+	if configure != nil {
+		configure(config)
+	}
+	ws := New(config)
+	httpServer := httptest.NewServer(ws.server.Handler)
+	t.Cleanup(httpServer.Close)
+	return ws, httpServer
+}
+
+// waitFor polls cond until it holds or the test times out
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// executionStatus returns the status of an execution, or "" if unknown
+func executionStatus(ws *WebhookServer, executionID string) string {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+	if execution, exists := ws.executions[executionID]; exists {
+		return execution.Status
+	}
+	return ""
+}
+
+// post sends a JSON body and returns the response with its body read
+func post(t *testing.T, url, body string, header http.Header) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request to %s failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp, string(data)
+}
+
+const approvalWorkflow = `name: Approve
+tasks:
+  - id: gate
+    name: Gate
+    type: approval
+    message: Ship it?
+    approvers: [alice]
+`
+
+func TestHandleApprovals(t *testing.T) {
+	ws, httpServer := newTestServer(t, map[string]string{"approve.yaml": approvalWorkflow}, func(c *Config) {
+		c.ApproverTokens = map[string]string{"alice-token": "alice", "bob-token": "bob"}
+	})
+
+	resp, _ := post(t, httpServer.URL+"/webhook", `{"event": "manual", "workflow": "approve.yaml"}`, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected the webhook to be accepted, got %d", resp.StatusCode)
+	}
+
+	var pending []approval.Request
+	waitFor(t, "the approval to be pending", func() bool {
+		pending = ws.gate.Pending("")
+		return len(pending) == 1
+	})
+	executionID := pending[0].ExecutionID
+	url := httpServer.URL + "/executions/" + executionID + "/approvals/gate"
+
+	// Listing needs no token
+	listResp, err := http.Get(httpServer.URL + "/executions/" + executionID + "/approvals")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var listed []approval.Request
+	_ = json.NewDecoder(listResp.Body).Decode(&listed)
+	listResp.Body.Close()
+	if len(listed) != 1 || listed[0].TaskID != "gate" {
+		t.Errorf("Expected the pending approval to be listed, got %v", listed)
+	}
+
+	tests := []struct {
+		name   string
+		header http.Header
+		body   string
+		status int
+	}{
+		{"anonymous caller", nil, `{"decision": "approve"}`, http.StatusUnauthorized},
+		{"unknown token", http.Header{"Authorization": {"Bearer nope"}}, `{"decision": "approve"}`, http.StatusUnauthorized},
+		{"claimed approver is ignored", http.Header{"Authorization": {"Bearer bob-token"}}, `{"decision": "approve", "approver": "alice"}`, http.StatusForbidden},
+		{"bad decision", http.Header{"Authorization": {"Bearer alice-token"}}, `{"decision": "maybe"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		resp, body := post(t, url, tt.body, tt.header)
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.name, tt.status, resp.StatusCode, body)
+		}
+	}
+	if executionStatus(ws, executionID) != "running" {
+		t.Fatalf("Expected the execution to still wait, got %s", executionStatus(ws, executionID))
+	}
+
+	resp, body := post(t, url, `{"decision": "approve", "comment": "ok"}`, http.Header{"Authorization": {"Bearer alice-token"}})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected alice's approval to be accepted, got %d: %s", resp.StatusCode, body)
+	}
+
+	waitFor(t, "the execution to finish", func() bool { return executionStatus(ws, executionID) == "completed" })
+	ws.mu.RLock()
+	output := ws.executions[executionID].Result.WorkflowResult.Tasks["gate"].Output
+	ws.mu.RUnlock()
+	if output["approver"] != "alice" || output["decision"] != "approved" || output["source"] != approval.SourceAPI {
+		t.Errorf("Expected approval by alice through the API, got %v", output)
+	}
+
+	// The approval is no longer pending
+	resp, _ = post(t, url, `{"decision": "approve"}`, http.Header{"Authorization": {"Bearer alice-token"}})
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected a resolved approval to be gone, got %d", resp.StatusCode)
+	}
+}
+
+func TestHandleWebhook_Wait(t *testing.T) {
+	release := filepath.Join(t.TempDir(), "release")
+	workflows := map[string]string{
+		"ok.yaml": `name: Ok
+tasks:
+  - id: build
+    name: Build
+    command: echo 1.4.0
+outputs:
+  version: "{{ .tasks.build.Stdout | trim }}"
+`,
+		"fail.yaml": `name: Fail
+tasks:
+  - id: boom
+    name: Boom
+    command: "false"
+`,
+		"slow.yaml": `name: Slow
+tasks:
+  - id: hold
+    name: Hold
+    type: command
+    script: while [ ! -f ` + release + ` ]; do sleep 0.02; done
+`,
+	}
+	ws, httpServer := newTestServer(t, workflows, nil)
+	ws.waitTimeout = 200 * time.Millisecond
+	t.Cleanup(func() { _ = os.WriteFile(release, nil, 0644) })
+
+	tests := []struct {
+		workflow string
+		status   int
+		want     string
+		outputs  map[string]interface{}
+	}{
+		{"ok.yaml", http.StatusOK, "completed", map[string]interface{}{"version": "1.4.0"}},
+		{"fail.yaml", http.StatusInternalServerError, "failed", nil},
+		{"slow.yaml", http.StatusAccepted, "running", nil},
+	}
+	for _, tt := range tests {
+		resp, body := post(t, httpServer.URL+"/webhook?wait=true", `{"event": "manual", "workflow": "`+tt.workflow+`"}`, nil)
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.workflow, tt.status, resp.StatusCode, body)
+			continue
+		}
+
+		var response struct {
+			Status      string                 `json:"status"`
+			ExecutionID string                 `json:"execution_id"`
+			Outputs     map[string]interface{} `json:"outputs"`
+			Error       string                 `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(body), &response); err != nil {
+			t.Fatalf("%s: invalid response %q: %v", tt.workflow, body, err)
+		}
+		if response.Status != tt.want || response.ExecutionID == "" {
+			t.Errorf("%s: expected status %s with an execution ID, got %+v", tt.workflow, tt.want, response)
+		}
+		if tt.outputs != nil && response.Outputs["version"] != tt.outputs["version"] {
+			t.Errorf("%s: expected outputs %v, got %v", tt.workflow, tt.outputs, response.Outputs)
+		}
+		if tt.want == "failed" && response.Error == "" {
+			t.Errorf("%s: expected the failure to be explained", tt.workflow)
+		}
+	}
+
+	// The execution that outlasted the wait still finishes
+	if err := os.WriteFile(release, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the slow execution to finish", func() bool {
+		ws.mu.RLock()
+		defer ws.mu.RUnlock()
+		for _, execution := range ws.executions {
+			if strings.HasSuffix(execution.Workflow, "slow.yaml") {
+				return execution.Status == "completed"
+			}
+		}
+		return false
+	})
+}
+
+// recordingExporter keeps the traces it is given
+type recordingExporter struct {
+	mu       sync.Mutex
+	requests []*tracing.ExportRequest
+}
+
+func (e *recordingExporter) Export(ctx context.Context, request *tracing.ExportRequest) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.requests = append(e.requests, request)
+	return nil
+}
+
+func TestHandleWebhook_TraceParent(t *testing.T) {
+	exporter := &recordingExporter{}
+	_, httpServer := newTestServer(t, map[string]string{"quick.yaml": "name: Quick\ntasks:\n  - name: Hi\n    command: echo hi\n"}, func(c *Config) {
+		c.TraceExporter = exporter
+	})
+
+	header := http.Header{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}}
+	if resp, body := post(t, httpServer.URL+"/webhook?wait=true", `{"event": "manual", "workflow": "quick.yaml"}`, header); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected the run to succeed, got %d: %s", resp.StatusCode, body)
+	}
+
+	var spans []tracing.Span
+	waitFor(t, "the trace to be exported", func() bool {
+		exporter.mu.Lock()
+		defer exporter.mu.Unlock()
+		if len(exporter.requests) == 0 {
+			return false
+		}
+		spans = exporter.requests[0].ResourceSpans[0].ScopeSpans[0].Spans
+		return true
+	})
+
+	if len(spans) != 2 {
+		t.Fatalf("Expected a root and a task span, got %d spans", len(spans))
+	}
+	root, task := spans[0], spans[1]
+	if root.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || root.ParentSpanID != "00f067aa0ba902b7" {
+		t.Errorf("Expected the root span to continue the caller's trace, got trace %s parent %s", root.TraceID, root.ParentSpanID)
+	}
+	if task.TraceID != root.TraceID || task.ParentSpanID != root.SpanID {
+		t.Errorf("Expected the task span to be a child of the root span, got trace %s parent %s", task.TraceID, task.ParentSpanID)
+	}
+}
@@ -0,0 +1,254 @@
+// ABOUTME: Approval task executor that pauses its branch of the workflow until a human signs off
+// ABOUTME: Waits on the execution's approval gate with an optional timeout and default action
+
+package approval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	approvalGate "github.com/sarlalian/ritual/internal/approval"
+	"github.com/sarlalian/ritual/pkg/coerce"
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+// Default actions applied when an approval times out
+const (
+	ActionApprove = "approve"
+	ActionReject  = "reject"
+)
+
+// Executor handles approval task execution
+type Executor struct{}
+
+// ApprovalConfig represents the configuration for an approval task
+type ApprovalConfig struct {
+	// Message shown to the approver
+	Message string `yaml:"message,omitempty" json:"message,omitempty"`
+
+	// Timeout after which the default action is applied (e.g. "30m"); empty waits indefinitely
+	Timeout string `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+
+	// DefaultAction applied on timeout: "approve" or "reject" (default)
+	DefaultAction string `yaml:"default_action,omitempty" json:"default_action,omitempty"`
+
+	// Approvers restricts who may resolve the approval; empty allows anyone
+	Approvers []string `yaml:"approvers,omitempty" json:"approvers,omitempty"`
+}
+
+// New creates a new approval executor
+func New() *Executor {
+	return &Executor{}
+}
+
+// Execute waits for an approval decision
+func (e *Executor) Execute(ctx context.Context, task *types.TaskConfig, contextManager types.ContextManager) *types.TaskResult {
+	result := &types.TaskResult{
+		ID:        task.ID,
+		Name:      task.Name,
+		Type:      task.Type,
+		StartTime: time.Now(),
+		Status:    types.TaskRunning,
+		Output:    make(map[string]interface{}),
+	}
+
+	finish := func(status types.TaskStatus, message string) *types.TaskResult {
+		result.Status = status
+		result.Message = message
+		result.EndTime = time.Now()
+		result.Duration = result.EndTime.Sub(result.StartTime)
+		return result
+	}
+
+	config, err := e.parseConfig(task, contextManager)
+	if err != nil {
+		return finish(types.TaskFailed, fmt.Sprintf("Failed to parse configuration: %v", err))
+	}
+
+	gate := approvalGate.GateFromContext(ctx)
+	if gate == nil {
+		return finish(types.TaskFailed, "Approval gates are not available in this execution")
+	}
+
+	var timeout time.Duration
+	if config.Timeout != "" {
+		timeout, _ = time.ParseDuration(config.Timeout) // validated in parseConfig
+	}
+
+	if !gate.CanResolve() && timeout == 0 {
+		return finish(types.TaskFailed, "Approval cannot be obtained: not attached to a terminal and no approval API is available (set a timeout and default_action to run unattended)")
+	}
+
+	request := approvalGate.Request{
+		ExecutionID: types.ExecutionIDFromContext(ctx),
+		TaskID:      task.ID,
+		TaskName:    task.Name,
+		Message:     config.Message,
+		Approvers:   config.Approvers,
+		RequestedAt: result.StartTime,
+	}
+
+	waitCtx := ctx
+	if timeout > 0 {
+		deadline := result.StartTime.Add(timeout)
+		request.Deadline = &deadline
+
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+
+	decision, err := gate.Wait(waitCtx, request)
+	if err != nil {
+		if !errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil {
+			return finish(types.TaskFailed, fmt.Sprintf("Approval was cancelled: %v", err))
+		}
+		decision = approvalGate.Decision{
+			Approved:  config.DefaultAction == ActionApprove,
+			Source:    approvalGate.SourceTimeout,
+			DecidedAt: time.Now(),
+		}
+	}
+
+	outcome, verb := "rejected", "Rejected"
+	if decision.Approved {
+		outcome, verb = "approved", "Approved"
+	}
+
+	result.Output["decision"] = outcome
+	result.Output["approver"] = decision.Approver
+	result.Output["comment"] = decision.Comment
+	result.Output["source"] = decision.Source
+	result.Output["requested_at"] = request.RequestedAt.Format(time.RFC3339)
+	result.Output["decided_at"] = decision.DecidedAt.Format(time.RFC3339)
+	result.Output["message"] = config.Message
+
+	var message string
+	if decision.Source == approvalGate.SourceTimeout {
+		message = fmt.Sprintf("Approval timed out after %s; default action '%s' applied", timeout, config.DefaultAction)
+	} else {
+		message = fmt.Sprintf("%s by %s", verb, decision.Approver)
+		if decision.Comment != "" {
+			message += ": " + decision.Comment
+		}
+	}
+
+	if decision.Approved {
+		return finish(types.TaskSuccess, message)
+	}
+	return finish(types.TaskFailed, message)
+}
+
+// Validate checks if the task configuration is valid
+func (e *Executor) Validate(task *types.TaskConfig) error {
+	config, err := e.parseConfigRaw(task.Config)
+	if err != nil {
+		return fmt.Errorf("invalid approval configuration: %w", err)
+	}
+
+	return validateConfig(config)
+}
+
+// SupportsDryRun indicates if this executor supports dry-run mode
+func (e *Executor) SupportsDryRun() bool {
+	return true
+}
+
+// ConfigSchema describes the fields of an approval task
+func (e *Executor) ConfigSchema() *types.ConfigSchema {
+	return &types.ConfigSchema{
+		Description: "Wait for a person to approve or reject before dependents run",
+		Fields: []types.FieldSchema{
+			{Name: "message", Type: types.FieldString, Description: "Shown to the approver"},
+			{Name: "timeout", Type: types.FieldDuration, Description: "Apply default_action after this long; unset waits indefinitely"},
+			{Name: "default_action", Type: types.FieldString, Default: ActionReject, Enum: []string{ActionApprove, ActionReject}},
+			{Name: "approvers", Type: types.FieldStringList, Description: "Who may resolve the approval; unset allows anyone"},
+		},
+	}
+}
+
+// OutputFields lists the keys this task may set in its result's Output map
+func (e *Executor) OutputFields() []string {
+	return []string{"approver", "comment", "decided_at", "decision", "message", "requested_at", "source"}
+}
+
+// parseConfig parses the task configuration and renders the message template
+func (e *Executor) parseConfig(task *types.TaskConfig, contextManager types.ContextManager) (*ApprovalConfig, error) {
+	config, err := e.parseConfigRaw(task.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateConfig(config); err != nil {
+		return nil, err
+	}
+
+	if config.Message != "" {
+		message, err := contextManager.EvaluateString(config.Message)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate message: %w", err)
+		}
+		config.Message = message
+	}
+
+	if config.DefaultAction == "" {
+		config.DefaultAction = ActionReject
+	}
+
+	return config, nil
+}
+
+// parseConfigRaw parses the configuration without template evaluation
+func (e *Executor) parseConfigRaw(configMap map[string]interface{}) (*ApprovalConfig, error) {
+	config := &ApprovalConfig{}
+
+	var err error
+	for key, value := range configMap {
+		if value == nil {
+			continue
+		}
+
+		switch key {
+		case "message":
+			config.Message, err = coerce.String(value)
+		case "timeout":
+			config.Timeout, err = coerce.String(value)
+		case "default_action":
+			config.DefaultAction, err = coerce.String(value)
+		case "approvers":
+			config.Approvers, err = coerce.StringSlice(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+
+	return config, nil
+}
+
+// validateConfig checks field values shared by Validate and Execute
+func validateConfig(config *ApprovalConfig) error {
+	if config.Timeout != "" {
+		timeout, err := time.ParseDuration(config.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid timeout format: %w", err)
+		}
+		if timeout <= 0 {
+			return fmt.Errorf("timeout must be positive")
+		}
+	}
+
+	switch config.DefaultAction {
+	case "", ActionApprove, ActionReject:
+	default:
+		return fmt.Errorf("default_action must be '%s' or '%s'", ActionApprove, ActionReject)
+	}
+
+	if config.DefaultAction != "" && config.Timeout == "" {
+		return fmt.Errorf("default_action requires a timeout")
+	}
+
+	return nil
+}
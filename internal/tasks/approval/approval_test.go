@@ -0,0 +1,186 @@
+// ABOUTME: Tests for the approval task executor and its gate interaction
+// ABOUTME: Validates configuration parsing, API decisions and timeout default actions
+
+package approval
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	approvalGate "github.com/sarlalian/ritual/internal/approval"
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+// MockContextManager for testing
+type MockContextManager struct{}
+
+func (m *MockContextManager) EvaluateMap(data map[string]interface{}) (map[string]interface{}, error) {
+	return data, nil
+}
+func (m *MockContextManager) EvaluateString(templateStr string) (string, error) {
+	return templateStr, nil
+}
+func (m *MockContextManager) Initialize(workflow *types.Workflow, envVars []string) error { return nil }
+func (m *MockContextManager) GetContext() *types.WorkflowContext                          { return nil }
+func (m *MockContextManager) GetVariable(name string) (interface{}, error)                { return nil, nil }
+func (m *MockContextManager) SetVariable(name string, value interface{}) error            { return nil }
+func (m *MockContextManager) GetEnvironment(name, defaultValue string) string             { return defaultValue }
+func (m *MockContextManager) SetEnvironment(name, value string) error                     { return nil }
+func (m *MockContextManager) RegisterTaskResult(taskResult *types.TaskResult) error       { return nil }
+func (m *MockContextManager) GetTaskResult(identifier string) (*types.TaskResult, error) {
+	return nil, nil
+}
+func (m *MockContextManager) GetTemplateEngine() types.TemplateEngine { return nil }
+func (m *MockContextManager) Clone() types.ContextManager             { return &MockContextManager{} }
+
+func approvalTask(config map[string]interface{}) *types.TaskConfig {
+	return &types.TaskConfig{ID: "promote", Name: "Promote", Type: "approval", Config: config}
+}
+
+func TestExecutor_Validate(t *testing.T) {
+	executor := New()
+
+	valid := []map[string]interface{}{
+		{},
+		{"message": "Promote?", "approvers": []interface{}{"alice", "bob"}},
+		{"timeout": "30m", "default_action": "approve"},
+		{"timeout": "1h"},
+		// Scalars are coerced: a single approver is a one-item list
+		{"approvers": "alice"},
+		{"message": 42},
+	}
+	for _, config := range valid {
+		if err := executor.Validate(approvalTask(config)); err != nil {
+			t.Errorf("Expected config %v to be valid, got %v", config, err)
+		}
+	}
+
+	invalid := []map[string]interface{}{
+		{"timeout": "soon"},
+		{"timeout": "-5m"},
+		{"timeout": "5m", "default_action": "maybe"},
+		{"default_action": "approve"},
+		{"approvers": map[string]interface{}{"alice": true}},
+		{"message": []interface{}{"a", "b"}},
+	}
+	for _, config := range invalid {
+		if err := executor.Validate(approvalTask(config)); err == nil {
+			t.Errorf("Expected config %v to be invalid", config)
+		}
+	}
+}
+
+func TestExecutor_FailsWithoutGate(t *testing.T) {
+	result := New().Execute(context.Background(), approvalTask(nil), &MockContextManager{})
+	if result.Status != types.TaskFailed {
+		t.Errorf("Expected failure without a gate, got %s", result.Status)
+	}
+}
+
+func TestExecutor_FailsWhenNothingCanResolve(t *testing.T) {
+	ctx := approvalGate.WithGate(context.Background(), approvalGate.NewGate())
+
+	result := New().Execute(ctx, approvalTask(nil), &MockContextManager{})
+	if result.Status != types.TaskFailed {
+		t.Errorf("Expected failure when no prompter or API is available, got %s", result.Status)
+	}
+}
+
+func TestExecutor_ResolvedThroughGate(t *testing.T) {
+	tests := []struct {
+		name     string
+		approved bool
+		status   types.TaskStatus
+		decision string
+	}{
+		{"approved", true, types.TaskSuccess, "approved"},
+		{"rejected", false, types.TaskFailed, "rejected"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gate := approvalGate.NewGate()
+			gate.EnableRemote()
+			ctx := types.WithExecutionID(approvalGate.WithGate(context.Background(), gate), "exec_1")
+
+			done := make(chan *types.TaskResult, 1)
+			go func() {
+				done <- New().Execute(ctx, approvalTask(map[string]interface{}{"message": "Promote?"}), &MockContextManager{})
+			}()
+
+			deadline := time.Now().Add(2 * time.Second)
+			for len(gate.Pending("exec_1")) == 0 {
+				if time.Now().After(deadline) {
+					t.Fatal("approval never became pending")
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+
+			err := gate.Resolve("exec_1", "promote", approvalGate.Decision{
+				Approved: tt.approved,
+				Approver: "alice",
+				Comment:  "checked staging",
+				Source:   approvalGate.SourceAPI,
+			})
+			if err != nil {
+				t.Fatalf("Resolve failed: %v", err)
+			}
+
+			result := <-done
+			if result.Status != tt.status {
+				t.Errorf("Expected status %s, got %s: %s", tt.status, result.Status, result.Message)
+			}
+			if result.Output["decision"] != tt.decision {
+				t.Errorf("Expected decision %q, got %v", tt.decision, result.Output["decision"])
+			}
+			if result.Output["approver"] != "alice" || result.Output["comment"] != "checked staging" {
+				t.Errorf("Expected approver and comment in output, got %v", result.Output)
+			}
+			if result.Output["source"] != approvalGate.SourceAPI {
+				t.Errorf("Expected source %q, got %v", approvalGate.SourceAPI, result.Output["source"])
+			}
+		})
+	}
+}
+
+func TestExecutor_TimeoutAppliesDefaultAction(t *testing.T) {
+	tests := []struct {
+		action string
+		status types.TaskStatus
+	}{
+		{"approve", types.TaskSuccess},
+		{"reject", types.TaskFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			// Nothing can resolve this gate, so only the timeout ends the wait
+			ctx := approvalGate.WithGate(context.Background(), approvalGate.NewGate())
+
+			result := New().Execute(ctx, approvalTask(map[string]interface{}{
+				"timeout":        "20ms",
+				"default_action": tt.action,
+			}), &MockContextManager{})
+
+			if result.Status != tt.status {
+				t.Errorf("Expected status %s, got %s: %s", tt.status, result.Status, result.Message)
+			}
+			if result.Output["source"] != approvalGate.SourceTimeout {
+				t.Errorf("Expected timeout source, got %v", result.Output["source"])
+			}
+		})
+	}
+}
+
+func TestExecutor_CancelledContext(t *testing.T) {
+	gate := approvalGate.NewGate()
+	gate.EnableRemote()
+	ctx, cancel := context.WithCancel(approvalGate.WithGate(context.Background(), gate))
+	cancel()
+
+	result := New().Execute(ctx, approvalTask(map[string]interface{}{"timeout": "1h"}), &MockContextManager{})
+	if result.Status != types.TaskFailed {
+		t.Errorf("Expected cancelled approval to fail, got %s", result.Status)
+	}
+}
@@ -15,6 +15,7 @@ import (
 
 	"golang.org/x/crypto/blake2b"
 
+	"github.com/sarlalian/ritual/pkg/coerce"
 	"github.com/sarlalian/ritual/pkg/types"
 )
 
@@ -85,13 +86,16 @@ func (e *Executor) Execute(ctx context.Context, task *types.TaskConfig, contextM
 
 	// Write checksum to output file if specified
 	if config.Output != "" {
-		if err := e.writeChecksumToFile(config.Output, checksum); err != nil {
+		written, err := e.writeChecksumToFile(config.Output, checksum)
+		if err != nil {
 			result.Status = types.TaskFailed
 			result.Message = fmt.Sprintf("Failed to write checksum to file: %v", err)
 			return result
 		}
 		result.Output["output_file"] = config.Output
+		result.Changed = written
 	}
+	result.Output["changed"] = result.Changed
 
 	// Perform action
 	switch config.Action {
@@ -132,20 +136,20 @@ func (e *Executor) Execute(ctx context.Context, task *types.TaskConfig, contextM
 
 // Validate checks if the task configuration is valid
 func (e *Executor) Validate(task *types.TaskConfig) error {
-	config := &ChecksumConfig{}
-
 	// Extract path
-	if path, ok := task.Config["path"].(string); ok {
-		config.Path = path
-	} else {
+	if _, ok, err := coerce.Field(task.Config, "path", coerce.String); err != nil {
+		return fmt.Errorf("checksum task '%s': %w", task.Name, err)
+	} else if !ok {
 		return fmt.Errorf("checksum task '%s': path is required", task.Name)
 	}
 
 	// Extract algorithm
-	if algo, ok := task.Config["algorithm"].(string); ok {
-		config.Algorithm = algo
-	} else {
-		config.Algorithm = "sha256" // Default
+	algorithm, _, err := coerce.Field(task.Config, "algorithm", coerce.String)
+	if err != nil {
+		return fmt.Errorf("checksum task '%s': %w", task.Name, err)
+	}
+	if algorithm == "" {
+		algorithm = "sha256" // Default
 	}
 
 	// Validate algorithm
@@ -156,8 +160,8 @@ func (e *Executor) Validate(task *types.TaskConfig) error {
 		"blake2b": true,
 	}
 
-	if !validAlgos[config.Algorithm] {
-		return fmt.Errorf("checksum task '%s': invalid algorithm '%s' (must be sha256, sha512, md5, or blake2b)", task.Name, config.Algorithm)
+	if !validAlgos[algorithm] {
+		return fmt.Errorf("checksum task '%s': invalid algorithm '%s' (must be sha256, sha512, md5, or blake2b)", task.Name, algorithm)
 	}
 
 	return nil
@@ -168,6 +172,31 @@ func (e *Executor) SupportsDryRun() bool {
 	return true
 }
 
+// ReportsChange returns true as results are only marked changed when the
+// task changed something
+func (e *Executor) ReportsChange() bool {
+	return true
+}
+
+// ConfigSchema describes the fields of a checksum task
+func (e *Executor) ConfigSchema() *types.ConfigSchema {
+	return &types.ConfigSchema{
+		Description: "Calculate or verify a file checksum",
+		Fields: []types.FieldSchema{
+			{Name: "path", Type: types.FieldString, Required: true, Description: "File to hash"},
+			{Name: "algorithm", Type: types.FieldString, Default: "sha256", Enum: []string{"sha256", "sha512", "md5", "blake2b"}},
+			{Name: "expected", Type: types.FieldString, Description: "Checksum to verify against"},
+			{Name: "action", Type: types.FieldString, Description: "verify when expected is set, otherwise calculate", Enum: []string{"calculate", "verify"}},
+			{Name: "output", Type: types.FieldString, Description: "File to write the checksum to"},
+		},
+	}
+}
+
+// OutputFields lists the keys this task may set in its result's Output map
+func (e *Executor) OutputFields() []string {
+	return []string{"algorithm", "changed", "checksum", "expected", "output_file", "path", "verified"}
+}
+
 // parseConfig extracts and evaluates the configuration
 func (e *Executor) parseConfig(task *types.TaskConfig, contextManager types.ContextManager) (*ChecksumConfig, error) {
 	config := &ChecksumConfig{}
@@ -179,32 +208,29 @@ func (e *Executor) parseConfig(task *types.TaskConfig, contextManager types.Cont
 	}
 
 	// Extract path (required)
-	if path, ok := evaluatedConfig["path"].(string); ok {
-		config.Path = path
-	} else {
-		return nil, fmt.Errorf("path is required")
+	path, ok, err := coerce.Field(evaluatedConfig, "path", coerce.String)
+	if err != nil {
+		return nil, err
 	}
-
-	// Extract algorithm (optional, default to sha256)
-	if algo, ok := evaluatedConfig["algorithm"].(string); ok {
-		config.Algorithm = algo
-	} else {
-		config.Algorithm = "sha256"
+	if !ok {
+		return nil, fmt.Errorf("path is required")
 	}
-
-	// Extract expected checksum (optional)
-	if expected, ok := evaluatedConfig["expected"].(string); ok {
-		config.Expected = expected
+	config.Path = path
+
+	// Extract optional fields; algorithm defaults to sha256
+	optional := map[string]*string{
+		"algorithm": &config.Algorithm,
+		"expected":  &config.Expected,
+		"action":    &config.Action,
+		"output":    &config.Output,
 	}
-
-	// Extract action (optional)
-	if action, ok := evaluatedConfig["action"].(string); ok {
-		config.Action = action
+	for key, dst := range optional {
+		if *dst, _, err = coerce.Field(evaluatedConfig, key, coerce.String); err != nil {
+			return nil, err
+		}
 	}
-
-	// Extract output (optional)
-	if output, ok := evaluatedConfig["output"].(string); ok {
-		config.Output = output
+	if config.Algorithm == "" {
+		config.Algorithm = "sha256"
 	}
 
 	return config, nil
@@ -264,17 +290,23 @@ func (e *Executor) calculateChecksum(path, algorithm string) (string, error) {
 	return hex.EncodeToString(sum), nil
 }
 
-// writeChecksumToFile writes the checksum to a file
-func (e *Executor) writeChecksumToFile(path, checksum string) error {
+// writeChecksumToFile writes the checksum to a file, leaving it untouched when it
+// already holds the same value. It reports whether the file was written.
+func (e *Executor) writeChecksumToFile(path, checksum string) (bool, error) {
+	content := checksum + "\n"
+	if existing, err := os.ReadFile(path); err == nil && string(existing) == content {
+		return false, nil
+	}
+
 	file, err := os.Create(path)
 	if err != nil {
-		return fmt.Errorf("failed to create output file: %w", err)
+		return false, fmt.Errorf("failed to create output file: %w", err)
 	}
 	defer func() { _ = file.Close() }()
 
-	if _, err := file.WriteString(checksum + "\n"); err != nil {
-		return fmt.Errorf("failed to write checksum: %w", err)
+	if _, err := file.WriteString(content); err != nil {
+		return false, fmt.Errorf("failed to write checksum: %w", err)
 	}
 
-	return nil
+	return true, nil
 }
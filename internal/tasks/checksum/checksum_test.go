@@ -248,6 +248,16 @@ func TestChecksum_Validate(t *testing.T) {
 			},
 			shouldErr: true,
 		},
+		{
+			name: "Default algorithm",
+			task: &types.TaskConfig{
+				Name: "Test",
+				Config: map[string]interface{}{
+					"path": "/tmp/test.txt",
+				},
+			},
+			shouldErr: false,
+		},
 		{
 			name: "Invalid algorithm",
 			task: &types.TaskConfig{
@@ -280,3 +290,41 @@ func TestChecksum_SupportsDryRun(t *testing.T) {
 		t.Error("Expected checksum executor to support dry run")
 	}
 }
+
+func TestChecksum_OutputFileChanged(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.txt")
+	outputFile := filepath.Join(tmpDir, "test.txt.sha256")
+	if err := os.WriteFile(testFile, []byte("Hello, World!"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	executor := New()
+	task := &types.TaskConfig{
+		ID:   "test-checksum-output",
+		Name: "Test Checksum Output",
+		Type: "checksum",
+		Config: map[string]interface{}{
+			"path":      testFile,
+			"algorithm": "sha256",
+			"output":    outputFile,
+		},
+	}
+
+	contextManager := &MockContextManager{}
+	result := executor.Execute(context.Background(), task, contextManager)
+	if result.Status != types.TaskSuccess {
+		t.Fatalf("Expected task success, got %s: %s", result.Status, result.Message)
+	}
+	if !result.Changed {
+		t.Error("Expected first run to write the output file")
+	}
+
+	result = executor.Execute(context.Background(), task, contextManager)
+	if result.Status != types.TaskSuccess {
+		t.Fatalf("Expected task success, got %s: %s", result.Status, result.Message)
+	}
+	if result.Changed {
+		t.Error("Expected second run to leave the output file unchanged")
+	}
+}
@@ -6,6 +6,7 @@ package command
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -13,6 +14,10 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/sarlalian/ritual/internal/events"
+	"github.com/sarlalian/ritual/internal/output"
+	"github.com/sarlalian/ritual/internal/redact"
+	"github.com/sarlalian/ritual/pkg/coerce"
 	"github.com/sarlalian/ritual/pkg/types"
 )
 
@@ -65,7 +70,7 @@ func (e *Executor) Execute(ctx context.Context, task *types.TaskConfig, contextM
 	}
 
 	// Execute the command
-	execResult := e.executeCommand(ctx, config)
+	execResult := e.executeCommand(ctx, task, config)
 
 	// Update result
 	result.Status = execResult.Status
@@ -73,6 +78,7 @@ func (e *Executor) Execute(ctx context.Context, task *types.TaskConfig, contextM
 	result.Stdout = execResult.Stdout
 	result.Stderr = execResult.Stderr
 	result.ReturnCode = execResult.ReturnCode
+	result.Output = execResult.Output
 	result.EndTime = time.Now()
 	result.Duration = result.EndTime.Sub(result.StartTime)
 
@@ -112,6 +118,35 @@ func (e *Executor) SupportsDryRun() bool {
 	return true
 }
 
+// ConfigSchema describes the fields of a command task
+func (e *Executor) ConfigSchema() *types.ConfigSchema {
+	return &types.ConfigSchema{
+		Description: "Run a shell command or script",
+		Fields: []types.FieldSchema{
+			{Name: "command", Type: types.FieldString, Description: "Command to run; with args, the program to execute directly"},
+			{Name: "args", Type: types.FieldStringList, Description: "Arguments passed to command without a shell"},
+			{Name: "script", Type: types.FieldString, Description: "Script run with the shell"},
+			{Name: "shell", Type: types.FieldString, Description: "Shell that runs the script", Default: "/bin/sh"},
+			{Name: "working_dir", Type: types.FieldString, Description: "Directory to run in"},
+			{Name: "environment", Type: types.FieldStringMap, Description: "Extra environment variables"},
+			{Name: "timeout", Type: types.FieldDuration, Description: "Kill the command after this long"},
+			{Name: "fail_on_error", Type: types.FieldBoolean, Description: "Fail the task on a non-zero exit code", Default: true},
+			{Name: "capture", Type: types.FieldObject, Description: "Output to keep in the result", Fields: []types.FieldSchema{
+				{Name: "stdout", Type: types.FieldBoolean, Default: true},
+				{Name: "stderr", Type: types.FieldBoolean, Default: true},
+				{Name: "combined", Type: types.FieldBoolean, Default: false},
+			}},
+		},
+		OneOf: [][]string{{"command", "script"}},
+	}
+}
+
+// OutputFields lists the keys this task may set in its result's Output map;
+// they describe where full stdout and stderr were spooled
+func (e *Executor) OutputFields() []string {
+	return output.AnnotationFields()
+}
+
 // parseConfig parses and evaluates the task configuration
 func (e *Executor) parseConfig(task *types.TaskConfig, contextManager types.ContextManager) (*CommandConfig, error) {
 	// First evaluate all templates in the config
@@ -134,112 +169,71 @@ func (e *Executor) parseConfigRaw(configMap map[string]interface{}) (*CommandCon
 		},
 	}
 
+	var err error
 	for key, value := range configMap {
+		if value == nil {
+			continue
+		}
+
 		switch key {
 		case "command":
-			if str, ok := value.(string); ok {
-				config.Command = str
-			} else {
+			// A bare number is almost certainly a YAML mistake, not a program name
+			command, ok := value.(string)
+			if !ok {
 				return nil, fmt.Errorf("command must be a string")
 			}
-
+			config.Command = command
 		case "args":
-			if args, ok := value.([]interface{}); ok {
-				config.Args = make([]string, len(args))
-				for i, arg := range args {
-					if str, ok := arg.(string); ok {
-						config.Args[i] = str
-					} else {
-						return nil, fmt.Errorf("all args must be strings")
-					}
-				}
-			} else {
-				return nil, fmt.Errorf("args must be an array of strings")
-			}
-
+			config.Args, err = coerce.StringSlice(value)
 		case "script":
-			if str, ok := value.(string); ok {
-				config.Script = str
-			} else {
-				return nil, fmt.Errorf("script must be a string")
-			}
-
+			config.Script, err = coerce.String(value)
 		case "shell":
-			if str, ok := value.(string); ok {
-				config.Shell = str
-			} else {
-				return nil, fmt.Errorf("shell must be a string")
-			}
-
+			config.Shell, err = coerce.String(value)
 		case "working_dir":
-			if str, ok := value.(string); ok {
-				config.WorkingDir = str
-			} else {
-				return nil, fmt.Errorf("working_dir must be a string")
-			}
-
+			config.WorkingDir, err = coerce.String(value)
 		case "environment":
-			if env, ok := value.(map[string]interface{}); ok {
-				config.Environment = make(map[string]string)
-				for k, v := range env {
-					if str, ok := v.(string); ok {
-						config.Environment[k] = str
-					} else {
-						return nil, fmt.Errorf("all environment values must be strings")
-					}
-				}
-			} else {
-				return nil, fmt.Errorf("environment must be a map of strings")
-			}
-
+			config.Environment, err = coerce.StringMap(value)
 		case "timeout":
-			if str, ok := value.(string); ok {
-				config.Timeout = str
-			} else {
-				return nil, fmt.Errorf("timeout must be a string")
-			}
-
+			config.Timeout, err = coerce.String(value)
 		case "fail_on_error":
-			if b, ok := value.(bool); ok {
-				config.FailOnError = b
-			} else {
-				return nil, fmt.Errorf("fail_on_error must be a boolean")
-			}
-
+			config.FailOnError, err = coerce.Bool(value)
 		case "capture":
-			if capture, ok := value.(map[string]interface{}); ok {
-				if stdout, exists := capture["stdout"]; exists {
-					if b, ok := stdout.(bool); ok {
-						config.Capture.Stdout = b
-					} else {
-						return nil, fmt.Errorf("capture.stdout must be a boolean")
-					}
-				}
-				if stderr, exists := capture["stderr"]; exists {
-					if b, ok := stderr.(bool); ok {
-						config.Capture.Stderr = b
-					} else {
-						return nil, fmt.Errorf("capture.stderr must be a boolean")
-					}
-				}
-				if combined, exists := capture["combined"]; exists {
-					if b, ok := combined.(bool); ok {
-						config.Capture.Combined = b
-					} else {
-						return nil, fmt.Errorf("capture.combined must be a boolean")
-					}
-				}
-			} else {
-				return nil, fmt.Errorf("capture must be a map")
-			}
+			err = parseCapture(value, &config.Capture)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
 		}
 	}
 
 	return config, nil
 }
 
+// parseCapture applies the capture map's stdout, stderr and combined flags
+func parseCapture(value interface{}, capture *CaptureConfig) error {
+	captureMap, err := coerce.Map(value)
+	if err != nil {
+		return err
+	}
+
+	flags := map[string]*bool{
+		"stdout":   &capture.Stdout,
+		"stderr":   &capture.Stderr,
+		"combined": &capture.Combined,
+	}
+	for name, dst := range flags {
+		b, ok, err := coerce.Field(captureMap, name, coerce.Bool)
+		if err != nil {
+			return err
+		}
+		if ok {
+			*dst = b
+		}
+	}
+	return nil
+}
+
 // executeCommand executes the actual command
-func (e *Executor) executeCommand(ctx context.Context, config *CommandConfig) *types.TaskResult {
+func (e *Executor) executeCommand(ctx context.Context, task *types.TaskConfig, config *CommandConfig) *types.TaskResult {
 	result := &types.TaskResult{
 		Status: types.TaskRunning,
 	}
@@ -305,32 +299,49 @@ func (e *Executor) executeCommand(ctx context.Context, config *CommandConfig) *t
 		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
 	}
 
-	// Set up output capture
-	var stdoutBuf, stderrBuf strings.Builder
+	// Set up output capture, publishing lines as they are produced. Only the
+	// tail of each stream is kept in the result; the rest is spooled to a file.
+	settings := output.SettingsFromContext(ctx)
+	executionID := types.ExecutionIDFromContext(ctx)
+	stdoutBuf := output.NewCapture(settings, executionID, task.ID, events.StreamStdout)
+	stderrBuf := output.NewCapture(settings, executionID, task.ID, events.StreamStderr)
+	defer func() { _ = stdoutBuf.Close() }()
+	defer func() { _ = stderrBuf.Close() }()
+	stdoutLines := events.NewLineWriter(ctx, task, events.StreamStdout)
+	stderrLines := events.NewLineWriter(ctx, task, events.StreamStderr)
 	if config.Capture.Combined {
-		cmd.Stdout = &stdoutBuf
-		cmd.Stderr = &stdoutBuf
+		// A single writer keeps exec from writing to the buffer from two goroutines
+		combined := io.MultiWriter(stdoutBuf, stdoutLines)
+		cmd.Stdout = combined
+		cmd.Stderr = combined
 	} else {
 		if config.Capture.Stdout {
-			cmd.Stdout = &stdoutBuf
+			cmd.Stdout = io.MultiWriter(stdoutBuf, stdoutLines)
 		}
 		if config.Capture.Stderr {
-			cmd.Stderr = &stderrBuf
+			cmd.Stderr = io.MultiWriter(stderrBuf, stderrLines)
 		}
 	}
 
 	// Log command details before execution
-	e.logCommandDetails(cmd, config)
+	redactor := redact.FromContext(ctx)
+	e.logCommandDetails(cmd, config, redactor)
 
 	// Execute command
 	err := cmd.Run()
+	stdoutLines.Flush()
+	stderrLines.Flush()
 
 	// Get output
-	if config.Capture.Combined {
-		result.Stdout = stdoutBuf.String()
-	} else {
-		result.Stdout = stdoutBuf.String()
+	spool := make(map[string]interface{})
+	result.Stdout = stdoutBuf.String()
+	stdoutBuf.Annotate(spool, events.StreamStdout)
+	if !config.Capture.Combined {
 		result.Stderr = stderrBuf.String()
+		stderrBuf.Annotate(spool, events.StreamStderr)
+	}
+	if len(spool) > 0 {
+		result.Output = spool
 	}
 
 	// Determine result status
@@ -339,7 +350,7 @@ func (e *Executor) executeCommand(ctx context.Context, config *CommandConfig) *t
 			result.Status = types.TaskFailed
 			result.Message = fmt.Sprintf("Command timed out after %s", config.Timeout)
 			result.ReturnCode = -1
-			e.logCommandFailure(cmd, config, result)
+			e.logCommandFailure(cmd, config, result, redactor)
 		} else if exitError, ok := err.(*exec.ExitError); ok {
 			// Command executed but returned non-zero exit code
 			if status, ok := exitError.Sys().(syscall.WaitStatus); ok {
@@ -351,7 +362,7 @@ func (e *Executor) executeCommand(ctx context.Context, config *CommandConfig) *t
 			if config.FailOnError {
 				result.Status = types.TaskFailed
 				result.Message = fmt.Sprintf("Command failed with exit code %d", result.ReturnCode)
-				e.logCommandFailure(cmd, config, result)
+				e.logCommandFailure(cmd, config, result, redactor)
 			} else {
 				result.Status = types.TaskSuccess
 				result.Message = fmt.Sprintf("Command completed with exit code %d (ignored)", result.ReturnCode)
@@ -361,7 +372,7 @@ func (e *Executor) executeCommand(ctx context.Context, config *CommandConfig) *t
 			result.Status = types.TaskFailed
 			result.Message = fmt.Sprintf("Failed to execute command: %v", err)
 			result.ReturnCode = -1
-			e.logCommandFailure(cmd, config, result)
+			e.logCommandFailure(cmd, config, result, redactor)
 		}
 	} else {
 		// Command executed successfully
@@ -373,14 +384,15 @@ func (e *Executor) executeCommand(ctx context.Context, config *CommandConfig) *t
 	return result
 }
 
-// logCommandDetails logs detailed information about the command being executed
-func (e *Executor) logCommandDetails(cmd *exec.Cmd, config *CommandConfig) {
+// logCommandDetails logs detailed information about the command being
+// executed, with sensitive values masked
+func (e *Executor) logCommandDetails(cmd *exec.Cmd, config *CommandConfig, redactor *redact.Redactor) {
 	// Always log to stderr for visibility
 	if config.Script != "" {
 		fmt.Fprintf(os.Stderr, "[COMMAND] Executing script via %s:\n", config.Shell)
-		fmt.Fprintf(os.Stderr, "[COMMAND] Script: %s\n", config.Script)
+		fmt.Fprintf(os.Stderr, "[COMMAND] Script: %s\n", redactor.String(config.Script))
 	} else {
-		fmt.Fprintf(os.Stderr, "[COMMAND] Executing: %s %s\n", cmd.Path, strings.Join(cmd.Args[1:], " "))
+		fmt.Fprintf(os.Stderr, "[COMMAND] Executing: %s %s\n", cmd.Path, redactor.String(strings.Join(cmd.Args[1:], " ")))
 	}
 
 	if config.WorkingDir != "" {
@@ -389,7 +401,7 @@ func (e *Executor) logCommandDetails(cmd *exec.Cmd, config *CommandConfig) {
 
 	if len(config.Environment) > 0 {
 		fmt.Fprintf(os.Stderr, "[COMMAND] Custom Environment Variables:\n")
-		for key, value := range config.Environment {
+		for key, value := range redactor.Value(config.Environment).(map[string]string) {
 			fmt.Fprintf(os.Stderr, "[COMMAND]   %s=%s\n", key, value)
 		}
 	}
@@ -399,21 +411,22 @@ func (e *Executor) logCommandDetails(cmd *exec.Cmd, config *CommandConfig) {
 	}
 }
 
-// logCommandFailure logs detailed failure information
-func (e *Executor) logCommandFailure(cmd *exec.Cmd, config *CommandConfig, result *types.TaskResult) {
+// logCommandFailure logs detailed failure information, with sensitive
+// values masked. It runs before the executor redacts the result.
+func (e *Executor) logCommandFailure(cmd *exec.Cmd, config *CommandConfig, result *types.TaskResult, redactor *redact.Redactor) {
 	fmt.Fprintf(os.Stderr, "[COMMAND FAILED] Exit Code: %d\n", result.ReturnCode)
 
 	if config.Script != "" {
-		fmt.Fprintf(os.Stderr, "[COMMAND FAILED] Script: %s\n", config.Script)
+		fmt.Fprintf(os.Stderr, "[COMMAND FAILED] Script: %s\n", redactor.String(config.Script))
 	} else {
-		fmt.Fprintf(os.Stderr, "[COMMAND FAILED] Command: %s %s\n", cmd.Path, strings.Join(cmd.Args[1:], " "))
+		fmt.Fprintf(os.Stderr, "[COMMAND FAILED] Command: %s %s\n", cmd.Path, redactor.String(strings.Join(cmd.Args[1:], " ")))
 	}
 
 	if result.Stderr != "" {
-		fmt.Fprintf(os.Stderr, "[COMMAND FAILED] STDERR:\n%s\n", result.Stderr)
+		fmt.Fprintf(os.Stderr, "[COMMAND FAILED] STDERR:\n%s\n", redactor.String(result.Stderr))
 	}
 
 	if result.Stdout != "" && result.Stderr == "" {
-		fmt.Fprintf(os.Stderr, "[COMMAND FAILED] STDOUT:\n%s\n", result.Stdout)
+		fmt.Fprintf(os.Stderr, "[COMMAND FAILED] STDOUT:\n%s\n", redactor.String(result.Stdout))
 	}
 }
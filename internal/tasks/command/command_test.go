@@ -5,12 +5,17 @@ package command
 
 import (
 	"context"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"testing"
 
+	"github.com/sarlalian/ritual/internal/events"
+	"github.com/sarlalian/ritual/internal/output"
+	"github.com/sarlalian/ritual/internal/redact"
 	"github.com/sarlalian/ritual/pkg/types"
 )
 
@@ -239,6 +244,85 @@ func TestExecutor_Execute_Script(t *testing.T) {
 	}
 }
 
+func TestExecutor_Execute_PublishesOutputLines(t *testing.T) {
+	executor := New()
+	contextManager := NewMockContextManager()
+
+	bus := events.NewBus()
+	var mu sync.Mutex
+	lines := make(map[string][]string)
+	bus.Subscribe(events.ObserverFunc(func(event events.Event) {
+		if event.Type != events.LogLine {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		lines[event.Stream] = append(lines[event.Stream], event.Line)
+	}))
+
+	task := &types.TaskConfig{
+		ID:   "test",
+		Name: "Test Streaming",
+		Type: "command",
+		Config: map[string]interface{}{
+			"script": getTestScript("echo 'first'\necho 'second'\necho 'oops' >&2"),
+		},
+	}
+
+	result := executor.Execute(events.WithBus(context.Background(), bus), task, contextManager)
+	if result.Status != types.TaskSuccess {
+		t.Fatalf("Expected task success, got %s: %s", result.Status, result.Message)
+	}
+
+	if got := strings.Join(lines[events.StreamStdout], ","); got != "first,second" {
+		t.Errorf("Expected stdout lines 'first,second', got %q", got)
+	}
+	if got := strings.Join(lines[events.StreamStderr], ","); got != "oops" {
+		t.Errorf("Expected stderr line 'oops', got %q", got)
+	}
+}
+
+func TestExecutor_Execute_OutputLimit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell loop")
+	}
+
+	executor := New()
+	contextManager := NewMockContextManager()
+	spoolDir := t.TempDir()
+
+	task := &types.TaskConfig{
+		ID:   "dump",
+		Name: "Large Output",
+		Type: "command",
+		Config: map[string]interface{}{
+			"script": "for i in $(seq 1 100); do echo line$i; done",
+		},
+	}
+
+	ctx := output.WithSettings(types.WithExecutionID(context.Background(), "exec_1"), output.Settings{MaxBytes: 20, SpoolDir: spoolDir})
+	result := executor.Execute(ctx, task, contextManager)
+	if result.Status != types.TaskSuccess {
+		t.Fatalf("Expected task success, got %s: %s", result.Status, result.Message)
+	}
+
+	if len(result.Stdout) != 20 || !strings.HasSuffix(result.Stdout, "line100\n") {
+		t.Errorf("Expected the last 20 bytes of output, got %q", result.Stdout)
+	}
+	if result.Output["stdout_truncated"] != true {
+		t.Errorf("Expected stdout to be marked truncated, got %v", result.Output)
+	}
+
+	file, _ := result.Output["stdout_file"].(string)
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("Expected spooled output at %q: %v", file, err)
+	}
+	if !strings.HasPrefix(string(data), "line1\n") || !strings.HasSuffix(string(data), "line100\n") {
+		t.Error("Expected the spool file to hold the complete output")
+	}
+}
+
 func TestExecutor_Execute_WithArgs(t *testing.T) {
 	executor := New()
 	contextManager := NewMockContextManager()
@@ -348,6 +432,52 @@ func TestExecutor_Execute_Failure(t *testing.T) {
 	}
 }
 
+func TestExecutor_Execute_FailureRedacted(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Script uses POSIX shell syntax")
+	}
+
+	executor := New()
+	contextManager := NewMockContextManager()
+	redactor := redact.New(nil)
+	redactor.Add("hunter2-secret")
+	ctx := redact.WithRedactor(context.Background(), redactor)
+
+	task := &types.TaskConfig{
+		ID:   "test",
+		Name: "Test Failure Redacted",
+		Type: "command",
+		Config: map[string]interface{}{
+			"script": "echo token=hunter2-secret; echo hunter2-secret >&2; exit 3",
+		},
+	}
+
+	// The failure details are written to stderr
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	stderr := os.Stderr
+	os.Stderr = writer
+	result := executor.Execute(ctx, task, contextManager)
+	os.Stderr = stderr
+	_ = writer.Close()
+	logged, _ := io.ReadAll(reader)
+
+	if result.Status != types.TaskFailed {
+		t.Fatalf("Expected task failure, got %s", result.Status)
+	}
+	if !strings.Contains(string(logged), "[COMMAND FAILED] STDERR:") {
+		t.Fatalf("Expected failure details on stderr, got: %s", logged)
+	}
+	if strings.Contains(string(logged), "hunter2-secret") {
+		t.Errorf("Expected the secret to be masked in the failure log, got: %s", logged)
+	}
+	if !strings.Contains(string(logged), redact.Mask) {
+		t.Errorf("Expected the mask in the failure log, got: %s", logged)
+	}
+}
+
 func TestExecutor_Execute_FailureIgnored(t *testing.T) {
 	executor := New()
 	contextManager := NewMockContextManager()
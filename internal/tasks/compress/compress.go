@@ -17,6 +17,7 @@ import (
 	"time"
 
 	bzip2w "github.com/dsnet/compress/bzip2"
+	"github.com/sarlalian/ritual/pkg/coerce"
 	"github.com/sarlalian/ritual/pkg/types"
 )
 
@@ -90,6 +91,7 @@ func (e *Executor) Execute(ctx context.Context, task *types.TaskConfig, contextM
 
 	// Update result
 	result.Status = execResult.Status
+	result.Changed = execResult.Changed
 	result.Message = execResult.Message
 	result.Output = execResult.Output
 	result.EndTime = time.Now()
@@ -153,6 +155,38 @@ func (e *Executor) SupportsDryRun() bool {
 	return true
 }
 
+// ReportsChange returns true as results are only marked changed when the
+// task changed something
+func (e *Executor) ReportsChange() bool {
+	return true
+}
+
+// ConfigSchema describes the fields of a compress task
+func (e *Executor) ConfigSchema() *types.ConfigSchema {
+	return &types.ConfigSchema{
+		Description: "Create or extract archives",
+		Fields: []types.FieldSchema{
+			{Name: "path", Type: types.FieldString, Required: true, Description: "Archive path"},
+			{Name: "state", Type: types.FieldString, Description: "create or present builds the archive; extract unpacks it; absent removes it",
+				Default: StatePresent, Enum: []string{StateCreate, StateExtract, StatePresent, StateAbsent}},
+			{Name: "format", Type: types.FieldString, Description: "Archive format; guessed from path when unset",
+				Enum: []string{FormatTarGz, FormatTgz, FormatTarBz2, FormatTbz2, FormatTar, FormatZip, FormatGzip, FormatGz, FormatBzip2, FormatBz2}},
+			{Name: "sources", Type: types.FieldStringList, Description: "Files and directories to archive"},
+			{Name: "destination", Type: types.FieldString, Description: "Directory to extract into"},
+			{Name: "exclude", Type: types.FieldStringList, Description: "Glob patterns to leave out"},
+			{Name: "include", Type: types.FieldStringList, Description: "Glob patterns to keep"},
+			{Name: "base_dir", Type: types.FieldString, Description: "Directory archive paths are relative to"},
+			{Name: "overwrite", Type: types.FieldBoolean, Description: "Replace existing files"},
+			{Name: "preserve_dir", Type: types.FieldBoolean, Description: "Keep the top-level directory of sources"},
+		},
+	}
+}
+
+// OutputFields lists the keys this task may set in its result's Output map
+func (e *Executor) OutputFields() []string {
+	return []string{"archived_files", "changed", "destination", "extracted_files", "format", "path"}
+}
+
 // parseConfig parses and evaluates the task configuration
 func (e *Executor) parseConfig(task *types.TaskConfig, contextManager types.ContextManager) (*CompressConfig, error) {
 	// First evaluate all templates in the config
@@ -170,98 +204,36 @@ func (e *Executor) parseConfigRaw(configMap map[string]interface{}) (*CompressCo
 		State: StatePresent, // Default to present
 	}
 
+	var err error
 	for key, value := range configMap {
+		if value == nil {
+			continue
+		}
+
 		switch key {
 		case "path":
-			if str, ok := value.(string); ok {
-				config.Path = str
-			} else {
-				return nil, fmt.Errorf("path must be a string")
-			}
-
+			config.Path, err = coerce.String(value)
 		case "state":
-			if str, ok := value.(string); ok {
-				config.State = str
-			} else {
-				return nil, fmt.Errorf("state must be a string")
-			}
-
+			config.State, err = coerce.String(value)
 		case "format":
-			if str, ok := value.(string); ok {
-				config.Format = str
-			} else {
-				return nil, fmt.Errorf("format must be a string")
-			}
-
+			config.Format, err = coerce.String(value)
 		case "sources":
-			if sources, ok := value.([]interface{}); ok {
-				config.Sources = make([]string, len(sources))
-				for i, source := range sources {
-					if str, ok := source.(string); ok {
-						config.Sources[i] = str
-					} else {
-						return nil, fmt.Errorf("all sources must be strings")
-					}
-				}
-			} else {
-				return nil, fmt.Errorf("sources must be an array of strings")
-			}
-
+			config.Sources, err = coerce.StringSlice(value)
 		case "destination":
-			if str, ok := value.(string); ok {
-				config.Destination = str
-			} else {
-				return nil, fmt.Errorf("destination must be a string")
-			}
-
+			config.Destination, err = coerce.String(value)
 		case "exclude":
-			if exclude, ok := value.([]interface{}); ok {
-				config.Exclude = make([]string, len(exclude))
-				for i, item := range exclude {
-					if str, ok := item.(string); ok {
-						config.Exclude[i] = str
-					} else {
-						return nil, fmt.Errorf("all exclude patterns must be strings")
-					}
-				}
-			} else {
-				return nil, fmt.Errorf("exclude must be an array of strings")
-			}
-
+			config.Exclude, err = coerce.StringSlice(value)
 		case "include":
-			if include, ok := value.([]interface{}); ok {
-				config.Include = make([]string, len(include))
-				for i, item := range include {
-					if str, ok := item.(string); ok {
-						config.Include[i] = str
-					} else {
-						return nil, fmt.Errorf("all include patterns must be strings")
-					}
-				}
-			} else {
-				return nil, fmt.Errorf("include must be an array of strings")
-			}
-
+			config.Include, err = coerce.StringSlice(value)
 		case "base_dir":
-			if str, ok := value.(string); ok {
-				config.BaseDir = str
-			} else {
-				return nil, fmt.Errorf("base_dir must be a string")
-			}
-
+			config.BaseDir, err = coerce.String(value)
 		case "overwrite":
-			if b, ok := value.(bool); ok {
-				config.Overwrite = b
-			} else {
-				return nil, fmt.Errorf("overwrite must be a boolean")
-			}
-
+			config.Overwrite, err = coerce.Bool(value)
 		case "preserve_dir":
-			if b, ok := value.(bool); ok {
-				config.PreserveDir = b
-			} else {
-				return nil, fmt.Errorf("preserve_dir must be a boolean")
-			}
+			config.PreserveDir, err = coerce.Bool(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
 		}
 	}
 
@@ -332,7 +304,7 @@ func (e *Executor) handleAbsent(path string, result *types.TaskResult) *types.Ta
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		result.Status = types.TaskSuccess
 		result.Message = "Archive is already absent"
-		result.Output["changed"] = false
+		setChanged(result, false)
 		return result
 	}
 
@@ -344,7 +316,7 @@ func (e *Executor) handleAbsent(path string, result *types.TaskResult) *types.Ta
 
 	result.Status = types.TaskSuccess
 	result.Message = "Archive removed successfully"
-	result.Output["changed"] = true
+	setChanged(result, true)
 	return result
 }
 
@@ -395,7 +367,7 @@ func (e *Executor) handleExtract(path string, config *CompressConfig, result *ty
 
 	result.Status = types.TaskSuccess
 	result.Message = fmt.Sprintf("Archive extracted successfully (%d files)", len(extractedFiles))
-	result.Output["changed"] = true
+	setChanged(result, true)
 	result.Output["extracted_files"] = len(extractedFiles)
 	result.Output["destination"] = destDir
 
@@ -408,7 +380,7 @@ func (e *Executor) handleCreate(path string, config *CompressConfig, result *typ
 	if _, err := os.Stat(path); err == nil && !config.Overwrite {
 		result.Status = types.TaskSuccess
 		result.Message = "Archive already exists"
-		result.Output["changed"] = false
+		setChanged(result, false)
 		return result
 	}
 
@@ -445,12 +417,18 @@ func (e *Executor) handleCreate(path string, config *CompressConfig, result *typ
 
 	result.Status = types.TaskSuccess
 	result.Message = fmt.Sprintf("Archive created successfully (%d files)", len(archivedFiles))
-	result.Output["changed"] = true
+	setChanged(result, true)
 	result.Output["archived_files"] = len(archivedFiles)
 
 	return result
 }
 
+// setChanged marks whether the archive operation touched the file system
+func setChanged(result *types.TaskResult, changed bool) {
+	result.Changed = changed
+	result.Output["changed"] = changed
+}
+
 // extractTarGz extracts a tar.gz archive
 func (e *Executor) extractTarGz(archivePath, destDir string, config *CompressConfig) ([]string, error) {
 	file, err := os.Open(archivePath)
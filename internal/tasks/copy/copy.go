@@ -4,7 +4,9 @@
 package copy
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"fmt"
 	"io"
 	"os"
@@ -15,6 +17,7 @@ import (
 	"github.com/spf13/afero"
 
 	"github.com/sarlalian/ritual/internal/filesystem"
+	"github.com/sarlalian/ritual/pkg/coerce"
 	"github.com/sarlalian/ritual/pkg/types"
 )
 
@@ -56,6 +59,7 @@ type CopyResult struct {
 	BytesCopied   int64
 	FilesCopied   int
 	Skipped       int
+	Unchanged     int
 	AlreadyExists bool
 	Output        map[string]interface{}
 }
@@ -92,6 +96,7 @@ func (e *Executor) Execute(ctx context.Context, task *types.TaskConfig, contextM
 	// Update result
 	if copyResult.Status == "success" {
 		result.Status = types.TaskSuccess
+		result.Changed = copyResult.FilesCopied > 0
 	} else {
 		result.Status = types.TaskFailed
 	}
@@ -135,6 +140,52 @@ func (e *Executor) SupportsDryRun() bool {
 	return true
 }
 
+// ReportsChange returns true as results are only marked changed when the
+// task changed something
+func (e *Executor) ReportsChange() bool {
+	return true
+}
+
+// ConfigSchema describes the fields of a copy task
+func (e *Executor) ConfigSchema() *types.ConfigSchema {
+	return &types.ConfigSchema{
+		Description: "Copy files between local paths, s3:// and sftp://",
+		Fields: []types.FieldSchema{
+			{Name: "src", Type: types.FieldString, Description: "Source path or URI"},
+			{Name: "source", Type: types.FieldString, Description: "Alias of src"},
+			{Name: "dest", Type: types.FieldString, Description: "Destination path or URI"},
+			{Name: "destination", Type: types.FieldString, Description: "Alias of dest"},
+			{Name: "recursive", Type: types.FieldBoolean, Description: "Copy directories recursively"},
+			{Name: "force", Type: types.FieldBoolean, Description: "Overwrite the destination even when it is unchanged"},
+			{Name: "create_dirs", Type: types.FieldBoolean, Description: "Create missing destination directories", Default: true},
+			{Name: "backup", Type: types.FieldBoolean, Description: "Keep a copy of a destination before overwriting it"},
+			{Name: "backup_ext", Type: types.FieldString, Description: "Extension of backups", Default: ".bak"},
+			{Name: "mode", Type: types.FieldString, Description: "Octal permissions as a quoted string, such as \"0644\""},
+			{Name: "follow_links", Type: types.FieldBoolean, Description: "Copy what symbolic links point to"},
+			{Name: "attributes", Type: types.FieldObject, Description: "Extended attributes"},
+			{Name: "aws_access_key_id", Type: types.FieldString},
+			{Name: "aws_secret_access_key", Type: types.FieldString},
+			{Name: "aws_session_token", Type: types.FieldString},
+			{Name: "aws_region", Type: types.FieldString},
+			{Name: "ssh_user", Type: types.FieldString},
+			{Name: "ssh_password", Type: types.FieldString},
+			{Name: "ssh_private_key", Type: types.FieldString, Description: "Private key in PEM form"},
+			{Name: "ssh_private_key_path", Type: types.FieldString},
+		},
+		OneOf: [][]string{{"src", "source"}, {"dest", "destination"}},
+	}
+}
+
+// OutputFields lists the keys this task may set in its result's Output map
+func (e *Executor) OutputFields() []string {
+	return []string{"backup_created", "bytes_copied", "destination", "files_copied", "mode_applied", "mode_warning", "skipped", "source", "unchanged"}
+}
+
+// SecretFields returns the config fields holding credentials
+func (e *Executor) SecretFields() []string {
+	return []string{"aws_secret_access_key", "aws_session_token", "ssh_password", "ssh_private_key"}
+}
+
 // parseConfig parses and evaluates the configuration with template evaluation
 func (e *Executor) parseConfig(task *types.TaskConfig, contextManager types.ContextManager) (*CopyConfig, error) {
 	// First evaluate templates in the config
@@ -154,84 +205,45 @@ func (e *Executor) parseConfigRaw(config map[string]interface{}) (*CopyConfig, e
 		CreateDirs: true,
 	}
 
+	stringFields := map[string]*string{
+		"src":                   &copyConfig.Source,
+		"source":                &copyConfig.Source,
+		"dest":                  &copyConfig.Destination,
+		"destination":           &copyConfig.Destination,
+		"backup_ext":            &copyConfig.BackupExt,
+		"mode":                  &copyConfig.Mode,
+		"aws_access_key_id":     &copyConfig.AWSAccessKeyID,
+		"aws_secret_access_key": &copyConfig.AWSSecretAccessKey,
+		"aws_session_token":     &copyConfig.AWSSessionToken,
+		"aws_region":            &copyConfig.AWSRegion,
+		"ssh_user":              &copyConfig.SSHUser,
+		"ssh_password":          &copyConfig.SSHPassword,
+		"ssh_private_key":       &copyConfig.SSHPrivateKey,
+		"ssh_private_key_path":  &copyConfig.SSHPrivateKeyPath,
+	}
+	boolFields := map[string]*bool{
+		"recursive":    &copyConfig.Recursive,
+		"force":        &copyConfig.Force,
+		"create_dirs":  &copyConfig.CreateDirs,
+		"backup":       &copyConfig.Backup,
+		"follow_links": &copyConfig.FollowLinks,
+	}
+
+	var err error
 	for key, value := range config {
-		switch key {
-		case "src", "source":
-			if str, ok := value.(string); ok {
-				copyConfig.Source = str
-			} else {
-				return nil, fmt.Errorf("src must be a string")
-			}
-		case "dest", "destination":
-			if str, ok := value.(string); ok {
-				copyConfig.Destination = str
-			} else {
-				return nil, fmt.Errorf("dest must be a string")
-			}
-		case "recursive":
-			if b, ok := value.(bool); ok {
-				copyConfig.Recursive = b
-			}
-		case "force":
-			if b, ok := value.(bool); ok {
-				copyConfig.Force = b
-			}
-		case "create_dirs":
-			if b, ok := value.(bool); ok {
-				copyConfig.CreateDirs = b
-			}
-		case "backup":
-			if b, ok := value.(bool); ok {
-				copyConfig.Backup = b
-			}
-		case "backup_ext":
-			if str, ok := value.(string); ok {
-				copyConfig.BackupExt = str
-			}
-		case "mode":
-			if str, ok := value.(string); ok {
-				copyConfig.Mode = str
-			}
-		case "follow_links":
-			if b, ok := value.(bool); ok {
-				copyConfig.FollowLinks = b
-			}
-		case "attributes":
-			if m, ok := value.(map[string]interface{}); ok {
-				copyConfig.Attributes = m
-			}
-		case "aws_access_key_id":
-			if str, ok := value.(string); ok {
-				copyConfig.AWSAccessKeyID = str
-			}
-		case "aws_secret_access_key":
-			if str, ok := value.(string); ok {
-				copyConfig.AWSSecretAccessKey = str
-			}
-		case "aws_session_token":
-			if str, ok := value.(string); ok {
-				copyConfig.AWSSessionToken = str
-			}
-		case "aws_region":
-			if str, ok := value.(string); ok {
-				copyConfig.AWSRegion = str
-			}
-		case "ssh_user":
-			if str, ok := value.(string); ok {
-				copyConfig.SSHUser = str
-			}
-		case "ssh_password":
-			if str, ok := value.(string); ok {
-				copyConfig.SSHPassword = str
-			}
-		case "ssh_private_key":
-			if str, ok := value.(string); ok {
-				copyConfig.SSHPrivateKey = str
-			}
-		case "ssh_private_key_path":
-			if str, ok := value.(string); ok {
-				copyConfig.SSHPrivateKeyPath = str
-			}
+		if value == nil {
+			continue
+		}
+
+		if dst, ok := stringFields[key]; ok {
+			*dst, err = coerce.String(value)
+		} else if dst, ok := boolFields[key]; ok {
+			*dst, err = coerce.Bool(value)
+		} else if key == "attributes" {
+			copyConfig.Attributes, err = coerce.Map(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
 		}
 	}
 
@@ -311,6 +323,23 @@ func (e *Executor) executeCopy(ctx context.Context, config *CopyConfig) *CopyRes
 	destInfo, destErr := dstFs.Stat(dstInfo.Path)
 	destExists := destErr == nil
 
+	// Leave identical destination files alone so reruns report no change
+	if !isDir && destExists && config.Force && !destInfo.IsDir() {
+		same, err := sameContent(srcFs, srcInfo.Path, sourceInfo, dstFs, dstInfo.Path, destInfo)
+		if err == nil && same {
+			result.Status = "success"
+			result.Message = "Destination is already up to date"
+			result.Unchanged = 1
+			result.Output["source"] = config.Source
+			result.Output["destination"] = config.Destination
+			result.Output["files_copied"] = 0
+			result.Output["bytes_copied"] = int64(0)
+			result.Output["skipped"] = 0
+			result.Output["unchanged"] = 1
+			return result
+		}
+	}
+
 	// Handle backup if requested and destination exists
 	if config.Backup && destExists && !destInfo.IsDir() {
 		backupPath := dstInfo.Path + config.BackupExt
@@ -368,6 +397,8 @@ func (e *Executor) executeCopy(ctx context.Context, config *CopyConfig) *CopyRes
 			result.Message = fmt.Sprintf("Copied %d file(s), %d bytes", result.FilesCopied, result.BytesCopied)
 		} else if result.Skipped > 0 {
 			result.Message = fmt.Sprintf("Skipped %d file(s) (already exist)", result.Skipped)
+		} else if result.Unchanged > 0 {
+			result.Message = fmt.Sprintf("%d file(s) already up to date", result.Unchanged)
 		} else {
 			result.Message = "Copy completed"
 		}
@@ -379,6 +410,7 @@ func (e *Executor) executeCopy(ctx context.Context, config *CopyConfig) *CopyRes
 	result.Output["files_copied"] = result.FilesCopied
 	result.Output["bytes_copied"] = result.BytesCopied
 	result.Output["skipped"] = result.Skipped
+	result.Output["unchanged"] = result.Unchanged
 
 	// Apply mode if specified
 	if config.Mode != "" && result.FilesCopied > 0 {
@@ -449,7 +481,7 @@ func (e *Executor) copyDirCrossFS(srcFs afero.Fs, srcPath string, dstFs afero.Fs
 			}
 		} else {
 			// Check if destination exists
-			_, destErr := dstFs.Stat(dstEntryPath)
+			destEntryInfo, destErr := dstFs.Stat(dstEntryPath)
 			destExists := destErr == nil
 
 			if destExists && !config.Force {
@@ -457,6 +489,13 @@ func (e *Executor) copyDirCrossFS(srcFs afero.Fs, srcPath string, dstFs afero.Fs
 				continue
 			}
 
+			if destExists {
+				if same, err := sameContent(srcFs, srcEntryPath, entry, dstFs, dstEntryPath, destEntryInfo); err == nil && same {
+					result.Unchanged++
+					continue
+				}
+			}
+
 			// Copy file
 			if err := e.copyFileCrossFS(srcFs, srcEntryPath, dstFs, dstEntryPath); err != nil {
 				return fmt.Errorf("failed to copy %s: %w", srcEntryPath, err)
@@ -470,6 +509,40 @@ func (e *Executor) copyDirCrossFS(srcFs afero.Fs, srcPath string, dstFs afero.Fs
 	return nil
 }
 
+// sameContent reports whether two files hold identical bytes. Sizes are compared
+// first so that differing files are usually detected without reading either side.
+func sameContent(srcFs afero.Fs, srcPath string, srcInfo os.FileInfo, dstFs afero.Fs, dstPath string, dstInfo os.FileInfo) (bool, error) {
+	if srcInfo.Size() != dstInfo.Size() {
+		return false, nil
+	}
+
+	srcSum, err := fileDigest(srcFs, srcPath)
+	if err != nil {
+		return false, err
+	}
+	dstSum, err := fileDigest(dstFs, dstPath)
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(srcSum, dstSum), nil
+}
+
+// fileDigest returns the SHA-256 digest of a file's contents
+func fileDigest(fs afero.Fs, path string) ([]byte, error) {
+	f, err := fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
 // parseFileMode parses a file mode string (e.g., "0644") into os.FileMode
 func parseFileMode(mode string) (os.FileMode, error) {
 	// Remove any leading "0" for octal notation
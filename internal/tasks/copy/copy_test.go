@@ -542,3 +542,74 @@ func TestParseFileMode(t *testing.T) {
 		}
 	}
 }
+
+func TestExecutor_Execute_ForceIdenticalContent(t *testing.T) {
+	executor := New()
+	contextManager := &MockContextManager{}
+
+	tmpDir := t.TempDir()
+	srcFile := filepath.Join(tmpDir, "source.txt")
+	dstFile := filepath.Join(tmpDir, "dest.txt")
+
+	if err := os.WriteFile(srcFile, []byte("same content"), 0644); err != nil {
+		t.Fatalf("Failed to create source file: %v", err)
+	}
+	if err := os.WriteFile(dstFile, []byte("same content"), 0644); err != nil {
+		t.Fatalf("Failed to create destination file: %v", err)
+	}
+
+	task := &types.TaskConfig{
+		ID:   "test-identical",
+		Name: "Test Identical Copy",
+		Type: "copy",
+		Config: map[string]interface{}{
+			"src":   srcFile,
+			"dest":  dstFile,
+			"force": true,
+		},
+	}
+
+	result := executor.Execute(context.Background(), task, contextManager)
+
+	if result.Status != types.TaskSuccess {
+		t.Fatalf("Expected task success, got %s: %s", result.Status, result.Message)
+	}
+	if result.Changed {
+		t.Error("Expected unchanged result when destination already matches source")
+	}
+	if result.Output["unchanged"] != 1 {
+		t.Errorf("Expected unchanged=1, got %v", result.Output["unchanged"])
+	}
+}
+
+func TestExecutor_Execute_CopyFileReportsChanged(t *testing.T) {
+	executor := New()
+	contextManager := &MockContextManager{}
+
+	tmpDir := t.TempDir()
+	srcFile := filepath.Join(tmpDir, "source.txt")
+	dstFile := filepath.Join(tmpDir, "dest.txt")
+
+	if err := os.WriteFile(srcFile, []byte("content"), 0644); err != nil {
+		t.Fatalf("Failed to create source file: %v", err)
+	}
+
+	task := &types.TaskConfig{
+		ID:   "test-changed",
+		Name: "Test Changed Copy",
+		Type: "copy",
+		Config: map[string]interface{}{
+			"src":  srcFile,
+			"dest": dstFile,
+		},
+	}
+
+	result := executor.Execute(context.Background(), task, contextManager)
+
+	if result.Status != types.TaskSuccess {
+		t.Fatalf("Expected task success, got %s: %s", result.Status, result.Message)
+	}
+	if !result.Changed {
+		t.Error("Expected changed result for a new copy")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/sarlalian/ritual/pkg/coerce"
 	"github.com/sarlalian/ritual/pkg/types"
 )
 
@@ -103,6 +104,22 @@ func (e *Executor) SupportsDryRun() bool {
 	return true
 }
 
+// ConfigSchema describes the fields of a debug task
+func (e *Executor) ConfigSchema() *types.ConfigSchema {
+	return &types.ConfigSchema{
+		Description: "Log a templated message",
+		Fields: []types.FieldSchema{
+			{Name: "message", Type: types.FieldAny, Required: true, Description: "Message to log; lists and maps are printed as they are"},
+			{Name: "level", Type: types.FieldString, Default: LevelInfo, Enum: []string{LevelInfo, LevelDebug, LevelWarn, LevelError}},
+		},
+	}
+}
+
+// OutputFields lists the keys this task may set in its result's Output map
+func (e *Executor) OutputFields() []string {
+	return []string{"level", "message"}
+}
+
 // parseConfig parses and evaluates the task configuration
 func (e *Executor) parseConfig(task *types.TaskConfig, contextManager types.ContextManager) (*DebugConfig, error) {
 	// First evaluate all templates in the config
@@ -123,18 +140,19 @@ func (e *Executor) parseConfigRaw(configMap map[string]interface{}) (*DebugConfi
 	for key, value := range configMap {
 		switch key {
 		case "message":
-			if str, ok := value.(string); ok {
+			// Debug output prints whatever a template produced, lists and maps included
+			if str, err := coerce.String(value); err == nil {
 				config.Message = str
 			} else {
-				return nil, fmt.Errorf("message must be a string")
+				config.Message = fmt.Sprintf("%v", value)
 			}
 
 		case "level":
-			if str, ok := value.(string); ok {
-				config.Level = str
-			} else {
-				return nil, fmt.Errorf("level must be a string")
+			str, err := coerce.String(value)
+			if err != nil {
+				return nil, fmt.Errorf("level: %w", err)
 			}
+			config.Level = str
 		}
 	}
 
@@ -10,6 +10,7 @@ import (
 	"net/smtp"
 	"strings"
 
+	"github.com/sarlalian/ritual/pkg/coerce"
 	"github.com/sarlalian/ritual/pkg/types"
 )
 
@@ -110,36 +111,30 @@ func (e *Executor) Execute(ctx context.Context, task *types.TaskConfig, contextM
 // Validate checks if the task configuration is valid
 func (e *Executor) Validate(task *types.TaskConfig) error {
 	// Extract required fields
-	if host, ok := task.Config["host"].(string); !ok || host == "" {
-		return fmt.Errorf("email task '%s': host is required", task.Name)
+	required := []struct {
+		key  string
+		name string
+	}{
+		{"host", "host"},
+		{"from", "from address"},
+		{"subject", "subject"},
+		{"body", "body"},
 	}
-
-	if from, ok := task.Config["from"].(string); !ok || from == "" {
-		return fmt.Errorf("email task '%s': from address is required", task.Name)
-	}
-
-	// Validate To field (can be string or slice)
-	if to, ok := task.Config["to"]; !ok {
-		return fmt.Errorf("email task '%s': to address(es) required", task.Name)
-	} else {
-		switch to.(type) {
-		case string:
-			// Single recipient is ok
-		case []interface{}:
-			// Multiple recipients is ok
-		case []string:
-			// Multiple recipients is ok
-		default:
-			return fmt.Errorf("email task '%s': to must be a string or list of strings", task.Name)
+	for _, field := range required {
+		value, _, err := coerce.Field(task.Config, field.key, coerce.String)
+		if err != nil {
+			return fmt.Errorf("email task '%s': %w", task.Name, err)
+		}
+		if value == "" {
+			return fmt.Errorf("email task '%s': %s is required", task.Name, field.name)
 		}
 	}
 
-	if subject, ok := task.Config["subject"].(string); !ok || subject == "" {
-		return fmt.Errorf("email task '%s': subject is required", task.Name)
-	}
-
-	if body, ok := task.Config["body"].(string); !ok || body == "" {
-		return fmt.Errorf("email task '%s': body is required", task.Name)
+	// Validate To field (can be string or list)
+	if _, ok, err := coerce.Field(task.Config, "to", coerce.StringSlice); err != nil {
+		return fmt.Errorf("email task '%s': %w", task.Name, err)
+	} else if !ok {
+		return fmt.Errorf("email task '%s': to address(es) required", task.Name)
 	}
 
 	return nil
@@ -150,6 +145,38 @@ func (e *Executor) SupportsDryRun() bool {
 	return true
 }
 
+// ConfigSchema describes the fields of an email task
+func (e *Executor) ConfigSchema() *types.ConfigSchema {
+	return &types.ConfigSchema{
+		Description: "Send an email through an SMTP server",
+		Fields: []types.FieldSchema{
+			{Name: "host", Type: types.FieldString, Required: true, Description: "SMTP server"},
+			{Name: "port", Type: types.FieldInteger, Description: "587 with TLS, otherwise 25"},
+			{Name: "username", Type: types.FieldString},
+			{Name: "password", Type: types.FieldString},
+			{Name: "from", Type: types.FieldString, Required: true},
+			{Name: "to", Type: types.FieldStringList, Required: true},
+			{Name: "cc", Type: types.FieldStringList},
+			{Name: "bcc", Type: types.FieldStringList},
+			{Name: "subject", Type: types.FieldString, Required: true},
+			{Name: "body", Type: types.FieldString, Required: true},
+			{Name: "is_html", Type: types.FieldBoolean, Description: "Send body as HTML"},
+			{Name: "use_tls", Type: types.FieldBoolean, Default: true},
+			{Name: "insecure_skip_verify", Type: types.FieldBoolean, Description: "Accept any TLS certificate"},
+		},
+	}
+}
+
+// OutputFields lists the keys this task may set in its result's Output map
+func (e *Executor) OutputFields() []string {
+	return []string{"host", "subject", "to"}
+}
+
+// SecretFields returns the config fields holding credentials
+func (e *Executor) SecretFields() []string {
+	return []string{"password"}
+}
+
 // parseConfig extracts and evaluates the configuration
 func (e *Executor) parseConfig(task *types.TaskConfig, contextManager types.ContextManager) (*EmailConfig, error) {
 	config := &EmailConfig{
@@ -163,87 +190,64 @@ func (e *Executor) parseConfig(task *types.TaskConfig, contextManager types.Cont
 	}
 
 	// Extract required fields
-	if host, ok := evaluatedConfig["host"].(string); ok {
-		config.Host = host
-	} else {
-		return nil, fmt.Errorf("host is required")
-	}
-
-	if from, ok := evaluatedConfig["from"].(string); ok {
-		config.From = from
-	} else {
-		return nil, fmt.Errorf("from address is required")
+	required := []struct {
+		key  string
+		name string
+		dst  *string
+	}{
+		{"host", "host", &config.Host},
+		{"from", "from address", &config.From},
+		{"subject", "subject", &config.Subject},
+		{"body", "body", &config.Body},
 	}
-
-	// Parse To field (can be string or slice)
-	if to, ok := evaluatedConfig["to"].(string); ok {
-		config.To = []string{to}
-	} else if toList, ok := evaluatedConfig["to"].([]interface{}); ok {
-		config.To = make([]string, len(toList))
-		for i, addr := range toList {
-			config.To[i] = fmt.Sprintf("%v", addr)
+	for _, field := range required {
+		value, ok, err := coerce.Field(evaluatedConfig, field.key, coerce.String)
+		if err != nil {
+			return nil, err
 		}
-	} else if toList, ok := evaluatedConfig["to"].([]string); ok {
-		config.To = toList
-	} else {
-		return nil, fmt.Errorf("to address(es) required")
+		if !ok {
+			return nil, fmt.Errorf("%s is required", field.name)
+		}
+		*field.dst = value
 	}
 
-	if subject, ok := evaluatedConfig["subject"].(string); ok {
-		config.Subject = subject
-	} else {
-		return nil, fmt.Errorf("subject is required")
+	// Parse To field (can be string or list)
+	to, ok, err := coerce.Field(evaluatedConfig, "to", coerce.StringSlice)
+	if err != nil {
+		return nil, err
 	}
-
-	if body, ok := evaluatedConfig["body"].(string); ok {
-		config.Body = body
-	} else {
-		return nil, fmt.Errorf("body is required")
+	if !ok {
+		return nil, fmt.Errorf("to address(es) required")
 	}
+	config.To = to
 
 	// Extract optional fields
-	if port, ok := evaluatedConfig["port"].(int); ok {
-		config.Port = port
+	if config.Port, _, err = coerce.Field(evaluatedConfig, "port", coerce.Int); err != nil {
+		return nil, err
 	}
-
-	if username, ok := evaluatedConfig["username"].(string); ok {
-		config.Username = username
+	if config.Username, _, err = coerce.Field(evaluatedConfig, "username", coerce.String); err != nil {
+		return nil, err
 	}
-
-	if password, ok := evaluatedConfig["password"].(string); ok {
-		config.Password = password
+	if config.Password, _, err = coerce.Field(evaluatedConfig, "password", coerce.String); err != nil {
+		return nil, err
 	}
-
-	// Parse CC
-	if cc, ok := evaluatedConfig["cc"].([]interface{}); ok {
-		config.CC = make([]string, len(cc))
-		for i, addr := range cc {
-			config.CC[i] = fmt.Sprintf("%v", addr)
-		}
-	} else if cc, ok := evaluatedConfig["cc"].([]string); ok {
-		config.CC = cc
+	if config.CC, _, err = coerce.Field(evaluatedConfig, "cc", coerce.StringSlice); err != nil {
+		return nil, err
 	}
-
-	// Parse BCC
-	if bcc, ok := evaluatedConfig["bcc"].([]interface{}); ok {
-		config.BCC = make([]string, len(bcc))
-		for i, addr := range bcc {
-			config.BCC[i] = fmt.Sprintf("%v", addr)
-		}
-	} else if bcc, ok := evaluatedConfig["bcc"].([]string); ok {
-		config.BCC = bcc
+	if config.BCC, _, err = coerce.Field(evaluatedConfig, "bcc", coerce.StringSlice); err != nil {
+		return nil, err
 	}
-
-	if isHTML, ok := evaluatedConfig["is_html"].(bool); ok {
-		config.IsHTML = isHTML
+	if config.IsHTML, _, err = coerce.Field(evaluatedConfig, "is_html", coerce.Bool); err != nil {
+		return nil, err
 	}
-
-	if useTLS, ok := evaluatedConfig["use_tls"].(bool); ok {
-		config.UseTLS = useTLS
+	if config.InsecureSkipVerify, _, err = coerce.Field(evaluatedConfig, "insecure_skip_verify", coerce.Bool); err != nil {
+		return nil, err
 	}
 
-	if insecure, ok := evaluatedConfig["insecure_skip_verify"].(bool); ok {
-		config.InsecureSkipVerify = insecure
+	if useTLS, ok, err := coerce.Field(evaluatedConfig, "use_tls", coerce.Bool); err != nil {
+		return nil, err
+	} else if ok {
+		config.UseTLS = useTLS
 	}
 
 	return config, nil
@@ -14,6 +14,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/sarlalian/ritual/pkg/coerce"
 	"github.com/sarlalian/ritual/pkg/types"
 )
 
@@ -77,6 +78,7 @@ func (e *Executor) Execute(ctx context.Context, task *types.TaskConfig, contextM
 
 	// Update result
 	result.Status = execResult.Status
+	result.Changed = execResult.Changed
 	result.Message = execResult.Message
 	result.Output = execResult.Output
 	result.EndTime = time.Now()
@@ -132,6 +134,40 @@ func (e *Executor) SupportsDryRun() bool {
 	return true
 }
 
+// ReportsChange returns true as results are only marked changed when the
+// task changed something
+func (e *Executor) ReportsChange() bool {
+	return true
+}
+
+// ConfigSchema describes the fields of a file task
+func (e *Executor) ConfigSchema() *types.ConfigSchema {
+	return &types.ConfigSchema{
+		Description: "Create, update or remove files, directories and links",
+		Fields: []types.FieldSchema{
+			{Name: "path", Type: types.FieldString, Required: true, Description: "Path to manage"},
+			{Name: "state", Type: types.FieldString, Description: "What path should be", Default: StateFile,
+				Enum: []string{StatePresent, StateAbsent, StateFile, StateDir, StateLink, StateTouch}},
+			{Name: "source", Type: types.FieldString, Description: "File to copy from, or the target of a link"},
+			{Name: "content", Type: types.FieldString, Description: "Content to write"},
+			{Name: "mode", Type: types.FieldString, Description: "Octal permissions as a quoted string, such as \"0644\""},
+			{Name: "owner", Type: types.FieldString, Description: "Owning user"},
+			{Name: "group", Type: types.FieldString, Description: "Owning group"},
+			{Name: "backup", Type: types.FieldBoolean, Description: "Keep a copy of a file before changing it"},
+			{Name: "backup_ext", Type: types.FieldString, Description: "Extension of backups", Default: ".bak"},
+			{Name: "create_dirs", Type: types.FieldBoolean, Description: "Create missing parent directories"},
+			{Name: "force", Type: types.FieldBoolean, Description: "Replace what is at path even when it is of another kind"},
+			{Name: "template", Type: types.FieldBoolean, Description: "Render source as a template"},
+			{Name: "attributes", Type: types.FieldObject, Description: "Extended attributes"},
+		},
+	}
+}
+
+// OutputFields lists the keys this task may set in its result's Output map
+func (e *Executor) OutputFields() []string {
+	return []string{"backup_file", "changed", "exists", "group", "mode", "owner", "path"}
+}
+
 // parseConfig parses and evaluates the task configuration
 func (e *Executor) parseConfig(task *types.TaskConfig, contextManager types.ContextManager) (*FileConfig, error) {
 	// First evaluate all templates in the config
@@ -164,98 +200,42 @@ func (e *Executor) parseConfigRaw(configMap map[string]interface{}) (*FileConfig
 		BackupExt: ".bak",    // Default backup extension
 	}
 
+	var err error
 	for key, value := range configMap {
+		if value == nil {
+			continue
+		}
+
 		switch key {
 		case "path":
-			if str, ok := value.(string); ok {
-				config.Path = str
-			} else {
-				return nil, fmt.Errorf("path must be a string")
-			}
-
+			config.Path, err = coerce.String(value)
 		case "state":
-			if str, ok := value.(string); ok {
-				config.State = str
-			} else {
-				return nil, fmt.Errorf("state must be a string")
-			}
-
+			config.State, err = coerce.String(value)
 		case "source":
-			if str, ok := value.(string); ok {
-				config.Source = str
-			} else {
-				return nil, fmt.Errorf("source must be a string")
-			}
-
+			config.Source, err = coerce.String(value)
 		case "content":
-			if str, ok := value.(string); ok {
-				config.Content = str
-			} else {
-				return nil, fmt.Errorf("content must be a string")
-			}
-
+			config.Content, err = coerce.String(value)
 		case "mode":
-			if str, ok := value.(string); ok {
-				config.Mode = str
-			} else {
-				return nil, fmt.Errorf("mode must be a string")
-			}
-
+			config.Mode, err = coerce.String(value)
 		case "owner":
-			if str, ok := value.(string); ok {
-				config.Owner = str
-			} else {
-				return nil, fmt.Errorf("owner must be a string")
-			}
-
+			config.Owner, err = coerce.String(value)
 		case "group":
-			if str, ok := value.(string); ok {
-				config.Group = str
-			} else {
-				return nil, fmt.Errorf("group must be a string")
-			}
-
+			config.Group, err = coerce.String(value)
 		case "backup":
-			if b, ok := value.(bool); ok {
-				config.Backup = b
-			} else {
-				return nil, fmt.Errorf("backup must be a boolean")
-			}
-
+			config.Backup, err = coerce.Bool(value)
 		case "backup_ext":
-			if str, ok := value.(string); ok {
-				config.BackupExt = str
-			} else {
-				return nil, fmt.Errorf("backup_ext must be a string")
-			}
-
+			config.BackupExt, err = coerce.String(value)
 		case "create_dirs":
-			if b, ok := value.(bool); ok {
-				config.CreateDirs = b
-			} else {
-				return nil, fmt.Errorf("create_dirs must be a boolean")
-			}
-
+			config.CreateDirs, err = coerce.Bool(value)
 		case "force":
-			if b, ok := value.(bool); ok {
-				config.Force = b
-			} else {
-				return nil, fmt.Errorf("force must be a boolean")
-			}
-
+			config.Force, err = coerce.Bool(value)
 		case "template":
-			if b, ok := value.(bool); ok {
-				config.Template = b
-			} else {
-				return nil, fmt.Errorf("template must be a boolean")
-			}
-
+			config.Template, err = coerce.Bool(value)
 		case "attributes":
-			if attrs, ok := value.(map[string]interface{}); ok {
-				config.Attributes = attrs
-			} else {
-				return nil, fmt.Errorf("attributes must be a map")
-			}
+			config.Attributes, err = coerce.Map(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
 		}
 	}
 
@@ -316,7 +296,7 @@ func (e *Executor) handleAbsent(path string, exists bool, config *FileConfig, re
 	if !exists {
 		result.Status = types.TaskSuccess
 		result.Message = "File is already absent"
-		result.Output["changed"] = false
+		setChanged(result, false)
 		return result
 	}
 
@@ -340,7 +320,7 @@ func (e *Executor) handleAbsent(path string, exists bool, config *FileConfig, re
 
 	result.Status = types.TaskSuccess
 	result.Message = "File removed successfully"
-	result.Output["changed"] = true
+	setChanged(result, true)
 	return result
 }
 
@@ -396,7 +376,7 @@ func (e *Executor) handleDirectory(path string, info os.FileInfo, exists bool, c
 
 	result.Status = types.TaskSuccess
 	result.Message = "Directory created successfully"
-	result.Output["changed"] = true
+	setChanged(result, true)
 	return result
 }
 
@@ -424,7 +404,7 @@ func (e *Executor) handleTouch(path string, info os.FileInfo, exists bool, confi
 
 		result.Status = types.TaskSuccess
 		result.Message = "File created successfully"
-		result.Output["changed"] = true
+		setChanged(result, true)
 	} else {
 		// Update timestamp
 		now := time.Now()
@@ -436,7 +416,7 @@ func (e *Executor) handleTouch(path string, info os.FileInfo, exists bool, confi
 
 		result.Status = types.TaskSuccess
 		result.Message = "File timestamp updated"
-		result.Output["changed"] = true
+		setChanged(result, true)
 	}
 
 	return e.updatePermissions(path, nil, config, result)
@@ -534,14 +514,14 @@ func (e *Executor) handleFile(path string, info os.FileInfo, exists bool, config
 			_ = file.Close()
 		}
 
-		result.Output["changed"] = true
+		setChanged(result, true)
 		if !exists {
 			result.Message = "File created successfully"
 		} else {
 			result.Message = "File updated successfully"
 		}
 	} else {
-		result.Output["changed"] = false
+		setChanged(result, false)
 		result.Message = "File is already up to date"
 	}
 
@@ -560,10 +540,18 @@ func (e *Executor) updatePermissions(path string, info os.FileInfo, config *File
 		}
 
 		mode := fs.FileMode(modeInt)
-		if err := os.Chmod(path, mode); err != nil {
-			result.Status = types.TaskFailed
-			result.Message = fmt.Sprintf("Failed to set permissions: %v", err)
-			return result
+		modeChanged := true
+		if current, err := os.Stat(path); err == nil {
+			modeChanged = current.Mode().Perm() != mode.Perm()
+		}
+
+		if modeChanged {
+			if err := os.Chmod(path, mode); err != nil {
+				result.Status = types.TaskFailed
+				result.Message = fmt.Sprintf("Failed to set permissions: %v", err)
+				return result
+			}
+			setChanged(result, true)
 		}
 
 		result.Output["mode"] = config.Mode
@@ -581,13 +569,24 @@ func (e *Executor) updatePermissions(path string, info os.FileInfo, config *File
 	// Set success status if no errors occurred
 	if result.Status == types.TaskRunning {
 		result.Status = types.TaskSuccess
-		result.Message = "Permissions updated successfully"
-		result.Output["changed"] = false
+		if result.Changed {
+			result.Message = "Permissions updated successfully"
+		} else {
+			result.Message = "Directory is already up to date"
+			setChanged(result, false)
+		}
 	}
 
 	return result
 }
 
+// setChanged records whether the operation modified the file system, both on the
+// result itself and in its output for templates that read .Output.changed
+func setChanged(result *types.TaskResult, changed bool) {
+	result.Changed = changed
+	result.Output["changed"] = changed
+}
+
 // copyFile copies a file from src to dst
 func (e *Executor) copyFile(src, dst string) error {
 	sourceFile, err := os.Open(src)
@@ -576,3 +576,45 @@ func TestExecutor_Execute_BackupFile(t *testing.T) {
 		t.Errorf("Expected backup_file in output to be '%s', got %v", backupFile, backupPath)
 	}
 }
+
+func TestExecutor_Execute_DirectoryModeIdempotent(t *testing.T) {
+	executor := New()
+	contextManager := &MockContextManager{}
+
+	tmpDir := t.TempDir()
+	dirPath := filepath.Join(tmpDir, "existing")
+	if err := os.Mkdir(dirPath, 0750); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.Chmod(dirPath, 0750); err != nil {
+		t.Fatalf("Failed to chmod directory: %v", err)
+	}
+
+	task := &types.TaskConfig{
+		ID:   "test",
+		Name: "Ensure Directory",
+		Type: "file",
+		Config: map[string]interface{}{
+			"path":  dirPath,
+			"state": "directory",
+			"mode":  "0750",
+		},
+	}
+
+	result := executor.Execute(context.Background(), task, contextManager)
+	if result.Status != types.TaskSuccess {
+		t.Fatalf("Expected task success, got %s: %s", result.Status, result.Message)
+	}
+	if result.Changed {
+		t.Errorf("Expected unchanged result for directory with matching mode, got: %s", result.Message)
+	}
+
+	task.Config["mode"] = "0700"
+	result = executor.Execute(context.Background(), task, contextManager)
+	if result.Status != types.TaskSuccess {
+		t.Fatalf("Expected task success, got %s: %s", result.Status, result.Message)
+	}
+	if !result.Changed {
+		t.Error("Expected changed result after mode change")
+	}
+}
@@ -4,6 +4,11 @@
 package tasks
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sarlalian/ritual/internal/tasks/approval"
 	"github.com/sarlalian/ritual/internal/tasks/checksum"
 	"github.com/sarlalian/ritual/internal/tasks/command"
 	"github.com/sarlalian/ritual/internal/tasks/compress"
@@ -77,6 +82,9 @@ func (r *Registry) RegisterBuiltinTasks() {
 	// Debug task for logging templated strings
 	r.Register("debug", debug.New())
 	r.Register("log", debug.New()) // Alias
+
+	// Approval task for pausing a branch until a human signs off
+	r.Register("approval", approval.New())
 }
 
 // Register adds a task executor for a specific task type
@@ -90,6 +98,34 @@ func (r *Registry) Get(taskType string) (types.TaskExecutor, bool) {
 	return executor, exists
 }
 
+// OutputFields returns the Output keys declared by a task type's executor.
+// ok is false when the type is unknown or does not declare its outputs.
+func (r *Registry) OutputFields(taskType string) ([]string, bool) {
+	executor, exists := r.Get(taskType)
+	if !exists {
+		return nil, false
+	}
+	describer, ok := executor.(types.OutputDescriber)
+	if !ok {
+		return nil, false
+	}
+	return describer.OutputFields(), true
+}
+
+// ConfigSchema returns the config fields declared by a task type's executor.
+// ok is false when the type is unknown or does not describe its config.
+func (r *Registry) ConfigSchema(taskType string) (*types.ConfigSchema, bool) {
+	executor, exists := r.Get(taskType)
+	if !exists {
+		return nil, false
+	}
+	describer, ok := executor.(types.ConfigSchemaDescriber)
+	if !ok {
+		return nil, false
+	}
+	return describer.ConfigSchema(), true
+}
+
 // GetAvailableTypes returns all registered task types
 func (r *Registry) GetAvailableTypes() []string {
 	types := make([]string, 0, len(r.executors))
@@ -126,7 +162,38 @@ func (r *Registry) Validate(task *types.TaskConfig) error {
 		}
 	}
 
-	return executor.Validate(task)
+	if err := executor.Validate(task); err != nil {
+		return types.NewTaskError(task.ID, task.Name, task.Type, "", err).At(task.Source.Lookup(configKeyInError(task, err)))
+	}
+	return nil
+}
+
+// configKeyInError guesses which config key a validation error is about,
+// from the key or its value being quoted in the message, as in "invalid
+// state 'presnt'" or "must specify 'path'". It returns "" when no key is
+// quoted, which is the position of the task itself.
+func configKeyInError(task *types.TaskConfig, err error) string {
+	message := err.Error()
+	keys := make([]string, 0, len(task.Config))
+	for key := range task.Config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if strings.Contains(message, "'"+key+"'") {
+			return key
+		}
+	}
+	for _, key := range keys {
+		switch value := task.Config[key].(type) {
+		case string, int, float64, bool:
+			if strings.Contains(message, fmt.Sprintf("'%v'", value)) {
+				return key
+			}
+		}
+	}
+	return ""
 }
 
 // ValidateAll validates multiple task configurations
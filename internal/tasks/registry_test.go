@@ -258,6 +258,60 @@ func TestRegistry_RegisterToExecutor(t *testing.T) {
 	}
 }
 
+func TestRegistry_ConfigSchema(t *testing.T) {
+	registry := New()
+	registry.Register("mock", &MockTaskExecutor{})
+
+	config, ok := registry.ConfigSchema("checksum")
+	if !ok || len(config.Fields) == 0 {
+		t.Fatalf("Expected the checksum task to describe its config, got %v", config)
+	}
+	if _, ok := registry.ConfigSchema("mock"); ok {
+		t.Error("Expected no schema for an executor that does not describe its config")
+	}
+	if _, ok := registry.ConfigSchema("nonexistent"); ok {
+		t.Error("Expected no schema for an unknown task type")
+	}
+}
+
+func TestRegistry_ValidatePosition(t *testing.T) {
+	registry := New()
+	source := types.SourceMap{
+		"":      {File: "wf.yaml", Line: 7, Column: 5},
+		"path":  {File: "wf.yaml", Line: 8, Column: 5},
+		"state": {File: "wf.yaml", Line: 9, Column: 5},
+	}
+
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		line   int
+	}{
+		{"quoted value", map[string]interface{}{"path": "/tmp/x", "state": "presnt"}, 9},
+		{"missing key", map[string]interface{}{"state": "present"}, 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := &types.TaskConfig{ID: "dir", Name: "Make Dir", Type: "file", Config: tt.config, Source: source}
+			err := registry.Validate(task)
+			if err == nil {
+				t.Fatal("Expected validation error")
+			}
+			taskErr, ok := err.(*types.TaskError)
+			if !ok {
+				t.Fatalf("Expected TaskError, got %T", err)
+			}
+			if taskErr.Position.Line != tt.line {
+				t.Errorf("Expected line %d, got %v", tt.line, taskErr.Position)
+			}
+			if !strings.Contains(err.Error(), "task 'Make Dir (file)' at wf.yaml:") {
+				t.Errorf("Expected task and position in error, got %q", err.Error())
+			}
+		})
+	}
+}
+
 // Mock task executor for testing
 type MockTaskExecutor struct{}
 
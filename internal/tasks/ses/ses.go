@@ -5,6 +5,7 @@ package ses
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -15,6 +16,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"     //nolint:staticcheck // TODO: Migrate to AWS SDK v2
 	"github.com/aws/aws-sdk-go/service/ses"     //nolint:staticcheck // TODO: Migrate to AWS SDK v2
 
+	"github.com/sarlalian/ritual/pkg/coerce"
 	"github.com/sarlalian/ritual/pkg/types"
 )
 
@@ -209,6 +211,47 @@ func (e *Executor) SupportsDryRun() bool {
 	return true
 }
 
+// ConfigSchema describes the fields of an ses task
+func (e *Executor) ConfigSchema() *types.ConfigSchema {
+	return &types.ConfigSchema{
+		Description: "Send an email through Amazon SES",
+		Fields: []types.FieldSchema{
+			{Name: "region", Type: types.FieldString, Required: true},
+			{Name: "access_key_id", Type: types.FieldString},
+			{Name: "secret_access_key", Type: types.FieldString},
+			{Name: "session_token", Type: types.FieldString},
+			{Name: "from", Type: types.FieldString, Required: true, Description: "Verified sender address"},
+			{Name: "from_name", Type: types.FieldString},
+			{Name: "to", Type: types.FieldStringList, Required: true},
+			{Name: "cc", Type: types.FieldStringList},
+			{Name: "bcc", Type: types.FieldStringList},
+			{Name: "reply_to", Type: types.FieldStringList},
+			{Name: "subject", Type: types.FieldString, Required: true},
+			{Name: "body", Type: types.FieldString, Description: "Plain text body; one of body, body_html or template is required"},
+			{Name: "body_html", Type: types.FieldString},
+			{Name: "charset", Type: types.FieldString, Default: "UTF-8"},
+			{Name: "configuration_set", Type: types.FieldString},
+			{Name: "return_path", Type: types.FieldString},
+			{Name: "source_arn", Type: types.FieldString},
+			{Name: "return_path_arn", Type: types.FieldString},
+			{Name: "template", Type: types.FieldString, Description: "SES template name"},
+			{Name: "template_data", Type: types.FieldAny, Description: "Template data as a map or a JSON string"},
+			{Name: "tags", Type: types.FieldStringMap, Description: "Message tags"},
+			{Name: "dry_run", Type: types.FieldBoolean, Description: "Build the message without sending it"},
+		},
+	}
+}
+
+// OutputFields lists the keys this task may set in its result's Output map
+func (e *Executor) OutputFields() []string {
+	return []string{"dry_run", "from", "message_id", "region", "subject", "template", "to"}
+}
+
+// SecretFields returns the config fields holding credentials
+func (e *Executor) SecretFields() []string {
+	return []string{"secret_access_key", "session_token"}
+}
+
 // parseConfig parses and evaluates the configuration with template evaluation
 func (e *Executor) parseConfig(task *types.TaskConfig, contextManager types.ContextManager) (*SESConfig, error) {
 	// First evaluate templates in the config
@@ -227,135 +270,75 @@ func (e *Executor) parseConfigRaw(config map[string]interface{}) (*SESConfig, er
 		Charset: "UTF-8",
 	}
 
+	stringFields := map[string]*string{
+		"region":            &sesConfig.Region,
+		"access_key_id":     &sesConfig.AccessKeyID,
+		"secret_access_key": &sesConfig.SecretAccessKey,
+		"session_token":     &sesConfig.SessionToken,
+		"from":              &sesConfig.From,
+		"from_name":         &sesConfig.FromName,
+		"subject":           &sesConfig.Subject,
+		"body":              &sesConfig.Body,
+		"body_html":         &sesConfig.BodyHTML,
+		"charset":           &sesConfig.Charset,
+		"configuration_set": &sesConfig.ConfigurationSet,
+		"return_path":       &sesConfig.ReturnPath,
+		"source_arn":        &sesConfig.SourceArn,
+		"return_path_arn":   &sesConfig.ReturnPathArn,
+		"template":          &sesConfig.Template,
+	}
+	listFields := map[string]*[]string{
+		"to":       &sesConfig.To,
+		"cc":       &sesConfig.CC,
+		"bcc":      &sesConfig.BCC,
+		"reply_to": &sesConfig.ReplyTo,
+	}
+
+	var err error
 	for key, value := range config {
-		switch key {
-		case "region":
-			if str, ok := value.(string); ok {
-				sesConfig.Region = str
-			}
-		case "access_key_id":
-			if str, ok := value.(string); ok {
-				sesConfig.AccessKeyID = str
-			}
-		case "secret_access_key":
-			if str, ok := value.(string); ok {
-				sesConfig.SecretAccessKey = str
-			}
-		case "session_token":
-			if str, ok := value.(string); ok {
-				sesConfig.SessionToken = str
-			}
-		case "from":
-			if str, ok := value.(string); ok {
-				sesConfig.From = str
-			}
-		case "from_name":
-			if str, ok := value.(string); ok {
-				sesConfig.FromName = str
-			}
-		case "to":
-			if str, ok := value.(string); ok {
-				sesConfig.To = []string{str}
-			} else if list, ok := value.([]interface{}); ok {
-				for _, item := range list {
-					if str, ok := item.(string); ok {
-						sesConfig.To = append(sesConfig.To, str)
-					}
-				}
-			} else if list, ok := value.([]string); ok {
-				sesConfig.To = list
-			}
-		case "cc":
-			if list, ok := value.([]interface{}); ok {
-				for _, item := range list {
-					if str, ok := item.(string); ok {
-						sesConfig.CC = append(sesConfig.CC, str)
-					}
-				}
-			} else if list, ok := value.([]string); ok {
-				sesConfig.CC = list
-			}
-		case "bcc":
-			if list, ok := value.([]interface{}); ok {
-				for _, item := range list {
-					if str, ok := item.(string); ok {
-						sesConfig.BCC = append(sesConfig.BCC, str)
-					}
-				}
-			} else if list, ok := value.([]string); ok {
-				sesConfig.BCC = list
-			}
-		case "reply_to":
-			if list, ok := value.([]interface{}); ok {
-				for _, item := range list {
-					if str, ok := item.(string); ok {
-						sesConfig.ReplyTo = append(sesConfig.ReplyTo, str)
-					}
-				}
-			} else if list, ok := value.([]string); ok {
-				sesConfig.ReplyTo = list
-			}
-		case "subject":
-			if str, ok := value.(string); ok {
-				sesConfig.Subject = str
-			}
-		case "body":
-			if str, ok := value.(string); ok {
-				sesConfig.Body = str
-			}
-		case "body_html":
-			if str, ok := value.(string); ok {
-				sesConfig.BodyHTML = str
-			}
-		case "charset":
-			if str, ok := value.(string); ok {
-				sesConfig.Charset = str
-			}
-		case "configuration_set":
-			if str, ok := value.(string); ok {
-				sesConfig.ConfigurationSet = str
-			}
-		case "return_path":
-			if str, ok := value.(string); ok {
-				sesConfig.ReturnPath = str
-			}
-		case "source_arn":
-			if str, ok := value.(string); ok {
-				sesConfig.SourceArn = str
-			}
-		case "return_path_arn":
-			if str, ok := value.(string); ok {
-				sesConfig.ReturnPathArn = str
-			}
-		case "tags":
-			if m, ok := value.(map[string]interface{}); ok {
-				sesConfig.Tags = make(map[string]string)
-				for k, v := range m {
-					if str, ok := v.(string); ok {
-						sesConfig.Tags[k] = str
-					}
-				}
-			} else if m, ok := value.(map[string]string); ok {
-				sesConfig.Tags = m
-			}
-		case "template":
-			if str, ok := value.(string); ok {
-				sesConfig.Template = str
-			}
-		case "template_data":
-			if str, ok := value.(string); ok {
-				sesConfig.TemplateData = str
-			}
-		case "dry_run":
-			if b, ok := value.(bool); ok {
-				sesConfig.DryRun = b
+		if value == nil {
+			continue
+		}
+
+		if dst, ok := stringFields[key]; ok {
+			*dst, err = coerce.String(value)
+		} else if dst, ok := listFields[key]; ok {
+			*dst, err = coerce.StringSlice(value)
+		} else {
+			switch key {
+			case "tags":
+				sesConfig.Tags, err = coerce.StringMap(value)
+			case "template_data":
+				sesConfig.TemplateData, err = templateData(value)
+			case "dry_run":
+				sesConfig.DryRun, err = coerce.Bool(value)
 			}
 		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
 	}
 
 	return sesConfig, nil
 }
 
+// templateData accepts SES template data as a JSON string or as a map,
+// which is encoded to JSON
+func templateData(value interface{}) (string, error) {
+	if str, ok := value.(string); ok {
+		return str, nil
+	}
+	m, err := coerce.Map(value)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
 // sendEmail sends an email using AWS SES
 func (e *Executor) sendEmail(ctx context.Context, config *SESConfig) (string, error) {
 	// Create AWS session
@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/sarlalian/ritual/pkg/coerce"
 	"github.com/sarlalian/ritual/pkg/types"
 )
 
@@ -120,14 +121,15 @@ func (e *Executor) Execute(ctx context.Context, task *types.TaskConfig, contextM
 
 // Validate checks if the task configuration is valid
 func (e *Executor) Validate(task *types.TaskConfig) error {
-	// Webhook URL is required
-	if webhookURL, ok := task.Config["webhook_url"].(string); !ok || webhookURL == "" {
-		return fmt.Errorf("slack task '%s': webhook_url is required", task.Name)
-	}
-
-	// Message is required
-	if message, ok := task.Config["message"].(string); !ok || message == "" {
-		return fmt.Errorf("slack task '%s': message is required", task.Name)
+	// Webhook URL and message are required
+	for _, key := range []string{"webhook_url", "message"} {
+		value, _, err := coerce.Field(task.Config, key, coerce.String)
+		if err != nil {
+			return fmt.Errorf("slack task '%s': %w", task.Name, err)
+		}
+		if value == "" {
+			return fmt.Errorf("slack task '%s': %s is required", task.Name, key)
+		}
 	}
 
 	return nil
@@ -138,6 +140,35 @@ func (e *Executor) SupportsDryRun() bool {
 	return true
 }
 
+// ConfigSchema describes the fields of a slack task
+func (e *Executor) ConfigSchema() *types.ConfigSchema {
+	return &types.ConfigSchema{
+		Description: "Post a message to a Slack incoming webhook",
+		Fields: []types.FieldSchema{
+			{Name: "webhook_url", Type: types.FieldString, Required: true},
+			{Name: "message", Type: types.FieldString, Required: true},
+			{Name: "channel", Type: types.FieldString, Description: "Overrides the webhook's channel"},
+			{Name: "username", Type: types.FieldString},
+			{Name: "icon_emoji", Type: types.FieldString, Description: "Such as :robot_face:"},
+			{Name: "icon_url", Type: types.FieldString},
+			{Name: "color", Type: types.FieldString, Description: "good, warning, danger or a hex color"},
+			{Name: "title", Type: types.FieldString},
+			{Name: "title_link", Type: types.FieldString},
+			{Name: "fields", Type: types.FieldStringMap, Description: "Attachment fields"},
+		},
+	}
+}
+
+// OutputFields lists the keys this task may set in its result's Output map
+func (e *Executor) OutputFields() []string {
+	return []string{"channel", "message"}
+}
+
+// SecretFields returns the config fields holding credentials
+func (e *Executor) SecretFields() []string {
+	return []string{"webhook_url"}
+}
+
 // parseConfig extracts and evaluates the configuration
 func (e *Executor) parseConfig(task *types.TaskConfig, contextManager types.ContextManager) (*SlackConfig, error) {
 	config := &SlackConfig{}
@@ -149,55 +180,43 @@ func (e *Executor) parseConfig(task *types.TaskConfig, contextManager types.Cont
 	}
 
 	// Extract required fields
-	if webhookURL, ok := evaluatedConfig["webhook_url"].(string); ok {
-		config.WebhookURL = webhookURL
-	} else {
-		return nil, fmt.Errorf("webhook_url is required")
+	required := []struct {
+		key string
+		dst *string
+	}{
+		{"webhook_url", &config.WebhookURL},
+		{"message", &config.Message},
 	}
-
-	if message, ok := evaluatedConfig["message"].(string); ok {
-		config.Message = message
-	} else {
-		return nil, fmt.Errorf("message is required")
+	for _, field := range required {
+		value, ok, err := coerce.Field(evaluatedConfig, field.key, coerce.String)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("%s is required", field.key)
+		}
+		*field.dst = value
 	}
 
 	// Extract optional fields
-	if channel, ok := evaluatedConfig["channel"].(string); ok {
-		config.Channel = channel
+	optional := map[string]*string{
+		"channel":    &config.Channel,
+		"username":   &config.Username,
+		"icon_emoji": &config.IconEmoji,
+		"icon_url":   &config.IconURL,
+		"color":      &config.Color,
+		"title":      &config.Title,
+		"title_link": &config.TitleLink,
 	}
-
-	if username, ok := evaluatedConfig["username"].(string); ok {
-		config.Username = username
-	}
-
-	if iconEmoji, ok := evaluatedConfig["icon_emoji"].(string); ok {
-		config.IconEmoji = iconEmoji
-	}
-
-	if iconURL, ok := evaluatedConfig["icon_url"].(string); ok {
-		config.IconURL = iconURL
-	}
-
-	if color, ok := evaluatedConfig["color"].(string); ok {
-		config.Color = color
-	}
-
-	if title, ok := evaluatedConfig["title"].(string); ok {
-		config.Title = title
-	}
-
-	if titleLink, ok := evaluatedConfig["title_link"].(string); ok {
-		config.TitleLink = titleLink
+	for key, dst := range optional {
+		if *dst, _, err = coerce.Field(evaluatedConfig, key, coerce.String); err != nil {
+			return nil, err
+		}
 	}
 
 	// Extract fields
-	if fields, ok := evaluatedConfig["fields"].(map[string]interface{}); ok {
-		config.Fields = make(map[string]string)
-		for k, v := range fields {
-			config.Fields[k] = fmt.Sprintf("%v", v)
-		}
-	} else if fields, ok := evaluatedConfig["fields"].(map[string]string); ok {
-		config.Fields = fields
+	if config.Fields, _, err = coerce.Field(evaluatedConfig, "fields", coerce.StringMap); err != nil {
+		return nil, err
 	}
 
 	return config, nil
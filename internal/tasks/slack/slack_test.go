@@ -52,6 +52,28 @@ func TestSlack_Validate(t *testing.T) {
 			},
 			shouldErr: true,
 		},
+		{
+			name: "Numeric message",
+			task: &types.TaskConfig{
+				Name: "Test",
+				Config: map[string]interface{}{
+					"webhook_url": "https://hooks.slack.com/services/TEST/WEBHOOK/URL",
+					"message":     42,
+				},
+			},
+			shouldErr: false,
+		},
+		{
+			name: "List message",
+			task: &types.TaskConfig{
+				Name: "Test",
+				Config: map[string]interface{}{
+					"webhook_url": "https://hooks.slack.com/services/TEST/WEBHOOK/URL",
+					"message":     []interface{}{"a", "b"},
+				},
+			},
+			shouldErr: true,
+		},
 		{
 			name: "Missing message",
 			task: &types.TaskConfig{
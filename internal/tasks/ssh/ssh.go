@@ -4,9 +4,9 @@
 package ssh
 
 import (
-	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -14,6 +14,9 @@ import (
 
 	"golang.org/x/crypto/ssh"
 
+	"github.com/sarlalian/ritual/internal/events"
+	"github.com/sarlalian/ritual/internal/output"
+	"github.com/sarlalian/ritual/pkg/coerce"
 	"github.com/sarlalian/ritual/pkg/types"
 )
 
@@ -121,10 +124,20 @@ func (e *Executor) Execute(ctx context.Context, task *types.TaskConfig, contextM
 		command = strings.Join(envPrefix, "; ") + "; " + command
 	}
 
-	// Capture output
-	var stdout, stderr bytes.Buffer
-	session.Stdout = &stdout
-	session.Stderr = &stderr
+	// Capture output, publishing lines as they arrive. Only the tail of each
+	// stream is kept in the result; the rest is spooled to a file.
+	settings := output.SettingsFromContext(ctx)
+	executionID := types.ExecutionIDFromContext(ctx)
+	stdout := output.NewCapture(settings, executionID, task.ID, events.StreamStdout)
+	stderr := output.NewCapture(settings, executionID, task.ID, events.StreamStderr)
+	defer func() { _ = stdout.Close() }()
+	defer func() { _ = stderr.Close() }()
+	stdoutLines := events.NewLineWriter(ctx, task, events.StreamStdout)
+	stderrLines := events.NewLineWriter(ctx, task, events.StreamStderr)
+	defer stdoutLines.Flush()
+	defer stderrLines.Flush()
+	session.Stdout = io.MultiWriter(stdout, stdoutLines)
+	session.Stderr = io.MultiWriter(stderr, stderrLines)
 
 	// Execute command with timeout
 	errChan := make(chan error, 1)
@@ -169,6 +182,8 @@ func (e *Executor) Execute(ctx context.Context, task *types.TaskConfig, contextM
 		}
 	}
 
+	stdout.Annotate(result.Output, events.StreamStdout)
+	stderr.Annotate(result.Output, events.StreamStderr)
 	result.Output["host"] = fmt.Sprintf("%s@%s:%d", config.User, config.Host, config.Port)
 	result.Output["command"] = config.Command
 
@@ -177,39 +192,26 @@ func (e *Executor) Execute(ctx context.Context, task *types.TaskConfig, contextM
 
 // Validate checks if the task configuration is valid
 func (e *Executor) Validate(task *types.TaskConfig) error {
-	config := &SSHConfig{}
-
 	// Extract and validate required fields
-	if host, ok := task.Config["host"].(string); ok {
-		config.Host = host
-	} else {
-		return fmt.Errorf("ssh task '%s': host is required", task.Name)
-	}
-
-	if user, ok := task.Config["user"].(string); ok {
-		config.User = user
-	} else {
-		return fmt.Errorf("ssh task '%s': user is required", task.Name)
-	}
-
-	if command, ok := task.Config["command"].(string); ok {
-		config.Command = command
-	} else {
-		return fmt.Errorf("ssh task '%s': command is required", task.Name)
+	for _, key := range []string{"host", "user", "command"} {
+		if _, ok, err := coerce.Field(task.Config, key, coerce.String); err != nil {
+			return fmt.Errorf("ssh task '%s': %w", task.Name, err)
+		} else if !ok {
+			return fmt.Errorf("ssh task '%s': %s is required", task.Name, key)
+		}
 	}
 
 	// At least one authentication method is required
-	hasPassword := false
-	if password, ok := task.Config["password"].(string); ok && password != "" {
-		hasPassword = true
+	password, _, err := coerce.Field(task.Config, "password", coerce.String)
+	if err != nil {
+		return fmt.Errorf("ssh task '%s': %w", task.Name, err)
 	}
-
-	hasKeyFile := false
-	if keyFile, ok := task.Config["key_file"].(string); ok && keyFile != "" {
-		hasKeyFile = true
+	keyFile, _, err := coerce.Field(task.Config, "key_file", coerce.String)
+	if err != nil {
+		return fmt.Errorf("ssh task '%s': %w", task.Name, err)
 	}
 
-	if !hasPassword && !hasKeyFile {
+	if password == "" && keyFile == "" {
 		return fmt.Errorf("ssh task '%s': either password or key_file must be provided", task.Name)
 	}
 
@@ -221,6 +223,34 @@ func (e *Executor) SupportsDryRun() bool {
 	return true
 }
 
+// ConfigSchema describes the fields of an ssh task
+func (e *Executor) ConfigSchema() *types.ConfigSchema {
+	return &types.ConfigSchema{
+		Description: "Run a command on a remote host over SSH",
+		Fields: []types.FieldSchema{
+			{Name: "host", Type: types.FieldString, Required: true},
+			{Name: "port", Type: types.FieldInteger, Default: 22},
+			{Name: "user", Type: types.FieldString, Required: true},
+			{Name: "password", Type: types.FieldString, Description: "Password; either password or key_file is required"},
+			{Name: "key_file", Type: types.FieldString, Description: "Private key file"},
+			{Name: "passphrase", Type: types.FieldString, Description: "Passphrase of an encrypted key_file"},
+			{Name: "command", Type: types.FieldString, Required: true},
+			{Name: "environment", Type: types.FieldStringMap, Description: "Environment variables set before the command"},
+			{Name: "timeout", Type: types.FieldDuration, Description: "Limit for connecting and running the command"},
+		},
+	}
+}
+
+// OutputFields lists the keys this task may set in its result's Output map
+func (e *Executor) OutputFields() []string {
+	return append([]string{"command", "host"}, output.AnnotationFields()...)
+}
+
+// SecretFields returns the config fields holding credentials
+func (e *Executor) SecretFields() []string {
+	return []string{"password", "passphrase"}
+}
+
 // parseConfig extracts and evaluates the configuration
 func (e *Executor) parseConfig(task *types.TaskConfig, contextManager types.ContextManager) (*SSHConfig, error) {
 	config := &SSHConfig{}
@@ -231,56 +261,46 @@ func (e *Executor) parseConfig(task *types.TaskConfig, contextManager types.Cont
 		return nil, fmt.Errorf("failed to evaluate config: %w", err)
 	}
 
-	// Extract host (required)
-	if host, ok := evaluatedConfig["host"].(string); ok {
-		config.Host = host
-	} else {
-		return nil, fmt.Errorf("host is required")
+	// Extract required fields
+	required := []struct {
+		key string
+		dst *string
+	}{
+		{"host", &config.Host},
+		{"user", &config.User},
+		{"command", &config.Command},
 	}
-
-	// Extract user (required)
-	if user, ok := evaluatedConfig["user"].(string); ok {
-		config.User = user
-	} else {
-		return nil, fmt.Errorf("user is required")
-	}
-
-	// Extract command (required)
-	if command, ok := evaluatedConfig["command"].(string); ok {
-		config.Command = command
-	} else {
-		return nil, fmt.Errorf("command is required")
+	for _, field := range required {
+		value, ok, err := coerce.Field(evaluatedConfig, field.key, coerce.String)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("%s is required", field.key)
+		}
+		*field.dst = value
 	}
 
 	// Extract optional fields
-	if port, ok := evaluatedConfig["port"].(int); ok {
-		config.Port = port
+	if config.Port, _, err = coerce.Field(evaluatedConfig, "port", coerce.Int); err != nil {
+		return nil, err
 	}
-
-	if password, ok := evaluatedConfig["password"].(string); ok {
-		config.Password = password
+	if config.Password, _, err = coerce.Field(evaluatedConfig, "password", coerce.String); err != nil {
+		return nil, err
 	}
-
-	if keyFile, ok := evaluatedConfig["key_file"].(string); ok {
-		config.KeyFile = keyFile
+	if config.KeyFile, _, err = coerce.Field(evaluatedConfig, "key_file", coerce.String); err != nil {
+		return nil, err
 	}
-
-	if passphrase, ok := evaluatedConfig["passphrase"].(string); ok {
-		config.Passphrase = passphrase
+	if config.Passphrase, _, err = coerce.Field(evaluatedConfig, "passphrase", coerce.String); err != nil {
+		return nil, err
 	}
-
-	if timeout, ok := evaluatedConfig["timeout"].(string); ok {
-		config.Timeout = timeout
+	if config.Timeout, _, err = coerce.Field(evaluatedConfig, "timeout", coerce.String); err != nil {
+		return nil, err
 	}
 
 	// Extract environment variables
-	if env, ok := evaluatedConfig["environment"].(map[string]string); ok {
-		config.Environment = env
-	} else if env, ok := evaluatedConfig["environment"].(map[string]interface{}); ok {
-		config.Environment = make(map[string]string)
-		for k, v := range env {
-			config.Environment[k] = fmt.Sprintf("%v", v)
-		}
+	if config.Environment, _, err = coerce.Field(evaluatedConfig, "environment", coerce.StringMap); err != nil {
+		return nil, err
 	}
 
 	return config, nil
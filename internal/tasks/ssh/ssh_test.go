@@ -6,6 +6,8 @@ package ssh
 import (
 	"testing"
 
+	ritualcontext "github.com/sarlalian/ritual/internal/context"
+	"github.com/sarlalian/ritual/internal/template"
 	"github.com/sarlalian/ritual/pkg/types"
 )
 
@@ -112,3 +114,36 @@ func TestSSH_SupportsDryRun(t *testing.T) {
 		t.Error("Expected SSH executor to support dry run")
 	}
 }
+
+func TestSSH_ParseConfig_TemplatedPort(t *testing.T) {
+	manager := ritualcontext.New(template.New())
+	_ = manager.SetVariable("ssh_port", 2222)
+	_ = manager.SetVariable("cli_port", "2200")
+
+	for _, tc := range []struct {
+		port     string
+		expected int
+	}{
+		{"{{ .vars.ssh_port }}", 2222},
+		{"{{ .vars.cli_port }}", 2200},
+	} {
+		task := &types.TaskConfig{
+			Name: "remote",
+			Type: "ssh",
+			Config: map[string]interface{}{
+				"host":    "example.com",
+				"user":    "deploy",
+				"command": "uptime",
+				"port":    tc.port,
+			},
+		}
+
+		config, err := New().parseConfig(task, manager)
+		if err != nil {
+			t.Fatalf("parseConfig failed: %v", err)
+		}
+		if config.Port != tc.expected {
+			t.Errorf("Expected port %d for %s, got %d", tc.expected, tc.port, config.Port)
+		}
+	}
+}
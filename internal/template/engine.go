@@ -9,6 +9,7 @@ import (
 	"os"
 	"strings"
 	"text/template"
+	"text/template/parse"
 	"time"
 
 	"github.com/Masterminds/sprig/v3"
@@ -93,6 +94,9 @@ func (e *Engine) addCustomFunctions() {
 		"getEnv": func(name string) string {
 			return fmt.Sprintf("{{CONTEXT_ENV_%s}}", name)
 		},
+		"secret": func(name string) (string, error) {
+			return "", fmt.Errorf("secret '%s' is not available: secrets can only be used in task fields", name)
+		},
 	}
 
 	// Merge custom functions with existing funcMap
@@ -138,7 +142,101 @@ func (e *Engine) Evaluate(templateStr string, ctx *types.WorkflowContext) (strin
 	return result, nil
 }
 
-// EvaluateAll evaluates all template strings in a map
+// typedCaptureFunc is appended to single-expression pipelines so the
+// expression's value can be captured before it is printed
+const typedCaptureFunc = "__ritualTypedValue"
+
+// EvaluateTyped evaluates a template string, keeping the native type of the
+// result when the whole string, less surrounding whitespace, is a single
+// expression such as "{{ .vars.port }}" or "{{ .vars.hosts_json | fromJson }}".
+// Strings, missing values and anything else evaluate as Evaluate does, so
+// surrounding text such as a block scalar's trailing newline is kept. Maps and
+// lists are copied, so changing them leaves the workflow's variables alone.
+func (e *Engine) EvaluateTyped(templateStr string, ctx *types.WorkflowContext) (interface{}, error) {
+	if !strings.Contains(templateStr, "{{") || !strings.Contains(templateStr, "}}") {
+		return templateStr, nil
+	}
+
+	var captured interface{}
+	contextFuncMap := e.createContextFuncMap(ctx)
+	contextFuncMap[typedCaptureFunc] = func(value interface{}) string {
+		captured = value
+		return ""
+	}
+
+	trimmed := strings.TrimSpace(templateStr)
+	tmpl, err := template.New("template").Option("missingkey=error").Funcs(contextFuncMap).Parse(trimmed)
+	if err != nil {
+		return nil, types.NewTemplateError(templateStr, "", "failed to parse template", err)
+	}
+
+	action := singleAction(tmpl.Tree)
+	if action == nil {
+		return e.Evaluate(templateStr, ctx)
+	}
+
+	capture := &parse.CommandNode{NodeType: parse.NodeCommand, Pos: action.Pos}
+	capture.Args = []parse.Node{parse.NewIdentifier(typedCaptureFunc).SetTree(tmpl.Tree).SetPos(action.Pos)}
+	action.Pipe.Cmds = append(action.Pipe.Cmds, capture)
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, e.createTemplateData(ctx)); err != nil {
+		return nil, types.NewTemplateError(templateStr, "", "failed to execute template", err)
+	}
+
+	switch captured.(type) {
+	case nil, string:
+		return e.Evaluate(templateStr, ctx)
+	}
+	return copyValue(captured), nil
+}
+
+// singleAction returns the template's action when the template is exactly
+// one value-producing expression
+func singleAction(tree *parse.Tree) *parse.ActionNode {
+	if tree == nil || tree.Root == nil || len(tree.Root.Nodes) != 1 {
+		return nil
+	}
+
+	// Variable declarations such as {{ $x := 1 }} print nothing, and control
+	// structures (if, range, with, template) render text
+	action, ok := tree.Root.Nodes[0].(*parse.ActionNode)
+	if !ok || len(action.Pipe.Decl) > 0 {
+		return nil
+	}
+	return action
+}
+
+// copyValue copies the maps and lists in value, so a typed result does not
+// share them with the context it was read from
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			copied[key] = copyValue(val)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, val := range v {
+			copied[i] = copyValue(val)
+		}
+		return copied
+	case map[string]string:
+		copied := make(map[string]string, len(v))
+		for key, val := range v {
+			copied[key] = val
+		}
+		return copied
+	case []string:
+		return append([]string(nil), v...)
+	}
+	return value
+}
+
+// EvaluateAll evaluates all template strings in a map. String values that are
+// a single template expression keep the expression's native type.
 func (e *Engine) EvaluateAll(data map[string]interface{}, ctx *types.WorkflowContext) (map[string]interface{}, error) {
 	result := make(map[string]interface{})
 
@@ -157,7 +255,7 @@ func (e *Engine) EvaluateAll(data map[string]interface{}, ctx *types.WorkflowCon
 func (e *Engine) evaluateValue(value interface{}, ctx *types.WorkflowContext) (interface{}, error) {
 	switch v := value.(type) {
 	case string:
-		return e.Evaluate(v, ctx)
+		return e.EvaluateTyped(v, ctx)
 
 	case map[string]interface{}:
 		result := make(map[string]interface{})
@@ -224,6 +322,11 @@ func (e *Engine) createContextFuncMap(ctx *types.WorkflowContext) template.FuncM
 		return os.Getenv(name)
 	}
 
+	// Secrets are fetched here, at render time, and never land in the context
+	if ctx != nil && ctx.Secrets != nil {
+		contextFuncMap["secret"] = ctx.Secrets.Resolve
+	}
+
 	return contextFuncMap
 }
 
@@ -407,3 +407,115 @@ func TestEngine_CreateTemplateData(t *testing.T) {
 		t.Errorf("Expected workflow name to be 'test-workflow', got '%v'", workflow["name"])
 	}
 }
+
+func TestEngine_EvaluateTyped_PreservesNativeTypes(t *testing.T) {
+	engine := New().(*Engine)
+	ctx := &types.WorkflowContext{
+		Variables: map[string]interface{}{
+			"port":    2222,
+			"enabled": true,
+			"hosts":   []interface{}{"a.example.com", "b.example.com"},
+			"tags":    map[string]interface{}{"team": "ops"},
+			"json":    `["x", "y"]`,
+			"name":    "web",
+			"nothing": nil,
+		},
+	}
+
+	tests := []struct {
+		template string
+		expected interface{}
+	}{
+		{"{{ .vars.port }}", 2222},
+		{"  {{ .vars.enabled }}  ", true},
+		{"{{ .vars.name }}", "web"},
+		{"{{ .vars.port | add 1 }}", int64(2223)},
+		{"{{ getVar \"port\" }}", 2222},
+		{"{{ .vars.nothing }}", "<no value>"},
+		{"{{ .vars.name }}\n", "web\n"},
+		{"{{ .vars.port }}\n", 2222},
+		{"port {{ .vars.port }}", "port 2222"},
+		{"{{ .vars.name }}-{{ .vars.port }}", "web-2222"},
+		{"{{ if .vars.enabled }}yes{{ end }}", "yes"},
+		{"no templates", "no templates"},
+	}
+
+	for _, test := range tests {
+		result, err := engine.EvaluateTyped(test.template, ctx)
+		if err != nil {
+			t.Errorf("EvaluateTyped(%q) failed: %v", test.template, err)
+			continue
+		}
+		if result != test.expected {
+			t.Errorf("EvaluateTyped(%q) = %#v, expected %#v", test.template, result, test.expected)
+		}
+	}
+
+	hosts, err := engine.EvaluateTyped("{{ .vars.hosts }}", ctx)
+	if err != nil {
+		t.Fatalf("EvaluateTyped failed: %v", err)
+	}
+	if list, ok := hosts.([]interface{}); !ok || len(list) != 2 || list[1] != "b.example.com" {
+		t.Errorf("Expected hosts list, got %#v", hosts)
+	}
+
+	tags, _ := engine.EvaluateTyped("{{ .vars.tags }}", ctx)
+	if m, ok := tags.(map[string]interface{}); !ok || m["team"] != "ops" {
+		t.Errorf("Expected tags map, got %#v", tags)
+	}
+
+	// Typed maps and lists are copies of the variables
+	hosts.([]interface{})[0] = "changed"
+	tags.(map[string]interface{})["team"] = "changed"
+	if ctx.Variables["hosts"].([]interface{})[0] != "a.example.com" || ctx.Variables["tags"].(map[string]interface{})["team"] != "ops" {
+		t.Errorf("Expected the variables to be unchanged, got %v and %v", ctx.Variables["hosts"], ctx.Variables["tags"])
+	}
+
+	decoded, _ := engine.EvaluateTyped("{{ .vars.json | fromJson }}", ctx)
+	if list, ok := decoded.([]interface{}); !ok || len(list) != 2 {
+		t.Errorf("Expected fromJson to produce a list, got %#v", decoded)
+	}
+}
+
+func TestEngine_EvaluateAll_TypedValues(t *testing.T) {
+	engine := New()
+	ctx := &types.WorkflowContext{
+		Variables: map[string]interface{}{
+			"ssh_port":   22,
+			"recipients": []interface{}{"ops@example.com", "dev@example.com"},
+		},
+	}
+
+	result, err := engine.EvaluateAll(map[string]interface{}{
+		"port": "{{ .vars.ssh_port }}",
+		"to":   "{{ .vars.recipients }}",
+		"nested": map[string]interface{}{
+			"port": "{{ .vars.ssh_port }}",
+		},
+	}, ctx)
+	if err != nil {
+		t.Fatalf("EvaluateAll failed: %v", err)
+	}
+
+	if result["port"] != 22 {
+		t.Errorf("Expected int port, got %#v", result["port"])
+	}
+	if to, ok := result["to"].([]interface{}); !ok || len(to) != 2 {
+		t.Errorf("Expected recipient list, got %#v", result["to"])
+	}
+	if nested := result["nested"].(map[string]interface{}); nested["port"] != 22 {
+		t.Errorf("Expected nested int port, got %#v", nested["port"])
+	}
+}
+
+func TestEngine_EvaluateTyped_Errors(t *testing.T) {
+	engine := New().(*Engine)
+	ctx := &types.WorkflowContext{Variables: map[string]interface{}{}}
+
+	if _, err := engine.EvaluateTyped("{{ .vars.missing }}", ctx); err == nil {
+		t.Error("Expected error for missing variable")
+	}
+	if _, err := engine.EvaluateTyped("{{ .vars.x }}{{ end }}", ctx); err == nil {
+		t.Error("Expected parse error")
+	}
+}
@@ -0,0 +1,458 @@
+// ABOUTME: Pre-flight template checks run after the workflow context is initialised
+// ABOUTME: Renders templates that need only vars, env and metadata; checks task result references symbolically
+
+package template
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/sarlalian/ritual/internal/expression"
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+// OutputFieldsFunc returns the Output keys a task type declares; ok is false
+// when the type does not declare them and Output keys cannot be checked
+type OutputFieldsFunc func(taskType string) (fields []string, ok bool)
+
+// Preflight checks every template in the workflow's tasks before any task
+// runs. Templates that depend only on variables, environment and metadata are
+// rendered against ctx. Templates that read task results (.tasks or getTask)
+// or secrets cannot be rendered yet, so their task references are checked
+// against the TaskResult fields and the referenced task type's declared
+// Output keys, secret names against the declared secrets, and variable and
+// environment references for existence.
+// Every problem is returned, not just the first.
+func Preflight(tasks []types.TaskConfig, engine types.TemplateEngine, ctx *types.WorkflowContext, outputFields OutputFieldsFunc) []error {
+	p := &preflight{
+		engine:       engine,
+		funcs:        funcMapFor(engine),
+		ctx:          ctx,
+		outputFields: outputFields,
+		tasks:        make(map[string]*types.TaskConfig),
+	}
+	for i := range tasks {
+		task := &tasks[i]
+		p.tasks[task.ID] = task
+		if task.Name != "" {
+			p.tasks[task.Name] = task
+		}
+	}
+
+	var errs []error
+	for i := range tasks {
+		task := &tasks[i]
+
+		keys := make([]string, 0, len(task.Config))
+		for key := range task.Config {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			errs = append(errs, p.checkValue(task, key, task.Config[key])...)
+		}
+
+		if task.When != "" && expression.IsTemplate(task.When) {
+			errs = append(errs, p.checkTemplate(task, "when", task.When)...)
+		}
+	}
+	return errs
+}
+
+type preflight struct {
+	engine       types.TemplateEngine
+	funcs        template.FuncMap
+	ctx          *types.WorkflowContext
+	outputFields OutputFieldsFunc
+	tasks        map[string]*types.TaskConfig
+}
+
+// funcMapFor returns the functions templates may call, so parsing accepts
+// exactly what evaluation will
+func funcMapFor(engine types.TemplateEngine) template.FuncMap {
+	if e, ok := engine.(*Engine); ok {
+		return e.funcMap
+	}
+	return New().(*Engine).funcMap
+}
+
+func (p *preflight) checkValue(task *types.TaskConfig, field string, value interface{}) []error {
+	switch v := value.(type) {
+	case string:
+		if strings.Contains(v, "{{") && strings.Contains(v, "}}") {
+			return p.checkTemplate(task, field, v)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		var errs []error
+		for _, key := range keys {
+			errs = append(errs, p.checkValue(task, field+"."+key, v[key])...)
+		}
+		return errs
+	case []interface{}:
+		var errs []error
+		for i, item := range v {
+			errs = append(errs, p.checkValue(task, fmt.Sprintf("%s[%d]", field, i), item)...)
+		}
+		return errs
+	}
+	return nil
+}
+
+func (p *preflight) checkTemplate(task *types.TaskConfig, field, templateStr string) []error {
+	newError := func(message, suggestion string) error {
+		return &ValidationError{
+			TaskID:     task.ID,
+			TaskName:   task.Name,
+			Field:      field,
+			Template:   templateStr,
+			Message:    message,
+			Suggestion: suggestion,
+			Position:   task.Source.Lookup(field),
+		}
+	}
+
+	tmpl, err := template.New("preflight").Funcs(p.funcs).Parse(templateStr)
+	if err != nil {
+		return []error{newError(fmt.Sprintf("invalid template: %v", err), "")}
+	}
+
+	refs := &templateRefs{}
+	refs.walk(tmpl.Tree.Root, true)
+
+	if !refs.runtime {
+		if _, err := p.engine.Evaluate(templateStr, p.ctx); err != nil {
+			message, suggestion := p.describeRenderError(err)
+			return []error{newError(message, suggestion)}
+		}
+		return nil
+	}
+
+	var errs []error
+	for _, chain := range refs.tasks {
+		if message, suggestion := p.checkTaskChain(chain); message != "" {
+			errs = append(errs, newError(message, suggestion))
+		}
+	}
+	for _, chain := range refs.values {
+		if message, suggestion := p.checkValueChain(chain); message != "" {
+			errs = append(errs, newError(message, suggestion))
+		}
+	}
+	for _, name := range refs.secrets {
+		if message, suggestion := p.checkSecret(name); message != "" {
+			errs = append(errs, newError(message, suggestion))
+		}
+	}
+	return errs
+}
+
+// checkSecret checks that a secret referenced by name is declared
+func (p *preflight) checkSecret(name string) (string, string) {
+	var declared []string
+	if p.ctx != nil && p.ctx.Secrets != nil {
+		declared = p.ctx.Secrets.Names()
+	}
+	if contains(declared, name) {
+		return "", ""
+	}
+	return fmt.Sprintf("references undeclared secret '%s'", name), Suggest(name, declared)
+}
+
+// taskResultFields are the fields templates can read from .tasks.<id>
+var taskResultFields = func() []string {
+	var fields []string
+	t := reflect.TypeOf(types.TaskResult{})
+	for i := 0; i < t.NumField(); i++ {
+		fields = append(fields, t.Field(i).Name)
+	}
+	return fields
+}()
+
+// checkTaskChain checks a reference such as [build Output checksum], the
+// field chain following .tasks
+func (p *preflight) checkTaskChain(chain []string) (string, string) {
+	if len(chain) == 0 {
+		return "", ""
+	}
+
+	id := chain[0]
+	task, ok := p.tasks[id]
+	if !ok {
+		return fmt.Sprintf("references non-existent task '%s'", id), Suggest(id, keysOf(p.tasks))
+	}
+	if len(chain) < 2 {
+		return "", ""
+	}
+
+	field := chain[1]
+	if !contains(taskResultFields, field) {
+		return fmt.Sprintf("task result has no field '%s'", field), Suggest(field, taskResultFields)
+	}
+	if field != "Output" || len(chain) < 3 || p.outputFields == nil {
+		return "", ""
+	}
+
+	declared, ok := p.outputFields(task.Type)
+	if !ok {
+		return "", ""
+	}
+	if key := chain[2]; !contains(declared, key) {
+		return fmt.Sprintf("task '%s' (%s) has no output '%s'", id, task.Type, key), Suggest(key, declared)
+	}
+	return "", ""
+}
+
+// checkValueChain checks that a variable or environment reference such as
+// [vars region] names an existing key
+func (p *preflight) checkValueChain(chain []string) (string, string) {
+	if len(chain) < 2 || p.ctx == nil {
+		return "", ""
+	}
+
+	var keys []string
+	switch chain[0] {
+	case "vars", "variables":
+		if _, ok := p.ctx.Variables[chain[1]]; ok {
+			return "", ""
+		}
+		keys = keysOf(p.ctx.Variables)
+	case "env", "environment":
+		if _, ok := p.ctx.Environment[chain[1]]; ok {
+			return "", ""
+		}
+		keys = keysOf(p.ctx.Environment)
+	default:
+		return "", ""
+	}
+	return fmt.Sprintf("references undefined %s '%s'", chain[0], chain[1]), Suggest(chain[1], keys)
+}
+
+var missingKeyPattern = regexp.MustCompile(`at <([^>]*)>: (.*)$`)
+
+// describeRenderError turns a template execution error into a short message
+// and, for missing keys, a suggestion from the variables and environment
+func (p *preflight) describeRenderError(err error) (string, string) {
+	cause := err
+	var templateErr *types.TemplateError
+	if errors.As(err, &templateErr) && templateErr.Cause != nil {
+		cause = templateErr.Cause
+	}
+
+	message := cause.Error()
+	match := missingKeyPattern.FindStringSubmatch(message)
+	if match == nil {
+		return fmt.Sprintf("cannot render: %s", message), ""
+	}
+
+	message = fmt.Sprintf("cannot render <%s>: %s", match[1], match[2])
+	path := strings.Split(strings.TrimPrefix(match[1], "."), ".")
+	if len(path) >= 2 && p.ctx != nil {
+		switch path[0] {
+		case "vars", "variables":
+			return message, Suggest(path[1], keysOf(p.ctx.Variables))
+		case "env", "environment":
+			return message, Suggest(path[1], keysOf(p.ctx.Environment))
+		}
+	}
+	return message, ""
+}
+
+// templateRefs collects what a parsed template reads from the root data
+type templateRefs struct {
+	tasks   [][]string // field chains following .tasks
+	values  [][]string // vars and env chains, e.g. [vars region]
+	secrets []string   // literal names passed to secret
+	lookups []string   // literal names passed to getVar or index .vars
+	root    bool       // reads the root data as a whole
+	runtime bool       // reads task results or secrets, which are only available once tasks run
+}
+
+// walk visits the tree. rootDot is false inside range and with bodies, where
+// dot no longer refers to the root data.
+func (r *templateRefs) walk(node parse.Node, rootDot bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			r.walk(child, rootDot)
+		}
+	case *parse.ActionNode:
+		r.walk(n.Pipe, rootDot)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			r.walk(cmd, rootDot)
+		}
+	case *parse.CommandNode:
+		skip := -1
+		if len(n.Args) > 0 {
+			if ident, ok := n.Args[0].(*parse.IdentifierNode); ok {
+				switch ident.Ident {
+				case "getTask":
+					r.runtime = true
+				case "secret":
+					// Secrets are only fetched when the task runs
+					r.runtime = true
+					if len(n.Args) > 1 {
+						if name, ok := n.Args[1].(*parse.StringNode); ok {
+							r.secrets = append(r.secrets, name.Text)
+						}
+					}
+				case "getVar":
+					if len(n.Args) > 1 {
+						if name, ok := n.Args[1].(*parse.StringNode); ok {
+							r.lookups = append(r.lookups, name.Text)
+						}
+					}
+				case "index":
+					// index .vars "name" reads one variable, not all of them
+					if len(n.Args) > 2 && isVarsRoot(n.Args[1], rootDot) {
+						if name, ok := n.Args[2].(*parse.StringNode); ok {
+							r.lookups = append(r.lookups, name.Text)
+							skip = 1
+						}
+					}
+				}
+			}
+		}
+		for i, arg := range n.Args {
+			if i != skip {
+				r.walk(arg, rootDot)
+			}
+		}
+	case *parse.ChainNode:
+		r.walk(n.Node, rootDot)
+	case *parse.FieldNode:
+		if rootDot {
+			r.addChain(n.Ident)
+		}
+	case *parse.DotNode:
+		if rootDot {
+			r.root = true
+		}
+	case *parse.VariableNode:
+		// $ always refers to the root data
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			r.addChain(n.Ident[1:])
+		} else if len(n.Ident) == 1 && n.Ident[0] == "$" {
+			r.root = true
+		}
+	case *parse.IfNode:
+		r.walk(n.Pipe, rootDot)
+		r.walk(n.List, rootDot)
+		r.walk(n.ElseList, rootDot)
+	case *parse.RangeNode:
+		r.walk(n.Pipe, rootDot)
+		r.walk(n.List, false)
+		r.walk(n.ElseList, rootDot)
+	case *parse.WithNode:
+		r.walk(n.Pipe, rootDot)
+		r.walk(n.List, false)
+		r.walk(n.ElseList, rootDot)
+	case *parse.TemplateNode:
+		r.walk(n.Pipe, rootDot)
+	}
+}
+
+func (r *templateRefs) addChain(chain []string) {
+	if len(chain) == 0 {
+		return
+	}
+	switch chain[0] {
+	case "tasks":
+		r.runtime = true
+		r.tasks = append(r.tasks, chain[1:])
+	case "vars", "variables", "env", "environment":
+		r.values = append(r.values, chain)
+	}
+}
+
+// isVarsRoot reports whether node is .vars or $.vars
+func isVarsRoot(node parse.Node, rootDot bool) bool {
+	var chain []string
+	switch n := node.(type) {
+	case *parse.FieldNode:
+		if !rootDot {
+			return false
+		}
+		chain = n.Ident
+	case *parse.VariableNode:
+		if len(n.Ident) == 0 || n.Ident[0] != "$" {
+			return false
+		}
+		chain = n.Ident[1:]
+	}
+	return len(chain) == 1 && (chain[0] == "vars" || chain[0] == "variables")
+}
+
+// Suggest returns "did you mean 'x'?" for the closest candidate within a
+// small edit distance, or for a candidate differing only in case
+func Suggest(target string, candidates []string) string {
+	best, bestDistance := "", 3
+	for _, candidate := range candidates {
+		if strings.EqualFold(candidate, target) {
+			return fmt.Sprintf("did you mean '%s'?", candidate)
+		}
+		if d := editDistance(strings.ToLower(target), strings.ToLower(candidate)); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf("did you mean '%s'?", best)
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr := make([]int, len(b)+1)
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev = curr
+	}
+	return prev[len(b)]
+}
+
+func keysOf[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
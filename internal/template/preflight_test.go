@@ -0,0 +1,158 @@
+// ABOUTME: Tests for pre-flight template checks
+// ABOUTME: Verifies rendering of static templates and symbolic checks of task result references
+
+package template
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+func preflightContext() *types.WorkflowContext {
+	return &types.WorkflowContext{
+		Variables:   map[string]interface{}{"region": "us-east-1", "items": []interface{}{"a", "b"}},
+		Environment: map[string]string{"HOME": "/home/ritual"},
+		Tasks:       map[string]*types.TaskResult{},
+		Metadata:    map[string]interface{}{},
+	}
+}
+
+func preflightOutputFields(taskType string) ([]string, bool) {
+	if taskType == "checksum" {
+		return []string{"checksum", "algorithm", "path"}, true
+	}
+	return nil, false
+}
+
+func TestPreflight_Valid(t *testing.T) {
+	tasks := []types.TaskConfig{
+		{ID: "build", Name: "build", Type: "command", Config: map[string]interface{}{
+			"command": "make {{ .vars.region }} HOME={{ .env.HOME }}",
+		}},
+		{ID: "sum", Name: "sum", Type: "checksum", Config: map[string]interface{}{"path": "out"}},
+		{ID: "report", Name: "report", Type: "file", When: "{{ eq .tasks.build.Status \"success\" }}", Config: map[string]interface{}{
+			"content": "{{ .tasks.build.Stdout }} {{ .tasks.sum.Output.checksum }} {{ .vars.region }}",
+			"lines":   []interface{}{"{{ range .vars.items }}{{ . }}{{ end }}", "{{ with .tasks.sum }}{{ .Whatever }}{{ end }}"},
+		}},
+	}
+
+	if errs := Preflight(tasks, New(), preflightContext(), preflightOutputFields); len(errs) != 0 {
+		t.Fatalf("Expected no errors, got: %v", errs)
+	}
+}
+
+func TestPreflight_ReportsAllErrors(t *testing.T) {
+	tasks := []types.TaskConfig{
+		{ID: "build", Name: "build", Type: "command", Config: map[string]interface{}{
+			"command": "make {{ .vars.regoin }}",
+		}},
+		{ID: "sum", Name: "sum", Type: "checksum", Config: map[string]interface{}{"path": "out"}},
+		{ID: "report", Name: "report", Type: "file", Config: map[string]interface{}{
+			"content": "{{ .tasks.build.Stdotu }}",
+			"capture": map[string]interface{}{"stdout": "{{ .tasks.sum.Output.digest }}"},
+			"args":    []interface{}{"{{ .tasks.deploy.Status }}", "{{ .tasks.build.Status }} {{ .env.HOEM }}"},
+		}},
+	}
+
+	errs := Preflight(tasks, New(), preflightContext(), preflightOutputFields)
+
+	expected := []struct {
+		field    string
+		contains string
+		suggest  string
+	}{
+		{"command", "cannot render <.vars.regoin>", "did you mean 'region'?"},
+		{"args[0]", "references non-existent task 'deploy'", ""},
+		{"args[1]", "references undefined env 'HOEM'", "did you mean 'HOME'?"},
+		{"capture.stdout", "task 'sum' (checksum) has no output 'digest'", ""},
+		{"content", "task result has no field 'Stdotu'", "did you mean 'Stdout'?"},
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d: %v", len(expected), len(errs), errs)
+	}
+
+	for i, want := range expected {
+		var validationErr *ValidationError
+		if !errors.As(errs[i], &validationErr) {
+			t.Fatalf("Expected *ValidationError, got %T", errs[i])
+		}
+		if validationErr.Field != want.field {
+			t.Errorf("Expected field %q, got %q", want.field, validationErr.Field)
+		}
+		if !strings.Contains(validationErr.Message, want.contains) {
+			t.Errorf("Expected message containing %q, got %q", want.contains, validationErr.Message)
+		}
+		if want.suggest != "" && validationErr.Suggestion != want.suggest {
+			t.Errorf("Expected suggestion %q, got %q", want.suggest, validationErr.Suggestion)
+		}
+	}
+}
+
+func TestPreflight_UndeclaredOutputsNotChecked(t *testing.T) {
+	tasks := []types.TaskConfig{
+		{ID: "custom", Name: "custom", Type: "plugin"},
+		{ID: "report", Name: "report", Type: "file", Config: map[string]interface{}{
+			"content": "{{ .tasks.custom.Output.anything }}",
+		}},
+	}
+
+	if errs := Preflight(tasks, New(), preflightContext(), preflightOutputFields); len(errs) != 0 {
+		t.Fatalf("Expected no errors, got: %v", errs)
+	}
+}
+
+func TestPreflight_ParseError(t *testing.T) {
+	tasks := []types.TaskConfig{
+		{ID: "report", Name: "report", Type: "file", Config: map[string]interface{}{
+			"content": "{{ if .vars.region }}unterminated}}",
+		}},
+	}
+
+	errs := Preflight(tasks, New(), preflightContext(), nil)
+	if len(errs) != 1 {
+		t.Fatalf("Expected 1 error, got %d: %v", len(errs), errs)
+	}
+	if !strings.Contains(errs[0].Error(), "invalid template") {
+		t.Errorf("Expected invalid template error, got: %v", errs[0])
+	}
+}
+
+type preflightSecrets []string
+
+func (s preflightSecrets) Resolve(name string) (string, error) {
+	return "", fmt.Errorf("preflight must not resolve secret '%s'", name)
+}
+
+func (s preflightSecrets) Names() []string { return s }
+
+func TestPreflight_Secrets(t *testing.T) {
+	ctx := preflightContext()
+	ctx.Secrets = preflightSecrets{"db_password"}
+
+	tasks := []types.TaskConfig{
+		{ID: "deploy", Name: "deploy", Type: "command", Config: map[string]interface{}{
+			"command":  "deploy --password {{ secret \"db_password\" }} --region {{ .vars.region }}",
+			"password": "{{ secret \"db_pasword\" }}",
+		}},
+	}
+
+	errs := Preflight(tasks, New(), ctx, nil)
+	if len(errs) != 1 {
+		t.Fatalf("Expected 1 error, got %d: %v", len(errs), errs)
+	}
+
+	var validationErr *ValidationError
+	if !errors.As(errs[0], &validationErr) {
+		t.Fatalf("Expected *ValidationError, got %T", errs[0])
+	}
+	if validationErr.Field != "password" || !strings.Contains(validationErr.Message, "undeclared secret 'db_pasword'") {
+		t.Errorf("Expected undeclared secret error on password, got: %v", validationErr)
+	}
+	if validationErr.Suggestion != "did you mean 'db_password'?" {
+		t.Errorf("Expected suggestion for db_password, got %q", validationErr.Suggestion)
+	}
+}
@@ -0,0 +1,109 @@
+// ABOUTME: Static analysis of templates without a workflow context
+// ABOUTME: Lists the variables a template reads and renders templates that read nothing
+
+package template
+
+import (
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// VariableReferences lists what a template reads from .vars
+type VariableReferences struct {
+	Names   []string // read as .vars.<name>, which fails to render when missing
+	Lookups []string // read with getVar or index, which tolerate missing names
+	All     bool     // .vars is read as a whole, such as by range or toJson
+}
+
+// ReferencedVariables parses a template and returns the variables it reads
+func ReferencedVariables(templateStr string) (*VariableReferences, error) {
+	tmpl, err := template.New("refs").Funcs(funcMapFor(nil)).Parse(templateStr)
+	if err != nil {
+		return nil, err
+	}
+	refs := &templateRefs{}
+	refs.walk(tmpl.Tree.Root, true)
+
+	result := &VariableReferences{Lookups: refs.lookups, All: refs.root}
+	for _, chain := range refs.values {
+		if chain[0] != "vars" && chain[0] != "variables" {
+			continue
+		}
+		if len(chain) == 1 {
+			result.All = true
+			continue
+		}
+		result.Names = append(result.Names, chain[1])
+	}
+	return result, nil
+}
+
+// constantFuncs are the built-in functions whose results depend only on
+// their arguments
+var constantFuncs = map[string]bool{
+	"and": true, "or": true, "not": true, "len": true,
+	"eq": true, "ne": true, "lt": true, "le": true, "gt": true, "ge": true,
+	"print": true, "printf": true, "println": true,
+}
+
+// RenderConstant renders a template that reads nothing from the context and
+// calls only built-in functions, such as "{{ false }}" or "{{ eq 1 2 }}".
+// ok is false for any other template.
+func RenderConstant(templateStr string) (string, bool) {
+	tmpl, err := template.New("constant").Funcs(funcMapFor(nil)).Parse(templateStr)
+	if err != nil || !isConstant(tmpl.Tree.Root) {
+		return "", false
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, nil); err != nil {
+		return "", false
+	}
+	return b.String(), true
+}
+
+// isConstant reports whether a parsed template node reads no data and
+// calls no functions other than constantFuncs
+func isConstant(node parse.Node) bool {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return true
+		}
+		for _, child := range n.Nodes {
+			if !isConstant(child) {
+				return false
+			}
+		}
+		return true
+	case *parse.ActionNode:
+		return isConstant(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return true
+		}
+		if len(n.Decl) > 0 {
+			return false
+		}
+		for _, cmd := range n.Cmds {
+			if !isConstant(cmd) {
+				return false
+			}
+		}
+		return true
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if !isConstant(arg) {
+				return false
+			}
+		}
+		return true
+	case *parse.IdentifierNode:
+		return constantFuncs[n.Ident]
+	case *parse.IfNode:
+		return isConstant(n.Pipe) && isConstant(n.List) && isConstant(n.ElseList)
+	case *parse.TextNode, *parse.BoolNode, *parse.NumberNode, *parse.StringNode, *parse.NilNode:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+// ABOUTME: Tests for static template analysis
+// ABOUTME: Verifies variable reference listing and rendering of constant templates
+
+package template
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReferencedVariables(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		names    []string
+		lookups  []string
+		all      bool
+	}{
+		{"field", "{{ .vars.region }}", []string{"region"}, nil, false},
+		{"variables alias", "{{ .variables.host }}", []string{"host"}, nil, false},
+		{"nested field", "{{ .vars.db.host }}", []string{"db"}, nil, false},
+		{"getVar", `{{ getVar "port" }}`, nil, []string{"port"}, false},
+		{"index", `{{ index .vars "port" }}`, nil, []string{"port"}, false},
+		{"range over vars", "{{ range $k, $v := .vars }}{{ $k }}{{ end }}", nil, nil, true},
+		{"whole context", "{{ toJson . }}", nil, nil, true},
+		{"env only", "{{ .env.HOME }}", nil, nil, false},
+		{"plain text", "no templates here", nil, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refs, err := ReferencedVariables(tt.template)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !reflect.DeepEqual(refs.Names, tt.names) {
+				t.Errorf("Expected names %v, got %v", tt.names, refs.Names)
+			}
+			if !reflect.DeepEqual(refs.Lookups, tt.lookups) {
+				t.Errorf("Expected lookups %v, got %v", tt.lookups, refs.Lookups)
+			}
+			if refs.All != tt.all {
+				t.Errorf("Expected All %v, got %v", tt.all, refs.All)
+			}
+		})
+	}
+
+	if _, err := ReferencedVariables("{{ .vars.x "); err == nil {
+		t.Error("Expected error for unparseable template")
+	}
+}
+
+func TestRenderConstant(t *testing.T) {
+	tests := []struct {
+		template string
+		want     string
+		ok       bool
+	}{
+		{"{{ false }}", "false", true},
+		{"{{ eq 1 2 }}", "false", true},
+		{`{{ and true (ne "a" "b") }}`, "true", true},
+		{"{{ if true }}yes{{ else }}no{{ end }}", "yes", true},
+		{"plain", "plain", true},
+		{"{{ .vars.enabled }}", "", false},
+		{`{{ env "HOME" }}`, "", false},
+		{"{{ $x := true }}{{ $x }}", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := RenderConstant(tt.template)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("RenderConstant(%q): expected (%q, %v), got (%q, %v)", tt.template, tt.want, tt.ok, got, ok)
+		}
+	}
+}
@@ -6,8 +6,10 @@ package template
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 
+	"github.com/sarlalian/ritual/internal/expression"
 	"github.com/sarlalian/ritual/pkg/types"
 )
 
@@ -19,15 +21,25 @@ type ValidationError struct {
 	Template   string
 	Message    string
 	Suggestion string
+	Position   types.SourcePosition
 }
 
 func (e *ValidationError) Error() string {
+	location := ""
+	if e.Position.IsValid() {
+		location = fmt.Sprintf(" at %s", e.Position)
+	}
 	if e.Suggestion != "" {
-		return fmt.Sprintf("task '%s' (%s) field '%s': %s (suggestion: %s)",
-			e.TaskName, e.TaskID, e.Field, e.Message, e.Suggestion)
+		return fmt.Sprintf("task '%s' (%s) field '%s'%s: %s (suggestion: %s)",
+			e.TaskName, e.TaskID, e.Field, location, e.Message, e.Suggestion)
 	}
-	return fmt.Sprintf("task '%s' (%s) field '%s': %s",
-		e.TaskName, e.TaskID, e.Field, e.Message)
+	return fmt.Sprintf("task '%s' (%s) field '%s'%s: %s",
+		e.TaskName, e.TaskID, e.Field, location, e.Message)
+}
+
+// SourcePosition returns where the field was written
+func (e *ValidationError) SourcePosition() types.SourcePosition {
+	return e.Position
 }
 
 // ValidateTaskTemplates validates all templates in task configurations
@@ -58,6 +70,46 @@ func ValidateTaskTemplates(tasks []types.TaskConfig, templateEngine types.Templa
 	return errors
 }
 
+// ValidateOutputTemplates checks the syntax of the workflow's output templates
+// and that the tasks they reference exist
+func ValidateOutputTemplates(outputs map[string]interface{}, tasks []types.TaskConfig) []error {
+	availableTaskIDs := make(map[string]bool)
+	for _, task := range tasks {
+		availableTaskIDs[task.ID] = true
+		availableTaskIDs[task.Name] = true
+	}
+
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errors []error
+	taskRefPattern := regexp.MustCompile(`\.tasks\.([a-zA-Z0-9_-]+)`)
+	for _, name := range names {
+		templateStr, ok := outputs[name].(string)
+		if !ok {
+			continue
+		}
+		field := "outputs." + name
+		if err := ValidateTemplate(templateStr); err != nil {
+			errors = append(errors, types.NewValidationError(field, templateStr, err.Error()))
+			continue
+		}
+		for _, match := range taskRefPattern.FindAllStringSubmatch(templateStr, -1) {
+			if !availableTaskIDs[match[1]] {
+				message := fmt.Sprintf("references non-existent task '%s'", match[1])
+				if suggestion := findSimilarTaskID(match[1], availableTaskIDs); suggestion != "" {
+					message += " (" + suggestion + ")"
+				}
+				errors = append(errors, types.NewValidationError(field, templateStr, message))
+			}
+		}
+	}
+	return errors
+}
+
 // validateTaskReferences validates that task references in templates point to valid tasks
 func validateTaskReferences(task *types.TaskConfig, availableTaskIDs map[string]bool) []error {
 	var errors []error
@@ -68,6 +120,52 @@ func validateTaskReferences(task *types.TaskConfig, availableTaskIDs map[string]
 		errors = append(errors, fieldErrors...)
 	}
 
+	// Check the task condition
+	if task.When != "" {
+		errors = append(errors, validateCondition(task, availableTaskIDs)...)
+	}
+
+	return errors
+}
+
+// validateCondition parses a 'when' expression and checks the tasks it refers to.
+// Template conditions only get the task reference check applied to other fields.
+func validateCondition(task *types.TaskConfig, availableTaskIDs map[string]bool) []error {
+	if expression.IsTemplate(task.When) {
+		return checkTaskReferences(task, "when", task.When, availableTaskIDs)
+	}
+	if expression.IsLiteral(task.When) {
+		return nil
+	}
+
+	expr, err := expression.Parse(task.When)
+	if err != nil {
+		return []error{&ValidationError{
+			TaskID:   task.ID,
+			TaskName: task.Name,
+			Field:    "when",
+			Template: task.When,
+			Message:  fmt.Sprintf("invalid condition: %v", err),
+			Position: task.Source.Lookup("when"),
+		}}
+	}
+
+	var errors []error
+	for _, ref := range expr.TaskReferences() {
+		if availableTaskIDs[ref] {
+			continue
+		}
+		errors = append(errors, &ValidationError{
+			TaskID:     task.ID,
+			TaskName:   task.Name,
+			Field:      "when",
+			Template:   task.When,
+			Message:    fmt.Sprintf("references non-existent task '%s'", ref),
+			Suggestion: findSimilarTaskID(ref, availableTaskIDs),
+			Position:   task.Source.Lookup("when"),
+		})
+	}
+
 	return errors
 }
 
@@ -125,6 +223,7 @@ func checkTaskReferences(task *types.TaskConfig, field string, templateStr strin
 					Template:   templateStr,
 					Message:    fmt.Sprintf("references non-existent task '%s'", referencedTaskID),
 					Suggestion: suggestion,
+					Position:   task.Source.Lookup(field),
 				}
 				errors = append(errors, validationErr)
 			}
@@ -0,0 +1,75 @@
+// ABOUTME: Tests for pre-execution template and condition validation
+// ABOUTME: Verifies task reference checks and 'when' expression parsing errors
+
+package template
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+func TestValidateTaskTemplates_Conditions(t *testing.T) {
+	tests := []struct {
+		name     string
+		when     string
+		contains string
+	}{
+		{"valid expression", "tasks.build.Changed and env.ENVIRONMENT in ['prod', 'staging']", ""},
+		{"valid template", "{{ eq .tasks.build.Status \"success\" }}", ""},
+		{"truthy literal", "enabled", ""},
+		{"syntax error", "tasks.build.Status = 'success'", "invalid condition"},
+		{"unknown root", "build.Status == 'success'", "unknown identifier 'build'"},
+		{"missing task in expression", "tasks.biuld.Changed", "non-existent task 'biuld'"},
+		{"missing task in template", "{{ .tasks.deploy.Changed }}", "non-existent task 'deploy'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks := []types.TaskConfig{
+				{ID: "build", Name: "build", Type: "command"},
+				{ID: "notify", Name: "notify", Type: "command", When: tt.when},
+			}
+
+			errs := ValidateTaskTemplates(tasks, nil)
+			if tt.contains == "" {
+				if len(errs) != 0 {
+					t.Fatalf("Expected no errors, got: %v", errs)
+				}
+				return
+			}
+
+			if len(errs) != 1 {
+				t.Fatalf("Expected 1 error, got %d: %v", len(errs), errs)
+			}
+			if !strings.Contains(errs[0].Error(), tt.contains) {
+				t.Errorf("Expected error containing %q, got: %v", tt.contains, errs[0])
+			}
+			if !strings.Contains(errs[0].Error(), "field 'when'") {
+				t.Errorf("Expected error to name the 'when' field, got: %v", errs[0])
+			}
+		})
+	}
+}
+
+func TestValidateOutputTemplates(t *testing.T) {
+	tasks := []types.TaskConfig{{ID: "build", Name: "Build", Type: "command"}}
+	outputs := map[string]interface{}{
+		"version":  "{{ .tasks.build.Stdout | trim }}",
+		"replicas": 3,
+		"broken":   "{{ if .tasks.build.Changed }}rebuilt",
+		"missing":  "{{ .tasks.biuld.Stdout }}",
+	}
+
+	errs := ValidateOutputTemplates(outputs, tasks)
+	if len(errs) != 2 {
+		t.Fatalf("Expected 2 errors, got %d: %v", len(errs), errs)
+	}
+	if !strings.Contains(errs[0].Error(), "outputs.broken") {
+		t.Errorf("Expected the first error to name outputs.broken, got: %v", errs[0])
+	}
+	if !strings.Contains(errs[1].Error(), "outputs.missing") || !strings.Contains(errs[1].Error(), "non-existent task 'biuld'") {
+		t.Errorf("Expected the second error to report the missing task, got: %v", errs[1])
+	}
+}
@@ -0,0 +1,197 @@
+// ABOUTME: OTLP/JSON trace encoding and exporters for files and HTTP collectors
+// ABOUTME: Encodes spans as an ExportTraceServiceRequest with hex IDs and string nanosecond times
+
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// OTLP span kinds and status codes used by ritual
+const (
+	spanKindInternal = 1
+	spanKindServer   = 2
+
+	statusUnset = 0
+	statusOK    = 1
+	statusError = 2
+)
+
+// ExportRequest is an OTLP ExportTraceServiceRequest in its JSON encoding
+type ExportRequest struct {
+	ResourceSpans []ResourceSpans `json:"resourceSpans"`
+}
+
+// ResourceSpans groups spans produced by one resource
+type ResourceSpans struct {
+	Resource   Resource     `json:"resource"`
+	ScopeSpans []ScopeSpans `json:"scopeSpans"`
+}
+
+// Resource describes the entity producing spans
+type Resource struct {
+	Attributes []KeyValue `json:"attributes"`
+}
+
+// ScopeSpans groups spans produced by one instrumentation scope
+type ScopeSpans struct {
+	Scope Scope  `json:"scope"`
+	Spans []Span `json:"spans"`
+}
+
+// Scope identifies the instrumentation library
+type Scope struct {
+	Name string `json:"name"`
+}
+
+// Span is an OTLP span
+type Span struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []KeyValue `json:"attributes,omitempty"`
+	Links             []Link     `json:"links,omitempty"`
+	Status            Status     `json:"status"`
+}
+
+// Link points from a span to another span it is causally related to
+type Link struct {
+	TraceID    string     `json:"traceId"`
+	SpanID     string     `json:"spanId"`
+	Attributes []KeyValue `json:"attributes,omitempty"`
+}
+
+// Status is a span's outcome
+type Status struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// KeyValue is an attribute
+type KeyValue struct {
+	Key   string   `json:"key"`
+	Value AnyValue `json:"value"`
+}
+
+// AnyValue holds one attribute value; integers are encoded as strings in OTLP/JSON
+type AnyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+	BoolValue   *bool   `json:"boolValue,omitempty"`
+}
+
+// String creates a string attribute
+func String(key, value string) KeyValue {
+	return KeyValue{Key: key, Value: AnyValue{StringValue: &value}}
+}
+
+// Int creates an integer attribute
+func Int(key string, value int64) KeyValue {
+	s := strconv.FormatInt(value, 10)
+	return KeyValue{Key: key, Value: AnyValue{IntValue: &s}}
+}
+
+// Bool creates a boolean attribute
+func Bool(key string, value bool) KeyValue {
+	return KeyValue{Key: key, Value: AnyValue{BoolValue: &value}}
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// Exporter delivers finished traces
+type Exporter interface {
+	Export(ctx context.Context, request *ExportRequest) error
+}
+
+// FileExporter appends each trace as one line of OTLP/JSON to a file
+type FileExporter struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileExporter creates an exporter writing to path
+func NewFileExporter(path string) *FileExporter {
+	return &FileExporter{path: path}
+}
+
+// Export appends the request to the file
+func (f *FileExporter) Export(ctx context.Context, request *ExportRequest) error {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to encode trace: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open trace file: %w", err)
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write trace file: %w", err)
+	}
+	return file.Close()
+}
+
+// HTTPExporter POSTs traces to an OTLP/HTTP collector endpoint
+type HTTPExporter struct {
+	endpoint string
+	client   *http.Client
+	headers  map[string]string
+}
+
+// NewHTTPExporter creates an exporter posting to endpoint, usually
+// http://collector:4318/v1/traces
+func NewHTTPExporter(endpoint string, headers map[string]string) *HTTPExporter {
+	return &HTTPExporter{
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		headers:  headers,
+	}
+}
+
+// Export posts the request as JSON
+func (h *HTTPExporter) Export(ctx context.Context, request *ExportRequest) error {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to encode trace: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.endpoint, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create trace export request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range h.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export trace: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("trace collector returned %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+// ABOUTME: W3C Trace Context helpers for parsing and generating traceparent values
+// ABOUTME: Lets webhook-triggered runs join the caller's distributed trace
+
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// SpanContext identifies a span within a trace
+type SpanContext struct {
+	TraceID string // 32 lowercase hex characters
+	SpanID  string // 16 lowercase hex characters
+	Sampled bool
+}
+
+// ParseTraceParent parses a W3C traceparent header such as
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+func ParseTraceParent(header string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 {
+		return SpanContext{}, fmt.Errorf("invalid traceparent %q: expected version-traceid-spanid-flags", header)
+	}
+
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	if !isHex(version, 2) || version == "ff" {
+		return SpanContext{}, fmt.Errorf("invalid traceparent %q: bad version", header)
+	}
+	// Version 00 has exactly four fields; later versions may append more
+	if version == "00" && len(parts) != 4 {
+		return SpanContext{}, fmt.Errorf("invalid traceparent %q: unexpected fields", header)
+	}
+	if !isHex(traceID, 32) || traceID == strings.Repeat("0", 32) {
+		return SpanContext{}, fmt.Errorf("invalid traceparent %q: bad trace ID", header)
+	}
+	if !isHex(spanID, 16) || spanID == strings.Repeat("0", 16) {
+		return SpanContext{}, fmt.Errorf("invalid traceparent %q: bad parent span ID", header)
+	}
+	if !isHex(flags, 2) {
+		return SpanContext{}, fmt.Errorf("invalid traceparent %q: bad flags", header)
+	}
+
+	flagBits, _ := hex.DecodeString(flags)
+	return SpanContext{TraceID: traceID, SpanID: spanID, Sampled: flagBits[0]&1 == 1}, nil
+}
+
+// TraceParent formats the span context as a version 00 traceparent header
+func (sc SpanContext) TraceParent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceID, sc.SpanID, flags)
+}
+
+// NewTraceID returns a random 16-byte trace ID in hex
+func NewTraceID() string {
+	return randomHex(16)
+}
+
+// NewSpanID returns a random 8-byte span ID in hex
+func NewSpanID() string {
+	return randomHex(8)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// isHex reports whether s is exactly n lowercase hex characters
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f') {
+			return false
+		}
+	}
+	return true
+}
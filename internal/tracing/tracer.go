@@ -0,0 +1,312 @@
+// ABOUTME: Event observer that turns workflow executions into OTLP spans
+// ABOUTME: The workflow is the root span, tasks are children and dependencies become span links
+
+package tracing
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sarlalian/ritual/internal/events"
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+// exportTimeout bounds how long a finished run waits on its exporter
+const exportTimeout = 15 * time.Second
+
+// Tracer collects spans for each execution and exports the trace when the
+// workflow finishes
+type Tracer struct {
+	exporter Exporter
+	logger   types.Logger
+
+	mu   sync.Mutex
+	runs map[string]*runTrace
+}
+
+type runTrace struct {
+	traceID      string
+	spanID       string
+	parentSpanID string
+	name         string
+	path         string
+	start        time.Time
+	tasks        map[string]*taskSpan
+}
+
+type taskSpan struct {
+	spanID       string
+	id           string
+	name         string
+	taskType     string
+	dependencies []string
+	queued       time.Time
+	start        time.Time
+	end          time.Time
+	status       types.TaskStatus
+	message      string
+	attempt      int
+	returnCode   int
+	hasResult    bool
+}
+
+// NewTracer creates a tracer exporting through exporter and logging export
+// failures to logger
+func NewTracer(exporter Exporter, logger types.Logger) *Tracer {
+	return &Tracer{
+		exporter: exporter,
+		logger:   logger,
+		runs:     make(map[string]*runTrace),
+	}
+}
+
+// OnEvent records span data and exports the trace on WorkflowFinished
+func (t *Tracer) OnEvent(event events.Event) {
+	switch event.Type {
+	case events.WorkflowStarted:
+		t.startRun(event)
+	case events.TaskQueued, events.TaskStarted, events.TaskFinished, events.TaskSkipped:
+		t.recordTask(event)
+	case events.WorkflowFinished:
+		t.finishRun(event)
+	}
+}
+
+func (t *Tracer) startRun(event events.Event) {
+	run := &runTrace{
+		traceID: NewTraceID(),
+		spanID:  NewSpanID(),
+		name:    event.Workflow,
+		path:    event.WorkflowPath,
+		start:   event.Time,
+		tasks:   make(map[string]*taskSpan),
+	}
+	if event.TraceParent != "" {
+		if parent, err := ParseTraceParent(event.TraceParent); err == nil {
+			run.traceID = parent.TraceID
+			run.parentSpanID = parent.SpanID
+		} else if t.logger != nil {
+			t.logger.Debug().Msgf("Ignoring traceparent: %v", err)
+		}
+	}
+
+	t.mu.Lock()
+	t.runs[event.ExecutionID] = run
+	t.mu.Unlock()
+}
+
+func (t *Tracer) recordTask(event events.Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	run, ok := t.runs[event.ExecutionID]
+	if !ok {
+		return
+	}
+
+	span, ok := run.tasks[event.TaskID]
+	if !ok {
+		span = &taskSpan{spanID: NewSpanID(), id: event.TaskID, queued: event.Time}
+		run.tasks[event.TaskID] = span
+	}
+	if event.TaskName != "" {
+		span.name = event.TaskName
+	}
+	if event.TaskType != "" {
+		span.taskType = event.TaskType
+	}
+
+	switch event.Type {
+	case events.TaskQueued:
+		span.dependencies = event.Dependencies
+	case events.TaskStarted:
+		span.start = event.Time
+	case events.TaskFinished, events.TaskSkipped:
+		span.end = event.Time
+		span.status = event.Status
+		span.message = event.Message
+		if result := event.TaskResult; result != nil {
+			span.hasResult = true
+			span.status = result.Status
+			span.returnCode = result.ReturnCode
+			span.attempt = result.AttemptCount
+			if result.Error != "" {
+				span.message = result.Error
+			}
+			if !result.StartTime.IsZero() && span.start.IsZero() {
+				span.start = result.StartTime
+			}
+			if !result.EndTime.IsZero() {
+				span.end = result.EndTime
+			}
+		}
+	}
+}
+
+func (t *Tracer) finishRun(event events.Event) {
+	t.mu.Lock()
+	run, ok := t.runs[event.ExecutionID]
+	delete(t.runs, event.ExecutionID)
+	t.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	request := run.build(event)
+
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+	if err := t.exporter.Export(ctx, request); err != nil && t.logger != nil {
+		t.logger.Info().Msgf("Failed to export trace: %v", err)
+	}
+}
+
+// build assembles the OTLP request for a finished run
+func (r *runTrace) build(finished events.Event) *ExportRequest {
+	end := finished.Time
+	root := Span{
+		TraceID:           r.traceID,
+		SpanID:            r.spanID,
+		ParentSpanID:      r.parentSpanID,
+		Name:              r.name,
+		Kind:              spanKindInternal,
+		StartTimeUnixNano: unixNano(r.start),
+		EndTimeUnixNano:   unixNano(end),
+		Attributes: []KeyValue{
+			String("ritual.workflow.name", r.name),
+			String("ritual.execution.id", finished.ExecutionID),
+		},
+		Status: workflowStatus(finished.Result),
+	}
+	if r.parentSpanID != "" {
+		// A run continuing an incoming trace was triggered by a remote caller
+		root.Kind = spanKindServer
+	}
+	if r.path != "" {
+		root.Attributes = append(root.Attributes, String("ritual.workflow.path", r.path))
+	}
+	if finished.Result != nil && finished.Result.WorkflowResult != nil {
+		root.Attributes = append(root.Attributes, String("ritual.workflow.status", string(finished.Result.WorkflowResult.Status)))
+	}
+
+	ids := make([]string, 0, len(r.tasks))
+	for id := range r.tasks {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := r.tasks[ids[i]], r.tasks[ids[j]]
+		if !a.queued.Equal(b.queued) {
+			return a.queued.Before(b.queued)
+		}
+		return a.id < b.id
+	})
+
+	spans := []Span{root}
+	for _, id := range ids {
+		spans = append(spans, r.taskSpan(r.tasks[id], end))
+	}
+
+	return &ExportRequest{
+		ResourceSpans: []ResourceSpans{{
+			Resource: Resource{Attributes: []KeyValue{
+				String("service.name", "ritual"),
+			}},
+			ScopeSpans: []ScopeSpans{{
+				Scope: Scope{Name: "github.com/sarlalian/ritual"},
+				Spans: spans,
+			}},
+		}},
+	}
+}
+
+func (r *runTrace) taskSpan(task *taskSpan, workflowEnd time.Time) Span {
+	start := task.start
+	if start.IsZero() {
+		start = task.queued
+	}
+	end := task.end
+	if end.IsZero() {
+		end = workflowEnd
+	}
+
+	name := task.name
+	if name == "" {
+		name = task.id
+	}
+
+	attributes := []KeyValue{
+		String("ritual.task.id", task.id),
+		String("ritual.task.name", name),
+	}
+	if task.taskType != "" {
+		attributes = append(attributes, String("ritual.task.type", task.taskType))
+	}
+	if task.status != "" {
+		attributes = append(attributes, String("ritual.task.status", string(task.status)))
+	}
+	if task.attempt > 0 {
+		attributes = append(attributes, Int("ritual.task.attempt", int64(task.attempt)))
+	}
+	if task.hasResult {
+		attributes = append(attributes, Int("ritual.task.return_code", int64(task.returnCode)))
+	}
+
+	var links []Link
+	for _, dep := range task.dependencies {
+		depSpan, ok := r.tasks[dep]
+		if !ok {
+			continue
+		}
+		links = append(links, Link{
+			TraceID:    r.traceID,
+			SpanID:     depSpan.spanID,
+			Attributes: []KeyValue{String("ritual.link.type", "depends_on")},
+		})
+	}
+
+	return Span{
+		TraceID:           r.traceID,
+		SpanID:            task.spanID,
+		ParentSpanID:      r.spanID,
+		Name:              name,
+		Kind:              spanKindInternal,
+		StartTimeUnixNano: unixNano(start),
+		EndTimeUnixNano:   unixNano(end),
+		Attributes:        attributes,
+		Links:             links,
+		Status:            taskStatus(task),
+	}
+}
+
+func taskStatus(task *taskSpan) Status {
+	switch task.status {
+	case types.TaskFailed:
+		return Status{Code: statusError, Message: task.message}
+	case types.TaskSuccess, types.TaskWarning:
+		return Status{Code: statusOK}
+	default:
+		return Status{Code: statusUnset}
+	}
+}
+
+func workflowStatus(result *types.Result) Status {
+	if result == nil {
+		return Status{Code: statusUnset}
+	}
+	switch {
+	case result.ParseError != nil:
+		return Status{Code: statusError, Message: result.ParseError.Error()}
+	case result.DependencyError != nil:
+		return Status{Code: statusError, Message: result.DependencyError.Error()}
+	case len(result.ValidationErrors) > 0:
+		return Status{Code: statusError, Message: result.ValidationErrors[0].Error()}
+	case result.ExecutionError != nil:
+		return Status{Code: statusError, Message: result.ExecutionError.Error()}
+	case result.WorkflowResult != nil && result.WorkflowResult.Status == types.WorkflowFailed:
+		return Status{Code: statusError, Message: "workflow failed"}
+	}
+	return Status{Code: statusOK}
+}
@@ -0,0 +1,186 @@
+// ABOUTME: Tests for traceparent parsing, span assembly and the OTLP/JSON exporters
+// ABOUTME: Uses a local httptest collector to check the exported span tree and links
+
+package tracing
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sarlalian/ritual/internal/events"
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	sc, err := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatalf("ParseTraceParent failed: %v", err)
+	}
+	if sc.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID != "00f067aa0ba902b7" || !sc.Sampled {
+		t.Errorf("Unexpected span context: %+v", sc)
+	}
+	if sc.TraceParent() != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Errorf("Round trip mismatch: %s", sc.TraceParent())
+	}
+
+	invalid := []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	}
+	for _, header := range invalid {
+		if _, err := ParseTraceParent(header); err == nil {
+			t.Errorf("Expected error for %q", header)
+		}
+	}
+}
+
+// publishRun sends the events for a two-task workflow where "deploy" depends on "build"
+func publishRun(bus *events.Bus, traceParent string) {
+	start := time.Now()
+	bus.Publish(events.Event{Type: events.WorkflowStarted, ExecutionID: "exec_1", Workflow: "release", Time: start, TraceParent: traceParent})
+	bus.Publish(events.Event{Type: events.TaskQueued, ExecutionID: "exec_1", TaskID: "build", TaskName: "Build", TaskType: "command", Time: start})
+	bus.Publish(events.Event{Type: events.TaskQueued, ExecutionID: "exec_1", TaskID: "deploy", TaskName: "Deploy", TaskType: "command", Dependencies: []string{"build"}, Time: start.Add(time.Millisecond)})
+	bus.Publish(events.Event{Type: events.TaskStarted, ExecutionID: "exec_1", TaskID: "build", Time: start.Add(time.Millisecond)})
+	bus.Publish(events.Event{Type: events.TaskFinished, ExecutionID: "exec_1", TaskID: "build", Status: types.TaskSuccess, Time: start.Add(2 * time.Millisecond),
+		TaskResult: &types.TaskResult{ID: "build", Status: types.TaskSuccess, AttemptCount: 1}})
+	bus.Publish(events.Event{Type: events.TaskStarted, ExecutionID: "exec_1", TaskID: "deploy", Time: start.Add(3 * time.Millisecond)})
+	bus.Publish(events.Event{Type: events.TaskFinished, ExecutionID: "exec_1", TaskID: "deploy", Status: types.TaskFailed, Time: start.Add(4 * time.Millisecond),
+		TaskResult: &types.TaskResult{ID: "deploy", Status: types.TaskFailed, ReturnCode: 3, AttemptCount: 2, Error: "exit status 3"}})
+	bus.Publish(events.Event{Type: events.WorkflowFinished, ExecutionID: "exec_1", Workflow: "release", Time: start.Add(5 * time.Millisecond),
+		Result: &types.Result{WorkflowResult: &types.WorkflowResult{Name: "release", Status: types.WorkflowFailed}}})
+}
+
+func attr(span Span, key string) string {
+	for _, kv := range span.Attributes {
+		if kv.Key != key {
+			continue
+		}
+		switch {
+		case kv.Value.StringValue != nil:
+			return *kv.Value.StringValue
+		case kv.Value.IntValue != nil:
+			return *kv.Value.IntValue
+		}
+	}
+	return ""
+}
+
+func TestTracer_ExportsSpanTreeToCollector(t *testing.T) {
+	var mu sync.Mutex
+	var received []ExportRequest
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var req ExportRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		received = append(received, req)
+		mu.Unlock()
+	}))
+	defer collector.Close()
+
+	bus := events.NewBus()
+	bus.Subscribe(NewTracer(NewHTTPExporter(collector.URL+"/v1/traces", nil), nil))
+	publishRun(bus, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 1 {
+		t.Fatalf("Expected 1 export, got %d", len(received))
+	}
+	spans := received[0].ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %d", len(spans))
+	}
+
+	root, build, deploy := spans[0], spans[1], spans[2]
+	if root.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || root.ParentSpanID != "00f067aa0ba902b7" {
+		t.Errorf("Root span should continue the incoming trace: %+v", root)
+	}
+	if root.Status.Code != statusError {
+		t.Errorf("Expected failed workflow status, got %+v", root.Status)
+	}
+	for _, span := range []Span{build, deploy} {
+		if span.TraceID != root.TraceID || span.ParentSpanID != root.SpanID {
+			t.Errorf("Task span %s should be a child of the root span", span.Name)
+		}
+	}
+	if build.Name != "Build" || attr(build, "ritual.task.type") != "command" || attr(build, "ritual.task.status") != "success" {
+		t.Errorf("Unexpected build span: %+v", build)
+	}
+	if attr(deploy, "ritual.task.return_code") != "3" || attr(deploy, "ritual.task.attempt") != "2" {
+		t.Errorf("Unexpected deploy attributes: %+v", deploy.Attributes)
+	}
+	if deploy.Status.Code != statusError || deploy.Status.Message != "exit status 3" {
+		t.Errorf("Unexpected deploy status: %+v", deploy.Status)
+	}
+	if len(deploy.Links) != 1 || deploy.Links[0].SpanID != build.SpanID {
+		t.Errorf("Expected deploy to link to build, got %+v", deploy.Links)
+	}
+	if deploy.StartTimeUnixNano >= deploy.EndTimeUnixNano {
+		t.Errorf("Expected start before end, got %s..%s", deploy.StartTimeUnixNano, deploy.EndTimeUnixNano)
+	}
+}
+
+func TestTracer_FileExporterAppendsLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+
+	bus := events.NewBus()
+	bus.Subscribe(NewTracer(NewFileExporter(path), nil))
+	publishRun(bus, "")
+	publishRun(bus, "")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read trace file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 traces, got %d", len(lines))
+	}
+
+	var first, second ExportRequest
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("Invalid OTLP/JSON: %v", err)
+	}
+	_ = json.Unmarshal([]byte(lines[1]), &second)
+
+	root := first.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	if root.ParentSpanID != "" || len(root.TraceID) != 32 {
+		t.Errorf("Expected a fresh root span, got %+v", root)
+	}
+	if root.TraceID == second.ResourceSpans[0].ScopeSpans[0].Spans[0].TraceID {
+		t.Error("Expected each run to get its own trace ID")
+	}
+}
+
+func TestHTTPExporter_ErrorsOnRejectedExport(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+	}))
+	defer collector.Close()
+
+	err := NewHTTPExporter(collector.URL, nil).Export(t.Context(), &ExportRequest{})
+	if err == nil || !strings.Contains(err.Error(), "quota exceeded") {
+		t.Errorf("Expected collector error, got %v", err)
+	}
+}
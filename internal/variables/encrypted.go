@@ -0,0 +1,221 @@
+// ABOUTME: Encrypted variable files, either armored whole or with each value encrypted in place
+// ABOUTME: Per-value files keep their keys readable so changes can be reviewed in diffs
+
+package variables
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/sarlalian/ritual/internal/secrets"
+)
+
+// Variable file formats. JSON files are handled as YAML. Values can only be
+// encrypted one by one in YAML and .env files.
+const (
+	FormatYAML = "yaml"
+	FormatEnv  = "env"
+	FormatTOML = "toml"
+	FormatHCL  = "hcl"
+)
+
+// EncryptedSuffix may be appended to a variable file's name, as in
+// prod.yaml.enc, without changing how its contents are parsed
+const EncryptedSuffix = ".enc"
+
+// FormatOf returns the format of a variable file from its name, falling back
+// to its contents when the extension is not recognised
+func FormatOf(filePath string, content []byte) string {
+	switch strings.ToLower(filepath.Ext(strings.TrimSuffix(filePath, EncryptedSuffix))) {
+	case ".yaml", ".yml", ".json":
+		return FormatYAML
+	case ".env":
+		return FormatEnv
+	case ".toml":
+		return FormatTOML
+	case ".hcl", ".tfvars":
+		return FormatHCL
+	}
+
+	var probe map[string]interface{}
+	if yaml.Unmarshal(content, &probe) == nil {
+		return FormatYAML
+	}
+	return FormatEnv
+}
+
+// HasEncryptedValues reports whether content contains values encrypted in place
+func HasEncryptedValues(content []byte) bool {
+	return bytes.Contains(content, []byte("ENC[AES256_GCM,"))
+}
+
+// DecryptValues returns content with every encrypted value replaced by its
+// plaintext. onValue, when set, is called with each decrypted value's path
+// and plaintext.
+func DecryptValues(content []byte, format string, cipher *secrets.ValueCipher, onValue func(path, value string)) ([]byte, error) {
+	return rewriteValues(content, format, func(path, text, typ string) (string, string, error) {
+		if !secrets.IsEncryptedValue(text) {
+			return text, typ, nil
+		}
+		plaintext, plainType, err := cipher.DecryptValue(text, path)
+		if err != nil {
+			return "", "", fmt.Errorf("value '%s': %w", path, err)
+		}
+		if onValue != nil {
+			onValue(path, plaintext)
+		}
+		return plaintext, plainType, nil
+	})
+}
+
+// EncryptValues returns content with the values whose paths satisfy match
+// encrypted in place. A nil match encrypts every value. Values that are
+// already encrypted are left alone.
+func EncryptValues(content []byte, format string, cipher *secrets.ValueCipher, match func(path string) bool) ([]byte, error) {
+	return rewriteValues(content, format, func(path, text, typ string) (string, string, error) {
+		if secrets.IsEncryptedValue(text) || (match != nil && !match(path)) {
+			return text, typ, nil
+		}
+		encrypted, err := cipher.EncryptValue(text, typ, path)
+		if err != nil {
+			return "", "", fmt.Errorf("value '%s': %w", path, err)
+		}
+		return encrypted, "str", nil
+	})
+}
+
+// ValuePaths returns the path of every scalar value in content, which also
+// checks that content parses as format
+func ValuePaths(content []byte, format string) ([]string, error) {
+	var paths []string
+	_, err := rewriteValues(content, format, func(path, text, typ string) (string, string, error) {
+		paths = append(paths, path)
+		return text, typ, nil
+	})
+	return paths, err
+}
+
+// rewriteValues calls fn with the path, text and type of every scalar value
+// in content and returns content with the values fn returned. Keys, comments
+// and layout are kept. Paths join map keys and list indexes with dots, as in
+// "database.replicas.0.password"; .env values are addressed by their key.
+func rewriteValues(content []byte, format string, fn func(path, text, typ string) (string, string, error)) ([]byte, error) {
+	switch format {
+	case FormatEnv:
+		return rewriteEnvValues(content, fn)
+	case FormatTOML, FormatHCL:
+		return nil, fmt.Errorf("values can only be encrypted one by one in YAML, JSON and .env files")
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return content, nil
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("variable file must contain a map of names to values")
+	}
+	if err := rewriteNode(doc.Content[0], "", fn); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to write YAML: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write YAML: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+func rewriteNode(node *yaml.Node, path string, fn func(path, text, typ string) (string, string, error)) error {
+	join := func(key string) string {
+		if path == "" {
+			return key
+		}
+		return path + "." + key
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if err := rewriteNode(node.Content[i+1], join(node.Content[i].Value), fn); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			if err := rewriteNode(item, join(fmt.Sprint(i)), fn); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		typ := scalarType(node)
+		if typ == "" {
+			return nil
+		}
+		text, newType, err := fn(path, node.Value, typ)
+		if err != nil {
+			return err
+		}
+		if text != node.Value || newType != typ {
+			node.Value = text
+			node.Tag = "!!" + newType
+			node.Style = 0
+			if strings.Contains(text, "\n") {
+				node.Style = yaml.LiteralStyle
+			}
+		}
+	}
+	return nil
+}
+
+// scalarType returns the type recorded for an encrypted scalar, or "" for
+// nulls and other values that are never encrypted
+func scalarType(node *yaml.Node) string {
+	switch node.ShortTag() {
+	case "!!str":
+		return "str"
+	case "!!int":
+		return "int"
+	case "!!float":
+		return "float"
+	case "!!bool":
+		return "bool"
+	default:
+		return ""
+	}
+}
+
+// rewriteEnvValues applies fn to the value of each KEY=VALUE line, keeping
+// comments and blank lines
+func rewriteEnvValues(content []byte, fn func(path, text, typ string) (string, string, error)) ([]byte, error) {
+	lines := strings.Split(string(content), "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(trimmed, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid format at line %d: %s", i+1, trimmed)
+		}
+		text, _, err := fn(key, value, "str")
+		if err != nil {
+			return nil, err
+		}
+		if text != value {
+			lines[i] = key + "=" + text
+		}
+	}
+	return []byte(strings.Join(lines, "\n")), nil
+}
@@ -0,0 +1,164 @@
+// ABOUTME: Tests for loading variable files encrypted whole or value by value
+// ABOUTME: Covers YAML and .env files, type restoration and passphrase errors
+
+package variables
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sarlalian/ritual/internal/secrets"
+)
+
+const plainYAML = `# production settings
+region: us-east-1
+db:
+  user: app
+  password: hunter22
+  port: 5432
+replicas:
+  - token-aaaa
+enabled: true
+`
+
+func TestFileLoader_PerValueEncryptedYAML(t *testing.T) {
+	t.Setenv(secrets.PassphraseEnv, "pw")
+	dir := t.TempDir()
+
+	encrypted, err := EncryptValues([]byte(plainYAML), FormatYAML, secrets.NewValueCipher([]byte("pw")), func(path string) bool {
+		return path != "region"
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	text := string(encrypted)
+	for _, want := range []string{"# production settings", "region: us-east-1", "password: ENC[AES256_GCM,"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected encrypted file to contain %q, got:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "hunter22") || strings.Contains(text, "5432") {
+		t.Errorf("Expected values to be encrypted, got:\n%s", text)
+	}
+	writeFile(t, filepath.Join(dir, "prod.yaml"), text)
+
+	loader := New(dir)
+	var decrypted []string
+	loader.OnDecrypt(func(value string) { decrypted = append(decrypted, value) })
+
+	vars, err := loader.LoadVariableFile("prod.yaml")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	db := vars["db"].(map[string]interface{})
+	if db["password"] != "hunter22" || db["port"] != 5432 || vars["enabled"] != true || vars["region"] != "us-east-1" {
+		t.Errorf("Expected values and types to be restored, got: %v", vars)
+	}
+	if replicas := vars["replicas"].([]interface{}); replicas[0] != "token-aaaa" {
+		t.Errorf("Expected list values to be decrypted, got: %v", replicas)
+	}
+	if len(decrypted) != 5 {
+		t.Errorf("Expected every decrypted value to be reported, got: %v", decrypted)
+	}
+}
+
+func TestFileLoader_WholeFileEncrypted(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key")
+	writeFile(t, keyFile, "from-key-file\n")
+
+	yamlData, err := secrets.Encrypt([]byte(plainYAML), []byte("from-key-file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, "prod.yaml.enc"), string(yamlData))
+
+	envData, err := secrets.Encrypt([]byte("API_TOKEN=abc-123\nRETRIES=3\n"), []byte("from-key-file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, "prod.env"), string(envData))
+
+	loader := New(dir)
+	if _, err := loader.LoadVariableFile("prod.yaml.enc"); err == nil || !strings.Contains(err.Error(), "no passphrase") {
+		t.Errorf("Expected missing passphrase error, got: %v", err)
+	}
+
+	loader.SetKeySource(keyFile, "")
+	var decrypted []string
+	loader.OnDecrypt(func(value string) { decrypted = append(decrypted, value) })
+
+	vars, err := loader.LoadVariableFile("prod.yaml.enc")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if vars["db"].(map[string]interface{})["password"] != "hunter22" {
+		t.Errorf("Expected decrypted YAML values, got: %v", vars)
+	}
+	if !contains(decrypted, "us-east-1") || contains(decrypted, "true") {
+		t.Errorf("Expected all non-boolean values to be reported, got: %v", decrypted)
+	}
+
+	vars, err = loader.LoadVariableFile("prod.env")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if vars["API_TOKEN"] != "abc-123" || vars["RETRIES"] != 3 {
+		t.Errorf("Expected decrypted .env values, got: %v", vars)
+	}
+}
+
+func TestFileLoader_PerValueEncryptedEnv(t *testing.T) {
+	t.Setenv("TEST_VARS_PASSPHRASE", "pw")
+	dir := t.TempDir()
+
+	plain := "# deploy\nREGION=eu-west-1\nAPI_TOKEN=abc-123\n"
+	encrypted, err := EncryptValues([]byte(plain), FormatEnv, secrets.NewValueCipher([]byte("pw")), func(path string) bool {
+		return path == "API_TOKEN"
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !strings.HasPrefix(string(encrypted), "# deploy\nREGION=eu-west-1\nAPI_TOKEN=ENC[") {
+		t.Errorf("Expected only API_TOKEN to be encrypted, got:\n%s", encrypted)
+	}
+	writeFile(t, filepath.Join(dir, "deploy.env"), string(encrypted))
+
+	loader := New(dir)
+	loader.SetKeySource("", "TEST_VARS_PASSPHRASE")
+	vars, err := loader.LoadVariableFile("deploy.env")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if vars["API_TOKEN"] != "abc-123" || vars["REGION"] != "eu-west-1" {
+		t.Errorf("Expected decrypted values, got: %v", vars)
+	}
+
+	t.Setenv("TEST_VARS_PASSPHRASE", "wrong")
+	if _, err := New(dir).LoadVariableFile("deploy.env"); err == nil {
+		t.Error("Expected error without the right passphrase")
+	}
+	loader = New(dir)
+	loader.SetKeySource("", "TEST_VARS_PASSPHRASE")
+	if _, err := loader.LoadVariableFile("deploy.env"); err == nil || !strings.Contains(err.Error(), "API_TOKEN") {
+		t.Errorf("Expected decryption error naming the value, got: %v", err)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func contains(values []string, want string) bool {
+	for _, value := range values {
+		if value == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,423 @@
+// ABOUTME: Parser for HCL-like variable files (.hcl, .tfvars)
+// ABOUTME: Handles attributes, nested blocks, lists, objects, heredocs and comments, without expressions
+
+package variables
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// parseHCL parses the attributes and blocks of an HCL-like file.
+//
+//	region = "eu-west-1"
+//	replicas = 3
+//	tags = ["web", "api"]
+//	database {
+//	  host = "db.internal"
+//	}
+//	service "api" {
+//	  port = 8080
+//	}
+//
+// A block becomes a map, nested under each of its labels. A block name
+// repeated without labels becomes a list of maps. Interpolations and
+// function calls are not evaluated; use templates in the values instead.
+func parseHCL(content []byte) (map[string]interface{}, error) {
+	p := &hclParser{src: []rune(string(content)), line: 1}
+	body, err := p.body(false)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", p.line, err)
+	}
+	return body, nil
+}
+
+type hclParser struct {
+	src  []rune
+	pos  int
+	line int
+}
+
+// body parses attributes and blocks up to the end of input, or up to the
+// closing brace when nested
+func (p *hclParser) body(nested bool) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	for {
+		p.skipSpace(true)
+		if p.eof() {
+			if nested {
+				return nil, fmt.Errorf("unexpected end of file, expected '}'")
+			}
+			return result, nil
+		}
+		if nested && p.peek() == '}' {
+			p.pos++
+			return result, nil
+		}
+
+		name, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace(false)
+
+		if p.peek() == '=' {
+			p.pos++
+			value, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			if _, exists := result[name]; exists {
+				return nil, fmt.Errorf("duplicate attribute '%s'", name)
+			}
+			result[name] = value
+			if err := p.endOfItem(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// A block: name, any labels, then a body
+		var labels []string
+		for p.peek() != '{' {
+			if p.eof() || p.peek() == '\n' {
+				return nil, fmt.Errorf("expected '=' or '{' after '%s'", name)
+			}
+			label, err := p.key()
+			if err != nil {
+				return nil, err
+			}
+			labels = append(labels, label)
+			p.skipSpace(false)
+		}
+		p.pos++
+		block, err := p.body(true)
+		if err != nil {
+			return nil, err
+		}
+		if err := addBlock(result, name, labels, block); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// addBlock nests block under name and its labels
+func addBlock(result map[string]interface{}, name string, labels []string, block map[string]interface{}) error {
+	if len(labels) == 0 {
+		switch existing := result[name].(type) {
+		case nil:
+			result[name] = block
+		case map[string]interface{}:
+			result[name] = []interface{}{existing, block}
+		case []interface{}:
+			result[name] = append(existing, block)
+		default:
+			return fmt.Errorf("block '%s' conflicts with an attribute of the same name", name)
+		}
+		return nil
+	}
+
+	parent, ok := result[name].(map[string]interface{})
+	if !ok {
+		if _, exists := result[name]; exists {
+			return fmt.Errorf("block '%s' conflicts with an attribute of the same name", name)
+		}
+		parent = make(map[string]interface{})
+		result[name] = parent
+	}
+	return addBlock(parent, labels[0], labels[1:], block)
+}
+
+// value parses a string, number, bool, null, list or object
+func (p *hclParser) value() (interface{}, error) {
+	p.skipSpace(false)
+	if p.eof() {
+		return nil, fmt.Errorf("unexpected end of file, expected a value")
+	}
+
+	switch r := p.peek(); {
+	case r == '"':
+		return p.quoted()
+	case r == '<' && strings.HasPrefix(string(p.src[p.pos:]), "<<"):
+		return p.heredoc()
+	case r == '[':
+		p.pos++
+		return p.list()
+	case r == '{':
+		p.pos++
+		return p.object()
+	case r == '-' || unicode.IsDigit(r):
+		return p.number()
+	default:
+		word := p.word()
+		switch word {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		case "":
+			return nil, fmt.Errorf("unexpected '%c'", r)
+		}
+		return nil, fmt.Errorf("unsupported expression '%s' (only literal values are allowed)", word)
+	}
+}
+
+// list parses the items of a list after its opening bracket
+func (p *hclParser) list() ([]interface{}, error) {
+	items := []interface{}{}
+	for {
+		p.skipSpace(true)
+		if p.peek() == ']' {
+			p.pos++
+			return items, nil
+		}
+		item, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		p.skipSpace(true)
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			return nil, fmt.Errorf("expected ',' or ']' in list")
+		}
+	}
+}
+
+// object parses the entries of an object after its opening brace
+func (p *hclParser) object() (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	for {
+		p.skipSpace(true)
+		if p.peek() == '}' {
+			p.pos++
+			return result, nil
+		}
+		name, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace(false)
+		if r := p.peek(); r != '=' && r != ':' {
+			return nil, fmt.Errorf("expected '=' or ':' after '%s'", name)
+		}
+		p.pos++
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		result[name] = value
+		p.skipSpace(false)
+		if p.peek() == ',' {
+			p.pos++
+		}
+	}
+}
+
+// key parses an identifier or a quoted string
+func (p *hclParser) key() (string, error) {
+	if p.peek() == '"' {
+		return p.quoted()
+	}
+	word := p.word()
+	if word == "" {
+		if p.eof() {
+			return "", fmt.Errorf("unexpected end of file")
+		}
+		return "", fmt.Errorf("unexpected '%c', expected a name", p.peek())
+	}
+	return word, nil
+}
+
+// word reads an identifier
+func (p *hclParser) word() string {
+	start := p.pos
+	for !p.eof() {
+		r := p.peek()
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' {
+			break
+		}
+		p.pos++
+	}
+	return string(p.src[start:p.pos])
+}
+
+// quoted reads a double-quoted string with escapes
+func (p *hclParser) quoted() (string, error) {
+	p.pos++
+	var b strings.Builder
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", fmt.Errorf("unterminated string")
+		}
+		r := p.src[p.pos]
+		p.pos++
+		switch r {
+		case '"':
+			return b.String(), nil
+		case '\\':
+			if p.eof() {
+				return "", fmt.Errorf("unterminated string")
+			}
+			escaped := p.src[p.pos]
+			p.pos++
+			switch escaped {
+			case 'n':
+				b.WriteRune('\n')
+			case 't':
+				b.WriteRune('\t')
+			case 'r':
+				b.WriteRune('\r')
+			case '"', '\\':
+				b.WriteRune(escaped)
+			default:
+				return "", fmt.Errorf("unknown escape '\\%c'", escaped)
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+}
+
+// heredoc reads a <<MARKER or indented <<-MARKER string
+func (p *hclParser) heredoc() (string, error) {
+	p.pos += 2
+	indented := p.peek() == '-'
+	if indented {
+		p.pos++
+	}
+	marker := p.word()
+	if marker == "" {
+		return "", fmt.Errorf("expected a heredoc marker after '<<'")
+	}
+	p.skipSpace(false)
+	if p.peek() != '\n' {
+		return "", fmt.Errorf("expected a newline after heredoc marker '%s'", marker)
+	}
+	p.pos++
+	p.line++
+
+	var lines []string
+	for !p.eof() {
+		start := p.pos
+		for !p.eof() && p.peek() != '\n' {
+			p.pos++
+		}
+		line := string(p.src[start:p.pos])
+		if strings.TrimSpace(line) == marker {
+			if indented {
+				lines = dedent(lines)
+			}
+			return strings.Join(lines, "\n") + "\n", nil
+		}
+		lines = append(lines, line)
+		if !p.eof() {
+			p.pos++
+			p.line++
+		}
+	}
+	return "", fmt.Errorf("heredoc '%s' is not terminated", marker)
+}
+
+// dedent removes the indentation common to all non-blank lines
+func dedent(lines []string) []string {
+	common := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if common < 0 || indent < common {
+			common = indent
+		}
+	}
+	if common <= 0 {
+		return lines
+	}
+	for i, line := range lines {
+		if len(line) >= common {
+			lines[i] = line[common:]
+		}
+	}
+	return lines
+}
+
+// number reads an integer or float
+func (p *hclParser) number() (interface{}, error) {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+	for !p.eof() && strings.ContainsRune("0123456789.eE+-_", p.peek()) {
+		p.pos++
+	}
+	text := string(p.src[start:p.pos])
+	if i, err := strconv.Atoi(text); err == nil {
+		return i, nil
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil {
+		return f, nil
+	}
+	return nil, fmt.Errorf("invalid number '%s'", text)
+}
+
+// endOfItem expects the end of a line, a comment, a closing brace or the
+// end of input after an attribute
+func (p *hclParser) endOfItem() error {
+	p.skipSpace(false)
+	if p.eof() || p.peek() == '\n' || p.peek() == '}' {
+		return nil
+	}
+	return fmt.Errorf("unexpected '%c' after value", p.peek())
+}
+
+// skipSpace skips blanks and comments, and newlines too when newlines is set
+func (p *hclParser) skipSpace(newlines bool) {
+	for !p.eof() {
+		r := p.peek()
+		rest := string(p.src[p.pos:min(p.pos+2, len(p.src))])
+		switch {
+		case r == '\n':
+			if !newlines {
+				return
+			}
+			p.line++
+			p.pos++
+		case r == ' ' || r == '\t' || r == '\r':
+			p.pos++
+		case r == '#' || rest == "//":
+			for !p.eof() && p.peek() != '\n' {
+				p.pos++
+			}
+		case rest == "/*":
+			p.pos += 2
+			for !p.eof() && string(p.src[p.pos:min(p.pos+2, len(p.src))]) != "*/" {
+				if p.peek() == '\n' {
+					p.line++
+				}
+				p.pos++
+			}
+			p.pos = min(p.pos+2, len(p.src))
+		default:
+			return
+		}
+	}
+}
+
+func (p *hclParser) peek() rune {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *hclParser) eof() bool {
+	return p.pos >= len(p.src)
+}
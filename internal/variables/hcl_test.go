@@ -0,0 +1,96 @@
+// ABOUTME: Tests for the HCL-like variable file parser
+// ABOUTME: Covers attributes, blocks, labels, heredocs, comments and parse errors
+
+package variables
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseHCL(t *testing.T) {
+	content := `# Production settings
+region   = "eu-west-1"
+replicas = 3
+ratio    = 0.5
+debug    = false
+tags     = ["web", "api",]
+limits   = { cpu = "500m", "memory": "1Gi" }
+
+/* the primary database */
+database {
+  host = "db.internal" // inline comment
+  port = 5432
+}
+
+service "api" {
+  port = 8080
+}
+service "web" {
+  port = 80
+}
+
+rule {
+  allow = true
+}
+rule {
+  allow = false
+}
+
+motd = <<-EOT
+    Welcome
+      to prod
+    EOT
+`
+
+	got, err := parseHCL([]byte(content))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"region":   "eu-west-1",
+		"replicas": 3,
+		"ratio":    0.5,
+		"debug":    false,
+		"tags":     []interface{}{"web", "api"},
+		"limits":   map[string]interface{}{"cpu": "500m", "memory": "1Gi"},
+		"database": map[string]interface{}{"host": "db.internal", "port": 5432},
+		"service": map[string]interface{}{
+			"api": map[string]interface{}{"port": 8080},
+			"web": map[string]interface{}{"port": 80},
+		},
+		"rule": []interface{}{
+			map[string]interface{}{"allow": true},
+			map[string]interface{}{"allow": false},
+		},
+		"motd": "Welcome\n  to prod\n",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestParseHCL_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"unterminated string", "a = \"x\n", "line 1: unterminated string"},
+		{"missing brace", "a {\n  b = 1\n", "expected '}'"},
+		{"expression", "a = var.region\n", "only literal values are allowed"},
+		{"duplicate", "a = 1\na = 2\n", "line 2: duplicate attribute 'a'"},
+		{"trailing", "a = 1 2\n", "unexpected '2' after value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseHCL([]byte(tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+// ABOUTME: Locates the line each top-level key is defined on in YAML, JSON, TOML, HCL and .env content
+// ABOUTME: Used to report where a variable was set
+
+package variables
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// KeyLines returns the 1-based line of each top-level key in content. With a
+// section, it returns the lines of the keys in the mapping under that
+// top-level key instead. Content that cannot be parsed yields no lines.
+func KeyLines(content []byte, format, section string) map[string]int {
+	lines := make(map[string]int)
+
+	if format == FormatEnv {
+		if section != "" {
+			return lines
+		}
+		for i, line := range strings.Split(string(content), "\n") {
+			line = strings.TrimPrefix(strings.TrimSpace(line), "export ")
+			if key, _, ok := strings.Cut(line, "="); ok && !strings.HasPrefix(line, "#") {
+				lines[strings.TrimSpace(key)] = i + 1
+			}
+		}
+		return lines
+	}
+
+	if format == FormatTOML || format == FormatHCL {
+		if section != "" {
+			return lines
+		}
+		return scanKeyLines(content, format)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil || len(doc.Content) == 0 {
+		return lines
+	}
+	mapping := doc.Content[0]
+	if section != "" {
+		mapping = mappingValue(mapping, section)
+	}
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return lines
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		lines[mapping.Content[i].Value] = mapping.Content[i].Line
+	}
+	return lines
+}
+
+// mappingValue returns the value of key in a mapping node, or nil
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// scanKeyLines finds the top-level keys of TOML or HCL content line by line.
+// In TOML a table header such as [database] or [[servers]] names a key, and
+// keys after the first header belong to a table. In HCL both attributes and
+// blocks at the top level name keys.
+func scanKeyLines(content []byte, format string) map[string]int {
+	lines := make(map[string]int)
+	depth := 0
+	inTable := false
+	for i, line := range strings.Split(string(content), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if depth == 0 && trimmed != "" && !strings.HasPrefix(trimmed, "#") && !strings.HasPrefix(trimmed, "//") {
+			var key string
+			if format == FormatTOML && strings.HasPrefix(trimmed, "[") {
+				header := strings.Trim(strings.TrimSpace(strings.SplitN(trimmed, "#", 2)[0]), "[] ")
+				key, inTable = strings.Trim(strings.SplitN(header, ".", 2)[0], `"' `), true
+			} else if fields := strings.FieldsFunc(trimmed, func(r rune) bool {
+				return r == '=' || r == '{' || r == ' ' || r == '\t'
+			}); !inTable && len(fields) > 0 {
+				key = strings.Trim(fields[0], `"'`)
+				if format == FormatTOML {
+					key = strings.SplitN(key, ".", 2)[0]
+				}
+			}
+			if _, seen := lines[key]; key != "" && !seen {
+				lines[key] = i + 1
+			}
+		}
+		depth += nesting(line)
+	}
+	return lines
+}
+
+// nesting returns the change in bracket depth over a line, ignoring
+// brackets in strings and comments
+func nesting(line string) int {
+	change := 0
+	inString := false
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case inString && c == '\\':
+			i++
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '#' || (c == '/' && i+1 < len(line) && line[i+1] == '/'):
+			return change
+		case c == '{' || c == '[':
+			change++
+		case c == '}' || c == ']':
+			change--
+		}
+	}
+	return change
+}
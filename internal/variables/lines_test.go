@@ -0,0 +1,36 @@
+// ABOUTME: Tests for locating the lines variables are defined on
+// ABOUTME: Covers YAML, JSON, TOML, HCL, .env content and keys under a section
+
+package variables
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeyLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		format  string
+		section string
+		want    map[string]int
+	}{
+		{"yaml", "# comment\nregion: eu\nnested:\n  key: value\nsize: 3\n", FormatYAML, "", map[string]int{"region": 2, "nested": 3, "size": 5}},
+		{"json", "{\n  \"region\": \"eu\",\n  \"size\": 3\n}\n", FormatYAML, "", map[string]int{"region": 2, "size": 3}},
+		{"env", "# comment\nREGION=eu\n\nexport SIZE=3\n", FormatEnv, "", map[string]int{"REGION": 2, "SIZE": 4}},
+		{"section", "name: wf\nvars:\n  region: eu\n  size: 3\n", FormatYAML, "vars", map[string]int{"region": 3, "size": 4}},
+		{"missing section", "name: wf\n", FormatYAML, "vars", map[string]int{}},
+		{"invalid", "region: [eu\n", FormatYAML, "", map[string]int{}},
+		{"toml", "# comment\nregion = \"eu\"\nports = [\n  80,\n]\n\n[database]\nhost = \"db\"\n[[servers]]\nname = \"a\"\n[[servers]]\n", FormatTOML, "", map[string]int{"region": 2, "ports": 3, "database": 7, "servers": 9}},
+		{"hcl", "region = \"eu\"\ndatabase {\n  host = \"db\"\n}\n// comment\nservice \"api\" {\n  port = 80\n}\n", FormatHCL, "", map[string]int{"region": 1, "database": 2, "service": 6}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := KeyLines([]byte(tt.content), tt.format, tt.section); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -1,5 +1,5 @@
 // ABOUTME: Variable file loader for loading workflow variables from external files
-// ABOUTME: Supports YAML, JSON, and .env file formats for flexible variable management
+// ABOUTME: Supports YAML, JSON, TOML, HCL-like and .env files, local or remote, encrypted whole or per value
 
 package variables
 
@@ -10,14 +10,30 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/pelletier/go-toml/v2"
 	"gopkg.in/yaml.v3"
+
+	"github.com/sarlalian/ritual/internal/filesystem"
+	"github.com/sarlalian/ritual/internal/secrets"
+	"github.com/sarlalian/ritual/pkg/types"
 )
 
 // FileLoader handles loading variables from external files
 type FileLoader struct {
-	basePath string
+	basePath      string
+	keyFile       string
+	passphraseEnv string
+	key           []byte
+	onDecrypt     func(value string)
+	onLoad        func(path string, lines map[string]int)
+	merge         *types.MergeConfig
+	fsConfig      *filesystem.Config
 }
 
+// EnvironmentsDir holds the overlay file of each environment, relative to
+// the workflow's directory
+const EnvironmentsDir = "variables/environments"
+
 // New creates a new variable file loader
 func New(basePath string) *FileLoader {
 	return &FileLoader{
@@ -25,40 +41,169 @@ func New(basePath string) *FileLoader {
 	}
 }
 
-// LoadVariableFile loads variables from a file and returns them as a map
+// SetKeySource sets where the passphrase for encrypted files is read from.
+// Empty values fall back to RITUAL_PASSPHRASE and RITUAL_KEY_FILE.
+func (fl *FileLoader) SetKeySource(keyFile, passphraseEnv string) {
+	fl.keyFile = keyFile
+	fl.passphraseEnv = passphraseEnv
+	fl.key = nil
+}
+
+// OnDecrypt registers fn to be called with every value read from an
+// encrypted file or decrypted in place, so the values can be masked
+func (fl *FileLoader) OnDecrypt(fn func(value string)) {
+	fl.onDecrypt = fn
+}
+
+// OnLoad registers fn to be called with the path of every file loaded and
+// the line each of its top-level keys is on
+func (fl *FileLoader) OnLoad(fn func(path string, lines map[string]int)) {
+	fl.onLoad = fn
+}
+
+// SetFilesystemConfig sets the credentials used to read remote variable
+// files; nil uses the filesystem defaults
+func (fl *FileLoader) SetFilesystemConfig(config *filesystem.Config) {
+	fl.fsConfig = config
+}
+
+// SetMergeConfig sets how LoadVariableFiles merges later files into earlier
+// ones; nil merges maps deeply and replaces lists
+func (fl *FileLoader) SetMergeConfig(cfg *types.MergeConfig) {
+	fl.merge = cfg
+}
+
+// EnvironmentOverlay returns the path, relative to the base path, of the
+// variable file for environment in EnvironmentsDir, trying .yaml, .yml,
+// .json and .env, each optionally encrypted
+func (fl *FileLoader) EnvironmentOverlay(environment string) (string, error) {
+	if environment == "" || environment != filepath.Base(environment) || strings.HasPrefix(environment, ".") {
+		return "", fmt.Errorf("invalid environment name '%s'", environment)
+	}
+
+	for _, ext := range []string{".yaml", ".yml", ".json", ".env"} {
+		for _, suffix := range []string{"", EncryptedSuffix} {
+			candidate := filepath.Join(EnvironmentsDir, environment+ext+suffix)
+			if _, err := os.Stat(filepath.Join(fl.basePath, candidate)); err == nil {
+				return candidate, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no variable file for environment '%s' in %s", environment, filepath.Join(fl.basePath, EnvironmentsDir))
+}
+
+// LoadVariableFile loads variables from a file and returns them as a map.
+// filePath may be a local path or any URI the filesystem factory supports,
+// optionally pinned with a "#sha256=<hex>" or "#sha512=<hex>" suffix.
 func (fl *FileLoader) LoadVariableFile(filePath string) (map[string]interface{}, error) {
+	filePath, algorithm, digest, err := splitChecksum(filePath)
+	if err != nil {
+		return nil, err
+	}
+
 	// Resolve relative paths against base path
-	if !filepath.IsAbs(filePath) && fl.basePath != "" {
-		filePath = filepath.Join(fl.basePath, filePath)
+	filePath = fl.resolvePath(filePath)
+
+	content, err := fl.readFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// The pin covers the file as stored, encrypted or not
+	if err := verifyChecksum(filePath, content, algorithm, digest); err != nil {
+		return nil, err
 	}
 
-	// Check if file exists
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("variable file not found: %s", filePath)
+	// Decrypted content is only ever held in memory
+	content, err = fl.decrypt(content, filePath)
+	if err != nil {
+		return nil, err
 	}
 
-	// Determine file format by extension
-	ext := strings.ToLower(filepath.Ext(filePath))
+	if fl.onLoad != nil {
+		fl.onLoad(filePath, KeyLines(content, FormatOf(filePath, content), ""))
+	}
+
+	// Determine file format by extension, ignoring a trailing .enc
+	ext := strings.ToLower(filepath.Ext(strings.TrimSuffix(filePath, EncryptedSuffix)))
 	switch ext {
 	case ".yaml", ".yml":
-		return fl.loadYAMLFile(filePath)
+		return fl.loadYAMLFile(filePath, content)
 	case ".json":
-		return fl.loadJSONFile(filePath)
+		return fl.loadJSONFile(filePath, content)
 	case ".env":
-		return fl.loadEnvFile(filePath)
+		return fl.loadEnvFile(filePath, content)
+	case ".toml":
+		return fl.loadTOMLFile(filePath, content)
+	case ".hcl", ".tfvars":
+		return fl.loadHCLFile(filePath, content)
 	default:
 		// Try to detect format from content
-		return fl.loadAutoDetect(filePath)
+		return fl.loadAutoDetect(filePath, content)
 	}
 }
 
-// loadYAMLFile loads variables from a YAML file
-func (fl *FileLoader) loadYAMLFile(filePath string) (map[string]interface{}, error) {
-	content, err := os.ReadFile(filePath)
+// decrypt returns the plaintext of an encrypted file, or content with any
+// values encrypted in place decrypted. Plain files are returned unchanged.
+func (fl *FileLoader) decrypt(content []byte, filePath string) ([]byte, error) {
+	wholeFile := secrets.IsEncrypted(content)
+	if !wholeFile && !HasEncryptedValues(content) {
+		return content, nil
+	}
+
+	passphrase, err := fl.passphrase()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read YAML file '%s': %w", filePath, err)
+		return nil, fmt.Errorf("failed to decrypt variable file '%s': %w", filePath, err)
+	}
+
+	if !wholeFile {
+		cipher := secrets.NewValueCipher(passphrase)
+		plaintext, err := DecryptValues(content, FormatOf(filePath, content), cipher, func(path, value string) {
+			if fl.onDecrypt != nil {
+				fl.onDecrypt(value)
+			}
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt variable file '%s': %w", filePath, err)
+		}
+		return plaintext, nil
+	}
+
+	plaintext, err := secrets.Decrypt(content, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt variable file '%s': %w", filePath, err)
+	}
+	if fl.onDecrypt != nil {
+		// Everything in an encrypted file is treated as sensitive. Parse
+		// errors are reported when the plaintext is loaded.
+		if format := FormatOf(filePath, plaintext); format == FormatTOML || format == FormatHCL {
+			fl.reportValues(filePath, plaintext)
+			return plaintext, nil
+		}
+		_, _ = rewriteValues(plaintext, FormatOf(filePath, plaintext), func(path, text, typ string) (string, string, error) {
+			if typ != "bool" {
+				fl.onDecrypt(text)
+			}
+			return text, typ, nil
+		})
+	}
+	return plaintext, nil
+}
+
+// passphrase loads the passphrase on first use and keeps it for later files
+func (fl *FileLoader) passphrase() ([]byte, error) {
+	if fl.key == nil {
+		key, err := secrets.LoadPassphrase(fl.keyFile, fl.passphraseEnv)
+		if err != nil {
+			return nil, err
+		}
+		fl.key = key
 	}
+	return fl.key, nil
+}
 
+// loadYAMLFile loads variables from YAML file content
+func (fl *FileLoader) loadYAMLFile(filePath string, content []byte) (map[string]interface{}, error) {
 	var variables map[string]interface{}
 	if err := yaml.Unmarshal(content, &variables); err != nil {
 		return nil, fmt.Errorf("failed to parse YAML file '%s': %w", filePath, err)
@@ -67,13 +212,8 @@ func (fl *FileLoader) loadYAMLFile(filePath string) (map[string]interface{}, err
 	return variables, nil
 }
 
-// loadJSONFile loads variables from a JSON file
-func (fl *FileLoader) loadJSONFile(filePath string) (map[string]interface{}, error) {
-	content, err := os.ReadFile(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read JSON file '%s': %w", filePath, err)
-	}
-
+// loadJSONFile loads variables from JSON file content
+func (fl *FileLoader) loadJSONFile(filePath string, content []byte) (map[string]interface{}, error) {
 	var variables map[string]interface{}
 	if err := yaml.Unmarshal(content, &variables); err != nil {
 		// Try JSON parsing as fallback
@@ -85,9 +225,79 @@ func (fl *FileLoader) loadJSONFile(filePath string) (map[string]interface{}, err
 	return variables, nil
 }
 
-// loadEnvFile loads variables from a .env file
-func (fl *FileLoader) loadEnvFile(filePath string) (map[string]interface{}, error) {
-	envVars, err := loadEnvironmentFile(filePath)
+// loadTOMLFile loads variables from TOML file content
+func (fl *FileLoader) loadTOMLFile(filePath string, content []byte) (map[string]interface{}, error) {
+	var variables map[string]interface{}
+	if err := toml.Unmarshal(content, &variables); err != nil {
+		return nil, fmt.Errorf("failed to parse TOML file '%s': %w", filePath, err)
+	}
+
+	// Integers and local dates are converted to the types YAML files give
+	return normalizeTOML(variables).(map[string]interface{}), nil
+}
+
+// normalizeTOML converts int64 values to int and local dates and times to
+// strings
+func normalizeTOML(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeTOML(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeTOML(item)
+		}
+	case int64:
+		return int(v)
+	case toml.LocalDate, toml.LocalTime, toml.LocalDateTime:
+		return fmt.Sprint(v)
+	}
+	return value
+}
+
+// loadHCLFile loads variables from HCL-like file content
+func (fl *FileLoader) loadHCLFile(filePath string, content []byte) (map[string]interface{}, error) {
+	variables, err := parseHCL(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HCL file '%s': %w", filePath, err)
+	}
+
+	return variables, nil
+}
+
+// reportValues passes every string and number in a decrypted TOML or HCL
+// file to the decrypt callback
+func (fl *FileLoader) reportValues(filePath string, plaintext []byte) {
+	var variables map[string]interface{}
+	if FormatOf(filePath, plaintext) == FormatTOML {
+		variables, _ = fl.loadTOMLFile(filePath, plaintext)
+	} else {
+		variables, _ = fl.loadHCLFile(filePath, plaintext)
+	}
+
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		case []interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		case bool, nil:
+		default:
+			fl.onDecrypt(fmt.Sprint(v))
+		}
+	}
+	walk(variables)
+}
+
+// loadEnvFile loads variables from .env file content
+func (fl *FileLoader) loadEnvFile(filePath string, content []byte) (map[string]interface{}, error) {
+	envVars, err := parseEnvironmentFile(filePath, content)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load .env file '%s': %w", filePath, err)
 	}
@@ -105,12 +315,7 @@ func (fl *FileLoader) loadEnvFile(filePath string) (map[string]interface{}, erro
 }
 
 // loadAutoDetect attempts to detect file format and load accordingly
-func (fl *FileLoader) loadAutoDetect(filePath string) (map[string]interface{}, error) {
-	content, err := os.ReadFile(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read file '%s': %w", filePath, err)
-	}
-
+func (fl *FileLoader) loadAutoDetect(filePath string, content []byte) (map[string]interface{}, error) {
 	contentStr := strings.TrimSpace(string(content))
 
 	// Try YAML first (most permissive)
@@ -121,7 +326,7 @@ func (fl *FileLoader) loadAutoDetect(filePath string) (map[string]interface{}, e
 
 	// Try .env format if it looks like key=value lines
 	if strings.Contains(contentStr, "=") && !strings.Contains(contentStr, "{") {
-		return fl.loadEnvFile(filePath)
+		return fl.loadEnvFile(filePath, content)
 	}
 
 	return nil, fmt.Errorf("unable to determine format of file '%s'", filePath)
@@ -137,10 +342,8 @@ func (fl *FileLoader) LoadVariableFiles(filePaths []string) (map[string]interfac
 			return nil, fmt.Errorf("failed to load variable file '%s': %w", filePath, err)
 		}
 
-		// Merge variables (later files override earlier ones)
-		for key, value := range variables {
-			merged[key] = value
-		}
+		// Later files override earlier ones, merging maps deeply by default
+		merged = Merge(merged, variables, fl.merge)
 	}
 
 	return merged, nil
@@ -196,7 +399,7 @@ func (fl *FileLoader) ListVariableFiles(dirPath string) ([]string, error) {
 	}
 
 	var varFiles []string
-	extensions := []string{".yaml", ".yml", ".json", ".env", ".vars"}
+	extensions := []string{".yaml", ".yml", ".json", ".env", ".toml", ".hcl", ".tfvars", ".vars", EncryptedSuffix}
 
 	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -242,22 +445,24 @@ func (fl *FileLoader) GetVariableFileInfo(filePath string, loadContent bool) *Va
 	}
 
 	// Resolve relative paths
-	fullPath := filePath
-	if !filepath.IsAbs(filePath) && fl.basePath != "" {
-		fullPath = filepath.Join(fl.basePath, filePath)
+	fullPath, _, _, err := splitChecksum(filePath)
+	if err != nil {
+		info.Error = err.Error()
+		return info
 	}
+	fullPath = fl.resolvePath(fullPath)
 
 	// Get file info
-	fileInfo, err := os.Stat(fullPath)
+	content, err := fl.readFile(fullPath)
 	if err != nil {
 		info.Error = fmt.Sprintf("File not found: %v", err)
 		return info
 	}
 
-	info.Size = fileInfo.Size()
+	info.Size = int64(len(content))
 
 	// Determine format
-	ext := strings.ToLower(filepath.Ext(filePath))
+	ext := strings.ToLower(filepath.Ext(fullPath))
 	switch ext {
 	case ".yaml", ".yml":
 		info.Format = "YAML"
@@ -265,6 +470,10 @@ func (fl *FileLoader) GetVariableFileInfo(filePath string, loadContent bool) *Va
 		info.Format = "JSON"
 	case ".env":
 		info.Format = "Environment"
+	case ".toml":
+		info.Format = "TOML"
+	case ".hcl", ".tfvars":
+		info.Format = "HCL"
 	default:
 		info.Format = "Auto-detect"
 	}
@@ -282,13 +491,8 @@ func (fl *FileLoader) GetVariableFileInfo(filePath string, loadContent bool) *Va
 	return info
 }
 
-// loadEnvironmentFile loads environment variables from a .env file
-func loadEnvironmentFile(filename string) ([]string, error) {
-	content, err := os.ReadFile(filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read environment file '%s': %w", filename, err)
-	}
-
+// parseEnvironmentFile parses the KEY=value lines of a .env file
+func parseEnvironmentFile(filename string, content []byte) ([]string, error) {
 	vars := make([]string, 0)
 	lines := strings.Split(string(content), "\n")
 
@@ -0,0 +1,106 @@
+// ABOUTME: Tests for loading variable files by path or URI
+// ABOUTME: Covers TOML and encrypted HCL files, file:// URIs and checksum pinning
+
+package variables
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/sarlalian/ritual/internal/secrets"
+)
+
+func TestLoadVariableFile_TOML(t *testing.T) {
+	dir := t.TempDir()
+	content := "region = \"eu\"\nreplicas = 3\nrelease = 2024-05-01\n\n[database]\nport = 5432\n"
+	if err := os.WriteFile(filepath.Join(dir, "prod.toml"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	vars, err := New(dir).LoadVariableFile("prod.toml")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if vars["region"] != "eu" || vars["replicas"] != 3 || vars["release"] != "2024-05-01" {
+		t.Errorf("Expected region, replicas and release, got %v", vars)
+	}
+	if db, ok := vars["database"].(map[string]interface{}); !ok || db["port"] != 5432 {
+		t.Errorf("Expected database.port to be the int 5432, got %v", vars["database"])
+	}
+}
+
+func TestLoadVariableFile_URIAndChecksum(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "shared.yaml")
+	content := []byte("region: eu\n")
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(content)
+	digest := hex.EncodeToString(sum[:])
+
+	loader := New("/elsewhere")
+	vars, err := loader.LoadVariableFile("file://" + path + "#sha256=" + digest)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if vars["region"] != "eu" {
+		t.Errorf("Expected region eu, got %v", vars)
+	}
+
+	_, err = loader.LoadVariableFile("file://" + path + "#sha256=" + strings.Repeat("0", 64))
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Expected a checksum mismatch, got %v", err)
+	}
+
+	_, err = loader.LoadVariableFile(path + "#sha512=xyz")
+	if err == nil || !strings.Contains(err.Error(), "invalid sha512 checksum") {
+		t.Errorf("Expected an invalid checksum error, got %v", err)
+	}
+
+	// References can be pinned too
+	resolved, err := loader.ResolveVariableReferences(map[string]interface{}{"shared": "@file://" + path + "#sha256=" + digest})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if shared, ok := resolved["shared"].(map[string]interface{}); !ok || shared["region"] != "eu" {
+		t.Errorf("Expected the referenced file's variables, got %v", resolved["shared"])
+	}
+
+	if _, err := loader.LoadVariableFile("ftp://host/vars.yaml"); err == nil || !strings.Contains(err.Error(), "unsupported filesystem scheme") {
+		t.Errorf("Expected an unsupported scheme error, got %v", err)
+	}
+}
+
+func TestLoadVariableFile_EncryptedHCL(t *testing.T) {
+	t.Setenv(secrets.PassphraseEnv, "pw")
+	encrypted, err := secrets.Encrypt([]byte("token = \"s3cret\"\nport = 80\ntls = true\n"), []byte("pw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "prod.tfvars.enc"), encrypted, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := New(dir)
+	var decrypted []string
+	loader.OnDecrypt(func(value string) { decrypted = append(decrypted, value) })
+	vars, err := loader.LoadVariableFile("prod.tfvars.enc")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if vars["token"] != "s3cret" || vars["port"] != 80 {
+		t.Errorf("Expected the decrypted variables, got %v", vars)
+	}
+
+	sort.Strings(decrypted)
+	if strings.Join(decrypted, ",") != "80,s3cret" {
+		t.Errorf("Expected token and port to be reported for masking, got %v", decrypted)
+	}
+}
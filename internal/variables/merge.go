@@ -0,0 +1,184 @@
+// ABOUTME: Deep merging of variables from layered variable files
+// ABOUTME: Maps merge key by key; lists are replaced, appended or merged by an identifying key
+
+package variables
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+// Merge modes
+const (
+	MergeDeep    = "deep"
+	MergeShallow = "shallow"
+)
+
+// List strategies
+const (
+	ListReplace    = "replace"
+	ListAppend     = "append"
+	ListMergeByKey = "merge_by_key"
+)
+
+// DefaultMergeKey identifies list items for merge_by_key when none is set
+const DefaultMergeKey = "name"
+
+// CheckMergeConfig validates a workflow's variable_merge section
+func CheckMergeConfig(cfg *types.MergeConfig) []error {
+	if cfg == nil {
+		return nil
+	}
+
+	var errs []error
+	if cfg.Mode != "" && cfg.Mode != MergeDeep && cfg.Mode != MergeShallow {
+		errs = append(errs, types.NewValidationError("variable_merge.mode", cfg.Mode,
+			fmt.Sprintf("unknown mode '%s' (expected %s or %s)", cfg.Mode, MergeDeep, MergeShallow)))
+	}
+	if cfg.Lists != "" && !knownListStrategy(cfg.Lists) {
+		errs = append(errs, types.NewValidationError("variable_merge.lists", cfg.Lists, listStrategyMessage(cfg.Lists)))
+	}
+
+	paths := make([]string, 0, len(cfg.Paths))
+	for path := range cfg.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if strategy := cfg.Paths[path]; !knownListStrategy(strategy) {
+			errs = append(errs, types.NewValidationError("variable_merge.paths."+path, strategy, listStrategyMessage(strategy)))
+		}
+	}
+	return errs
+}
+
+// Merge merges src into a copy of dst following cfg, so that later files
+// override earlier ones. Neither map is modified.
+func Merge(dst, src map[string]interface{}, cfg *types.MergeConfig) map[string]interface{} {
+	merged := make(map[string]interface{}, len(dst)+len(src))
+	for key, value := range dst {
+		merged[key] = value
+	}
+	for key, value := range src {
+		if existing, ok := merged[key]; ok {
+			value = MergeValue(key, existing, value, cfg)
+		}
+		merged[key] = value
+	}
+	return merged
+}
+
+// MergeValue returns the result of layering incoming over existing for the
+// variable at the dotted path. Neither value is modified.
+func MergeValue(path string, existing, incoming interface{}, cfg *types.MergeConfig) interface{} {
+	if cfg == nil {
+		cfg = &types.MergeConfig{}
+	}
+	if cfg.Mode == MergeShallow {
+		return incoming
+	}
+	return mergeValue(path, existing, incoming, cfg)
+}
+
+func mergeValue(path string, existing, incoming interface{}, cfg *types.MergeConfig) interface{} {
+	switch in := incoming.(type) {
+	case map[string]interface{}:
+		current, ok := existing.(map[string]interface{})
+		if !ok {
+			return incoming
+		}
+		merged := make(map[string]interface{}, len(current)+len(in))
+		for key, value := range current {
+			merged[key] = value
+		}
+		for key, value := range in {
+			if prior, ok := merged[key]; ok {
+				value = mergeValue(path+"."+key, prior, value, cfg)
+			}
+			merged[key] = value
+		}
+		return merged
+
+	case []interface{}:
+		current, ok := existing.([]interface{})
+		if !ok {
+			return incoming
+		}
+		switch listStrategy(path, cfg) {
+		case ListAppend:
+			merged := make([]interface{}, 0, len(current)+len(in))
+			return append(append(merged, current...), in...)
+		case ListMergeByKey:
+			return mergeByKey(path, current, in, cfg)
+		}
+	}
+	return incoming
+}
+
+// mergeByKey merges list items that are maps sharing the same merge key
+// value, in place, and appends the rest in order
+func mergeByKey(path string, current, incoming []interface{}, cfg *types.MergeConfig) []interface{} {
+	key := cfg.MergeKey
+	if key == "" {
+		key = DefaultMergeKey
+	}
+
+	merged := make([]interface{}, len(current), len(current)+len(incoming))
+	copy(merged, current)
+
+	index := make(map[string]int)
+	for i, item := range merged {
+		if id, ok := itemKey(item, key); ok {
+			index[id] = i
+		}
+	}
+
+	for _, item := range incoming {
+		id, ok := itemKey(item, key)
+		if i, exists := index[id]; ok && exists {
+			merged[i] = mergeValue(path, merged[i], item, cfg)
+			continue
+		}
+		if ok {
+			index[id] = len(merged)
+		}
+		merged = append(merged, item)
+	}
+	return merged
+}
+
+// itemKey returns the value of key in a map list item
+func itemKey(item interface{}, key string) (string, bool) {
+	m, ok := item.(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	value, ok := m[key]
+	if !ok || value == nil {
+		return "", false
+	}
+	return fmt.Sprint(value), true
+}
+
+// listStrategy returns the strategy for the list at path
+func listStrategy(path string, cfg *types.MergeConfig) string {
+	if strategy, ok := cfg.Paths[path]; ok {
+		return strategy
+	}
+	if cfg.Lists != "" {
+		return cfg.Lists
+	}
+	return ListReplace
+}
+
+func knownListStrategy(strategy string) bool {
+	return strategy == ListReplace || strategy == ListAppend || strategy == ListMergeByKey
+}
+
+func listStrategyMessage(strategy string) string {
+	return fmt.Sprintf("unknown list strategy '%s' (expected %s)", strategy,
+		strings.Join([]string{ListReplace, ListAppend, ListMergeByKey}, ", "))
+}
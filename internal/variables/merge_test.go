@@ -0,0 +1,133 @@
+// ABOUTME: Tests for deep merging of layered variable files
+// ABOUTME: Covers nested maps, list strategies, shallow mode and config validation
+
+package variables
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+func TestMerge(t *testing.T) {
+	base := map[string]interface{}{
+		"db":    map[string]interface{}{"host": "localhost", "port": 5432},
+		"tags":  []interface{}{"a", "b"},
+		"hosts": []interface{}{map[string]interface{}{"name": "web", "size": "small"}, map[string]interface{}{"name": "db", "size": "small"}},
+		"kept":  "base",
+	}
+	overlay := map[string]interface{}{
+		"db":    map[string]interface{}{"host": "db.prod"},
+		"tags":  []interface{}{"c"},
+		"hosts": []interface{}{map[string]interface{}{"name": "db", "size": "large"}, map[string]interface{}{"name": "cache"}},
+	}
+
+	tests := []struct {
+		name string
+		cfg  *types.MergeConfig
+		want map[string]interface{}
+	}{
+		{
+			name: "default",
+			cfg:  nil,
+			want: map[string]interface{}{
+				"db":    map[string]interface{}{"host": "db.prod", "port": 5432},
+				"tags":  []interface{}{"c"},
+				"hosts": overlay["hosts"],
+				"kept":  "base",
+			},
+		},
+		{
+			name: "append and merge by key",
+			cfg:  &types.MergeConfig{Lists: ListAppend, Paths: map[string]string{"hosts": ListMergeByKey}},
+			want: map[string]interface{}{
+				"db":   map[string]interface{}{"host": "db.prod", "port": 5432},
+				"tags": []interface{}{"a", "b", "c"},
+				"hosts": []interface{}{
+					map[string]interface{}{"name": "web", "size": "small"},
+					map[string]interface{}{"name": "db", "size": "large"},
+					map[string]interface{}{"name": "cache"},
+				},
+				"kept": "base",
+			},
+		},
+		{
+			name: "shallow",
+			cfg:  &types.MergeConfig{Mode: MergeShallow, Lists: ListAppend},
+			want: map[string]interface{}{
+				"db":    map[string]interface{}{"host": "db.prod"},
+				"tags":  []interface{}{"c"},
+				"hosts": overlay["hosts"],
+				"kept":  "base",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Merge(base, overlay, tt.cfg); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	// Neither input is modified
+	if db := base["db"].(map[string]interface{}); len(db) != 2 || db["host"] != "localhost" {
+		t.Errorf("Expected base to be unchanged, got %v", db)
+	}
+	if tags := base["tags"].([]interface{}); len(tags) != 2 {
+		t.Errorf("Expected base tags to be unchanged, got %v", tags)
+	}
+}
+
+func TestMergeValue_NestedPath(t *testing.T) {
+	cfg := &types.MergeConfig{Paths: map[string]string{"app.ports": ListAppend}}
+	existing := map[string]interface{}{"ports": []interface{}{80}, "args": []interface{}{"-v"}}
+	incoming := map[string]interface{}{"ports": []interface{}{443}, "args": []interface{}{"-q"}}
+
+	got := MergeValue("app", existing, incoming, cfg)
+	want := map[string]interface{}{"ports": []interface{}{80, 443}, "args": []interface{}{"-q"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestCheckMergeConfig(t *testing.T) {
+	if errs := CheckMergeConfig(nil); len(errs) != 0 {
+		t.Errorf("Expected no errors for nil config, got %v", errs)
+	}
+
+	cfg := &types.MergeConfig{Mode: "weird", Lists: ListAppend, Paths: map[string]string{"a": "zip", "b": ListMergeByKey}}
+	errs := CheckMergeConfig(cfg)
+	if len(errs) != 2 {
+		t.Fatalf("Expected 2 errors, got %v", errs)
+	}
+	if ve, ok := errs[1].(*types.ValidationError); !ok || ve.Field != "variable_merge.paths.a" {
+		t.Errorf("Expected an error for variable_merge.paths.a, got %v", errs[1])
+	}
+}
+
+func TestEnvironmentOverlay(t *testing.T) {
+	dir := t.TempDir()
+	loader := New(dir)
+	if _, err := loader.EnvironmentOverlay("../prod"); err == nil {
+		t.Error("Expected an error for a path in the environment name")
+	}
+	if _, err := loader.EnvironmentOverlay("prod"); err == nil {
+		t.Error("Expected an error for a missing overlay")
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, EnvironmentsDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, EnvironmentsDir, "prod.env"), []byte("REGION=eu\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	path, err := loader.EnvironmentOverlay("prod")
+	if err != nil || path != filepath.Join(EnvironmentsDir, "prod.env") {
+		t.Errorf("Expected %s, got %s (%v)", filepath.Join(EnvironmentsDir, "prod.env"), path, err)
+	}
+}
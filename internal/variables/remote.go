@@ -0,0 +1,99 @@
+// ABOUTME: Reading variable files from local paths or remote URIs such as s3:// and sftp://
+// ABOUTME: Verifies the checksum pinned with a "#sha256=" or "#sha512=" suffix before parsing
+
+package variables
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+
+	"github.com/sarlalian/ritual/internal/filesystem"
+)
+
+// IsRemote reports whether path is a URI for the filesystem factory rather
+// than a local path
+func IsRemote(path string) bool {
+	return strings.Contains(path, "://")
+}
+
+// splitChecksum splits a path such as "s3://bucket/prod.yaml#sha256=<hex>"
+// into the path and its pinned algorithm and digest
+func splitChecksum(path string) (string, string, string, error) {
+	i := strings.LastIndex(path, "#")
+	if i < 0 {
+		return path, "", "", nil
+	}
+	algorithm, digest, ok := strings.Cut(path[i+1:], "=")
+	if !ok || (algorithm != "sha256" && algorithm != "sha512") {
+		// Not a pin; "#" is part of the name
+		return path, "", "", nil
+	}
+	if _, err := hex.DecodeString(digest); err != nil || digest == "" {
+		return "", "", "", fmt.Errorf("invalid %s checksum '%s' for '%s'", algorithm, digest, path[:i])
+	}
+	return path[:i], algorithm, strings.ToLower(digest), nil
+}
+
+// verifyChecksum checks content against the pinned digest, if any
+func verifyChecksum(path string, content []byte, algorithm, digest string) error {
+	var h hash.Hash
+	switch algorithm {
+	case "":
+		return nil
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	}
+	h.Write(content)
+	if actual := hex.EncodeToString(h.Sum(nil)); actual != digest {
+		return fmt.Errorf("checksum mismatch for variable file '%s': expected %s %s, got %s", path, algorithm, digest, actual)
+	}
+	return nil
+}
+
+// resolvePath resolves a local path against the base path; URIs are
+// returned unchanged
+func (fl *FileLoader) resolvePath(path string) string {
+	if IsRemote(path) || filepath.IsAbs(path) || fl.basePath == "" {
+		return path
+	}
+	return filepath.Join(fl.basePath, path)
+}
+
+// readFile reads a local file, or a remote one through the filesystem its
+// URI selects
+func (fl *FileLoader) readFile(path string) ([]byte, error) {
+	if !IsRemote(path) {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return nil, fmt.Errorf("variable file not found: %s", path)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read variable file '%s': %w", path, err)
+		}
+		return content, nil
+	}
+
+	info, err := filesystem.ParsePath(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid variable file URI '%s': %w", path, err)
+	}
+	fs, err := filesystem.GetFilesystem(path, fl.fsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open filesystem for variable file '%s': %w", path, err)
+	}
+	content, err := afero.ReadFile(fs, info.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read variable file '%s': %w", path, err)
+	}
+	return content, nil
+}
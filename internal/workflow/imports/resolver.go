@@ -201,6 +201,20 @@ func (r *Resolver) mergeWorkflows(main *types.Workflow, imports map[string]*type
 		}
 	}
 
+	// Merge secret declarations by name (main workflow takes precedence), so
+	// imported tasks can use the names their own workflow declared
+	merged.Secrets = make(map[string]types.SecretConfig, len(main.Secrets))
+	for name, secret := range main.Secrets {
+		merged.Secrets[name] = secret
+	}
+	for _, imported := range imports {
+		for name, secret := range imported.Secrets {
+			if _, exists := merged.Secrets[name]; !exists {
+				merged.Secrets[name] = secret
+			}
+		}
+	}
+
 	// Merge tasks (imported tasks get prefixed with import name)
 	originalTaskCount := len(merged.Tasks)
 	for importName, imported := range imports {
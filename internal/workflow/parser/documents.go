@@ -0,0 +1,251 @@
+// ABOUTME: Multi-document workflow files and YAML merge key resolution
+// ABOUTME: Selects a workflow by name from files holding several, as in deploy.yaml#rollback
+
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+
+	"github.com/sarlalian/ritual/pkg/types"
+)
+
+// ExtensionPrefix starts top-level keys the parser ignores, such as a block
+// of anchors for tasks to merge in with <<
+const ExtensionPrefix = "x-"
+
+// SplitWorkflowRef splits a reference such as pipelines.yaml#deploy into the
+// file and the name of the workflow to use from it. name is "" when the
+// reference is a plain file.
+func SplitWorkflowRef(ref string) (path, name string) {
+	i := strings.LastIndex(ref, "#")
+	if i <= 0 || i == len(ref)-1 || strings.ContainsAny(ref[i+1:], `/\`) {
+		return ref, ""
+	}
+	return ref[:i], ref[i+1:]
+}
+
+// ResolveWorkflowRef is SplitWorkflowRef for references to files on fs, or
+// on disk when fs is nil. A file whose name holds a #, such as a#b.yaml, is
+// taken as it is when it exists.
+func ResolveWorkflowRef(fs afero.Fs, ref string) (path, name string) {
+	if fs == nil {
+		fs = afero.NewOsFs()
+	}
+	if exists, _ := afero.Exists(fs, ref); exists {
+		return ref, ""
+	}
+	return SplitWorkflowRef(ref)
+}
+
+// WorkflowNames returns the names of the workflows in a YAML file, one per
+// document, in file order
+func WorkflowNames(data []byte) ([]string, error) {
+	var names []string
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var node yaml.Node
+		if err := decoder.Decode(&node); err != nil {
+			if errors.Is(err, io.EOF) {
+				return names, nil
+			}
+			return nil, err
+		}
+		if isEmptyDocument(&node) {
+			continue
+		}
+		name := ""
+		if mapping := node.Content[0]; mapping.Kind == yaml.MappingNode {
+			for _, pair := range mergedPairs(mapping) {
+				if pair.key.Value == "name" {
+					name = pair.value.Value
+				}
+			}
+		}
+		names = append(names, name)
+	}
+}
+
+// document is one workflow of a YAML file, decoded both as a workflow and
+// as nodes, which know where everything was written
+type document struct {
+	workflow types.Workflow
+	node     yaml.Node
+}
+
+// decodeDocuments decodes every non-empty document of a YAML file
+func decodeDocuments(data []byte, filename string) ([]*document, error) {
+	// Decode strictly to catch typos, and as nodes, in step
+	strict := yaml.NewDecoder(bytes.NewReader(data))
+	strict.KnownFields(true)
+	nodes := yaml.NewDecoder(bytes.NewReader(data))
+
+	var docs []*document
+	for {
+		doc := &document{}
+		if err := nodes.Decode(&doc.node); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, types.NewParseError(filename, yamlErrorLine(err), 0, "failed to parse YAML", err)
+		}
+		if err := strict.Decode(&doc.workflow); err != nil {
+			return nil, types.NewParseError(filename, yamlErrorLine(err), 0, "failed to parse YAML", err)
+		}
+		if isEmptyDocument(&doc.node) {
+			continue
+		}
+		docs = append(docs, doc)
+	}
+
+	if len(docs) == 0 {
+		return nil, types.NewParseError(filename, 0, 0, "failed to parse YAML", io.EOF)
+	}
+	return docs, nil
+}
+
+// selectDocument picks the workflow called name, or the only workflow when
+// name is "". Each workflow of a file holding several must have a name.
+func selectDocument(docs []*document, filename, name string) (*document, error) {
+	if len(docs) == 1 && name == "" {
+		return docs[0], nil
+	}
+
+	names := make([]string, len(docs))
+	for i, doc := range docs {
+		if doc.workflow.Name == "" {
+			// Without a name the workflow cannot be selected
+			pos := nodePosition(doc.node.Content[0], filename)
+			return nil, types.NewParseError(filename, pos.Line, pos.Column,
+				fmt.Sprintf("workflow %d of %d has no name; each workflow in a file holding several needs one", i+1, len(docs)), nil)
+		}
+		names[i] = doc.workflow.Name
+	}
+	for _, doc := range docs {
+		if doc.workflow.Name == name {
+			return doc, nil
+		}
+	}
+
+	if name != "" {
+		return nil, types.NewParseError(filename, 0, 0,
+			fmt.Sprintf("no workflow named '%s' (found: %s)", name, strings.Join(names, ", ")), nil)
+	}
+	example := filename
+	if example == "" {
+		example = "workflow.yaml"
+	}
+	return nil, types.NewParseError(filename, 0, 0,
+		fmt.Sprintf("found %d workflows (%s); select one by name, as in %s#%s", len(docs), strings.Join(names, ", "), example, names[0]), nil)
+}
+
+// checkExtensions rejects top-level keys that are neither workflow fields
+// nor extension keys, as strict decoding would without the extension map
+func checkExtensions(workflow *types.Workflow, filename string) error {
+	keys := make([]string, 0, len(workflow.Extensions))
+	for key := range workflow.Extensions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if strings.HasPrefix(key, ExtensionPrefix) {
+			continue
+		}
+		pos := workflow.Source.Lookup(key)
+		return types.NewParseError(filename, pos.Line, pos.Column,
+			fmt.Sprintf("unknown field '%s' (top-level fields of your own, such as anchors, must start with %s)", key, ExtensionPrefix), nil)
+	}
+	return nil
+}
+
+func isEmptyDocument(node *yaml.Node) bool {
+	return len(node.Content) == 0 || (node.Content[0].Kind == yaml.ScalarNode && node.Content[0].Tag == "!!null")
+}
+
+// pair is a mapping entry. merged is set for entries a << key brought in,
+// and overrides for entries written over one.
+type pair struct {
+	key, value *yaml.Node
+	merged     bool
+	overrides  bool
+}
+
+// mergedPairs returns a mapping's entries with its << merge keys resolved,
+// as the decoder resolves them: keys written in the mapping come first, in
+// order, then merged keys they do not override, earlier sources first.
+// Merged entries keep the positions of the anchored mapping they came from.
+func mergedPairs(mapping *yaml.Node) []pair {
+	var own, merged []pair
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key, value := mapping.Content[i], mapping.Content[i+1]
+		if key.Tag == "!!merge" {
+			merged = append(merged, mergeSources(value)...)
+			continue
+		}
+		own = append(own, pair{key: key, value: value})
+	}
+
+	mergedKeys := make(map[string]bool)
+	for _, p := range merged {
+		mergedKeys[p.key.Value] = true
+	}
+	seen := make(map[string]bool)
+	pairs := make([]pair, 0, len(own)+len(merged))
+	for _, p := range own {
+		p.overrides = mergedKeys[p.key.Value]
+		seen[p.key.Value] = true
+		pairs = append(pairs, p)
+	}
+	for _, p := range merged {
+		if !seen[p.key.Value] {
+			p.merged, p.overrides = true, false
+			seen[p.key.Value] = true
+			pairs = append(pairs, p)
+		}
+	}
+	return pairs
+}
+
+// mergeSources returns the entries a << value merges in: a mapping, an
+// alias of one or a list of them
+func mergeSources(value *yaml.Node) []pair {
+	value = resolveAlias(value)
+	switch value.Kind {
+	case yaml.MappingNode:
+		return mergedPairs(value)
+	case yaml.SequenceNode:
+		var pairs []pair
+		for _, item := range value.Content {
+			pairs = append(pairs, mergeSources(item)...)
+		}
+		return pairs
+	}
+	return nil
+}
+
+// removes reports whether the entry sets a merged key to null, which
+// removes the key
+func (p pair) removes() bool {
+	return p.overrides && isNull(p.value)
+}
+
+func isNull(node *yaml.Node) bool {
+	node = resolveAlias(node)
+	return node.Kind == yaml.ScalarNode && node.Tag == "!!null"
+}
+
+// resolveAlias returns the node an alias refers to, or node itself
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
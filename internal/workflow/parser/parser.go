@@ -7,6 +7,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/spf13/afero"
@@ -28,33 +31,51 @@ func New(fs afero.Fs) types.Parser {
 	return &Parser{fs: fs}
 }
 
-// Parse parses a workflow from YAML bytes
+// Parse parses a workflow from YAML bytes. Bytes holding several
+// workflows, as YAML documents separated by ---, need ParseFile with the
+// name of the one to use.
 func (p *Parser) Parse(data []byte) (*types.Workflow, error) {
-	var workflow types.Workflow
+	return p.parse(data, "", "")
+}
 
-	// Parse YAML with strict mode to catch typos
-	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
-	decoder.KnownFields(true)
+// parse parses the workflow called name, or the only workflow, recording
+// source positions against filename
+func (p *Parser) parse(data []byte, filename, name string) (*types.Workflow, error) {
+	docs, err := decodeDocuments(data, filename)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := selectDocument(docs, filename, name)
+	if err != nil {
+		return nil, err
+	}
+	workflow := &doc.workflow
 
-	if err := decoder.Decode(&workflow); err != nil {
-		return nil, types.NewParseError("", 0, 0, "failed to parse YAML", err)
+	attachSource(workflow, &doc.node, filename)
+	resolveMergedTasks(workflow, &doc.node)
+	if err := checkExtensions(workflow, filename); err != nil {
+		return nil, err
 	}
 
 	// Set defaults
-	if err := p.setDefaults(&workflow); err != nil {
+	if err := p.setDefaults(workflow); err != nil {
 		return nil, err
 	}
 
 	// Validate the workflow
-	if err := p.Validate(&workflow); err != nil {
+	if err := p.Validate(workflow); err != nil {
 		return nil, err
 	}
 
-	return &workflow, nil
+	return workflow, nil
 }
 
-// ParseFile parses a workflow from a file
+// ParseFile parses a workflow from a file. A reference such as
+// pipelines.yaml#deploy parses the workflow named deploy from a file holding
+// several.
 func (p *Parser) ParseFile(filename string) (*types.Workflow, error) {
+	filename, name := ResolveWorkflowRef(p.fs, filename)
+
 	// Check if file exists
 	exists, err := afero.Exists(p.fs, filename)
 	if err != nil {
@@ -71,7 +92,7 @@ func (p *Parser) ParseFile(filename string) (*types.Workflow, error) {
 	}
 
 	// Parse content
-	workflow, err := p.Parse(data)
+	workflow, err := p.parse(data, filename, name)
 	if err != nil {
 		// Add filename context to parse errors
 		if parseErr, ok := err.(*types.ParseError); ok {
@@ -87,16 +108,16 @@ func (p *Parser) ParseFile(filename string) (*types.Workflow, error) {
 // Validate validates a workflow definition
 func (p *Parser) Validate(workflow *types.Workflow) error {
 	if workflow.Name == "" {
-		return types.NewValidationError("name", workflow.Name, "workflow name is required")
+		return types.NewValidationError("name", workflow.Name, "workflow name is required").At(workflow.Source.Lookup("name"))
 	}
 
 	if len(workflow.Tasks) == 0 {
-		return types.NewValidationError("tasks", workflow.Tasks, "workflow must have at least one task")
+		return types.NewValidationError("tasks", workflow.Tasks, "workflow must have at least one task").At(workflow.Source.Lookup("tasks"))
 	}
 
 	// Validate execution mode
 	if workflow.Mode != "" && workflow.Mode != types.ParallelMode && workflow.Mode != types.SequentialMode {
-		return types.NewValidationError("mode", workflow.Mode, "mode must be 'parallel' or 'sequential'")
+		return types.NewValidationError("mode", workflow.Mode, "mode must be 'parallel' or 'sequential'").At(workflow.Source.Lookup("mode"))
 	}
 
 	// Create task ID map for dependency validation
@@ -117,13 +138,13 @@ func (p *Parser) Validate(workflow *types.Workflow) error {
 
 		// Check for duplicate IDs
 		if taskIDs[task.ID] {
-			return types.NewValidationError("tasks", task.ID, fmt.Sprintf("duplicate task ID: %s", task.ID))
+			return types.NewValidationError("tasks", task.ID, fmt.Sprintf("duplicate task ID: %s", task.ID)).At(task.Source.Lookup("id"))
 		}
 		taskIDs[task.ID] = true
 
 		// Check for duplicate names
 		if taskNames[task.Name] {
-			return types.NewValidationError("tasks", task.Name, fmt.Sprintf("duplicate task name: %s", task.Name))
+			return types.NewValidationError("tasks", task.Name, fmt.Sprintf("duplicate task name: %s", task.Name)).At(task.Source.Lookup("name"))
 		}
 		taskNames[task.Name] = true
 	}
@@ -177,20 +198,25 @@ func (p *Parser) setDefaults(workflow *types.Workflow) error {
 // validateTask validates a single task configuration
 func (p *Parser) validateTask(task *types.TaskConfig, index int) error {
 	if task.Name == "" {
-		return types.NewValidationError("name", task.Name, fmt.Sprintf("task[%d] name is required", index))
+		return types.NewValidationError("name", task.Name, fmt.Sprintf("task[%d] name is required", index)).At(task.Source.Lookup("name"))
 	}
 
 	// Determine task type from config keys
 	taskType := p.inferTaskType(task)
 	if taskType == "" {
-		return types.NewValidationError("type", task.Config, fmt.Sprintf("task[%d] '%s' must specify a task type", index, task.Name))
+		return types.NewValidationError("type", task.Config, fmt.Sprintf("task[%d] '%s' must specify a task type", index, task.Name)).At(task.Source.Lookup("type"))
 	}
 
 	task.Type = taskType
 
 	// Validate retry configuration
 	if task.RetryCount < 0 {
-		return types.NewValidationError("retry_count", task.RetryCount, fmt.Sprintf("task[%d] '%s' retry_count cannot be negative", index, task.Name))
+		return types.NewValidationError("retry_count", task.RetryCount, fmt.Sprintf("task[%d] '%s' retry_count cannot be negative", index, task.Name)).At(task.Source.Lookup("retry_count"))
+	}
+
+	// Validate trigger rule
+	if task.TriggerRule != "" && !task.TriggerRule.IsValid() {
+		return types.NewValidationError("trigger_rule", task.TriggerRule, fmt.Sprintf("task[%d] '%s' trigger_rule must be one of all_success, all_done, one_failed, none_failed", index, task.Name)).At(task.Source.Lookup("trigger_rule"))
 	}
 
 	return nil
@@ -198,9 +224,9 @@ func (p *Parser) validateTask(task *types.TaskConfig, index int) error {
 
 // validateTaskDependencies validates task dependency references
 func (p *Parser) validateTaskDependencies(task *types.TaskConfig, taskIDs, taskNames map[string]bool) error {
-	for _, dep := range task.DependsOn {
+	for i, dep := range task.DependsOn {
 		if !taskIDs[dep] && !taskNames[dep] {
-			return types.NewDependencyError(task.ID, task.DependsOn, fmt.Sprintf("dependency '%s' not found", dep))
+			return types.NewDependencyError(task.ID, task.DependsOn, fmt.Sprintf("dependency '%s' not found", dep)).At(task.Source.Lookup(fmt.Sprintf("depends_on[%d]", i)))
 		}
 	}
 	return nil
@@ -255,29 +281,170 @@ func (p *Parser) inferTaskType(task *types.TaskConfig) string {
 		return task.Type
 	}
 
-	// Infer from top-level task keys (Ansible-style)
+	// Infer from top-level task keys (Ansible-style), in a fixed order so a
+	// task with several type keys always gets the same type, preferring keys
+	// with a value to bare markers such as "file:"
+	keys := make([]string, 0, len(task.Config))
 	for key := range task.Config {
-		switch key {
-		case "command":
-			return "command"
-		case "file":
-			return "file"
-		case "compress":
-			return "compress"
-		case "checksum":
-			return "checksum"
-		case "email":
-			return "email"
-		case "slack":
-			return "slack"
-		case "ssh":
-			return "ssh"
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	marker := ""
+	for _, key := range keys {
+		taskType := typeForKey(key)
+		if taskType == "" {
+			continue
+		}
+		if task.Config[key] != nil {
+			return taskType
+		}
+		if marker == "" {
+			marker = taskType
+		}
+	}
+	return marker
+}
+
+// inferTaskTypeFromNode infers the type of a task from its YAML mapping,
+// where merge keys show which keys were written on the task. Keys written
+// on the task win over merged ones, then keys with a value over bare
+// markers, then earlier keys over later ones.
+func inferTaskTypeFromNode(pairs []pair) string {
+	best, bestRank := "", 4
+	for _, p := range pairs {
+		taskType := typeForKey(p.key.Value)
+		if taskType == "" || p.removes() {
+			continue
+		}
+		rank := 0
+		if p.merged {
+			rank += 2
+		}
+		if isNull(p.value) {
+			rank++
+		}
+		if rank < bestRank {
+			best, bestRank = taskType, rank
 		}
 	}
+	return best
+}
 
+// typeForKey returns the task type a top-level task key selects, or ""
+func typeForKey(key string) string {
+	switch key {
+	case "command", "file", "compress", "checksum", "email", "slack", "ssh":
+		return key
+	}
 	return ""
 }
 
+// yamlLinePattern finds the line a YAML error reports, as in "line 4: field
+// variables not found"
+var yamlLinePattern = regexp.MustCompile(`line (\d+)`)
+
+// yamlErrorLine returns the first line a YAML error mentions, or 0
+func yamlErrorLine(err error) int {
+	if match := yamlLinePattern.FindStringSubmatch(err.Error()); match != nil {
+		line, _ := strconv.Atoi(match[1])
+		return line
+	}
+	return 0
+}
+
+// attachSource records where the workflow's keys and list items were
+// written, and gives each task the positions of its own keys
+func attachSource(workflow *types.Workflow, root *yaml.Node, filename string) {
+	workflow.Source = make(types.SourceMap)
+	indexSource(root, "", filename, workflow.Source)
+
+	forEachTaskNode(workflow, root, func(task *types.TaskConfig, node *yaml.Node) {
+		source := types.SourceMap{"": nodePosition(node, filename)}
+		indexSource(node, "", filename, source)
+		task.Source = source
+	})
+}
+
+// resolveMergedTasks applies what merge keys mean for tasks beyond what the
+// decoder did: a key set to null over a merged one removes it, and the type
+// is inferred from the keys written on the task before merged ones
+func resolveMergedTasks(workflow *types.Workflow, root *yaml.Node) {
+	forEachTaskNode(workflow, root, func(task *types.TaskConfig, node *yaml.Node) {
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		pairs := mergedPairs(node)
+		for _, p := range pairs {
+			if p.removes() {
+				delete(task.Config, p.key.Value)
+			}
+		}
+		if task.Type == "" {
+			task.Type = inferTaskTypeFromNode(pairs)
+		}
+	})
+}
+
+// forEachTaskNode calls fn with each task of the workflow and the YAML node
+// it was decoded from
+func forEachTaskNode(workflow *types.Workflow, root *yaml.Node, fn func(task *types.TaskConfig, node *yaml.Node)) {
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return
+	}
+	for _, p := range mergedPairs(root.Content[0]) {
+		var tasks []types.TaskConfig
+		switch p.key.Value {
+		case "tasks":
+			tasks = workflow.Tasks
+		case "on_success":
+			tasks = workflow.OnSuccess
+		case "on_failure":
+			tasks = workflow.OnFailure
+		default:
+			continue
+		}
+		for j, item := range resolveAlias(p.value).Content {
+			if j >= len(tasks) {
+				break
+			}
+			fn(&tasks[j], resolveAlias(item))
+		}
+	}
+}
+
+// indexSource records the position of every mapping key and list item
+// under node, by path such as tasks[2].state. Keys merged in with << are
+// recorded at the position of the anchored mapping they came from.
+func indexSource(node *yaml.Node, path, filename string, source types.SourceMap) {
+	node = resolveAlias(node)
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			indexSource(child, path, filename, source)
+		}
+	case yaml.MappingNode:
+		for _, p := range mergedPairs(node) {
+			childPath := p.key.Value
+			if path != "" {
+				childPath = path + "." + p.key.Value
+			}
+			source[childPath] = nodePosition(p.key, filename)
+			indexSource(p.value, childPath, filename, source)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			childPath := fmt.Sprintf("%s[%d]", path, i)
+			source[childPath] = nodePosition(item, filename)
+			indexSource(item, childPath, filename, source)
+		}
+	}
+}
+
+func nodePosition(node *yaml.Node, filename string) types.SourcePosition {
+	return types.SourcePosition{File: filename, Line: node.Line, Column: node.Column}
+}
+
 // ParseString is a convenience function for parsing YAML strings
 func ParseString(yamlContent string) (*types.Workflow, error) {
 	parser := New(nil)
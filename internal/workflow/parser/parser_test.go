@@ -164,6 +164,59 @@ func TestParser_Validate_InvalidDependency(t *testing.T) {
 	}
 }
 
+func TestParser_Validate_InvalidTriggerRule(t *testing.T) {
+	workflow := &types.Workflow{
+		Name: "test",
+		Tasks: []types.TaskConfig{
+			{
+				Name:        "test-task",
+				Config:      map[string]interface{}{"command": map[string]interface{}{"cmd": "echo test"}},
+				TriggerRule: "any_success",
+			},
+		},
+	}
+
+	parser := New(nil)
+	err := parser.Validate(workflow)
+
+	if err == nil {
+		t.Fatal("Expected validation error for invalid trigger rule")
+	}
+
+	if validationErr, ok := err.(*types.ValidationError); !ok {
+		t.Errorf("Expected ValidationError, got %T", err)
+	} else if validationErr.Field != "trigger_rule" {
+		t.Errorf("Expected field 'trigger_rule', got '%s'", validationErr.Field)
+	}
+}
+
+func TestParser_Parse_TriggerRule(t *testing.T) {
+	yamlContent := `
+name: trigger rules
+tasks:
+  - name: build
+    command: make build
+  - name: report
+    command: ./report.sh
+    depends_on: [build]
+    trigger_rule: all_done
+`
+
+	parser := New(nil)
+	workflow, err := parser.Parse([]byte(yamlContent))
+	if err != nil {
+		t.Fatalf("Failed to parse workflow: %v", err)
+	}
+
+	report := workflow.Tasks[1]
+	if report.TriggerRule != types.TriggerAllDone {
+		t.Errorf("Expected trigger rule 'all_done', got '%s'", report.TriggerRule)
+	}
+	if _, leaked := report.Config["trigger_rule"]; leaked {
+		t.Error("Expected trigger_rule not to be treated as task configuration")
+	}
+}
+
 func TestParser_InferTaskType(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -276,6 +329,115 @@ tasks:
 	}
 }
 
+func TestParser_ParseFile_SourcePositions(t *testing.T) {
+	yamlContent := `name: positions
+vars:
+  region: eu-west-1
+tasks:
+  - name: first
+    command: echo one
+  - name: second
+    file:
+    path: /tmp/out
+on_failure:
+  - name: alert
+    command: echo failed
+`
+
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, "wf.yaml", []byte(yamlContent), 0644)
+
+	workflow, err := New(fs).ParseFile("wf.yaml")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expected := types.SourcePosition{File: "wf.yaml", Line: 3, Column: 3}
+	if pos := workflow.Source.Lookup("vars.region"); pos != expected {
+		t.Errorf("Expected vars.region at %v, got %v", expected, pos)
+	}
+
+	second := workflow.Tasks[1]
+	expected = types.SourcePosition{File: "wf.yaml", Line: 7, Column: 5}
+	if pos := second.Source.Lookup(""); pos != expected {
+		t.Errorf("Expected task at %v, got %v", expected, pos)
+	}
+	expected = types.SourcePosition{File: "wf.yaml", Line: 9, Column: 5}
+	if pos := second.Source.Lookup("path"); pos != expected {
+		t.Errorf("Expected path at %v, got %v", expected, pos)
+	}
+	// Keys that were not written fall back to the task
+	if pos := second.Source.Lookup("state"); pos.Line != 7 {
+		t.Errorf("Expected state to fall back to line 7, got %v", pos)
+	}
+
+	if pos := workflow.OnFailure[0].Source.Lookup("command"); pos.Line != 12 {
+		t.Errorf("Expected on_failure command at line 12, got %v", pos)
+	}
+}
+
+func TestParser_ParseFile_ErrorPositions(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		line    int
+		column  int
+	}{
+		{
+			name:    "unknown field",
+			content: "name: x\nvariables:\n  a: 1\ntasks:\n  - name: a\n    command: echo\n",
+			line:    2,
+			column:  1,
+		},
+		{
+			name:    "unknown task field",
+			content: "name: x\ntasks:\n  - name: a\n    command: echo\n    retries: 2\n    depends_on: {a: 1}\n",
+			line:    6,
+		},
+		{
+			name:    "invalid trigger rule",
+			content: "name: x\ntasks:\n  - name: a\n    command: echo\n    trigger_rule: sometimes\n",
+			line:    5,
+			column:  5,
+		},
+		{
+			name:    "missing dependency",
+			content: "name: x\ntasks:\n  - name: a\n    command: echo\n  - name: b\n    command: echo\n    depends_on: [a, c]\n",
+			line:    7,
+			column:  21,
+		},
+		{
+			name:    "missing task type",
+			content: "name: x\ntasks:\n  - name: a\n    command: echo\n  - name: b\n    when: \"true\"\n",
+			line:    5,
+			column:  5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			_ = afero.WriteFile(fs, "wf.yaml", []byte(tt.content), 0644)
+
+			_, err := New(fs).ParseFile("wf.yaml")
+			if err == nil {
+				t.Fatal("Expected error")
+			}
+			pos, ok := types.ErrorPosition(err)
+			if !ok {
+				t.Fatalf("Expected error with a position, got %v", err)
+			}
+			expected := types.SourcePosition{File: "wf.yaml", Line: tt.line, Column: tt.column}
+			if pos != expected {
+				t.Errorf("Expected position %v, got %v", expected, pos)
+			}
+			if !strings.Contains(err.Error(), expected.String()) {
+				t.Errorf("Expected error to mention %s, got %v", expected, err)
+			}
+		})
+	}
+}
+
 func TestValidateFileStructure(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -304,3 +466,164 @@ func TestValidateFileStructure(t *testing.T) {
 		})
 	}
 }
+
+const multiDocument = `name: build
+tasks:
+  - name: compile
+    command: make
+---
+name: deploy
+tasks:
+  - name: ship
+    command: ./deploy.sh
+---
+`
+
+func TestParser_ParseFile_MultipleDocuments(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, "pipelines.yaml", []byte(multiDocument), 0644)
+	parser := New(fs)
+
+	workflow, err := parser.ParseFile("pipelines.yaml#deploy")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if workflow.Name != "deploy" || workflow.Tasks[0].Name != "ship" {
+		t.Errorf("Expected the deploy workflow, got %s with task %s", workflow.Name, workflow.Tasks[0].Name)
+	}
+	if pos := workflow.Tasks[0].Source.Lookup("command"); pos.File != "pipelines.yaml" || pos.Line != 9 {
+		t.Errorf("Expected command at pipelines.yaml:9, got %v", pos)
+	}
+
+	_, err = parser.ParseFile("pipelines.yaml")
+	if err == nil || !strings.Contains(err.Error(), "found 2 workflows (build, deploy)") {
+		t.Errorf("Expected error listing the workflows, got %v", err)
+	}
+
+	_, err = parser.ParseFile("pipelines.yaml#rollback")
+	if err == nil || !strings.Contains(err.Error(), "no workflow named 'rollback'") {
+		t.Errorf("Expected error for unknown workflow, got %v", err)
+	}
+
+	_ = afero.WriteFile(fs, "unnamed.yaml", []byte(multiDocument+"tasks: []\n"), 0644)
+	_, err = parser.ParseFile("unnamed.yaml#deploy")
+	if err == nil || !strings.Contains(err.Error(), "unnamed.yaml:11:1: workflow 3 of 3 has no name") {
+		t.Errorf("Expected error for the unnamed workflow, got %v", err)
+	}
+
+	names, err := WorkflowNames([]byte(multiDocument))
+	if err != nil || len(names) != 2 || names[0] != "build" || names[1] != "deploy" {
+		t.Errorf("Expected names [build deploy], got %v (%v)", names, err)
+	}
+}
+
+func TestSplitWorkflowRef(t *testing.T) {
+	tests := []struct {
+		ref, path, name string
+	}{
+		{"pipelines.yaml#deploy", "pipelines.yaml", "deploy"},
+		{"dir/pipelines.yaml#Deploy App", "dir/pipelines.yaml", "Deploy App"},
+		{"pipelines.yaml", "pipelines.yaml", ""},
+		{"pipelines.yaml#", "pipelines.yaml#", ""},
+		{"odd#dir/pipelines.yaml", "odd#dir/pipelines.yaml", ""},
+	}
+	for _, tt := range tests {
+		path, name := SplitWorkflowRef(tt.ref)
+		if path != tt.path || name != tt.name {
+			t.Errorf("SplitWorkflowRef(%q): expected (%q, %q), got (%q, %q)", tt.ref, tt.path, tt.name, path, name)
+		}
+	}
+}
+
+func TestParser_ParseFile_HashInName(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, "a#b.yaml", []byte("name: hashed\ntasks:\n  - name: hi\n    command: echo hi\n"), 0644)
+	_ = afero.WriteFile(fs, "c#d.yaml", []byte(multiDocument), 0644)
+	parser := New(fs)
+
+	// An existing file is taken as it is before # names a workflow
+	workflow, err := parser.ParseFile("a#b.yaml")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if workflow.Name != "hashed" {
+		t.Errorf("Expected the hashed workflow, got %s", workflow.Name)
+	}
+
+	workflow, err = parser.ParseFile("c#d.yaml#deploy")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if workflow.Name != "deploy" {
+		t.Errorf("Expected the deploy workflow, got %s", workflow.Name)
+	}
+
+	if path, name := ResolveWorkflowRef(fs, "a#b.yaml"); path != "a#b.yaml" || name != "" {
+		t.Errorf("Expected (%q, %q), got (%q, %q)", "a#b.yaml", "", path, name)
+	}
+}
+
+func TestParser_Parse_MergeKeys(t *testing.T) {
+	yamlContent := `name: merges
+x-defaults: &defaults
+  retry_count: 2
+  command: echo default
+  env: &env
+    REGION: eu-west-1
+    LEVEL: info
+tasks:
+  - name: plain
+    <<: *defaults
+  - name: override
+    <<: *defaults
+    command: echo override
+    env:
+      <<: *env
+      LEVEL: debug
+  - name: as-file
+    <<: *defaults
+    command: null
+    file:
+      path: /tmp/out
+  - name: marker
+    <<: [*defaults]
+    file:
+    path: /tmp/out
+`
+
+	workflow, err := New(nil).Parse([]byte(yamlContent))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	plain, override, asFile, marker := workflow.Tasks[0], workflow.Tasks[1], workflow.Tasks[2], workflow.Tasks[3]
+	if plain.Type != "command" || plain.RetryCount != 2 || plain.Config["command"] != "echo default" {
+		t.Errorf("Expected merged defaults, got type %s retry %d config %v", plain.Type, plain.RetryCount, plain.Config)
+	}
+	if override.Config["command"] != "echo override" {
+		t.Errorf("Expected command written on the task to win, got %v", override.Config["command"])
+	}
+	env, _ := override.Config["env"].(map[string]interface{})
+	if env["LEVEL"] != "debug" || env["REGION"] != "eu-west-1" {
+		t.Errorf("Expected nested merge, got %v", env)
+	}
+
+	// Keys written on the task decide its type over merged ones
+	if asFile.Type != "file" {
+		t.Errorf("Expected file task, got %s", asFile.Type)
+	}
+	if _, ok := asFile.Config["command"]; ok {
+		t.Error("Expected command set to null to be removed")
+	}
+	if marker.Type != "file" {
+		t.Errorf("Expected bare file marker to win over merged command, got %s", marker.Type)
+	}
+
+	// Merged keys point at the anchor they came from
+	if pos := plain.Source.Lookup("command"); pos.Line != 4 {
+		t.Errorf("Expected merged command at line 4, got %v", pos)
+	}
+	if len(workflow.Extensions) != 1 {
+		t.Errorf("Expected the x-defaults extension, got %v", workflow.Extensions)
+	}
+}
@@ -40,8 +40,10 @@ func New() *DependencyResolver {
 	}
 }
 
-// BuildGraph builds the dependency graph from workflow tasks
+// BuildGraph builds the dependency graph from workflow tasks, replacing any
+// graph built before
 func (r *DependencyResolver) BuildGraph(tasks []types.TaskConfig) error {
+	r.Clear()
 	r.tasks = make([]types.TaskConfig, len(tasks))
 	copy(r.tasks, tasks) // Store original tasks
 
@@ -452,3 +452,27 @@ func TestDependencyResolver_Clear(t *testing.T) {
 		t.Errorf("Expected layers to be empty after clear, got %d", len(resolver.layers))
 	}
 }
+
+func TestDependencyResolver_BuildGraph_Rebuild(t *testing.T) {
+	resolver := New()
+	if err := resolver.BuildGraph([]types.TaskConfig{{ID: "old", Name: "Old"}}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := resolver.GetExecutionLayers(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if err := resolver.BuildGraph([]types.TaskConfig{{ID: "new", Name: "New"}}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := resolver.ValidateGraph(); err != nil {
+		t.Errorf("Expected the rebuilt graph to be valid, got: %v", err)
+	}
+	order, err := resolver.GetTaskOrder()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(order) != 1 || order[0].Task.ID != "new" {
+		t.Errorf("Expected only the new task, got %d tasks", len(order))
+	}
+}
@@ -0,0 +1,276 @@
+// ABOUTME: Shared type coercion for evaluated task configuration values
+// ABOUTME: Converts template results, YAML scalars and lists into the Go types task parsers need
+
+package coerce
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// String converts scalars to their string form. Strings pass through, numbers
+// and booleans are formatted, and lists and maps are rejected.
+func String(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case fmt.Stringer:
+		return v.String(), nil
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	}
+
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+		return "", fmt.Errorf("expected a string, got %s", describe(value))
+	}
+	return fmt.Sprint(value), nil
+}
+
+// Int converts integers, integral floats and numeric strings to int
+func Int(value interface{}) (int, error) {
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case int8:
+		return int(v), nil
+	case int16:
+		return int(v), nil
+	case int32:
+		return int(v), nil
+	case int64:
+		return int(v), nil
+	case uint:
+		return int(v), nil
+	case uint8:
+		return int(v), nil
+	case uint16:
+		return int(v), nil
+	case uint32:
+		return int(v), nil
+	case uint64:
+		return int(v), nil
+	case float32:
+		return integral(float64(v), value)
+	case float64:
+		return integral(v, value)
+	case json.Number:
+		return Int(string(v))
+	case string:
+		s := strings.TrimSpace(v)
+		if i, err := strconv.Atoi(s); err == nil {
+			return i, nil
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return integral(f, value)
+		}
+	}
+	return 0, fmt.Errorf("expected an integer, got %s", describe(value))
+}
+
+func integral(f float64, original interface{}) (int, error) {
+	if f != math.Trunc(f) || math.IsInf(f, 0) || math.IsNaN(f) {
+		return 0, fmt.Errorf("expected an integer, got %s", describe(original))
+	}
+	return int(f), nil
+}
+
+// Float converts numbers and numeric strings to float64
+func Float(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case string:
+		if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			return f, nil
+		}
+	default:
+		if i, err := Int(value); err == nil {
+			return float64(i), nil
+		}
+	}
+	return 0, fmt.Errorf("expected a number, got %s", describe(value))
+}
+
+// Bool converts booleans and strings such as "true", "false", "yes", "no",
+// "1" and "0" to bool
+func Bool(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "true", "yes", "on", "1":
+			return true, nil
+		case "false", "no", "off", "0", "":
+			return false, nil
+		}
+	case int:
+		if v == 0 || v == 1 {
+			return v == 1, nil
+		}
+	}
+	return false, fmt.Errorf("expected a boolean, got %s", describe(value))
+}
+
+// Duration converts durations and duration strings such as "30s" to
+// time.Duration. Bare numbers are taken as seconds.
+func Duration(value interface{}) (time.Duration, error) {
+	switch v := value.(type) {
+	case time.Duration:
+		return v, nil
+	case string:
+		s := strings.TrimSpace(v)
+		if d, err := time.ParseDuration(s); err == nil {
+			return d, nil
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return time.Duration(f * float64(time.Second)), nil
+		}
+	default:
+		if f, err := Float(value); err == nil {
+			return time.Duration(f * float64(time.Second)), nil
+		}
+	}
+	return 0, fmt.Errorf("expected a duration, got %s", describe(value))
+}
+
+// StringSlice converts lists to []string. A single scalar becomes a one-item
+// list, and a string holding a JSON array is decoded, so a list rendered with
+// toJson can be passed through a string-only channel such as an environment
+// variable.
+func StringSlice(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []string:
+		return v, nil
+	case string:
+		if trimmed := strings.TrimSpace(v); strings.HasPrefix(trimmed, "[") {
+			var list []interface{}
+			if err := json.Unmarshal([]byte(trimmed), &list); err == nil {
+				return StringSlice(list)
+			}
+		}
+		return []string{v}, nil
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		result := make([]string, rv.Len())
+		for i := range result {
+			s, err := String(rv.Index(i).Interface())
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %w", i, err)
+			}
+			result[i] = s
+		}
+		return result, nil
+	}
+
+	s, err := String(value)
+	if err != nil {
+		return nil, fmt.Errorf("expected a list, got %s", describe(value))
+	}
+	return []string{s}, nil
+}
+
+// Map converts maps with string keys to map[string]interface{}
+func Map(value interface{}) (map[string]interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		return v, nil
+	case string:
+		if trimmed := strings.TrimSpace(v); strings.HasPrefix(trimmed, "{") {
+			var m map[string]interface{}
+			if err := json.Unmarshal([]byte(trimmed), &m); err == nil {
+				return m, nil
+			}
+		}
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Map {
+		result := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			result[fmt.Sprint(iter.Key().Interface())] = iter.Value().Interface()
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("expected a map, got %s", describe(value))
+}
+
+// StringMap converts maps to map[string]string, formatting scalar values
+func StringMap(value interface{}) (map[string]string, error) {
+	if m, ok := value.(map[string]string); ok {
+		return m, nil
+	}
+
+	m, err := Map(value)
+	if err != nil || m == nil {
+		return nil, err
+	}
+
+	result := make(map[string]string, len(m))
+	for key, val := range m {
+		s, err := String(val)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", key, err)
+		}
+		result[key] = s
+	}
+	return result, nil
+}
+
+// describe names a value's type for error messages
+func describe(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "nothing"
+	case string:
+		return fmt.Sprintf("string %q", v)
+	}
+
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Slice, reflect.Array:
+		return "a list"
+	case reflect.Map:
+		return "a map"
+	}
+	return fmt.Sprintf("%T %v", value, value)
+}
+
+// Field reads key from an evaluated config map and converts it with convert,
+// for example coerce.Field(config, "port", coerce.Int). ok is false when the
+// key is absent or nil; conversion errors name the key.
+func Field[T any](config map[string]interface{}, key string, convert func(interface{}) (T, error)) (T, bool, error) {
+	var zero T
+	value, exists := config[key]
+	if !exists || value == nil {
+		return zero, false, nil
+	}
+
+	converted, err := convert(value)
+	if err != nil {
+		return zero, true, fmt.Errorf("%s: %w", key, err)
+	}
+	return converted, true, nil
+}
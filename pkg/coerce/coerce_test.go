@@ -0,0 +1,173 @@
+// ABOUTME: Tests for the shared task configuration coercion helpers
+// ABOUTME: Covers scalar conversion, list and map handling, and keyed field errors
+
+package coerce
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected string
+	}{
+		{"text", "text"},
+		{42, "42"},
+		{int64(7), "7"},
+		{2.5, "2.5"},
+		{true, "true"},
+		{nil, ""},
+		{5 * time.Second, "5s"},
+	}
+	for _, test := range tests {
+		got, err := String(test.value)
+		if err != nil || got != test.expected {
+			t.Errorf("String(%#v) = %q, %v; expected %q", test.value, got, err, test.expected)
+		}
+	}
+
+	for _, value := range []interface{}{[]interface{}{"a"}, map[string]interface{}{"a": 1}} {
+		if _, err := String(value); err == nil {
+			t.Errorf("Expected String(%#v) to fail", value)
+		}
+	}
+}
+
+func TestInt(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected int
+	}{
+		{22, 22},
+		{int64(22), 22},
+		{uint16(22), 22},
+		{22.0, 22},
+		{"22", 22},
+		{" 2222 ", 2222},
+		{"1e3", 1000},
+	}
+	for _, test := range tests {
+		got, err := Int(test.value)
+		if err != nil || got != test.expected {
+			t.Errorf("Int(%#v) = %d, %v; expected %d", test.value, got, err, test.expected)
+		}
+	}
+
+	for _, value := range []interface{}{"ssh", 2.5, true, nil, []interface{}{1}} {
+		if _, err := Int(value); err == nil {
+			t.Errorf("Expected Int(%#v) to fail", value)
+		}
+	}
+}
+
+func TestBool(t *testing.T) {
+	for _, value := range []interface{}{true, "true", "YES", "on", "1", 1} {
+		if got, err := Bool(value); err != nil || !got {
+			t.Errorf("Bool(%#v) = %v, %v; expected true", value, got, err)
+		}
+	}
+	for _, value := range []interface{}{false, "false", "no", "0", 0, ""} {
+		if got, err := Bool(value); err != nil || got {
+			t.Errorf("Bool(%#v) = %v, %v; expected false", value, got, err)
+		}
+	}
+	if _, err := Bool("maybe"); err == nil {
+		t.Error("Expected Bool(\"maybe\") to fail")
+	}
+}
+
+func TestDuration(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected time.Duration
+	}{
+		{"1m30s", 90 * time.Second},
+		{30, 30 * time.Second},
+		{"1.5", 1500 * time.Millisecond},
+		{time.Hour, time.Hour},
+	}
+	for _, test := range tests {
+		got, err := Duration(test.value)
+		if err != nil || got != test.expected {
+			t.Errorf("Duration(%#v) = %s, %v; expected %s", test.value, got, err, test.expected)
+		}
+	}
+	if _, err := Duration("soon"); err == nil {
+		t.Error("Expected Duration(\"soon\") to fail")
+	}
+}
+
+func TestStringSlice(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected []string
+	}{
+		{"a@example.com", []string{"a@example.com"}},
+		{[]interface{}{"a", 1, true}, []string{"a", "1", "true"}},
+		{[]string{"x", "y"}, []string{"x", "y"}},
+		{`["x", "y"]`, []string{"x", "y"}},
+		{nil, nil},
+	}
+	for _, test := range tests {
+		got, err := StringSlice(test.value)
+		if err != nil || !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("StringSlice(%#v) = %#v, %v; expected %#v", test.value, got, err, test.expected)
+		}
+	}
+
+	if _, err := StringSlice(map[string]interface{}{"a": 1}); err == nil {
+		t.Error("Expected StringSlice of a map to fail")
+	}
+	if _, err := StringSlice([]interface{}{"a", []interface{}{"nested"}}); err == nil || !strings.Contains(err.Error(), "item 1") {
+		t.Errorf("Expected nested list error naming the item, got %v", err)
+	}
+}
+
+func TestStringMap(t *testing.T) {
+	got, err := StringMap(map[string]interface{}{"PORT": 8080, "DEBUG": true, "NAME": "web"})
+	if err != nil {
+		t.Fatalf("StringMap failed: %v", err)
+	}
+	expected := map[string]string{"PORT": "8080", "DEBUG": "true", "NAME": "web"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	got, err = StringMap(map[interface{}]interface{}{"key": 1})
+	if err != nil || got["key"] != "1" {
+		t.Errorf("Expected interface-keyed map to convert, got %v, %v", got, err)
+	}
+
+	if _, err := StringMap("not a map"); err == nil {
+		t.Error("Expected StringMap of a string to fail")
+	}
+}
+
+func TestField(t *testing.T) {
+	config := map[string]interface{}{
+		"port":    "2222",
+		"bad":     "ssh",
+		"missing": nil,
+	}
+
+	port, ok, err := Field(config, "port", Int)
+	if err != nil || !ok || port != 2222 {
+		t.Errorf("Field(port) = %d, %v, %v", port, ok, err)
+	}
+
+	if _, ok, err := Field(config, "missing", Int); ok || err != nil {
+		t.Errorf("Expected nil value to be absent, got ok=%v err=%v", ok, err)
+	}
+	if _, ok, err := Field(config, "absent", Int); ok || err != nil {
+		t.Errorf("Expected absent key, got ok=%v err=%v", ok, err)
+	}
+
+	_, _, err = Field(config, "bad", Int)
+	if err == nil || !strings.HasPrefix(err.Error(), "bad: ") {
+		t.Errorf("Expected error naming the key, got %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+// ABOUTME: Helpers for carrying execution-scoped values through context.Context
+// ABOUTME: Carries the execution ID, trace parent and caller-supplied workflow inputs
+
+package types
+
+import "context"
+
+// executionIDKey is the context key for the current execution ID
+type executionIDKey struct{}
+
+// WithExecutionID returns a context carrying the given workflow execution ID
+func WithExecutionID(ctx context.Context, executionID string) context.Context {
+	return context.WithValue(ctx, executionIDKey{}, executionID)
+}
+
+// ExecutionIDFromContext returns the workflow execution ID carried by ctx, if any
+func ExecutionIDFromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(executionIDKey{}).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// traceParentKey is the context key for an incoming W3C traceparent header
+type traceParentKey struct{}
+
+// WithTraceParent returns a context carrying a W3C traceparent value that the
+// execution's trace should continue
+func WithTraceParent(ctx context.Context, traceParent string) context.Context {
+	return context.WithValue(ctx, traceParentKey{}, traceParent)
+}
+
+// TraceParentFromContext returns the traceparent carried by ctx, if any
+func TraceParentFromContext(ctx context.Context) string {
+	if tp, ok := ctx.Value(traceParentKey{}).(string); ok {
+		return tp
+	}
+	return ""
+}
+
+// inputsKey is the context key for values supplied for a workflow's inputs
+type inputsKey struct{}
+
+// WithInputs returns a context carrying values for the workflow's declared
+// inputs, such as those given with --var or in a webhook payload
+func WithInputs(ctx context.Context, inputs map[string]interface{}) context.Context {
+	return context.WithValue(ctx, inputsKey{}, inputs)
+}
+
+// InputsFromContext returns the input values carried by ctx, if any
+func InputsFromContext(ctx context.Context) map[string]interface{} {
+	inputs, _ := ctx.Value(inputsKey{}).(map[string]interface{})
+	return inputs
+}
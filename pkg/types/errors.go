@@ -43,6 +43,7 @@ type TaskError struct {
 	TaskType string
 	Message  string
 	Cause    error
+	Position SourcePosition
 }
 
 func (e *TaskError) Error() string {
@@ -53,17 +54,35 @@ func (e *TaskError) Error() string {
 	if e.TaskType != "" {
 		task = fmt.Sprintf("%s (%s)", task, e.TaskType)
 	}
+	task = fmt.Sprintf("task '%s'", task)
+	if e.Position.IsValid() {
+		task = fmt.Sprintf("%s at %s", task, e.Position)
+	}
 
-	if e.Cause != nil {
-		return fmt.Sprintf("task '%s': %s: %v", task, e.Message, e.Cause)
+	switch {
+	case e.Cause != nil && e.Message == "":
+		return fmt.Sprintf("%s: %v", task, e.Cause)
+	case e.Cause != nil:
+		return fmt.Sprintf("%s: %s: %v", task, e.Message, e.Cause)
 	}
-	return fmt.Sprintf("task '%s': %s", task, e.Message)
+	return fmt.Sprintf("%s: %s", task, e.Message)
 }
 
 func (e *TaskError) Unwrap() error {
 	return e.Cause
 }
 
+// SourcePosition returns where the task, or the config key at fault, was written
+func (e *TaskError) SourcePosition() SourcePosition {
+	return e.Position
+}
+
+// At sets where the error was found and returns the error
+func (e *TaskError) At(pos SourcePosition) *TaskError {
+	e.Position = pos
+	return e
+}
+
 // NewTaskError creates a new task error
 func NewTaskError(taskID, taskName, taskType, message string, cause error) *TaskError {
 	return &TaskError{
@@ -77,16 +96,32 @@ func NewTaskError(taskID, taskName, taskType, message string, cause error) *Task
 
 // ValidationError represents an error in workflow or task validation
 type ValidationError struct {
-	Field   string
-	Value   interface{}
-	Message string
+	Field    string
+	Value    interface{}
+	Message  string
+	Position SourcePosition
 }
 
 func (e *ValidationError) Error() string {
+	location := ""
+	if e.Position.IsValid() {
+		location = fmt.Sprintf(" at %s", e.Position)
+	}
 	if e.Field != "" {
-		return fmt.Sprintf("validation error in field '%s': %s", e.Field, e.Message)
+		return fmt.Sprintf("validation error in field '%s'%s: %s", e.Field, location, e.Message)
 	}
-	return fmt.Sprintf("validation error: %s", e.Message)
+	return fmt.Sprintf("validation error%s: %s", location, e.Message)
+}
+
+// SourcePosition returns where the invalid field was written
+func (e *ValidationError) SourcePosition() SourcePosition {
+	return e.Position
+}
+
+// At sets where the error was found and returns the error
+func (e *ValidationError) At(pos SourcePosition) *ValidationError {
+	e.Position = pos
+	return e
 }
 
 // NewValidationError creates a new validation error
@@ -130,6 +165,11 @@ func (e *ParseError) Unwrap() error {
 	return e.Cause
 }
 
+// SourcePosition returns where parsing failed
+func (e *ParseError) SourcePosition() SourcePosition {
+	return SourcePosition{File: e.File, Line: e.Line, Column: e.Column}
+}
+
 // NewParseError creates a new parse error
 func NewParseError(file string, line, column int, message string, cause error) *ParseError {
 	return &ParseError{
@@ -146,14 +186,30 @@ type DependencyError struct {
 	TaskID       string
 	Dependencies []string
 	Message      string
+	Position     SourcePosition
 }
 
 func (e *DependencyError) Error() string {
+	location := ""
+	if e.Position.IsValid() {
+		location = fmt.Sprintf(" at %s", e.Position)
+	}
 	if len(e.Dependencies) > 0 {
 		deps := strings.Join(e.Dependencies, ", ")
-		return fmt.Sprintf("dependency error for task '%s' (depends on: %s): %s", e.TaskID, deps, e.Message)
+		return fmt.Sprintf("dependency error for task '%s' (depends on: %s)%s: %s", e.TaskID, deps, location, e.Message)
 	}
-	return fmt.Sprintf("dependency error for task '%s': %s", e.TaskID, e.Message)
+	return fmt.Sprintf("dependency error for task '%s'%s: %s", e.TaskID, location, e.Message)
+}
+
+// SourcePosition returns where the dependency at fault was written
+func (e *DependencyError) SourcePosition() SourcePosition {
+	return e.Position
+}
+
+// At sets where the error was found and returns the error
+func (e *DependencyError) At(pos SourcePosition) *DependencyError {
+	e.Position = pos
+	return e
 }
 
 // NewDependencyError creates a new dependency error
@@ -0,0 +1,85 @@
+// ABOUTME: Source positions of workflow definitions for error reporting
+// ABOUTME: Maps paths such as tasks[2].state to the file, line and column they were written at
+
+package types
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SourcePosition is where something was written in a workflow file. Line
+// and Column start at 1; a zero Line means the position is unknown.
+type SourcePosition struct {
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+}
+
+// IsValid reports whether the position is known
+func (p SourcePosition) IsValid() bool {
+	return p.Line > 0
+}
+
+// String formats the position as file:line:col, leaving out what is unknown
+func (p SourcePosition) String() string {
+	location := p.File
+	if p.Line > 0 {
+		if location != "" {
+			location += ":"
+		}
+		location += fmt.Sprintf("%d", p.Line)
+		if p.Column > 0 {
+			location += fmt.Sprintf(":%d", p.Column)
+		}
+	}
+	return location
+}
+
+// SourceMap holds the position of every mapping key and list item of a
+// workflow, or of a task, by path such as tasks[2].state or args[0]. The
+// empty path is the position of the task itself.
+type SourceMap map[string]SourcePosition
+
+// Lookup returns the position of path, or of its nearest ancestor when path
+// itself was not written, such as a default or an unknown key
+func (m SourceMap) Lookup(path string) SourcePosition {
+	for {
+		if pos, ok := m[path]; ok {
+			return pos
+		}
+		if path == "" {
+			return SourcePosition{}
+		}
+		path = ParentPath(path)
+	}
+}
+
+// ParentPath strips the last key or index from a path, so tasks[2].state
+// becomes tasks[2] and tasks[2] becomes tasks
+func ParentPath(path string) string {
+	i := strings.LastIndexAny(path, ".[")
+	if i < 0 {
+		return ""
+	}
+	return path[:i]
+}
+
+// Positioned is implemented by errors that know where in a workflow file
+// they were found
+type Positioned interface {
+	SourcePosition() SourcePosition
+}
+
+// ErrorPosition returns the first known position in err's chain
+func ErrorPosition(err error) (SourcePosition, bool) {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if positioned, ok := err.(Positioned); ok {
+			if pos := positioned.SourcePosition(); pos.IsValid() {
+				return pos, true
+			}
+		}
+	}
+	return SourcePosition{}, false
+}
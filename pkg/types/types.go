@@ -53,6 +53,29 @@ const (
 	WorkflowFailed WorkflowStatus = "failed"
 )
 
+// TriggerRule determines which dependency outcomes allow a task to run
+type TriggerRule string
+
+const (
+	// TriggerAllSuccess runs the task only when every dependency succeeded
+	TriggerAllSuccess TriggerRule = "all_success"
+	// TriggerAllDone runs the task once every dependency has finished, whatever the outcome
+	TriggerAllDone TriggerRule = "all_done"
+	// TriggerOneFailed runs the task only when at least one dependency failed
+	TriggerOneFailed TriggerRule = "one_failed"
+	// TriggerNoneFailed runs the task when no dependency failed; skipped dependencies are allowed
+	TriggerNoneFailed TriggerRule = "none_failed"
+)
+
+// IsValid reports whether the rule is one of the supported trigger rules
+func (r TriggerRule) IsValid() bool {
+	switch r {
+	case TriggerAllSuccess, TriggerAllDone, TriggerOneFailed, TriggerNoneFailed:
+		return true
+	}
+	return false
+}
+
 // Concurrency constraints for workflow execution
 const (
 	// MinConcurrency is the minimum allowed concurrent task execution
@@ -65,32 +88,79 @@ const (
 
 // Workflow represents a complete workflow definition
 type Workflow struct {
-	Name          string                 `yaml:"name" json:"name"`
-	Version       string                 `yaml:"version,omitempty" json:"version,omitempty"`
-	Description   string                 `yaml:"description,omitempty" json:"description,omitempty"`
-	Mode          ExecutionMode          `yaml:"mode,omitempty" json:"mode,omitempty"`
-	Environment   map[string]string      `yaml:"environment,omitempty" json:"environment,omitempty"`
-	Imports       []string               `yaml:"imports,omitempty" json:"imports,omitempty"`
-	VariableFiles []string               `yaml:"variable_files,omitempty" json:"variable_files,omitempty"`
-	Variables     map[string]interface{} `yaml:"vars,omitempty" json:"vars,omitempty"`
-	Tasks         []TaskConfig           `yaml:"tasks" json:"tasks"`
-	OnSuccess     []TaskConfig           `yaml:"on_success,omitempty" json:"on_success,omitempty"`
-	OnFailure     []TaskConfig           `yaml:"on_failure,omitempty" json:"on_failure,omitempty"`
+	Name          string                  `yaml:"name" json:"name"`
+	Version       string                  `yaml:"version,omitempty" json:"version,omitempty"`
+	Description   string                  `yaml:"description,omitempty" json:"description,omitempty"`
+	Mode          ExecutionMode           `yaml:"mode,omitempty" json:"mode,omitempty"`
+	Environment   map[string]string       `yaml:"environment,omitempty" json:"environment,omitempty"`
+	Imports       []string                `yaml:"imports,omitempty" json:"imports,omitempty"`
+	Inputs        map[string]InputConfig  `yaml:"inputs,omitempty" json:"inputs,omitempty"`
+	VariableFiles []string                `yaml:"variable_files,omitempty" json:"variable_files,omitempty"`
+	VariableMerge *MergeConfig            `yaml:"variable_merge,omitempty" json:"variable_merge,omitempty"`
+	Variables     map[string]interface{}  `yaml:"vars,omitempty" json:"vars,omitempty"`
+	Secrets       map[string]SecretConfig `yaml:"secrets,omitempty" json:"secrets,omitempty"`
+	Tasks         []TaskConfig            `yaml:"tasks" json:"tasks"`
+	Outputs       map[string]interface{}  `yaml:"outputs,omitempty" json:"outputs,omitempty"` // evaluated after all tasks finish
+	OnSuccess     []TaskConfig            `yaml:"on_success,omitempty" json:"on_success,omitempty"`
+	OnFailure     []TaskConfig            `yaml:"on_failure,omitempty" json:"on_failure,omitempty"`
+
+	// Extensions holds top-level keys starting with x-, which ritual ignores,
+	// such as a block of anchors for tasks to merge in with <<
+	Extensions map[string]interface{} `yaml:",inline" json:"-"`
+
+	// Source records where each key and list item was written, when the
+	// workflow was parsed from YAML
+	Source SourceMap `yaml:"-" json:"-"`
+}
+
+// InputConfig declares a value the workflow expects from whoever runs it,
+// given with --var, --var-file or a webhook's variables. Resolved inputs are
+// available as .vars.<name>.
+type InputConfig struct {
+	Type        string        `yaml:"type,omitempty" json:"type,omitempty"` // string (default), int, bool, list, map or enum
+	Default     interface{}   `yaml:"default,omitempty" json:"default,omitempty"`
+	Required    bool          `yaml:"required,omitempty" json:"required,omitempty"`
+	Description string        `yaml:"description,omitempty" json:"description,omitempty"`
+	Values      []interface{} `yaml:"values,omitempty" json:"values,omitempty"` // allowed values of an enum
+}
+
+// MergeConfig controls how values from later variable files are merged into
+// those from earlier ones. Maps are merged key by key unless Mode is
+// "shallow"; lists are replaced unless Lists, or Paths for a dotted path,
+// says "append" or "merge_by_key".
+type MergeConfig struct {
+	Mode     string            `yaml:"mode,omitempty" json:"mode,omitempty"`           // deep (default) or shallow
+	Lists    string            `yaml:"lists,omitempty" json:"lists,omitempty"`         // replace (default), append or merge_by_key
+	MergeKey string            `yaml:"merge_key,omitempty" json:"merge_key,omitempty"` // identifies list items for merge_by_key (default "name")
+	Paths    map[string]string `yaml:"paths,omitempty" json:"paths,omitempty"`         // list strategy by dotted path
+}
+
+// SecretConfig declares a named secret and the provider it is read from.
+// The remaining keys are options for the provider, such as a file path or
+// the environment variable to read.
+type SecretConfig struct {
+	Provider string                 `yaml:"provider" json:"provider"`
+	Options  map[string]interface{} `yaml:",inline" json:"options,omitempty"`
 }
 
 // TaskConfig represents a task definition in the workflow
 type TaskConfig struct {
-	ID         string                 `yaml:"id,omitempty" json:"id,omitempty"`
-	Name       string                 `yaml:"name" json:"name"`
-	Type       string                 `yaml:"type,omitempty" json:"type,omitempty"`
-	Config     map[string]interface{} `yaml:",inline" json:"config"`
-	DependsOn  []string               `yaml:"depends_on,omitempty" json:"depends_on,omitempty"`
-	When       string                 `yaml:"when,omitempty" json:"when,omitempty"`
-	Required   *bool                  `yaml:"required,omitempty" json:"required,omitempty"`
-	AlwaysRun  bool                   `yaml:"always_run,omitempty" json:"always_run,omitempty"`
-	Register   string                 `yaml:"register,omitempty" json:"register,omitempty"`
-	RetryCount int                    `yaml:"retry_count,omitempty" json:"retry_count,omitempty"`
-	RetryDelay time.Duration          `yaml:"retry_delay,omitempty" json:"retry_delay,omitempty"`
+	ID          string                 `yaml:"id,omitempty" json:"id,omitempty"`
+	Name        string                 `yaml:"name" json:"name"`
+	Type        string                 `yaml:"type,omitempty" json:"type,omitempty"`
+	Config      map[string]interface{} `yaml:",inline" json:"config"`
+	DependsOn   []string               `yaml:"depends_on,omitempty" json:"depends_on,omitempty"`
+	When        string                 `yaml:"when,omitempty" json:"when,omitempty"`
+	TriggerRule TriggerRule            `yaml:"trigger_rule,omitempty" json:"trigger_rule,omitempty"`
+	Required    *bool                  `yaml:"required,omitempty" json:"required,omitempty"`
+	AlwaysRun   bool                   `yaml:"always_run,omitempty" json:"always_run,omitempty"`
+	Register    string                 `yaml:"register,omitempty" json:"register,omitempty"`
+	RetryCount  int                    `yaml:"retry_count,omitempty" json:"retry_count,omitempty"`
+	RetryDelay  time.Duration          `yaml:"retry_delay,omitempty" json:"retry_delay,omitempty"`
+
+	// Source records where the task and each of its keys were written,
+	// relative to the task, so it stays with the task through imports
+	Source SourceMap `yaml:"-" json:"-"`
 }
 
 // IsRequired returns whether this task is required for workflow success
@@ -107,6 +177,7 @@ type TaskResult struct {
 	Name         string                 `json:"name"`
 	Type         string                 `json:"type"`
 	Status       TaskStatus             `json:"status"`
+	Changed      bool                   `json:"changed"`
 	Message      string                 `json:"message,omitempty"`
 	Output       map[string]interface{} `json:"output,omitempty"`
 	Stdout       string                 `json:"stdout,omitempty"`
@@ -117,6 +188,10 @@ type TaskResult struct {
 	EndTime      time.Time              `json:"end_time"`
 	Duration     time.Duration          `json:"duration"`
 	AttemptCount int                    `json:"attempt_count"`
+
+	// ChangeTracked is set when the task's executor reports changes, so an
+	// unchanged result means nothing needed changing
+	ChangeTracked bool `json:"change_tracked,omitempty"`
 }
 
 // WorkflowResult represents the overall result of executing a workflow
@@ -129,6 +204,7 @@ type WorkflowResult struct {
 	Duration  time.Duration          `json:"duration"`
 	Error     string                 `json:"error,omitempty"`
 	Variables map[string]interface{} `json:"variables,omitempty"`
+	Outputs   map[string]interface{} `json:"outputs,omitempty"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
 }
 
@@ -139,6 +215,16 @@ type WorkflowContext struct {
 	Tasks       map[string]*TaskResult // Task results by ID/name
 	Imports     map[string]*Workflow   // Imported workflows by name
 	Metadata    map[string]interface{} // Additional metadata
+	Secrets     SecretResolver         // Named secrets, resolved only when a template asks for one
+}
+
+// SecretResolver looks up declared secrets on demand. Secret values are never
+// copied into the context's variables.
+type SecretResolver interface {
+	// Resolve returns the value of the named secret
+	Resolve(name string) (string, error)
+	// Names returns the declared secret names
+	Names() []string
 }
 
 // NewWorkflowContext creates a new workflow context
@@ -251,6 +337,70 @@ type TaskExecutor interface {
 	SupportsDryRun() bool
 }
 
+// OutputDescriber is implemented by task executors that declare the keys they
+// may set in TaskResult.Output, so templates reading .tasks.<id>.Output can be
+// checked before a workflow runs
+type OutputDescriber interface {
+	OutputFields() []string
+}
+
+// ChangeReporter is implemented by task executors that set TaskResult.Changed
+// only when they changed something, so their other successful results mean
+// nothing needed changing
+type ChangeReporter interface {
+	ReportsChange() bool
+}
+
+// SecretFieldDescriber is implemented by task executors with config fields
+// that hold credentials. The rendered values of those fields are masked in
+// logs, task results and history.
+type SecretFieldDescriber interface {
+	SecretFields() []string
+}
+
+// Field types of a FieldSchema. Scalars other than strings may also be given
+// as a template that renders to the type.
+const (
+	FieldString     = "string"
+	FieldInteger    = "integer"
+	FieldNumber     = "number"
+	FieldBoolean    = "boolean"
+	FieldDuration   = "duration"    // "30s", "5m", or a number of seconds
+	FieldStringList = "string_list" // a string or a list of strings
+	FieldStringMap  = "string_map"  // a map of names to strings
+	FieldList       = "list"        // a list of Items
+	FieldObject     = "object"      // a map with the given Fields, or any keys when there are none
+	FieldAny        = "any"
+)
+
+// FieldSchema describes one field of a task's config
+type FieldSchema struct {
+	Name        string
+	Type        string
+	Description string
+	Required    bool
+	Enum        []string
+	Default     interface{}
+	Items       *FieldSchema  // element of a FieldList
+	Fields      []FieldSchema // keys of a FieldObject
+}
+
+// ConfigSchema describes the config fields a task type accepts
+type ConfigSchema struct {
+	Description string
+	Fields      []FieldSchema
+
+	// OneOf lists groups of fields of which exactly one must be set, such
+	// as a command task's command and script
+	OneOf [][]string
+}
+
+// ConfigSchemaDescriber is implemented by task executors that describe
+// their config, so workflow files can be checked and completed by editors
+type ConfigSchemaDescriber interface {
+	ConfigSchema() *ConfigSchema
+}
+
 // ContextManager manages workflow execution context and variable resolution
 type ContextManager interface {
 	// Initialize sets up the initial context from workflow and environment
@@ -1,9 +1,10 @@
 // ABOUTME: Tests for core types validation functions
-// ABOUTME: Validates concurrency constraints and error handling
+// ABOUTME: Validates concurrency constraints, error handling and source positions
 
 package types
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 )
@@ -112,3 +113,68 @@ func TestConcurrencyConstants(t *testing.T) {
 		t.Errorf("DefaultConcurrency (%d) seems unreasonable", DefaultConcurrency)
 	}
 }
+
+func TestSourceMapLookup(t *testing.T) {
+	source := SourceMap{
+		"":          {File: "wf.yaml", Line: 4, Column: 5},
+		"args":      {File: "wf.yaml", Line: 6, Column: 5},
+		"args[1]":   {File: "wf.yaml", Line: 8, Column: 9},
+		"env.DEBUG": {File: "wf.yaml", Line: 10, Column: 7},
+	}
+
+	tests := []struct {
+		path string
+		line int
+	}{
+		{"args[1]", 8},
+		{"args[0]", 6},
+		{"env.DEBUG", 10},
+		{"env.OTHER", 4},
+		{"state", 4},
+	}
+	for _, tt := range tests {
+		if pos := source.Lookup(tt.path); pos.Line != tt.line {
+			t.Errorf("Lookup(%q): expected line %d, got %d", tt.path, tt.line, pos.Line)
+		}
+	}
+
+	var empty SourceMap
+	if pos := empty.Lookup("tasks[0]"); pos.IsValid() {
+		t.Errorf("Expected no position from an empty map, got %v", pos)
+	}
+}
+
+func TestSourcePositionString(t *testing.T) {
+	tests := []struct {
+		pos      SourcePosition
+		expected string
+	}{
+		{SourcePosition{File: "wf.yaml", Line: 3, Column: 7}, "wf.yaml:3:7"},
+		{SourcePosition{File: "wf.yaml", Line: 3}, "wf.yaml:3"},
+		{SourcePosition{Line: 3, Column: 7}, "3:7"},
+		{SourcePosition{File: "wf.yaml"}, "wf.yaml"},
+	}
+	for _, tt := range tests {
+		if got := tt.pos.String(); got != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, got)
+		}
+	}
+}
+
+func TestErrorPosition(t *testing.T) {
+	pos := SourcePosition{File: "wf.yaml", Line: 12, Column: 5}
+	validationErr := NewValidationError("state", "presnt", "invalid state").At(pos)
+	if !strings.Contains(validationErr.Error(), "at wf.yaml:12:5") {
+		t.Errorf("Expected position in error, got %q", validationErr.Error())
+	}
+
+	// The position is found through wrapping, skipping errors without one
+	wrapped := fmt.Errorf("validation failed: %w", NewParseError("wf.yaml", 0, 0, "failed to parse workflow", validationErr))
+	if got, ok := ErrorPosition(wrapped); !ok || got != pos {
+		t.Errorf("Expected position %v, got %v (%v)", pos, got, ok)
+	}
+
+	if _, ok := ErrorPosition(NewTaskError("a", "A", "command", "failed", nil)); ok {
+		t.Error("Expected no position for an error without one")
+	}
+}